@@ -0,0 +1,94 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultAdmissionInterval is how often the fill ratio is refreshed by default
+const defaultAdmissionInterval = 10 * time.Second
+
+// Admission configures admission control. Once the share of set bits exceeds MaxFill,
+// inserts fail with a *SaturationError instead of silently driving the false-positive
+// rate towards one. A filter at its expected insertions has a fill ratio of about 0.5.
+type Admission struct {
+	// MaxFill is the fill ratio above which inserts are refused
+	MaxFill float64
+	// CheckInterval is how often the fill ratio is refreshed with BITCOUNT (defaults to 10s)
+	CheckInterval time.Duration
+	// Override admits inserts beyond MaxFill, still counting them, for example while the
+	// filter is being resized
+	Override bool
+}
+
+// SaturationError is returned for inserts refused by admission control
+type SaturationError struct {
+	Key     string
+	Fill    float64
+	MaxFill float64
+}
+
+// Error describes the saturated filter
+func (e *SaturationError) Error() string {
+	return fmt.Sprintf("filter %s is saturated: fill ratio %.3f exceeds %.3f", e.Key, e.Fill, e.MaxFill)
+}
+
+// Unwrap returns ErrFilterSaturated
+func (e *SaturationError) Unwrap() error {
+	return ErrFilterSaturated
+}
+
+// admission caches the fill ratio between BITCOUNT refreshes
+type admission struct {
+	cfg     Admission
+	mu      sync.Mutex
+	fill    float64
+	checked time.Time
+}
+
+// newAdmission creates the admission state for an Admission, or nil if it is disabled
+func newAdmission(cfg *Admission) *admission {
+	if cfg == nil || cfg.MaxFill <= 0 {
+		return nil
+	}
+	a := &admission{cfg: *cfg}
+	if a.cfg.CheckInterval <= 0 {
+		a.cfg.CheckInterval = defaultAdmissionInterval
+	}
+	return a
+}
+
+// admit returns a *SaturationError if the filter is too full to accept inserts
+func (bf *bloomFilter) admit(ctx context.Context) error {
+	a := bf.admission
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if time.Since(a.checked) >= a.cfg.CheckInterval {
+		client, err := bf.cmdable()
+		if err != nil {
+			return err
+		}
+		set, err := client.BitCount(ctx, bf.config.RedisKey, nil).Result()
+		if err != nil {
+			return err
+		}
+		a.fill, a.checked = float64(set)/float64(bf.bitSize), time.Now()
+		bf.metrics.SetGauge(MetricFillRatio, a.fill)
+	}
+
+	if a.fill <= a.cfg.MaxFill {
+		return nil
+	}
+	if a.cfg.Override {
+		bf.metrics.IncCounter(MetricAdmissionOverrides, 1)
+		return nil
+	}
+	bf.metrics.IncCounter(MetricAdmissionRejections, 1)
+	return &SaturationError{Key: bf.config.RedisKey, Fill: a.fill, MaxFill: a.cfg.MaxFill}
+}
@@ -0,0 +1,176 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Advisor defaults
+const (
+	defaultAdvisorGrowth = 2.0
+	// maxRedisStringBits is the largest bitmap a single Redis string can hold (512 MB)
+	maxRedisStringBits = uint64(512) << 20 * 8
+)
+
+// MigrationStrategy is how a resized filter should be populated
+type MigrationStrategy int
+
+const (
+	// MigrationNone means the filter does not need resizing
+	MigrationNone MigrationStrategy = iota
+	// MigrationReplayAudit rebuilds the filter from its audit stream (RebuildFromAudit)
+	MigrationReplayAudit
+	// MigrationRebuildFromSource rebuilds the filter from the system of record
+	MigrationRebuildFromSource
+)
+
+// String returns the name of the migration strategy
+func (s MigrationStrategy) String() string {
+	switch s {
+	case MigrationReplayAudit:
+		return "replay-audit"
+	case MigrationRebuildFromSource:
+		return "rebuild-from-source"
+	default:
+		return "none"
+	}
+}
+
+// AdvisorOptions configures an Advisor
+type AdvisorOptions struct {
+	// TargetFalsePositiveRate is the rate the plan sizes for (defaults to the filter's configured rate)
+	TargetFalsePositiveRate float64
+	// Growth is the headroom factor applied to the estimated insertions (defaults to 2)
+	Growth float64
+}
+
+// Advisor inspects a live filter and recommends how to resize it
+type Advisor struct {
+	opts AdvisorOptions
+}
+
+// ResizePlan is an Advisor's recommendation for a filter
+type ResizePlan struct {
+	Key string
+	// Current state
+	BitSize                  uint64
+	HashCount                uint
+	ExpectedInsertions       uint64
+	EstimatedInsertions      uint64
+	FillRatio                float64
+	CurrentFalsePositiveRate float64
+	TargetFalsePositiveRate  float64
+	// Recommendation
+	NeedsResize           bool
+	NewExpectedInsertions uint64
+	NewBitSize            uint64
+	NewHashCount          uint
+	// MemoryDeltaBytes is the change in bitmap size; negative when the filter shrinks
+	MemoryDeltaBytes int64
+	// Chunks is the number of 512 MB Redis strings the new bitmap needs; more than one
+	// means it must be split across several filters
+	Chunks    int
+	Migration MigrationStrategy
+}
+
+// NeedsChunking reports whether the new bitmap exceeds a single Redis string
+func (p *ResizePlan) NeedsChunking() bool {
+	return p.Chunks > 1
+}
+
+// NewAdvisor creates an advisor
+func NewAdvisor(opts AdvisorOptions) *Advisor {
+	if opts.Growth <= 1 {
+		opts.Growth = defaultAdvisorGrowth
+	}
+	return &Advisor{opts: opts}
+}
+
+// Plan measures the filter's fill ratio with BITCOUNT, estimates the number of distinct
+// items inserted and recommends new parameters when the filter exceeds its target rate
+// or its expected insertions
+func (a *Advisor) Plan(ctx context.Context, filter BloomFilter) (*ResizePlan, error) {
+	bf, ok := filter.(*bloomFilter)
+	if !ok || !bf.usesBitmap() {
+		return nil, ErrIncompatibleFilter
+	}
+	fill, err := bf.fillRatio(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	target := a.opts.TargetFalsePositiveRate
+	if target <= 0 || target >= 1 {
+		target = bf.config.FalsePositiveRate
+	}
+	plan := &ResizePlan{
+		Key:                      bf.config.RedisKey,
+		BitSize:                  bf.bitSize,
+		HashCount:                bf.hashCount,
+		ExpectedInsertions:       bf.config.ExpectedInsertions,
+		EstimatedInsertions:      estimateInsertions(bf.bitSize, bf.hashCount, fill),
+		FillRatio:                fill,
+		CurrentFalsePositiveRate: falsePositiveRate(fill, bf.hashCount),
+		TargetFalsePositiveRate:  target,
+	}
+
+	plan.NeedsResize = plan.CurrentFalsePositiveRate > target || plan.EstimatedInsertions > plan.ExpectedInsertions
+	if !plan.NeedsResize {
+		return plan, nil
+	}
+
+	base := plan.EstimatedInsertions
+	if fill >= 1 {
+		// A saturated filter only bounds its insertions from below: at least as many as
+		// leave a single bit unset
+		base = estimateInsertions(bf.bitSize, bf.hashCount, 1-1/float64(bf.bitSize))
+	}
+	if plan.ExpectedInsertions > base {
+		base = plan.ExpectedInsertions
+	}
+	plan.NewExpectedInsertions = uint64(math.Ceil(float64(base) * a.opts.Growth))
+	plan.NewBitSize, plan.NewHashCount = EstimateParameters(plan.NewExpectedInsertions, target)
+	plan.MemoryDeltaBytes = int64(bf.config.BitLayout.byteSize(plan.NewBitSize)) - int64(bf.config.BitLayout.byteSize(plan.BitSize))
+	plan.Chunks = int((plan.NewBitSize + maxRedisStringBits - 1) / maxRedisStringBits)
+	plan.Migration = MigrationRebuildFromSource
+	if bf.config.Audit != nil && bf.config.Audit.RawItems {
+		plan.Migration = MigrationReplayAudit
+	}
+	return plan, nil
+}
+
+// estimateInsertions estimates the distinct items inserted from the fill ratio:
+// n = -(m / k) * ln(1 - fill)
+func estimateInsertions(bitSize uint64, hashCount uint, fill float64) uint64 {
+	if fill >= 1 {
+		return math.MaxUint64
+	}
+	return uint64(math.Round(-float64(bitSize) / float64(hashCount) * math.Log(1-fill)))
+}
+
+// String renders the plan as a human-readable report
+func (p *ResizePlan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "filter %s: %d bits, %d hashes, sized for %d items\n", p.Key, p.BitSize, p.HashCount, p.ExpectedInsertions)
+	fmt.Fprintf(&b, "fill ratio %.4f, about %d items, false-positive rate %.4g (target %.4g)\n",
+		p.FillRatio, p.EstimatedInsertions, p.CurrentFalsePositiveRate, p.TargetFalsePositiveRate)
+	if !p.NeedsResize {
+		b.WriteString("no resize needed\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "resize to %d bits, %d hashes for %d items (memory %+d bytes)\n",
+		p.NewBitSize, p.NewHashCount, p.NewExpectedInsertions, p.MemoryDeltaBytes)
+	if p.NeedsChunking() {
+		fmt.Fprintf(&b, "the new bitmap exceeds one Redis string; split it across %d filters\n", p.Chunks)
+	}
+	switch p.Migration {
+	case MigrationReplayAudit:
+		b.WriteString("migrate by replaying the audit stream into the new filter (RebuildFromAudit)\n")
+	case MigrationRebuildFromSource:
+		b.WriteString("migrate by rebuilding the new filter from the source data, or rotate to a new key if the source is unavailable\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultAliasRefresh is how long a resolved alias is cached locally
+const defaultAliasRefresh = 5 * time.Second
+
+// AliasedFilter reads and writes the filter generation an alias key points to. The alias
+// is a plain Redis string holding the real key of the current generation, so a rebuild or
+// rotation can publish a new generation atomically with SwapAlias and every reader picks
+// it up within the refresh interval, without being redeployed.
+type AliasedFilter struct {
+	alias   string
+	cfg     Config
+	refresh time.Duration
+
+	mu         sync.Mutex
+	filter     *bloomFilter
+	resolvedAt time.Time
+}
+
+// NewAliasedFilter creates a filter that resolves its key through alias. Every generation
+// uses the parameters in cfg; cfg.RedisKey is ignored. A refresh of zero caches the
+// resolved key for five seconds.
+func NewAliasedFilter(cfg Config, alias string, refresh time.Duration) (*AliasedFilter, error) {
+	if alias == "" {
+		return nil, ErrEmptyRedisKey
+	}
+	if _, ok := cfg.RedisClient.(CmdableProvider); cfg.RedisClient != nil && !ok {
+		return nil, ErrCommandsUnsupported
+	}
+	if refresh <= 0 {
+		refresh = defaultAliasRefresh
+	}
+	// Validate the parameters once with the alias as a placeholder key
+	cfg.RedisKey = alias
+	if _, err := newBloomFilter(cfg); err != nil {
+		return nil, err
+	}
+	return &AliasedFilter{alias: alias, cfg: cfg, refresh: refresh}, nil
+}
+
+// Add adds an element to the current generation
+func (af *AliasedFilter) Add(data []byte) error {
+	filter, err := af.resolve(context.Background())
+	if err != nil {
+		return err
+	}
+	return filter.Add(data)
+}
+
+// Exists checks if an element exists in the current generation
+func (af *AliasedFilter) Exists(data []byte) (bool, error) {
+	filter, err := af.resolve(context.Background())
+	if err != nil {
+		return false, err
+	}
+	return filter.Exists(data)
+}
+
+// Key returns the real key of the current generation
+func (af *AliasedFilter) Key(ctx context.Context) (string, error) {
+	filter, err := af.resolve(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filter.config.RedisKey, nil
+}
+
+// Invalidate drops the locally cached resolution so the next call reads the alias again
+func (af *AliasedFilter) Invalidate() {
+	af.mu.Lock()
+	af.resolvedAt = time.Time{}
+	af.mu.Unlock()
+}
+
+// SwapAlias points the alias at key and returns the key it pointed to before, which is
+// empty if the alias did not exist. This process sees the new generation immediately;
+// other readers see it once their cached resolution expires.
+func (af *AliasedFilter) SwapAlias(ctx context.Context, key string) (string, error) {
+	if key == "" {
+		return "", ErrEmptyRedisKey
+	}
+	client := af.cfg.RedisClient.(CmdableProvider).Cmdable()
+	previous, err := client.SetArgs(ctx, af.alias, key, redis.SetArgs{Get: true}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	af.Invalidate()
+	return previous, nil
+}
+
+// resolve returns the filter of the current generation, reading the alias when the
+// cached resolution has expired
+func (af *AliasedFilter) resolve(ctx context.Context) (*bloomFilter, error) {
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	if af.filter != nil && time.Since(af.resolvedAt) < af.refresh {
+		return af.filter, nil
+	}
+
+	client := af.cfg.RedisClient.(CmdableProvider).Cmdable()
+	key, err := client.Get(ctx, af.alias).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrAliasNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if af.filter == nil || af.filter.config.RedisKey != key {
+		cfg := af.cfg
+		cfg.RedisKey = key
+		filter, err := newBloomFilter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		af.filter = filter
+	}
+	af.resolvedAt = time.Now()
+	return af.filter, nil
+}
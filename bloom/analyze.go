@@ -0,0 +1,147 @@
+package bloom
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AnalyzeOptions configures AnalyzeHashStrategy
+type AnalyzeOptions struct {
+	// ExpectedInsertions and FalsePositiveRate size the simulated filter
+	ExpectedInsertions uint64
+	FalsePositiveRate  float64
+	// Buckets is the number of equal-width buckets positions are counted in (defaults to 1024)
+	Buckets int
+}
+
+// AnalysisReport describes how well a hash strategy handles a sample of real keys
+type AnalysisReport struct {
+	Keys      int
+	BitSize   uint64
+	HashCount uint
+	// ChiSquare and DegreesOfFreedom measure bucket uniformity of the positions;
+	// for a uniform hash ChiSquare stays close to DegreesOfFreedom
+	ChiSquare        float64
+	DegreesOfFreedom int
+	// HashCorrelation is the Pearson correlation of the first two hashes across keys;
+	// double hashing needs them independent, so it should be close to zero
+	HashCorrelation float64
+	// IdenticalPositionSets counts keys that map to exactly the same bits as an earlier key
+	IdenticalPositionSets int
+	// FillRatio is the fraction of bits set after inserting the sample, and
+	// ExpectedFillRatio the fraction an ideal hash would set
+	FillRatio         float64
+	ExpectedFillRatio float64
+	// ProjectedFalsePositiveRate is the false-positive rate of the simulated filter after
+	// inserting the sample, and IdealFalsePositiveRate that of an ideal hash
+	ProjectedFalsePositiveRate float64
+	IdealFalsePositiveRate     float64
+}
+
+// NormalizedChiSquare returns ChiSquare divided by its degrees of freedom,
+// which is close to 1 for a well-distributed strategy
+func (r *AnalysisReport) NormalizedChiSquare() float64 {
+	if r.DegreesOfFreedom == 0 {
+		return 0
+	}
+	return r.ChiSquare / float64(r.DegreesOfFreedom)
+}
+
+// AnalyzeHashStrategy runs a sample of real keys through a strategy and a simulated filter
+// of the given sizing, reporting bucket uniformity, correlation of the two hashes used for
+// double hashing, duplicate position sets and the resulting false-positive rate. It catches
+// degenerate hashing of structured keys such as sequential IDs before they reach production.
+func AnalyzeHashStrategy(strategy HashStrategy, keys [][]byte, opts AnalyzeOptions) (*AnalysisReport, error) {
+	if opts.ExpectedInsertions == 0 {
+		return nil, ErrInvalidExpectedInsertions
+	}
+	if opts.FalsePositiveRate <= 0 || opts.FalsePositiveRate >= 1 {
+		return nil, ErrInvalidFalsePositiveRate
+	}
+	if opts.Buckets <= 1 {
+		opts.Buckets = defaultBenchmarkBuckets
+	}
+
+	bitSize, hashCount := EstimateParameters(opts.ExpectedInsertions, opts.FalsePositiveRate)
+	bf := &bloomFilter{bitSize: bitSize, hashCount: hashCount, hashStrategy: strategy}
+	report := &AnalysisReport{
+		Keys:             len(keys),
+		BitSize:          bitSize,
+		HashCount:        hashCount,
+		DegreesOfFreedom: opts.Buckets - 1,
+	}
+
+	counts := make([]uint64, opts.Buckets)
+	bitmap := make([]uint64, (bitSize+63)/64)
+	seen := make(map[string]struct{}, len(keys))
+	h1s := make([]float64, len(keys))
+	h2s := make([]float64, len(keys))
+	total, set := 0, uint64(0)
+	for i, key := range keys {
+		positions := bf.getHashPositions(key)
+		for _, pos := range positions {
+			counts[pos*uint64(opts.Buckets)/bitSize]++
+			if bitmap[pos/64]&(1<<(pos%64)) == 0 {
+				bitmap[pos/64] |= 1 << (pos % 64)
+				set++
+			}
+		}
+		total += len(positions)
+
+		signature := positionSignature(positions)
+		if _, dup := seen[signature]; dup {
+			report.IdenticalPositionSets++
+		}
+		seen[signature] = struct{}{}
+
+		h1s[i] = float64(strategy.Hash(key, 0)) / math.MaxUint64
+		h2s[i] = float64(strategy.Hash(key, 1)) / math.MaxUint64
+	}
+
+	report.ChiSquare = chiSquareUniform(counts, total)
+	report.HashCorrelation = pearson(h1s, h2s)
+	report.FillRatio = float64(set) / float64(bitSize)
+	report.ExpectedFillRatio = 1 - math.Exp(-float64(hashCount)*float64(len(keys))/float64(bitSize))
+	report.ProjectedFalsePositiveRate = math.Pow(report.FillRatio, float64(hashCount))
+	report.IdealFalsePositiveRate = math.Pow(report.ExpectedFillRatio, float64(hashCount))
+	return report, nil
+}
+
+// positionSignature encodes the set of positions of a key independent of their order
+func positionSignature(positions []uint64) string {
+	sorted := append([]uint64(nil), positions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var b strings.Builder
+	for _, pos := range sorted {
+		b.WriteString(strconv.FormatUint(pos, 36))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// pearson computes the Pearson correlation coefficient of two samples
+func pearson(x, y []float64) float64 {
+	n := float64(len(x))
+	if n < 2 {
+		return 0
+	}
+	var sx, sy float64
+	for i := range x {
+		sx += x[i]
+		sy += y[i]
+	}
+	mx, my := sx/n, sy/n
+	var cov, vx, vy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(vx*vy)
+}
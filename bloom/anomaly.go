@@ -0,0 +1,119 @@
+package bloom
+
+import (
+	"sync"
+	"time"
+)
+
+// Insert-rate anomaly detection defaults
+const (
+	defaultRateWindow        = time.Minute
+	defaultRateFactor        = 10
+	defaultRateWarmup        = 5
+	insertRateBaselineWeight = 0.1
+)
+
+// InsertRateAlert configures insert-rate anomaly detection. Inserts are counted in
+// consecutive windows, and the rate of each window is compared to a moving baseline of
+// the windows before it. A sudden spike usually means an upstream bug replaying or
+// duplicating keys, which would otherwise go unnoticed until the filter saturates.
+type InsertRateAlert struct {
+	// Window is the length of a counting window (defaults to one minute)
+	Window time.Duration
+	// Factor is how many times the baseline rate counts as a spike (defaults to 10)
+	Factor float64
+	// WarmupWindows is the number of windows observed before alerts fire (defaults to 5)
+	WarmupWindows int
+	// OnAnomaly is called once per window in which the rate exceeds the threshold
+	OnAnomaly func(InsertRateAnomaly)
+}
+
+// InsertRateAnomaly describes an insert-rate spike
+type InsertRateAnomaly struct {
+	Key string
+	// Rate is the number of inserts so far in the current window, per second of the window
+	Rate float64
+	// Baseline is the moving average of earlier windows, in items per second
+	Baseline float64
+	At       time.Time
+}
+
+// insertRate counts inserts per window and tracks the baseline rate
+type insertRate struct {
+	cfg      InsertRateAlert
+	mu       sync.Mutex
+	start    time.Time
+	count    int64
+	baseline float64
+	windows  int
+	alerted  bool
+}
+
+// newInsertRate creates the detector for an InsertRateAlert, or nil if it is disabled
+func newInsertRate(cfg *InsertRateAlert) *insertRate {
+	if cfg == nil {
+		return nil
+	}
+	r := &insertRate{cfg: *cfg, start: time.Now()}
+	if r.cfg.Window <= 0 {
+		r.cfg.Window = defaultRateWindow
+	}
+	if r.cfg.Factor <= 1 {
+		r.cfg.Factor = defaultRateFactor
+	}
+	if r.cfg.WarmupWindows <= 0 {
+		r.cfg.WarmupWindows = defaultRateWarmup
+	}
+	return r
+}
+
+// recordInserts counts n inserts and reports a spike through the metrics and callback
+func (bf *bloomFilter) recordInserts(n int) {
+	r := bf.insertRate
+	if r == nil || n <= 0 {
+		return
+	}
+	now := time.Now()
+	window := r.cfg.Window.Seconds()
+
+	r.mu.Lock()
+	// Close the windows that have ended, folding their rates into the baseline
+	closed := false
+	for now.Sub(r.start) >= r.cfg.Window {
+		rate := float64(r.count) / window
+		if r.windows == 0 {
+			r.baseline = rate
+		} else {
+			r.baseline = insertRateBaselineWeight*rate + (1-insertRateBaselineWeight)*r.baseline
+		}
+		r.windows++
+		r.start = r.start.Add(r.cfg.Window)
+		r.count, r.alerted, closed = 0, false, true
+	}
+	if closed {
+		bf.metrics.SetGauge(MetricInsertRateBaseline, r.baseline)
+	}
+	r.count += int64(n)
+
+	// Compare the count so far against the whole-window threshold, so spikes are
+	// reported as soon as they exceed it rather than when the window ends
+	var anomaly *InsertRateAnomaly
+	if !r.alerted && r.windows >= r.cfg.WarmupWindows && r.baseline > 0 &&
+		float64(r.count) > r.cfg.Factor*r.baseline*window {
+		r.alerted = true
+		anomaly = &InsertRateAnomaly{
+			Key:      bf.config.RedisKey,
+			Rate:     float64(r.count) / window,
+			Baseline: r.baseline,
+			At:       now,
+		}
+	}
+	r.mu.Unlock()
+
+	if anomaly != nil {
+		bf.metrics.IncCounter(MetricInsertRateAnomalies, 1)
+		if r.cfg.OnAnomaly != nil {
+			r.cfg.OnAnomaly(*anomaly)
+		}
+	}
+}
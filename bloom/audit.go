@@ -0,0 +1,162 @@
+package bloom
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spaolacci/murmur3"
+)
+
+// Audit stream layout
+const (
+	auditKeySuffix    = "audit"
+	auditFieldItem    = "item"
+	auditFieldHash    = "hash"
+	defaultAuditLimit = 1_000_000
+)
+
+// Audit configures a capped Redis Stream that receives an entry for every Add, giving
+// downstream systems an insertion log to consume and a source to rebuild the filter from
+type Audit struct {
+	// Stream is the stream key (defaults to a companion key of the filter in the same slot)
+	Stream string
+	// MaxLen approximately caps the stream length (defaults to 1000000)
+	MaxLen int64
+	// RawItems records the items themselves; otherwise only their 128-bit hashes are
+	// recorded, which keeps the log compact but cannot be replayed into a filter
+	RawItems bool
+}
+
+// auditStream returns the stream key of the filter's audit log
+func (bf *bloomFilter) auditStream() string {
+	if bf.config.Audit.Stream != "" {
+		return bf.config.Audit.Stream
+	}
+	return companionKey(bf.config.RedisKey, auditKeySuffix)
+}
+
+// appendAudit records insertions in the audit stream in one round trip
+func (bf *bloomFilter) appendAudit(ctx context.Context, items ...[]byte) error {
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	if len(items) == 1 {
+		return client.XAdd(ctx, bf.auditEntry(items[0])).Err()
+	}
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, data := range items {
+			pipe.XAdd(ctx, bf.auditEntry(data))
+		}
+		return nil
+	})
+	return err
+}
+
+// auditEntry builds the stream entry recording an insertion
+func (bf *bloomFilter) auditEntry(data []byte) *redis.XAddArgs {
+	maxLen := bf.config.Audit.MaxLen
+	if maxLen <= 0 {
+		maxLen = defaultAuditLimit
+	}
+	args := &redis.XAddArgs{Stream: bf.auditStream(), MaxLen: maxLen, Approx: true}
+	if bf.config.Audit.RawItems {
+		args.Values = []interface{}{auditFieldItem, data}
+	} else {
+		h1, h2 := murmur3.Sum128(data)
+		var sum [16]byte
+		putUint128(sum[:], h1, h2)
+		args.Values = []interface{}{auditFieldHash, hex.EncodeToString(sum[:])}
+	}
+	return args
+}
+
+// auditPageSize is the number of stream entries read per XRANGE while replaying
+const auditPageSize = 1000
+
+// auditIterator yields the raw items recorded in an audit stream
+type auditIterator struct {
+	ctx     context.Context
+	client  redis.Cmdable
+	stream  string
+	start   string
+	page    []redis.XMessage
+	done    bool
+	pending int
+}
+
+// NewAuditIterator creates an Iterator over the items recorded in an audit stream, in
+// insertion order. Next fails with ErrAuditNotReplayable on entries that only hold a hash.
+func NewAuditIterator(ctx context.Context, client redis.Cmdable, stream string) Iterator {
+	return &auditIterator{ctx: ctx, client: client, stream: stream, start: "-"}
+}
+
+// Next returns the next recorded item or io.EOF
+func (it *auditIterator) Next() ([]byte, error) {
+	for it.pending >= len(it.page) {
+		if it.done {
+			return nil, io.EOF
+		}
+		page, err := it.client.XRangeN(it.ctx, it.stream, it.start, "+", auditPageSize).Result()
+		if err != nil {
+			return nil, err
+		}
+		it.page, it.pending = page, 0
+		if len(page) < auditPageSize {
+			it.done = true
+		}
+		if len(page) > 0 {
+			it.start = "(" + page[len(page)-1].ID
+		}
+	}
+
+	msg := it.page[it.pending]
+	it.pending++
+	item, ok := msg.Values[auditFieldItem].(string)
+	if !ok {
+		return nil, ErrAuditNotReplayable
+	}
+	return []byte(item), nil
+}
+
+// RebuildFromAudit replays the raw items recorded in an audit stream into a fresh filter
+// built from cfg, so a filter can be resized or re-hashed after its source data is gone.
+// The bits are computed locally and swapped in atomically, replacing any filter stored at
+// cfg.RedisKey; items added to the stream during the replay are not included.
+func RebuildFromAudit(ctx context.Context, stream string, cfg Config) (BloomFilter, error) {
+	bf, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !bf.usesBitmap() {
+		return nil, ErrIncompatibleFilter
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	bitmap := make([]byte, cfg.BitLayout.byteSize(bf.bitSize))
+	source := NewAuditIterator(ctx, client, stream)
+	for {
+		item, err := source.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, offset := range bf.Positions(item) {
+			setBitmapBit(bitmap, offset)
+		}
+	}
+
+	if err := replaceBitmap(ctx, client, cfg.RedisKey, bitmap, cfg.TTL, bf.parameterFields()); err != nil {
+		return nil, err
+	}
+	bf.metadataRecorded = 1
+	return bf, nil
+}
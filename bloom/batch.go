@@ -0,0 +1,288 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// addBatchScript sets every offset in ARGV[2..] on KEYS[1], remembering the bits it
+// changed. If any write fails the changed bits are cleared again before the error is
+// returned, so the batch is either fully applied or absent. The TTL in ARGV[1] is
+// applied to the bitmap and the metadata key KEYS[2] as described for luaExpireSource.
+var addBatchScript = redis.NewScript(`
+local changed = {}
+for i = 2, #ARGV do
+	local prev = redis.pcall('SETBIT', KEYS[1], ARGV[i], 1)
+	if type(prev) == 'table' and prev.err then
+		for j = #changed, 1, -1 do
+			redis.call('SETBIT', KEYS[1], changed[j], 0)
+		end
+		return prev
+	end
+	if prev == 0 then
+		changed[#changed + 1] = ARGV[i]
+	end
+end
+` + luaExpireSource + `
+return #changed
+`)
+
+// addBatchItemsScript adds a batch of items to KEYS[1], each item atomically. ARGV[1] is
+// a TTL in milliseconds and is followed, for every item, by its number of offsets and then
+// the offsets themselves. When a write fails, the bits the item changed are cleared again
+// and its error message is reported in its place in the returned table; applied items
+// report 1. The TTL is applied as for addBatchScript.
+var addBatchItemsScript = redis.NewScript(`
+local results = {}
+local i = 2
+while i <= #ARGV do
+	local n = tonumber(ARGV[i])
+	local changed = {}
+	local failure
+	for j = i + 1, i + n do
+		local prev = redis.pcall('SETBIT', KEYS[1], ARGV[j], 1)
+		if type(prev) == 'table' and prev.err then
+			failure = prev.err
+			break
+		end
+		if prev == 0 then
+			changed[#changed + 1] = ARGV[j]
+		end
+	end
+	if failure then
+		for j = #changed, 1, -1 do
+			redis.call('SETBIT', KEYS[1], changed[j], 0)
+		end
+		results[#results + 1] = failure
+	else
+		results[#results + 1] = 1
+	end
+	i = i + n + 1
+end
+` + luaExpireSource + `
+return results
+`)
+
+// batchRetries is the number of times AddBatchPartial retries items whose writes failed
+const batchRetries = 1
+
+// BatchOutcome is the outcome of adding one item of a batch
+type BatchOutcome int
+
+const (
+	// BatchApplied means the item was added on the first attempt
+	BatchApplied BatchOutcome = iota
+	// BatchRetried means the item was added after its first attempt failed
+	BatchRetried
+	// BatchFailed means every attempt failed and none of the item's bits were left set
+	BatchFailed
+)
+
+// String returns the name of the outcome
+func (o BatchOutcome) String() string {
+	switch o {
+	case BatchApplied:
+		return "applied"
+	case BatchRetried:
+		return "retried"
+	default:
+		return "failed"
+	}
+}
+
+// BatchItemResult is the outcome of adding one item of a batch
+type BatchItemResult struct {
+	Outcome BatchOutcome
+	// Err is the error of the last attempt of a failed item
+	Err error
+}
+
+// BatchResult reports the outcome of every item of a batch, in input order
+type BatchResult struct {
+	Items []BatchItemResult
+}
+
+// Failed returns the indexes of the items that could not be added
+func (r *BatchResult) Failed() []int {
+	var failed []int
+	for i, item := range r.Items {
+		if item.Outcome == BatchFailed {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// Count returns the number of items with the given outcome
+func (r *BatchResult) Count(outcome BatchOutcome) int {
+	var n int
+	for _, item := range r.Items {
+		if item.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// AddBatch adds all elements atomically: a Lua script sets their bits and reverts the
+// bits it changed if any write fails, so the batch is either fully applied or absent.
+// Module filters add the batch with one BF.MADD, which is equally all-or-nothing.
+func (bf *bloomFilter) AddBatch(items [][]byte) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	if !bf.usesBitmap() {
+		return bf.addItems(ctx, items)
+	}
+	if !bf.config.Capabilities.Supports(FeatureLua) {
+		return ErrLuaUnsupported
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+
+	args := make([]interface{}, 1, 1+len(items)*int(bf.hashCount))
+	args[0] = bf.scriptTTL()
+	for _, data := range items {
+		for _, pos := range bf.getHashPositions(data) {
+			args = append(args, bf.offset(pos))
+		}
+	}
+	if err := bf.admit(ctx); err != nil {
+		return err
+	}
+	if err := bf.limiter.wait(ctx, len(args)-1); err != nil {
+		return err
+	}
+	if err := bf.recordCreation(ctx); err != nil {
+		return err
+	}
+
+	keys := []string{bf.config.RedisKey, metadataKey(bf.config.RedisKey)}
+	if err := addBatchScript.Run(ctx, client, keys, args...).Err(); err != nil {
+		return err
+	}
+	return bf.afterAdd(ctx, items...)
+}
+
+// AddBatchPartial adds all elements in one Lua script call without making the batch
+// atomic: each element is added atomically on its own, so if any of its writes fails
+// the bits it changed are reverted, while the other elements stay added. Failed elements
+// are retried once. The result reports the outcome of every element; the error is
+// ErrBatchIncomplete if some elements failed, or the cause if the batch as a whole
+// failed, in which case the result is nil. Module filters add the batch with one
+// BF.MADD, which either applies every element or fails as a whole.
+func (bf *bloomFilter) AddBatchPartial(items [][]byte) (*BatchResult, error) {
+	result := &BatchResult{Items: make([]BatchItemResult, len(items))}
+	if len(items) == 0 {
+		return result, nil
+	}
+	ctx := context.Background()
+	if !bf.usesBitmap() {
+		if err := bf.addItems(ctx, items); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	if !bf.config.Capabilities.Supports(FeatureLua) {
+		return nil, ErrLuaUnsupported
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+	if err := bf.admit(ctx); err != nil {
+		return nil, err
+	}
+	if err := bf.recordCreation(ctx); err != nil {
+		return nil, err
+	}
+
+	pending := make([]int, len(items))
+	for i := range items {
+		pending[i] = i
+	}
+	for attempt := 0; attempt <= batchRetries && len(pending) > 0; attempt++ {
+		failures, err := bf.runAddBatch(ctx, client, items, pending)
+		if err != nil {
+			if attempt == 0 {
+				return nil, err
+			}
+			for _, i := range pending {
+				result.Items[i].Err = err
+			}
+			break
+		}
+
+		var failed []int
+		for n, i := range pending {
+			switch {
+			case failures[n] != nil:
+				result.Items[i] = BatchItemResult{Outcome: BatchFailed, Err: failures[n]}
+				failed = append(failed, i)
+			case attempt > 0:
+				result.Items[i] = BatchItemResult{Outcome: BatchRetried}
+			default:
+				result.Items[i] = BatchItemResult{Outcome: BatchApplied}
+			}
+		}
+		pending = failed
+	}
+
+	added := make([][]byte, 0, len(items))
+	for i, item := range result.Items {
+		if item.Outcome != BatchFailed {
+			added = append(added, items[i])
+		}
+	}
+	if len(added) > 0 {
+		if err := bf.afterAdd(ctx, added...); err != nil {
+			return result, err
+		}
+	}
+	if len(added) < len(items) {
+		return result, ErrBatchIncomplete
+	}
+	return result, nil
+}
+
+// runAddBatch runs the batch script for the items at the given indexes and returns the
+// error of each item, nil for items that were added
+func (bf *bloomFilter) runAddBatch(ctx context.Context, client redis.Cmdable, items [][]byte, indexes []int) ([]error, error) {
+	args := make([]interface{}, 1, 1+len(indexes)*(1+int(bf.hashCount)))
+	args[0] = bf.scriptTTL()
+	var commands int
+	for _, i := range indexes {
+		positions := bf.getHashPositions(items[i])
+		args = append(args, len(positions))
+		for _, pos := range positions {
+			args = append(args, bf.offset(pos))
+		}
+		commands += len(positions)
+	}
+	if err := bf.limiter.wait(ctx, commands); err != nil {
+		return nil, err
+	}
+
+	keys := []string{bf.config.RedisKey, metadataKey(bf.config.RedisKey)}
+	replies, err := addBatchItemsScript.Run(ctx, client, keys, args...).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(replies) != len(indexes) {
+		return nil, fmt.Errorf("batch script returned %d results for %d items", len(replies), len(indexes))
+	}
+
+	failures := make([]error, len(indexes))
+	for n, reply := range replies {
+		if msg, ok := reply.(string); ok {
+			failures[n] = errors.New(msg)
+		}
+	}
+	return failures, nil
+}
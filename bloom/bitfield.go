@@ -0,0 +1,42 @@
+package bloom
+
+import (
+	"context"
+	"strconv"
+)
+
+// setBitfield sets the bits at the given positions with a single BITFIELD command
+func (bf *bloomFilter) setBitfield(ctx context.Context, positions []uint64) error {
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	args := make([]interface{}, 0, 4*len(positions))
+	for _, pos := range positions {
+		args = append(args, "SET", "u1", strconv.FormatInt(bf.offset(pos), 10), 1)
+	}
+	return client.BitField(ctx, bf.config.RedisKey, args...).Err()
+}
+
+// getBitfield reads the bits at the given positions with a single BITFIELD command
+func (bf *bloomFilter) getBitfield(ctx context.Context, positions []uint64) ([]int64, error) {
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, 0, 3*len(positions))
+	for _, pos := range positions {
+		args = append(args, "GET", "u1", strconv.FormatInt(bf.offset(pos), 10))
+	}
+	return client.BitField(ctx, bf.config.RedisKey, args...).Result()
+}
+
+// allOnes reports whether every bit read with BITFIELD is set
+func allOnes(bits []int64) bool {
+	for _, bit := range bits {
+		if bit == 0 {
+			return false
+		}
+	}
+	return true
+}
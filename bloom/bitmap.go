@@ -0,0 +1,106 @@
+package bloom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Bitmap transfer settings
+const (
+	// bitmapChunkSize is the number of bytes transferred per SETRANGE/GETRANGE
+	bitmapChunkSize = 1 << 20
+	// stagingKeySuffix names the key a bitmap is staged under before being swapped in
+	stagingKeySuffix = "staging"
+)
+
+// setBitmapBit sets the bit at a Redis offset in a raw bitmap, where offset 0 is the
+// most significant bit of the first byte (the SETBIT convention)
+func setBitmapBit(bitmap []byte, pos uint64) {
+	bitmap[pos>>3] |= 0x80 >> (pos & 7)
+}
+
+// replaceBitmap atomically replaces the bitmap stored at key. The data is staged under
+// a companion key in chunks, skipping all-zero chunks, and then renamed over key in a
+// transaction that also records the rebuild and the given parameter field/value pairs
+// in the filter metadata.
+func replaceBitmap(ctx context.Context, client redis.Cmdable, key string, bitmap []byte, ttl time.Duration, params []interface{}) error {
+	return replaceBitmapFrom(ctx, client, key, bytes.NewReader(bitmap), int64(len(bitmap)), ttl, params)
+}
+
+// replaceBitmapFrom is replaceBitmap for a bitmap of size bytes read from r, which is
+// streamed to Redis one chunk at a time
+func replaceBitmapFrom(ctx context.Context, client redis.Cmdable, key string, r io.Reader, size int64, ttl time.Duration, params []interface{}) error {
+	staging := companionKey(key, stagingKeySuffix)
+	if err := client.Del(ctx, staging).Err(); err != nil {
+		return err
+	}
+
+	written := false
+	n := int64(bitmapChunkSize)
+	if size < n {
+		n = size
+	}
+	buf, zero := make([]byte, n), make([]byte, n)
+	for offset := int64(0); offset < size; offset += bitmapChunkSize {
+		end := offset + bitmapChunkSize
+		if end > size {
+			end = size
+		}
+		chunk := buf[:end-offset]
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return err
+		}
+		if bytes.Equal(chunk, zero[:len(chunk)]) {
+			continue
+		}
+		if err := client.SetRange(ctx, staging, offset, string(chunk)).Err(); err != nil {
+			return err
+		}
+		written = true
+	}
+
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if written {
+			if ttl > 0 {
+				pipe.Expire(ctx, staging, ttl)
+			}
+			pipe.Rename(ctx, staging, key)
+		} else {
+			pipe.Del(ctx, key)
+		}
+		queueMarkRebuilt(ctx, pipe, key, time.Now())
+		if len(params) > 0 {
+			pipe.HSet(ctx, metadataKey(key), params...)
+		}
+		if ttl > 0 {
+			pipe.Expire(ctx, metadataKey(key), ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// readBitmap reads the first size bytes of the bitmap stored at key in chunks.
+// Missing keys and bytes beyond the end of the stored string read as zero.
+func readBitmap(ctx context.Context, client redis.Cmdable, key string, size int) ([]byte, error) {
+	bitmap := make([]byte, size)
+	for offset := 0; offset < size; offset += bitmapChunkSize {
+		end := offset + bitmapChunkSize
+		if end > size {
+			end = size
+		}
+		chunk, err := client.GetRange(ctx, key, int64(offset), int64(end-1)).Result()
+		if err != nil {
+			return nil, err
+		}
+		copy(bitmap[offset:end], chunk)
+		if len(chunk) < end-offset {
+			break
+		}
+	}
+	return bitmap, nil
+}
@@ -0,0 +1,165 @@
+package bloom
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/spaolacci/murmur3"
+)
+
+// HashBitsAndBlooms is the registered name of the bits-and-blooms/bloom compatible hash strategy
+const HashBitsAndBlooms = "bits-and-blooms"
+
+// bitsAndBloomsMaxBits is the largest bitmap a Redis string can hold
+const bitsAndBloomsMaxBits = 1 << 32
+
+func init() {
+	RegisterHashStrategy(HashBitsAndBlooms, NewBitsAndBloomsStrategy)
+}
+
+// bitsAndBloomsStrategy reproduces the hashing of github.com/bits-and-blooms/bloom
+// (formerly willf/bloom): four 64-bit base hashes from Murmur3 x64 128 of the item and of
+// the item followed by a 1 byte, combined by enhanced double hashing
+type bitsAndBloomsStrategy struct{}
+
+var _ PositionHasher = (*bitsAndBloomsStrategy)(nil)
+
+// NewBitsAndBloomsStrategy creates a hash strategy that computes the same bit indices as a
+// bits-and-blooms BloomFilter. Items must be passed as the bytes given to its Add, e.g.
+// []byte(s) for AddString.
+func NewBitsAndBloomsStrategy() HashStrategy {
+	return &bitsAndBloomsStrategy{}
+}
+
+// Hash returns the i-th location before it is reduced modulo the filter size
+func (b *bitsAndBloomsStrategy) Hash(data []byte, i uint) uint64 {
+	return bitsAndBloomsLocation(bitsAndBloomsBaseHashes(data), uint64(i))
+}
+
+// Positions derives the bit indices exactly as the library's location function does
+func (b *bitsAndBloomsStrategy) Positions(data []byte, hashCount uint, bitSize uint64) []uint64 {
+	h := bitsAndBloomsBaseHashes(data)
+	positions := make([]uint64, hashCount)
+	for i := range positions {
+		positions[i] = bitsAndBloomsLocation(h, uint64(i)) % bitSize
+	}
+	return positions
+}
+
+// bitsAndBloomsBaseHashes returns the library's baseHashes
+func bitsAndBloomsBaseHashes(data []byte) [4]uint64 {
+	h1, h2 := murmur3.Sum128(data)
+	h3, h4 := murmur3.Sum128(append(data[:len(data):len(data)], 1))
+	return [4]uint64{h1, h2, h3, h4}
+}
+
+// bitsAndBloomsLocation returns the i-th location: h[i%2] + i*h[2+((i+i%2)%4)/2]
+func bitsAndBloomsLocation(h [4]uint64, i uint64) uint64 {
+	return h[i%2] + i*h[2+((i+i%2)%4)/2]
+}
+
+// BitsAndBloomsFilter is a bits-and-blooms BloomFilter decoded from its WriteTo serialization
+type BitsAndBloomsFilter struct {
+	M uint64
+	K uint64
+	// Bits holds the bitset words as serialized: bits least significant first in 64-bit
+	// words stored big-endian, i.e. BitLayoutBigEndian64
+	Bits []byte
+}
+
+// DecodeBitsAndBlooms reads a filter written by bits-and-blooms' BloomFilter.WriteTo: m
+// and k as big-endian uint64 values followed by the bitset, which is its length in bits
+// as a big-endian uint64 and then the words as big-endian uint64 values
+func DecodeBitsAndBlooms(r io.Reader) (*BitsAndBloomsFilter, error) {
+	var header [24]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidSerializedFilter, err)
+	}
+
+	f := &BitsAndBloomsFilter{
+		M: binary.BigEndian.Uint64(header[0:]),
+		K: binary.BigEndian.Uint64(header[8:]),
+	}
+	length := binary.BigEndian.Uint64(header[16:])
+	if f.M == 0 || f.K == 0 || length != f.M || f.M > bitsAndBloomsMaxBits {
+		return nil, fmt.Errorf("%w: %d hash functions over %d bits with a bitset of %d", ErrInvalidSerializedFilter, f.K, f.M, length)
+	}
+
+	f.Bits = make([]byte, BitLayoutBigEndian64.byteSize(f.M))
+	if _, err := io.ReadFull(r, f.Bits); err != nil {
+		return nil, fmt.Errorf("%w: bit data: %v", ErrInvalidSerializedFilter, err)
+	}
+	return f, nil
+}
+
+// Encode writes the filter in bits-and-blooms' WriteTo format, so it can be loaded in Go
+// with BloomFilter.ReadFrom
+func (f *BitsAndBloomsFilter) Encode(w io.Writer) error {
+	var header [24]byte
+	binary.BigEndian.PutUint64(header[0:], f.M)
+	binary.BigEndian.PutUint64(header[8:], f.K)
+	binary.BigEndian.PutUint64(header[16:], f.M)
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(f.Bits)
+	return err
+}
+
+// ImportBitsAndBlooms decodes a bits-and-blooms serialization and stores its bits under
+// cfg.RedisKey, replacing any existing filter. The returned filter uses the library's
+// hashing, m and k, so it answers exactly like the in-memory filter; ExpectedInsertions,
+// FalsePositiveRate and HashStrategy in cfg are ignored.
+func ImportBitsAndBlooms(ctx context.Context, r io.Reader, cfg Config) (BloomFilter, error) {
+	f, err := DecodeBitsAndBlooms(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.HashStrategy = NewBitsAndBloomsStrategy()
+	bf, err := buildBloomFilter(cfg, f.M, uint(f.K))
+	if err != nil {
+		return nil, err
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	bitmap := convertBitmap(f.Bits, BitLayoutBigEndian64, cfg.BitLayout)[:cfg.BitLayout.byteSize(f.M)]
+
+	if err := replaceBitmap(ctx, client, cfg.RedisKey, bitmap, cfg.TTL, bf.parameterFields()); err != nil {
+		return nil, err
+	}
+	bf.metadataRecorded = 1
+	return bf, nil
+}
+
+// ExportBitsAndBlooms writes a filter that uses the bits-and-blooms strategy in the
+// library's WriteTo format, so services using the in-memory library can load and verify it
+func ExportBitsAndBlooms(ctx context.Context, w io.Writer, filter BloomFilter) error {
+	bf, ok := filter.(*bloomFilter)
+	if !ok {
+		return ErrIncompatibleFilter
+	}
+	if _, ok := bf.hashStrategy.(*bitsAndBloomsStrategy); !ok {
+		return ErrIncompatibleFilter
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+
+	raw, err := readBitmap(ctx, client, bf.config.RedisKey, int(bf.config.BitLayout.byteSize(bf.bitSize)))
+	if err != nil {
+		return err
+	}
+	f := &BitsAndBloomsFilter{
+		M:    bf.bitSize,
+		K:    uint64(bf.hashCount),
+		Bits: convertBitmap(raw, bf.config.BitLayout, BitLayoutBigEndian64)[:BitLayoutBigEndian64.byteSize(bf.bitSize)],
+	}
+	return f.Encode(w)
+}
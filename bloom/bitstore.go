@@ -0,0 +1,229 @@
+package bloom
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BitStore is the smallest storage backend a filter can run on: batched bit writes and
+// reads, key expiration and key deletion. Implement it to keep filters in rueidis, a
+// local file or another key-value store, and wrap it with NewBitStoreClient. Offsets are
+// Redis bit offsets, MSB-first within each byte unless Config.BitLayout says otherwise.
+type BitStore interface {
+	// SetBits sets the bits at offsets of key to 1, creating the key if needed
+	SetBits(ctx context.Context, key string, offsets []int64) error
+	// GetBits returns the bits at offsets of key; missing keys and bits read as false
+	GetBits(ctx context.Context, key string, offsets []int64) ([]bool, error)
+	// Expire sets a timeout on key; it does nothing if key does not exist
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+	// Delete removes key
+	Delete(ctx context.Context, key string) error
+}
+
+// BitStoreClient runs filters on a BitStore. Pipelines pass consecutive reads or writes
+// of one key to the store as a single GetBits or SetBits call. Since a BitStore only
+// sets bits, SETBIT replies are always 0, and features that need the full go-redis
+// command set (metadata, scripts, audit stream) are unavailable.
+type BitStoreClient struct {
+	store BitStore
+}
+
+var (
+	_ RedisClient = (*BitStoreClient)(nil)
+	_ BitStore    = (*redisBitStore)(nil)
+)
+
+// NewBitStoreClient creates a client that stores filters in store
+func NewBitStoreClient(store BitStore) *BitStoreClient {
+	return &BitStoreClient{store: store}
+}
+
+// Store returns the wrapped BitStore
+func (bc *BitStoreClient) Store() BitStore {
+	return bc.store
+}
+
+// SetBit sets a bit; clearing a bit fails with ErrBitClearUnsupported
+func (bc *BitStoreClient) SetBit(ctx context.Context, key string, offset int64, value int) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "setbit", key, offset, value)
+	bc.setBits(ctx, key, []*redis.IntCmd{cmd})
+	return cmd
+}
+
+// GetBit gets a bit
+func (bc *BitStoreClient) GetBit(ctx context.Context, key string, offset int64) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "getbit", key, offset)
+	bc.getBits(ctx, key, []*redis.IntCmd{cmd})
+	return cmd
+}
+
+// Expire sets a timeout on the key
+func (bc *BitStoreClient) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx, "expire", key, expiration)
+	bc.expire(ctx, cmd)
+	return cmd
+}
+
+// Pipeline returns a pipeline that batches its reads and writes per key on Exec
+func (bc *BitStoreClient) Pipeline() Pipeliner {
+	return &bitStorePipeline{client: bc}
+}
+
+// setBits writes the bits of SETBIT commands on one key with a single SetBits call
+func (bc *BitStoreClient) setBits(ctx context.Context, key string, cmds []*redis.IntCmd) {
+	offsets := make([]int64, 0, len(cmds))
+	for _, cmd := range cmds {
+		args := cmd.Args()
+		if args[3].(int) != 1 {
+			cmd.SetErr(ErrBitClearUnsupported)
+			continue
+		}
+		offsets = append(offsets, args[2].(int64))
+	}
+	if len(offsets) == 0 {
+		return
+	}
+	if err := bc.store.SetBits(ctx, key, offsets); err != nil {
+		for _, cmd := range cmds {
+			cmd.SetErr(err)
+		}
+	}
+}
+
+// expire applies an EXPIRE command with the store
+func (bc *BitStoreClient) expire(ctx context.Context, cmd *redis.BoolCmd) {
+	args := cmd.Args()
+	if err := bc.store.Expire(ctx, args[1].(string), args[2].(time.Duration)); err != nil {
+		cmd.SetErr(err)
+		return
+	}
+	cmd.SetVal(true)
+}
+
+// getBits reads the bits of GETBIT commands on one key with a single GetBits call
+func (bc *BitStoreClient) getBits(ctx context.Context, key string, cmds []*redis.IntCmd) {
+	offsets := make([]int64, len(cmds))
+	for i, cmd := range cmds {
+		offsets[i] = cmd.Args()[2].(int64)
+	}
+	bits, err := bc.store.GetBits(ctx, key, offsets)
+	for i, cmd := range cmds {
+		switch {
+		case err != nil:
+			cmd.SetErr(err)
+		case bits[i]:
+			cmd.SetVal(1)
+		}
+	}
+}
+
+// bitStorePipeline queues commands for a BitStoreClient
+type bitStorePipeline struct {
+	client *BitStoreClient
+	cmds   []redis.Cmder
+}
+
+// SetBit queues a SETBIT
+func (p *bitStorePipeline) SetBit(ctx context.Context, key string, offset int64, value int) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "setbit", key, offset, value)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// GetBit queues a GETBIT
+func (p *bitStorePipeline) GetBit(ctx context.Context, key string, offset int64) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "getbit", key, offset)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// Expire queues an EXPIRE
+func (p *bitStorePipeline) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx, "expire", key, expiration)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// Exec runs the queued commands in order, passing each run of consecutive reads or
+// writes of one key to the store at once, and empties the pipeline so it can be reused
+func (p *bitStorePipeline) Exec(ctx context.Context) ([]redis.Cmder, error) {
+	cmds := p.cmds
+	p.cmds = nil
+	if err := ctx.Err(); err != nil {
+		return cmds, err
+	}
+
+	for i := 0; i < len(cmds); {
+		name, key := cmds[i].Name(), cmds[i].Args()[1].(string)
+		if name == "expire" {
+			p.client.expire(ctx, cmds[i].(*redis.BoolCmd))
+			i++
+			continue
+		}
+		var run []*redis.IntCmd
+		for ; i < len(cmds) && cmds[i].Name() == name && cmds[i].Args()[1] == key; i++ {
+			run = append(run, cmds[i].(*redis.IntCmd))
+		}
+		if name == "setbit" {
+			p.client.setBits(ctx, key, run)
+		} else {
+			p.client.getBits(ctx, key, run)
+		}
+	}
+
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil {
+			return cmds, err
+		}
+	}
+	return cmds, nil
+}
+
+// redisBitStore is the BitStore of a go-redis client
+type redisBitStore struct {
+	client redis.Cmdable
+}
+
+// NewRedisBitStore creates a BitStore backed by a go-redis client, sending the bits of
+// each call in one pipeline
+func NewRedisBitStore(client redis.Cmdable) BitStore {
+	return &redisBitStore{client: client}
+}
+
+// SetBits sets the bits with pipelined SETBITs
+func (rs *redisBitStore) SetBits(ctx context.Context, key string, offsets []int64) error {
+	pipe := rs.client.Pipeline()
+	for _, offset := range offsets {
+		pipe.SetBit(ctx, key, offset, 1)
+	}
+	return execPipeline(ctx, pipe)
+}
+
+// GetBits reads the bits with pipelined GETBITs
+func (rs *redisBitStore) GetBits(ctx context.Context, key string, offsets []int64) ([]bool, error) {
+	pipe := rs.client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(offsets))
+	for i, offset := range offsets {
+		cmds[i] = pipe.GetBit(ctx, key, offset)
+	}
+	if err := execPipeline(ctx, pipe); err != nil {
+		return nil, err
+	}
+	bits := make([]bool, len(cmds))
+	for i, cmd := range cmds {
+		bits[i] = cmd.Val() == 1
+	}
+	return bits, nil
+}
+
+// Expire sets a timeout on the key
+func (rs *redisBitStore) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return rs.client.Expire(ctx, key, expiration).Err()
+}
+
+// Delete removes the key
+func (rs *redisBitStore) Delete(ctx context.Context, key string) error {
+	return rs.client.Del(ctx, key).Err()
+}
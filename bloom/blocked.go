@@ -0,0 +1,70 @@
+package bloom
+
+import "context"
+
+// blockBits is the size of a blocked-layout block: one 64-byte cache line
+const blockBits = 512
+
+// blockedBitSize rounds a bit size up to a whole number of blocks
+func blockedBitSize(bitSize uint64) uint64 {
+	return (bitSize + blockBits - 1) / blockBits * blockBits
+}
+
+// blockedPositions confines the k positions of data to the single block selected by the
+// first hash. The second hash is split into two 32-bit halves that drive double hashing
+// within the block.
+func (bf *bloomFilter) blockedPositions(data []byte) []uint64 {
+	h1 := bf.hashStrategy.Hash(data, 0)
+	h2 := bf.hashStrategy.Hash(data, 1)
+
+	base := h1 % (bf.bitSize / blockBits) * blockBits
+	lo, hi := uint32(h2), uint32(h2>>32)|1
+
+	positions := make([]uint64, bf.hashCount)
+	for i := uint(0); i < bf.hashCount; i++ {
+		positions[i] = base + uint64(lo+uint32(i)*hi)%blockBits
+	}
+	return positions
+}
+
+// readsBlocks reports whether a lookup of positions is served by a single GETRANGE
+func (bf *bloomFilter) readsBlocks(positions []uint64) bool {
+	if !bf.config.Blocked || len(positions) < 2 {
+		return false
+	}
+	_, ok := bf.config.RedisClient.(CmdableProvider)
+	return ok
+}
+
+// lookupCommands returns the number of Redis commands needed to check positions
+func (bf *bloomFilter) lookupCommands(positions []uint64) int {
+	if bf.readsBlocks(positions) {
+		return 1
+	}
+	return bf.bitCommands(len(positions))
+}
+
+// checkBlock reads the block holding positions with a single GETRANGE and checks the
+// bits locally. Every layout keeps a block's bits within its 64 bytes.
+func (bf *bloomFilter) checkBlock(ctx context.Context, positions []uint64) (bool, error) {
+	client, err := bf.cmdable()
+	if err != nil {
+		return false, err
+	}
+
+	start := int64(positions[0] / blockBits * (blockBits / 8))
+	block, err := client.GetRange(ctx, bf.config.RedisKey, start, start+blockBits/8-1).Result()
+	if err != nil {
+		return false, err
+	}
+
+	for _, pos := range positions {
+		offset := bf.offset(pos)
+		i := offset/8 - start
+		// Missing bytes past the end of the string are unset
+		if i >= int64(len(block)) || block[i]&(0x80>>(offset%8)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -2,6 +2,9 @@ package bloom
 
 import (
 	"context"
+	"io"
+	"math"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -9,129 +12,550 @@ import (
 // BloomFilter represents the main interface for Bloom Filter operations
 type BloomFilter interface {
 	Add(data []byte) error
+	AddMany(items [][]byte) error
+	AddBatch(items [][]byte) error
+	AddBatchPartial(items [][]byte) (*BatchResult, error)
+	AddStream(ctx context.Context, items <-chan []byte) <-chan error
+	TestAndAdd(data []byte) (bool, error)
+	BulkLoad(ctx context.Context, source Iterator, opts BulkLoadOptions) (int64, error)
 	Exists(data []byte) (bool, error)
+	ExistsMany(items [][]byte) ([]bool, error)
+	ExistsExplain(data []byte) (*Explanation, error)
+	PrefetchExists(items [][]byte) error
+	Stats() (*Stats, error)
+	EstimateCardinality(ctx context.Context) (uint64, error)
+	CurrentFalsePositiveRate(ctx context.Context) (float64, error)
+	Positions(data []byte) []uint64
+	ExportSpec() ([]byte, error)
+	Export(ctx context.Context, w io.Writer) error
+	Import(ctx context.Context, r io.Reader) error
+	Union(ctx context.Context, other BloomFilter) error
+	Intersect(ctx context.Context, other BloomFilter) error
+	MergeInto(ctx context.Context, destKey string) error
+	CopyTo(ctx context.Context, destKey string, opts CopyOptions) error
+	Clear(ctx context.Context, opts DeleteOptions) error
+	Drop(ctx context.Context, opts DeleteOptions) error
+	Rebuild(ctx context.Context, params ResizeParams, source Iterator) (BloomFilter, error)
+	MoveTo(ctx context.Context, targetAddr string, opts MoveOptions) error
+	PermissionsCheck(ctx context.Context) (*PermissionReport, error)
 }
 
 // RedisClient interface abstracts both Redis single-node and cluster clients
 type RedisClient interface {
 	SetBit(ctx context.Context, key string, offset int64, value int) *redis.IntCmd
 	GetBit(ctx context.Context, key string, offset int64) *redis.IntCmd
-	Pipeline() pipeliner
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	Pipeline() Pipeliner
 }
 
-// pipeliner is a minimal interface for pipelining, used for both production and test
-// In production, it is satisfied by redis.Pipeliner; in tests, by the minimal mock
-// This allows robust, testable code without mocking the full redis.Pipeliner interface
-type pipeliner interface {
+// Pipeliner is the minimal pipelining interface the filter needs. It is satisfied by
+// redis.Pipeliner, and custom RedisClient implementations (instrumented wrappers, mocks)
+// can return their own implementation without supporting the full go-redis interface.
+type Pipeliner interface {
 	SetBit(ctx context.Context, key string, offset int64, value int) *redis.IntCmd
 	GetBit(ctx context.Context, key string, offset int64) *redis.IntCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
 	Exec(ctx context.Context) ([]redis.Cmder, error)
 }
 
+// directCommandMaxHashes is the largest number of positions read or written with direct
+// commands instead of a pipeline; for such tiny k the pipeline setup dominates the cost
+const directCommandMaxHashes = 2
+
 // bloomFilter implements the BloomFilter interface
 type bloomFilter struct {
 	config       Config
 	bitSize      uint64
 	hashCount    uint
 	hashStrategy HashStrategy
+	limiter      *rateLimiter
+	degrader     *degrader
+	retrier      *retrier
+	circuit      *circuit
+	fallback     *localFallback
+	admission    *admission
+	insertRate   *insertRate
+	saturation   *saturation
+	cache        *resultCaching
+	pipelines    *pipelinePool
+	metrics      Metrics
+	scripts      *scriptManager
+	// metadataRecorded is set once the creation time and parameters have been stored
+	metadataRecorded uint32
+	// metadataExpiry is the Unix time in nanoseconds until which the recorded metadata of
+	// a filter with a TTL is known to exist
+	metadataExpiry int64
+	// reserved is set once a module filter has been reserved
+	reserved uint32
 }
 
-// NewBloomFilter creates a new Bloom Filter instance with the given configuration
+// NewBloomFilter creates a new Bloom Filter instance with the given configuration.
+// It fails with a *ParameterMismatchError if the key's metadata records a different
+// bit size, hash count or hash strategy.
 func NewBloomFilter(cfg Config) (BloomFilter, error) {
+	bf, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := bf.checkParameters(context.Background()); err != nil {
+		return nil, err
+	}
+	return bf, nil
+}
+
+// newBloomFilter validates the configuration and builds the concrete filter
+func newBloomFilter(cfg Config) (*bloomFilter, error) {
+	if cfg.BitSize > 0 && cfg.HashCount > 0 {
+		impliedSizing(&cfg, cfg.BitSize, cfg.HashCount)
+	}
 	if cfg.ExpectedInsertions == 0 {
 		return nil, ErrInvalidExpectedInsertions
 	}
 	if cfg.FalsePositiveRate <= 0 || cfg.FalsePositiveRate >= 1 {
 		return nil, ErrInvalidFalsePositiveRate
 	}
-	if cfg.RedisKey == "" {
-		return nil, ErrEmptyRedisKey
+
+	// Calculate optimal filter size and number of hash functions, unless overridden
+	bitSize, hashCount := EstimateParameters(cfg.ExpectedInsertions, cfg.FalsePositiveRate)
+	if _, ok := cfg.HashStrategy.(*guavaStrategy); ok {
+		bitSize, hashCount = EstimateGuavaParameters(cfg.ExpectedInsertions, cfg.FalsePositiveRate)
 	}
-	if cfg.RedisClient == nil {
-		return nil, ErrNilRedisClient
+	if cfg.HashCount > 0 {
+		hashCount = cfg.HashCount
+	}
+	switch {
+	case cfg.BitSize > 0:
+		bitSize = cfg.BitSize
+		if cfg.HashCount == 0 {
+			// The optimal count for the given size: k = (m / n) * ln(2)
+			hashCount = uint(math.Max(1, math.Ceil(float64(bitSize)/float64(cfg.ExpectedInsertions)*math.Ln2)))
+		}
+	case cfg.Blocked:
+		bitSize = blockedBitSize(bitSize)
+	case cfg.Partitioned:
+		bitSize = partitionedBitSize(bitSize, hashCount)
+	}
+
+	return buildBloomFilter(cfg, bitSize, hashCount)
+}
+
+// buildBloomFilter builds a filter with known parameters, applying configuration defaults
+func buildBloomFilter(cfg Config, bitSize uint64, hashCount uint) (*bloomFilter, error) {
+	if err := validateStorage(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateEngine(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateTTLMode(cfg); err != nil {
+		return nil, err
 	}
 
-	// Calculate optimal filter size and number of hash functions
-	bitSize, hashCount := calculateOptimalParameters(cfg.ExpectedInsertions, cfg.FalsePositiveRate)
+	if cfg.Metrics == nil {
+		cfg.Metrics = noopMetrics{}
+	}
 
 	// Set default hash strategy if not provided
 	if cfg.HashStrategy == nil {
 		cfg.HashStrategy = NewXXHashStrategy()
 	}
+	// A blocked layout derives positions itself and needs whole blocks
+	if _, ok := cfg.HashStrategy.(PositionHasher); cfg.Blocked && (ok || bitSize%blockBits != 0) {
+		return nil, ErrIncompatibleFilter
+	}
+	// A partitioned layout derives positions itself and needs whole, aligned partitions
+	if _, ok := cfg.HashStrategy.(PositionHasher); cfg.Partitioned && (ok || cfg.Blocked || bitSize%(uint64(hashCount)*partitionAlign) != 0) {
+		return nil, ErrIncompatibleFilter
+	}
+	if cfg.VerifyHashStrategy {
+		if err := VerifyHashStrategy(cfg.HashStrategy); err != nil {
+			return nil, err
+		}
+	}
+
+	hashStrategy := cfg.HashStrategy
+	if seed, ok := effectiveSeed(cfg); ok {
+		// Strategies that derive positions themselves follow a fixed external format
+		if _, ok := hashStrategy.(PositionHasher); ok {
+			return nil, ErrIncompatibleFilter
+		}
+		hashStrategy = &seededStrategy{inner: hashStrategy, seed: seed}
+	}
 
 	return &bloomFilter{
 		config:       cfg,
 		bitSize:      bitSize,
 		hashCount:    hashCount,
-		hashStrategy: cfg.HashStrategy,
+		hashStrategy: hashStrategy,
+		limiter:      newRateLimiter(cfg.RateLimit),
+		degrader:     newDegrader(cfg.Degradation, hashCount),
+		retrier:      newRetrier(cfg.Retry),
+		circuit:      newCircuit(cfg.CircuitBreaker),
+		fallback:     newLocalFallback(cfg.LocalFallback),
+		admission:    newAdmission(cfg.Admission),
+		insertRate:   newInsertRate(cfg.InsertRateAlert),
+		saturation:   newSaturation(cfg.SaturationAlert),
+		cache:        newResultCaching(cfg.ResultCache, cfg.RedisKey),
+		pipelines:    newPipelinePool(cfg.ReusePipelines),
+		scripts:      newScriptManager(),
+		metrics:      cfg.Metrics,
 	}, nil
 }
 
+// validateStorage checks the parts of the configuration that locate the filter in Redis
+func validateStorage(cfg Config) error {
+	if cfg.RedisKey == "" {
+		return ErrEmptyRedisKey
+	}
+	if cfg.RedisClient == nil {
+		return ErrNilRedisClient
+	}
+	if _, ok := cfg.RedisClient.(CmdableProvider); (cfg.Audit != nil || cfg.Admission != nil) && !ok {
+		return ErrCommandsUnsupported
+	}
+	return nil
+}
+
 // Add adds an element to the Bloom Filter
 func (bf *bloomFilter) Add(data []byte) error {
 	ctx := context.Background()
+	if !bf.usesBitmap() {
+		return bf.addItems(ctx, [][]byte{data})
+	}
+
+	timer := bf.startPhases(addPhases)
 	positions := bf.getHashPositions(data)
+	timer.hashed()
 
-	// Use pipeline for efficiency
-	pipe, ok := bf.config.RedisClient.Pipeline().(pipeliner)
-	if !ok {
-		return ErrNilRedisClient
+	commands := bf.bitCommands(len(positions))
+	if bf.config.TTL > 0 {
+		commands += 2
 	}
-	for _, pos := range positions {
-		pipe.SetBit(ctx, bf.config.RedisKey, int64(pos), 1)
+	if bf.config.Audit != nil {
+		commands++
 	}
-
-	// Execute pipeline
-	_, err := pipe.Exec(ctx)
-	if err != nil {
+	if err := bf.limiter.wait(ctx, commands); err != nil {
+		return err
+	}
+	if err := bf.admit(ctx); err != nil {
 		return err
 	}
 
-	// Set TTL if configured and greater than zero
-	if bf.config.TTL > 0 {
-		if adapter, ok := bf.config.RedisClient.(*RedisAdapter); ok {
-			adapter.client.Expire(ctx, bf.config.RedisKey, bf.config.TTL)
+	timer.skip()
+	if err := bf.call(ctx, func() error { return bf.setBits(ctx, positions, timer) }); err != nil {
+		if bf.bufferAdd(err, positions, data) {
+			return nil
 		}
+		return err
 	}
+	return bf.afterAdd(ctx, data)
+}
 
-	return nil
+// afterAdd performs the bookkeeping of Add once the items have been written: metadata,
+// audit log, result cache, insert counters, update notifications and TTL
+func (bf *bloomFilter) afterAdd(ctx context.Context, items ...[]byte) error {
+	if err := bf.recordAdd(ctx, items...); err != nil {
+		return err
+	}
+	return bf.refreshTTL(ctx)
+}
+
+// recordAdd performs the bookkeeping of afterAdd except the TTL, for writes that apply
+// the TTL themselves
+func (bf *bloomFilter) recordAdd(ctx context.Context, items ...[]byte) error {
+	if err := bf.recordCreation(ctx); err != nil {
+		return err
+	}
+	if bf.config.Audit != nil {
+		if err := bf.appendAudit(ctx, items...); err != nil {
+			return err
+		}
+	}
+	if bf.cache != nil {
+		for _, data := range items {
+			bf.cache.set(bf.cache.key(data), true)
+		}
+	}
+	bf.recordInserts(len(items))
+	bf.watchSaturation()
+	return bf.publishItems(ctx, items...)
 }
 
 // Exists checks if an element exists in the Bloom Filter
 func (bf *bloomFilter) Exists(data []byte) (bool, error) {
-	ctx := context.Background()
+	return bf.exists(context.Background(), data, true)
+}
+
+// exists looks data up through the cache, degradation, retries and fallback; slide
+// selects whether the lookup extends a sliding TTL
+func (bf *bloomFilter) exists(ctx context.Context, data []byte, slide bool) (bool, error) {
+	if !bf.usesBitmap() {
+		found, err := bf.existsMany(ctx, [][]byte{data}, slide)
+		if err != nil {
+			return false, err
+		}
+		return found[0], nil
+	}
+
+	var key string
+	if bf.cache != nil {
+		bf.validateCache(ctx)
+		key = bf.cache.key(data)
+		if exists, ok := bf.cache.get(key); ok {
+			bf.metrics.IncCounter(MetricCacheHits, 1)
+			return exists, nil
+		}
+		bf.metrics.IncCounter(MetricCacheMisses, 1)
+	}
+
+	timer := bf.startPhases(existsPhases)
 	positions := bf.getHashPositions(data)
+	timer.hashed()
 
-	// Use pipeline for efficiency
-	pipe, ok := bf.config.RedisClient.Pipeline().(pipeliner)
-	if !ok {
-		return false, ErrNilRedisClient
+	// Check only a prefix of the positions while Redis is slow
+	truncated := false
+	if limit := bf.degrader.limit(bf.hashCount); limit < uint(len(positions)) {
+		positions = positions[:limit]
+		truncated = true
+		bf.metrics.IncCounter(MetricDegradedLookups, 1)
 	}
-	cmds := make([]*redis.IntCmd, len(positions))
 
+	if err := bf.limiter.wait(ctx, bf.lookupCommands(positions)); err != nil {
+		return false, err
+	}
+
+	start := time.Now()
+	timer.skip()
+	var exists bool
+	err := bf.call(ctx, func() error {
+		var err error
+		exists, err = bf.checkBits(ctx, positions, timer)
+		return err
+	})
+	bf.observeLatency(time.Since(start))
+
+	if err != nil {
+		return bf.lookupFallback(err, positions)
+	}
+	// Degraded positives are less certain and are not cached
+	if bf.cache != nil && !(exists && truncated) {
+		bf.cache.set(key, exists)
+	}
+	if !slide {
+		return exists, nil
+	}
+	return exists, bf.slideTTL(ctx)
+}
+
+// Positions returns the Redis bit offsets the filter reads and writes for data,
+// after applying the configured hash strategy and bit layout, or nil for module filters
+func (bf *bloomFilter) Positions(data []byte) []uint64 {
+	if !bf.usesBitmap() {
+		return nil
+	}
+	positions := bf.getHashPositions(data)
 	for i, pos := range positions {
-		cmds[i] = pipe.GetBit(ctx, bf.config.RedisKey, int64(pos))
+		positions[i] = bf.config.BitLayout.Offset(pos)
+	}
+	return positions
+}
+
+// setBits sets the bits at the given positions
+func (bf *bloomFilter) setBits(ctx context.Context, positions []uint64, timer *phaseTimer) error {
+	switch bf.config.Engine {
+	case EngineBitfield:
+		timer.built()
+		defer timer.executed()
+		return bf.setBitfield(ctx, positions)
+	case EngineLua, EngineFunctions:
+		timer.built()
+		defer timer.executed()
+		return bf.setBitsLua(ctx, positions)
+	}
+
+	// Issue direct commands for tiny k, unless the TTL rides along in the pipeline
+	if len(positions) <= directCommandMaxHashes && !bf.pipelinesTTL() {
+		timer.built()
+		defer timer.executed()
+		for _, pos := range positions {
+			if err := bf.config.RedisClient.SetBit(ctx, bf.config.RedisKey, bf.offset(pos), 1).Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Use pipeline for efficiency
+	pipe, err := bf.pipeline()
+	if err != nil {
+		return err
+	}
+	defer bf.releasePipeline(pipe)
+	for _, pos := range positions {
+		pipe.SetBit(ctx, bf.config.RedisKey, bf.offset(pos), 1)
+	}
+	bf.pipelineTTL(ctx, pipe)
+	timer.built()
+
+	// Execute pipeline
+	defer timer.executed()
+	return execPipeline(ctx, pipe)
+}
+
+// checkBits reports whether all bits at the given positions are set
+func (bf *bloomFilter) checkBits(ctx context.Context, positions []uint64, timer *phaseTimer) (bool, error) {
+	// Read the whole block at once when the layout confines positions to one block
+	if bf.readsBlocks(positions) {
+		timer.built()
+		defer timer.executed()
+		return bf.checkBlock(ctx, positions)
+	}
+
+	switch bf.config.Engine {
+	case EngineBitfield:
+		timer.built()
+		defer timer.executed()
+		bits, err := bf.getBitfield(ctx, positions)
+		if err != nil {
+			return false, err
+		}
+		return allOnes(bits), nil
+	case EngineLua, EngineFunctions:
+		timer.built()
+		defer timer.executed()
+		found, err := bf.checkItemsLua(ctx, [][]uint64{positions})
+		if err != nil {
+			return false, err
+		}
+		return found[0], nil
+	}
+
+	// Issue direct commands for tiny k, stopping at the first unset bit
+	if len(positions) <= directCommandMaxHashes {
+		timer.built()
+		defer timer.executed()
+		for _, pos := range positions {
+			bit, err := bf.config.RedisClient.GetBit(ctx, bf.config.RedisKey, bf.offset(pos)).Result()
+			if err != nil {
+				return false, err
+			}
+			if bit == 0 {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	// Use pipeline for efficiency
+	pipe, err := bf.pipeline()
+	if err != nil {
+		return false, err
 	}
+	defer bf.releasePipeline(pipe)
+	allSet := bf.queueCheckBits(ctx, pipe, positions)
+	timer.built()
 
 	// Execute pipeline
-	_, err := pipe.Exec(ctx)
+	err = execPipeline(ctx, pipe)
+	timer.executed()
 	if err != nil {
 		return false, err
 	}
 
-	// Check if all bits are set
-	for _, cmd := range cmds {
-		if cmd.Val() == 0 {
-			return false, nil
+	return allSet(), nil
+}
+
+// offset returns the Redis bit offset of a logical position under the configured layout
+func (bf *bloomFilter) offset(pos uint64) int64 {
+	return int64(bf.config.BitLayout.Offset(pos))
+}
+
+// queueCheckBits queues GETBIT commands for the given positions on pipe and returns a
+// function that reports whether all bits are set once the pipeline has been executed.
+// It lets several filters sharing a client be evaluated in one round trip.
+func (bf *bloomFilter) queueCheckBits(ctx context.Context, pipe Pipeliner, positions []uint64) func() bool {
+	cmds := make([]*redis.IntCmd, len(positions))
+	for i, pos := range positions {
+		cmds[i] = pipe.GetBit(ctx, bf.config.RedisKey, bf.offset(pos))
+	}
+
+	return func() bool {
+		// Check if all bits are set
+		for _, cmd := range cmds {
+			if cmd.Val() == 0 {
+				return false
+			}
 		}
+		return true
 	}
+}
 
-	return true, nil
+// pipeline starts a pipeline on the configured client, reusing a pooled one if enabled
+func (bf *bloomFilter) pipeline() (Pipeliner, error) {
+	if pipe := bf.pipelines.get(); pipe != nil {
+		return pipe, nil
+	}
+	pipe := bf.config.RedisClient.Pipeline()
+	if pipe == nil {
+		return nil, ErrNilRedisClient
+	}
+	return pipe, nil
+}
+
+// releasePipeline hands a pipeline back once it has been executed
+func (bf *bloomFilter) releasePipeline(pipe Pipeliner) {
+	bf.pipelines.put(pipe)
+}
+
+// cmdable returns the full go-redis command set of the configured client
+func (bf *bloomFilter) cmdable() (redis.Cmdable, error) {
+	if provider, ok := bf.config.RedisClient.(CmdableProvider); ok {
+		return provider.Cmdable(), nil
+	}
+	return nil, ErrCommandsUnsupported
+}
+
+// withKey returns a copy of the filter with identical parameters stored under another key
+func (bf *bloomFilter) withKey(key string) *bloomFilter {
+	clone := *bf
+	clone.config.RedisKey = key
+	clone.cache = bf.cache.withNamespace(key)
+	clone.admission = newAdmission(bf.config.Admission)
+	clone.insertRate = newInsertRate(bf.config.InsertRateAlert)
+	clone.saturation = newSaturation(bf.config.SaturationAlert)
+	clone.metadataRecorded = 0
+	clone.reserved = 0
+	return &clone
+}
+
+// auxiliary returns a copy of the filter stored under another key that belongs to this
+// filter, such as a removed set or a rebuild target; it shares the owner's metadata and
+// does not write to the audit log
+func (bf *bloomFilter) auxiliary(key string) *bloomFilter {
+	aux := bf.withKey(key)
+	aux.config.Audit = nil
+	aux.admission = nil
+	aux.insertRate = nil
+	aux.saturation = nil
+	aux.metadataRecorded = 1
+	aux.metadataExpiry = math.MaxInt64
+	return aux
 }
 
 // getHashPositions calculates the k hash positions for the given data
-// using double hashing technique: position = (h1(data) + i * h2(data)) % m
+// using double hashing technique: position = (h1(data) + i * h2(data)) % m,
+// unless the layout is blocked or partitioned or the strategy derives positions itself
 func (bf *bloomFilter) getHashPositions(data []byte) []uint64 {
+	if bf.config.Blocked {
+		return bf.blockedPositions(data)
+	}
+	if bf.config.Partitioned {
+		return bf.partitionedPositions(data)
+	}
+	if hasher, ok := bf.hashStrategy.(PositionHasher); ok {
+		return hasher.Positions(data, bf.hashCount, bf.bitSize)
+	}
+
 	positions := make([]uint64, bf.hashCount)
 
 	// Get two hash values for double hashing
@@ -7,12 +7,23 @@
 package bloom
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/spaolacci/murmur3"
 )
 
 // Utility: clean up a Redis key before/after a test
@@ -20,6 +31,256 @@ func cleanupKey(client *redis.Client, key string) {
 	client.Del(context.Background(), key)
 }
 
+// countingCache is a Cache that counts the answers stored in it
+type countingCache struct {
+	mu   sync.Mutex
+	sets int
+}
+
+func (c *countingCache) Get(string) (bool, bool) {
+	return false, false
+}
+
+func (c *countingCache) Set(string, bool, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sets++
+}
+
+// hookIterator yields items and calls hook once before the first one
+type hookIterator struct {
+	Iterator
+	hook func()
+}
+
+func (it *hookIterator) Next() ([]byte, error) {
+	if it.hook != nil {
+		it.hook()
+		it.hook = nil
+	}
+	return it.Iterator.Next()
+}
+
+// commandCounter is a go-redis hook counting the commands sent on their own and in pipelines
+type commandCounter struct {
+	mu        sync.Mutex
+	commands  map[string]int
+	pipelined map[string]int
+}
+
+func newCommandCounter() *commandCounter {
+	return &commandCounter{commands: make(map[string]int), pipelined: make(map[string]int)}
+}
+
+func (c *commandCounter) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (c *commandCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		c.mu.Lock()
+		c.commands[cmd.Name()]++
+		c.mu.Unlock()
+		return next(ctx, cmd)
+	}
+}
+
+func (c *commandCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		c.mu.Lock()
+		for _, cmd := range cmds {
+			c.pipelined[cmd.Name()]++
+		}
+		c.mu.Unlock()
+		return next(ctx, cmds)
+	}
+}
+
+// counts returns the number of commands named name sent on their own and in pipelines
+func (c *commandCounter) counts(name string) (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.commands[name], c.pipelined[name]
+}
+
+// brokenStrategy keeps xxhash's known answers but hashes differently, like a miscompiled
+// or misconfigured strategy would
+type brokenStrategy struct {
+	XXHashStrategy
+}
+
+func (b *brokenStrategy) Hash(data []byte, i uint) uint64 {
+	return b.XXHashStrategy.Hash(data, i) + 1
+}
+
+// customClient is a RedisClient implemented outside the package, without the full
+// go-redis command set
+type customClient struct {
+	client *redis.Client
+	// execs counts the pipelines executed through countingPipeliner; nil returns the
+	// go-redis pipeline itself
+	execs *int64
+	// created counts the pipelines created
+	created int64
+}
+
+// countingPipeliner is a Pipeliner implemented outside the package
+type countingPipeliner struct {
+	redis.Pipeliner
+	execs *int64
+}
+
+func (p *countingPipeliner) Exec(ctx context.Context) ([]redis.Cmder, error) {
+	atomic.AddInt64(p.execs, 1)
+	return p.Pipeliner.Exec(ctx)
+}
+
+func (c *customClient) SetBit(ctx context.Context, key string, offset int64, value int) *redis.IntCmd {
+	return c.client.SetBit(ctx, key, offset, value)
+}
+
+func (c *customClient) GetBit(ctx context.Context, key string, offset int64) *redis.IntCmd {
+	return c.client.GetBit(ctx, key, offset)
+}
+
+func (c *customClient) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	return c.client.Expire(ctx, key, expiration)
+}
+
+func (c *customClient) Pipeline() Pipeliner {
+	atomic.AddInt64(&c.created, 1)
+	if c.execs == nil {
+		return c.client.Pipeline()
+	}
+	return &countingPipeliner{Pipeliner: c.client.Pipeline(), execs: c.execs}
+}
+
+// recordingMetrics is a Metrics that keeps the latest counters and gauges
+type recordingMetrics struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	gauges   map[string]float64
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{counters: make(map[string]int64), gauges: make(map[string]float64)}
+}
+
+func (m *recordingMetrics) IncCounter(name string, delta int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[name] += delta
+}
+
+func (m *recordingMetrics) SetGauge(name string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges[name] = value
+}
+
+func (m *recordingMetrics) ObserveDuration(string, time.Duration) {}
+
+func (m *recordingMetrics) counter(name string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counters[name]
+}
+
+func (m *recordingMetrics) gauge(name string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gauges[name]
+}
+
+// mapCache is an external Cache backed by a map
+type mapCache struct {
+	mu      sync.Mutex
+	answers map[string]bool
+}
+
+func (c *mapCache) Get(key string) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	exists, ok := c.answers[key]
+	return exists, ok
+}
+
+func (c *mapCache) Set(key string, exists bool, _ time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.answers[key] = exists
+}
+
+// delayHook is a go-redis hook that slows down every command and pipeline
+type delayHook time.Duration
+
+func (d delayHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (d delayHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		time.Sleep(time.Duration(d))
+		return next(ctx, cmd)
+	}
+}
+
+func (d delayHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		time.Sleep(time.Duration(d))
+		return next(ctx, cmds)
+	}
+}
+
+// misrouteHook is a go-redis hook that redirects every other SETBIT of a pipeline to
+// key, so Redis fails those commands while the rest succeed
+type misrouteHook string
+
+func (m misrouteHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (m misrouteHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (m misrouteHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		setbits := 0
+		for _, cmd := range cmds {
+			if cmd.Name() == "setbit" {
+				if setbits%2 == 1 {
+					cmd.Args()[1] = string(m)
+				}
+				setbits++
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// timingMetrics is a recordingMetrics that also keeps every observed duration
+type timingMetrics struct {
+	*recordingMetrics
+	durations map[string][]time.Duration
+}
+
+func newTimingMetrics() *timingMetrics {
+	return &timingMetrics{recordingMetrics: newRecordingMetrics(), durations: make(map[string][]time.Duration)}
+}
+
+func (m *timingMetrics) ObserveDuration(name string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.durations[name] = append(m.durations[name], d)
+}
+
+func (m *timingMetrics) observed(name string) []time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]time.Duration(nil), m.durations[name]...)
+}
+
 func TestIntegrationWithRealRedis(t *testing.T) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     "redis:6379",
@@ -67,6 +328,37 @@ func TestIntegrationWithRealRedis(t *testing.T) {
 		}
 	})
 
+	t.Run("AddManyAndExistsMany", func(t *testing.T) {
+		key := "integration:test:many"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := [][]byte{[]byte("many_1"), []byte("many_2"), []byte("many_3")}
+		if err := bf.AddMany(items); err != nil {
+			t.Fatalf("Failed to add items: %v", err)
+		}
+		found, err := bf.ExistsMany(append(items, []byte("many_absent")))
+		if err != nil {
+			t.Fatalf("Failed to check items: %v", err)
+		}
+		for i := range items {
+			if !found[i] {
+				t.Errorf("Item %d should exist after AddMany", i)
+			}
+		}
+		if found[len(items)] {
+			t.Error("Absent item reported as present")
+		}
+	})
+
 	t.Run("FalsePositiveRate", func(t *testing.T) {
 		key := "integration:test:fpr"
 		cleanupKey(client, key)
@@ -154,67 +446,199 @@ func TestIntegrationWithRealRedis(t *testing.T) {
 		}
 	})
 
-	t.Run("TTL", func(t *testing.T) {
-		key := "integration:test:ttl"
+	t.Run("DeletableFilter", func(t *testing.T) {
+		key := "integration:test:deletable"
+		cleanupKey(client, key)
+		cleanupKey(client, "{"+key+"}:removed")
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, "{"+key+"}:removed")
+		df, err := NewDeletableFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create deletable filter: %v", err)
+		}
+		kept, removed := []byte("integration_kept"), []byte("integration_removed")
+		for _, data := range [][]byte{kept, removed} {
+			if err := df.Add(data); err != nil {
+				t.Fatalf("Failed to add data: %v", err)
+			}
+		}
+		if err := df.Remove(removed); err != nil {
+			t.Fatalf("Failed to remove data: %v", err)
+		}
+		if exists, err := df.Exists(removed); err != nil || exists {
+			t.Errorf("Removed data should not exist (exists=%v, err=%v)", exists, err)
+		}
+		if err := df.Compact(ctx, NewSliceIterator([][]byte{kept, removed})); err != nil {
+			t.Fatalf("Failed to compact: %v", err)
+		}
+		for _, data := range [][]byte{kept, removed} {
+			if exists, err := df.Exists(data); err != nil || !exists {
+				t.Errorf("Live data %q should exist after compaction (exists=%v, err=%v)", data, exists, err)
+			}
+		}
+	})
+
+	t.Run("DeletableCompactConcurrentWrites", func(t *testing.T) {
+		key := "integration:test:deletable:concurrent"
+		cleanupKey(client, key)
+		cleanupKey(client, "{"+key+"}:removed")
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, "{"+key+"}:removed")
+		df, err := NewDeletableFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create deletable filter: %v", err)
+		}
+		kept, added, removed := []byte("integration_kept"), []byte("integration_added"), []byte("integration_removed")
+		for _, data := range [][]byte{kept, removed} {
+			if err := df.Add(data); err != nil {
+				t.Fatalf("Failed to add data: %v", err)
+			}
+		}
+		// The source still yields the removed item, as a snapshot taken before the removal would
+		source := &hookIterator{
+			Iterator: NewSliceIterator([][]byte{kept, removed}),
+			hook: func() {
+				if err := df.Add(added); err != nil {
+					t.Errorf("Add during compaction failed: %v", err)
+				}
+				if err := df.Remove(removed); err != nil {
+					t.Errorf("Remove during compaction failed: %v", err)
+				}
+				if err := df.Compact(ctx, NewSliceIterator(nil)); !errors.Is(err, ErrCompactionInProgress) {
+					t.Errorf("Nested Compact = %v, want ErrCompactionInProgress", err)
+				}
+			},
+		}
+		if err := df.Compact(ctx, source); err != nil {
+			t.Fatalf("Failed to compact: %v", err)
+		}
+		for _, data := range [][]byte{kept, added} {
+			if exists, err := df.Exists(data); err != nil || !exists {
+				t.Errorf("%q should exist after compaction (exists=%v, err=%v)", data, exists, err)
+			}
+		}
+		if exists, err := df.Exists(removed); err != nil || exists {
+			t.Errorf("Item removed during compaction should stay removed (exists=%v, err=%v)", exists, err)
+		}
+		if n := client.Exists(ctx, "{"+key+"}:compact", "{"+key+"}:compact:removed").Val(); n != 0 {
+			t.Errorf("%d staging keys left after compaction", n)
+		}
+	})
+
+	t.Run("FrequencyFilter", func(t *testing.T) {
+		key := "integration:test:frequency"
+		cleanupKey(client, key)
+		cleanupKey(client, "{"+key+"}:cms")
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, "{"+key+"}:cms")
+		ff, err := NewFrequencyFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}, FrequencyOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create frequency filter: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			if err := ff.Add([]byte("frequent")); err != nil {
+				t.Fatalf("Failed to add data: %v", err)
+			}
+		}
+		seen, count, err := ff.Query([]byte("frequent"))
+		if err != nil {
+			t.Fatalf("Failed to query data: %v", err)
+		}
+		if !seen || count < 3 {
+			t.Errorf("Expected item seen at least 3 times, got seen=%v count=%d", seen, count)
+		}
+		seen, count, err = ff.Query([]byte("never_added"))
+		if err != nil {
+			t.Fatalf("Failed to query data: %v", err)
+		}
+		if seen && count == 0 {
+			t.Error("A seen item should have a non-zero count")
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		key := "integration:test:stats"
 		cleanupKey(client, key)
 		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
 		bf, err := NewBloomFilter(Config{
 			RedisKey:           key,
 			RedisClient:        redisClient,
 			ExpectedInsertions: 1000,
 			FalsePositiveRate:  0.01,
-			TTL:                2 * time.Second,
+			TTL:                time.Hour,
 		})
 		if err != nil {
 			t.Fatalf("Failed to create Bloom Filter: %v", err)
 		}
-		testData := []byte("integration_ttl_test")
-		err = bf.Add(testData)
+		if err := bf.Add([]byte("measured")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		stats, err := bf.Stats()
 		if err != nil {
-			t.Fatalf("Failed to add data: %v", err)
+			t.Fatalf("Failed to read stats: %v", err)
 		}
-		exists, err := bf.Exists(testData)
+		if stats.BitSize == 0 || stats.HashCount == 0 || stats.HashStrategy != HashXXHash {
+			t.Errorf("Unexpected parameters: %+v", stats)
+		}
+		if stats.SetBits == 0 || stats.SetBits > uint64(stats.HashCount) || stats.FillRatio <= 0 {
+			t.Errorf("Unexpected bit counts: %+v", stats)
+		}
+		if stats.MemoryUsage <= 0 || stats.TTL <= 0 || stats.TTL > time.Hour {
+			t.Errorf("Unexpected memory usage or TTL: %+v", stats)
+		}
+	})
+
+	t.Run("EstimateCardinality", func(t *testing.T) {
+		key := "integration:test:cardinality"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 10000,
+			FalsePositiveRate:  0.01,
+		})
 		if err != nil {
-			t.Fatalf("Failed to check data: %v", err)
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
 		}
-		if !exists {
-			t.Error("Data should exist immediately after adding")
+		items := make([][]byte, 1000)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("distinct_%d", i))
 		}
-		t.Log("Waiting for TTL to expire...")
-		time.Sleep(3 * time.Second)
-		exists, err = bf.Exists(testData)
+		if err := bf.AddMany(items); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		n, err := bf.EstimateCardinality(ctx)
 		if err != nil {
-			t.Fatalf("Failed to check data after TTL: %v", err)
+			t.Fatalf("Failed to estimate cardinality: %v", err)
 		}
-		if exists {
-			t.Error("Data should not exist after TTL expiration")
+		if n < 950 || n > 1050 {
+			t.Errorf("Expected an estimate near 1000, got %d", n)
 		}
 	})
-}
 
-func TestIntegrationWithRedisCluster(t *testing.T) {
-	clusterClient := redis.NewClusterClient(&redis.ClusterOptions{
-		Addrs: []string{
-			"redis-cluster:7000",
-			"redis-cluster:7001",
-			"redis-cluster:7002",
-			"redis-cluster:7003",
-			"redis-cluster:7004",
-			"redis-cluster:7005",
-		},
-		RouteRandomly:  true,
-		RouteByLatency: true,
-	})
-	ctx := context.Background()
-	if err := clusterClient.Ping(ctx).Err(); err != nil {
-		t.Skipf("Redis Cluster not available, skipping cluster test: %v", err)
-	}
-	defer clusterClient.Close()
-	redisClient := NewClusterRedisClient(clusterClient)
-	t.Run("ClusterBasicFunctionality", func(t *testing.T) {
-		key := "bloom:{integration:cluster:test}"
-		clusterClient.Del(ctx, key)
-		defer clusterClient.Del(ctx, key)
+	t.Run("CurrentFalsePositiveRate", func(t *testing.T) {
+		key := "integration:test:currentfpr"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
 		bf, err := NewBloomFilter(Config{
 			RedisKey:           key,
 			RedisClient:        redisClient,
@@ -224,26 +648,4725 @@ func TestIntegrationWithRedisCluster(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to create Bloom Filter: %v", err)
 		}
-		testData := []byte("integration_cluster_test_data")
-		exists, err := bf.Exists(testData)
+		items := make([][]byte, 1000)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("fpr_%d", i))
+		}
+		if err := bf.AddMany(items[:500]); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		half, err := bf.CurrentFalsePositiveRate(ctx)
 		if err != nil {
-			t.Fatalf("Failed to check existence: %v", err)
+			t.Fatalf("Failed to compute false-positive rate: %v", err)
 		}
-		if exists {
-			t.Error("Data should not exist initially")
+		if err := bf.AddMany(items[500:]); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
 		}
-		err = bf.Add(testData)
+		full, err := bf.CurrentFalsePositiveRate(ctx)
+		if err != nil {
+			t.Fatalf("Failed to compute false-positive rate: %v", err)
+		}
+		if half >= full || full < 0.005 || full > 0.02 {
+			t.Errorf("Expected the rate to grow towards 0.01, got %g then %g", half, full)
+		}
+	})
+
+	t.Run("FrequencyFilterBookkeeping", func(t *testing.T) {
+		key := "integration:test:frequency-bookkeeping"
+		stream := companionKey(key, auditKeySuffix)
+		for _, k := range []string{key, metadataKey(key), companionKey(key, sketchKeySuffix), stream} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		ff, err := NewFrequencyFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			ResultCache:        &ResultCache{Cache: &mapCache{answers: make(map[string]bool)}, NegativeTTL: time.Minute},
+			Audit:              &Audit{RawItems: true},
+			Capabilities:       &Capabilities{Lua: true},
+		}, FrequencyOptions{})
 		if err != nil {
+			t.Fatalf("Failed to create frequency filter: %v", err)
+		}
+
+		// A cached negative answer is replaced by the Add
+		if exists, err := ff.Exists([]byte("counted")); err != nil || exists {
+			t.Fatalf("Expected counted to be absent before the Add (exists=%v, err=%v)", exists, err)
+		}
+		if err := ff.Add([]byte("counted")); err != nil {
 			t.Fatalf("Failed to add data: %v", err)
 		}
-		exists, err = bf.Exists(testData)
+		if exists, err := ff.Exists([]byte("counted")); err != nil || !exists {
+			t.Errorf("Expected counted to exist after the Add (exists=%v, err=%v)", exists, err)
+		}
+		entries, err := client.XRange(ctx, stream, "-", "+").Result()
 		if err != nil {
-			t.Fatalf("Failed to check existence after adding: %v", err)
+			t.Fatalf("Failed to read audit stream: %v", err)
 		}
-		if !exists {
-			t.Error("Data should exist after adding")
+		if len(entries) != 1 || entries[0].Values[auditFieldItem] != "counted" {
+			t.Errorf("Expected the Add in the audit stream, got %v", entries)
 		}
 	})
+
+	t.Run("SaturationAlert", func(t *testing.T) {
+		key := "integration:test:saturation"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		events := make(chan SaturationEvent, 4)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 100,
+			FalsePositiveRate:  0.01,
+			SaturationAlert: &SaturationAlert{
+				Interval:    time.Millisecond,
+				OnThreshold: func(e SaturationEvent) { events <- e },
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		for i := 0; i < 150; i++ {
+			if err := bf.Add([]byte(fmt.Sprintf("saturating_%d", i))); err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+			time.Sleep(2 * time.Millisecond)
+		}
+		for _, want := range []float64{0.8, 1} {
+			select {
+			case e := <-events:
+				if !e.Capacity || e.Threshold != want {
+					t.Errorf("Expected capacity threshold %g, got %+v", want, e)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Expected an alert for threshold %g", want)
+			}
+		}
+	})
+
+	t.Run("SaturationAlertAfterLastWrite", func(t *testing.T) {
+		key := "integration:test:saturation-trailing"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		events := make(chan SaturationEvent, 4)
+		metrics := newRecordingMetrics()
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 100,
+			FalsePositiveRate:  0.01,
+			Metrics:            metrics,
+			SaturationAlert: &SaturationAlert{
+				Interval:    200 * time.Millisecond,
+				OnThreshold: func(e SaturationEvent) { events <- e },
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		// The first write is sampled at once, the rest within the same interval and
+		// only by the sample that follows it
+		items := make([][]byte, 150)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("saturating_%d", i))
+		}
+		if err := bf.Add(items[0]); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		for deadline := time.Now().Add(time.Second); metrics.gauge(MetricFillRatio) == 0; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatal("Expected the first write to be sampled")
+			}
+		}
+		if err := bf.AddMany(items[1:]); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		for _, want := range []float64{0.8, 1} {
+			select {
+			case e := <-events:
+				if !e.Capacity || e.Threshold != want {
+					t.Errorf("Expected capacity threshold %g, got %+v", want, e)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Expected a trailing sample to alert for threshold %g", want)
+			}
+		}
+	})
+
+	t.Run("WatchSaturation", func(t *testing.T) {
+		key := "integration:test:saturation-watch"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 100,
+			FalsePositiveRate:  0.01,
+		}
+		writer, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := WatchSaturation(ctx, writer); !errors.Is(err, ErrSaturationAlertDisabled) {
+			t.Errorf("Expected ErrSaturationAlertDisabled without an alert, got %v", err)
+		}
+
+		events := make(chan SaturationEvent, 4)
+		cfg.SaturationAlert = &SaturationAlert{
+			Interval:    20 * time.Millisecond,
+			OnThreshold: func(e SaturationEvent) { events <- e },
+		}
+		watcher, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		watchCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() { done <- WatchSaturation(watchCtx, watcher) }()
+
+		// Writes through another instance are only seen by the watcher's ticker
+		items := make([][]byte, 150)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("saturating_%d", i))
+		}
+		if err := writer.AddMany(items); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		for _, want := range []float64{0.8, 1} {
+			select {
+			case e := <-events:
+				if !e.Capacity || e.Threshold != want {
+					t.Errorf("Expected capacity threshold %g, got %+v", want, e)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Expected the watcher to alert for threshold %g", want)
+			}
+		}
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected WatchSaturation to return the context's error, got %v", err)
+		}
+	})
+
+	t.Run("ParameterMismatch", func(t *testing.T) {
+		key := "integration:test:mismatch"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("recorded")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if _, err := NewBloomFilter(cfg); err != nil {
+			t.Errorf("Expected a matching filter to open, got %v", err)
+		}
+		cfg.ExpectedInsertions = 2000
+		_, err = NewBloomFilter(cfg)
+		var mismatch *ParameterMismatchError
+		if !errors.As(err, &mismatch) || mismatch.Field != "bits" {
+			t.Errorf("Expected a bit size mismatch, got %v", err)
+		}
+	})
+
+	t.Run("CreateAndOpen", func(t *testing.T) {
+		key := "integration:test:create"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		if _, err := OpenBloomFilter(ctx, Config{RedisKey: key, RedisClient: redisClient}); !errors.Is(err, ErrFilterNotFound) {
+			t.Fatalf("Expected ErrFilterNotFound before creation, got %v", err)
+		}
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}
+		created, err := CreateBloomFilter(ctx, cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if _, err := CreateBloomFilter(ctx, cfg); !errors.Is(err, ErrFilterExists) {
+			t.Errorf("Expected ErrFilterExists on a second create, got %v", err)
+		}
+		if err := created.Add([]byte("shared")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		opened, err := OpenBloomFilter(ctx, Config{RedisKey: key, RedisClient: redisClient})
+		if err != nil {
+			t.Fatalf("Failed to open Bloom Filter: %v", err)
+		}
+		if exists, err := opened.Exists([]byte("shared")); err != nil || !exists {
+			t.Errorf("Expected the opened filter to see the element, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("ConcurrentCreate", func(t *testing.T) {
+		key := "integration:test:create:concurrent"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		created := 0
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				_, err := CreateBloomFilter(ctx, cfg)
+				if err != nil && !errors.Is(err, ErrFilterExists) {
+					t.Errorf("Unexpected create error: %v", err)
+				}
+				if err == nil {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				// An opener sees either no filter or all of its parameters
+				if _, err := OpenBloomFilter(ctx, Config{RedisKey: key, RedisClient: redisClient}); err != nil && !errors.Is(err, ErrFilterNotFound) {
+					t.Errorf("Unexpected open error during creation: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if created != 1 {
+			t.Errorf("Expected exactly one creator to win, got %d", created)
+		}
+
+		// A bitmap written before metadata was recorded is not taken over
+		legacy := "integration:test:create:legacy"
+		cleanupKey(client, metadataKey(legacy))
+		defer cleanupKey(client, legacy)
+		client.SetBit(ctx, legacy, 1, 1)
+		cfg.RedisKey = legacy
+		if _, err := CreateBloomFilter(ctx, cfg); !errors.Is(err, ErrFilterExists) {
+			t.Errorf("Expected ErrFilterExists over an existing bitmap, got %v", err)
+		}
+		if n := client.Exists(ctx, metadataKey(legacy)).Val(); n != 0 {
+			t.Error("Expected no metadata to be written for an existing bitmap")
+		}
+	})
+
+	t.Run("Rebuild", func(t *testing.T) {
+		key := "integration:test:rebuild"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 10,
+			FalsePositiveRate:  0.1,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := make([][]byte, 100)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("rebuilt_%d", i))
+		}
+		if err := bf.AddMany(items); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		rebuilt, err := bf.Rebuild(ctx, ResizeParams{ExpectedInsertions: 1000, FalsePositiveRate: 0.01}, NewSliceIterator(items))
+		if err != nil {
+			t.Fatalf("Failed to rebuild filter: %v", err)
+		}
+		results, err := rebuilt.ExistsMany(items)
+		if err != nil {
+			t.Fatalf("Failed to check elements: %v", err)
+		}
+		for i, exists := range results {
+			if !exists {
+				t.Errorf("Expected %s to survive the rebuild", items[i])
+			}
+		}
+	})
+
+	t.Run("ExplicitParameters", func(t *testing.T) {
+		key := "integration:test:explicit"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:    key,
+			RedisClient: redisClient,
+			BitSize:     8192,
+			HashCount:   5,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if positions := bf.Positions([]byte("explicit")); len(positions) != 5 {
+			t.Errorf("Expected 5 positions, got %d", len(positions))
+		}
+		for _, pos := range bf.Positions([]byte("explicit")) {
+			if pos >= 8192 {
+				t.Errorf("Expected offsets below 8192, got %d", pos)
+			}
+		}
+	})
+
+	t.Run("PlanningHelpers", func(t *testing.T) {
+		m, k := EstimateParameters(1000, 0.01)
+		if m != 9586 || k != 7 {
+			t.Errorf("Expected 9586 bits and 7 hashes, got %d and %d", m, k)
+		}
+		if p := EstimateFPR(m, k, 1000); p < 0.009 || p > 0.011 {
+			t.Errorf("Expected a rate near 0.01 at capacity, got %g", p)
+		}
+		if n := EstimateCapacity(m, k, 0.01); n < 990 || n > 1010 {
+			t.Errorf("Expected a capacity near 1000, got %d", n)
+		}
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		key := "integration:test:retry"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		// A hash under the key makes every SETBIT fail with WRONGTYPE
+		if err := client.HSet(ctx, key, "f", "v").Err(); err != nil {
+			t.Fatalf("Failed to set up key: %v", err)
+		}
+		var attempts int
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			Retry: &RetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				Retryable: func(error) bool {
+					attempts++
+					return true
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		err = bf.Add([]byte("a"))
+		if err == nil {
+			t.Fatal("Expected Add to fail on a hash key")
+		}
+		if attempts != 2 {
+			t.Errorf("Expected 2 retries, got %d", attempts)
+		}
+		if IsTransientError(err) {
+			t.Error("Expected WRONGTYPE not to be transient")
+		}
+		if !IsTransientError(&PipelineError{Commands: []CommandResult{{Op: "setbit", Err: io.EOF}}}) {
+			t.Error("Expected a pipeline failing with EOF to be transient")
+		}
+	})
+
+	t.Run("CircuitBreaker", func(t *testing.T) {
+		key := "integration:test:circuit"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		// A hash under the key makes every SETBIT fail with WRONGTYPE
+		if err := client.HSet(ctx, key, "f", "v").Err(); err != nil {
+			t.Fatalf("Failed to set up key: %v", err)
+		}
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			CircuitBreaker: &CircuitBreaker{
+				FailureThreshold: 2,
+				OpenTimeout:      100 * time.Millisecond,
+				Policy:           FailClosed,
+				IsFailure:        func(error) bool { return true },
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := bf.Add([]byte("a")); err == nil || errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("Expected Add to reach Redis and fail, got %v", err)
+			}
+		}
+		if err := bf.Add([]byte("a")); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected ErrCircuitOpen, got %v", err)
+		}
+		if exists, err := bf.Exists([]byte("unseen")); err != nil || !exists {
+			t.Errorf("Expected a fail-closed answer, got %v, %v", exists, err)
+		}
+
+		cleanupKey(client, key)
+		time.Sleep(150 * time.Millisecond)
+		if err := bf.Add([]byte("a")); err != nil {
+			t.Fatalf("Expected the trial call to close the circuit, got %v", err)
+		}
+		if exists, err := bf.Exists([]byte("unseen")); err != nil || exists {
+			t.Errorf("Expected Redis to answer again, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("LocalFallback", func(t *testing.T) {
+		key := "integration:test:fallback"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		breaker := &CircuitBreaker{FailureThreshold: 1, OpenTimeout: time.Hour}
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			CircuitBreaker:     breaker,
+			LocalFallback:      &LocalFallback{},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		// Open the circuit without taking Redis down
+		bf.(*bloomFilter).circuit.record(io.EOF)
+
+		if err := bf.Add([]byte("buffered")); err != nil {
+			t.Fatalf("Expected Add to be buffered, got %v", err)
+		}
+		if exists, err := bf.Exists([]byte("buffered")); err != nil || !exists {
+			t.Errorf("Expected the buffered item to exist, got %v, %v", exists, err)
+		}
+		if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+			t.Error("Expected nothing to be written while the circuit is open")
+		}
+
+		bf.(*bloomFilter).circuit.record(nil)
+		if err := bf.Add([]byte("direct")); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for bf.(*bloomFilter).fallback.buffered() > 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		other, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if exists, err := other.Exists([]byte("buffered")); err != nil || !exists {
+			t.Errorf("Expected the buffered item to be replayed, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("BitStore", func(t *testing.T) {
+		key := "integration:test:bitstore"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        NewBitStoreClient(NewRedisBitStore(client)),
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			TTL:                time.Hour,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.AddMany([][]byte{[]byte("a"), []byte("b")}); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		found, err := bf.ExistsMany([][]byte{[]byte("a"), []byte("b"), []byte("c")})
+		if err != nil || !found[0] || !found[1] || found[2] {
+			t.Errorf("Expected [true true false], got %v, %v", found, err)
+		}
+		if ttl := client.TTL(ctx, key).Val(); ttl <= 0 {
+			t.Errorf("Expected the bitmap to expire, got %v", ttl)
+		}
+		if err := bf.Drop(ctx, DeleteOptions{}); err != nil {
+			t.Fatalf("Failed to drop filter: %v", err)
+		}
+		if n := client.Exists(ctx, key).Val(); n != 0 {
+			t.Error("Expected Drop to delete the bitmap")
+		}
+	})
+
+	t.Run("ClientSideCaching", func(t *testing.T) {
+		key := "integration:test:tracking"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		tc, err := NewTrackingClient(ctx, client, TrackingOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create tracking client: %v", err)
+		}
+		defer tc.Close()
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        tc,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}
+		cached, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		cfg.RedisClient = redisClient
+		direct, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+
+		if err := cached.Add([]byte("own")); err != nil {
+			t.Fatalf("Failed to add: %v", err)
+		}
+		if exists, err := cached.Exists([]byte("own")); err != nil || !exists {
+			t.Fatalf("Expected own write to be visible at once, got %v, %v", exists, err)
+		}
+		if exists, err := cached.Exists([]byte("other")); err != nil || exists {
+			t.Fatalf("Expected other item to be absent, got %v, %v", exists, err)
+		}
+		if err := direct.Add([]byte("other")); err != nil {
+			t.Fatalf("Failed to add: %v", err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			exists, err := cached.Exists([]byte("other"))
+			if err != nil {
+				t.Fatalf("Failed to check existence: %v", err)
+			}
+			if exists {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Expected invalidation after a write by another client")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("SnapshotRefresh", func(t *testing.T) {
+		key := "integration:test:snapshot"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		mirror, err := NewMirroredFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create mirror: %v", err)
+		}
+		if err := bf.Add([]byte("before")); err != nil {
+			t.Fatalf("Failed to add: %v", err)
+		}
+
+		refreshCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- mirror.Refresh(refreshCtx, RefreshOptions{Interval: 50 * time.Millisecond})
+		}()
+		waitFor := func(item string, want bool) {
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				exists, err := mirror.Exists([]byte(item))
+				if err != nil {
+					t.Fatalf("Failed to check existence: %v", err)
+				}
+				if mirror.Ready() && exists == want {
+					return
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Expected snapshot to report %q as %v", item, want)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+		waitFor("before", true)
+		if err := bf.Add([]byte("after")); err != nil {
+			t.Fatalf("Failed to add: %v", err)
+		}
+		waitFor("after", true)
+		cleanupKey(client, key)
+		waitFor("before", false)
+
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected Refresh to return context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("UpdateNotifications", func(t *testing.T) {
+		for _, offsets := range []bool{true, false} {
+			key := fmt.Sprintf("integration:test:updates:%v", offsets)
+			cleanupKey(client, key)
+			defer cleanupKey(client, key)
+			cfg := Config{
+				RedisKey:            key,
+				RedisClient:         redisClient,
+				ExpectedInsertions:  1000,
+				FalsePositiveRate:   0.01,
+				UpdateNotifications: &UpdateNotifications{Offsets: offsets},
+			}
+			bf, err := NewBloomFilter(cfg)
+			if err != nil {
+				t.Fatalf("Failed to create Bloom Filter: %v", err)
+			}
+			mirror, err := NewMirroredFilter(cfg)
+			if err != nil {
+				t.Fatalf("Failed to create mirror: %v", err)
+			}
+			refreshCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go mirror.Refresh(refreshCtx, RefreshOptions{Interval: time.Hour})
+			for deadline := time.Now().Add(2 * time.Second); !mirror.Ready(); time.Sleep(10 * time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Fatal("Expected the mirror to complete its first download")
+				}
+			}
+
+			if err := bf.Add([]byte("notified")); err != nil {
+				t.Fatalf("Failed to add: %v", err)
+			}
+			if err := bf.AddBatch([][]byte{[]byte("batched")}); err != nil {
+				t.Fatalf("Failed to add batch: %v", err)
+			}
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				notified, err := mirror.Exists([]byte("notified"))
+				if err != nil {
+					t.Fatalf("Failed to check existence: %v", err)
+				}
+				batched, err := mirror.Exists([]byte("batched"))
+				if err != nil {
+					t.Fatalf("Failed to check existence: %v", err)
+				}
+				if notified && batched {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Expected the mirror to follow notifications (offsets: %v)", offsets)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+		if got := parseUpdate("3 17 x"); got != nil {
+			t.Errorf("Expected malformed notification to list no offsets, got %v", got)
+		}
+	})
+
+	t.Run("ResultCacheValidation", func(t *testing.T) {
+		key := "integration:test:cache-validation"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			ResultCache:        &ResultCache{ValidateInterval: 20 * time.Millisecond},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("cached")); err != nil {
+			t.Fatalf("Failed to add: %v", err)
+		}
+		if exists, err := bf.Exists([]byte("cached")); err != nil || !exists {
+			t.Fatalf("Expected item to exist, got %v, %v", exists, err)
+		}
+		// Another process clears the filter
+		cleanupKey(client, key)
+		if exists, _ := bf.Exists([]byte("cached")); !exists {
+			t.Error("Expected the answer to be cached until the next validation")
+		}
+		time.Sleep(30 * time.Millisecond)
+		if exists, err := bf.Exists([]byte("cached")); err != nil || exists {
+			t.Errorf("Expected the cleared filter to be detected, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("WriteBehind", func(t *testing.T) {
+		key := "integration:test:write-behind"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		buffered, err := NewBufferedFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 10000,
+			FalsePositiveRate:  0.01,
+		}, WriteBehindOptions{MaxBatch: 100, FlushInterval: 20 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("Failed to create buffered filter: %v", err)
+		}
+		for i := 0; i < 250; i++ {
+			if err := buffered.Add([]byte(fmt.Sprintf("event:%d", i))); err != nil {
+				t.Fatalf("Failed to enqueue: %v", err)
+			}
+		}
+		if err := buffered.Flush(ctx); err != nil {
+			t.Fatalf("Failed to flush: %v", err)
+		}
+		for i := 0; i < 250; i++ {
+			if exists, err := buffered.Exists([]byte(fmt.Sprintf("event:%d", i))); err != nil || !exists {
+				t.Fatalf("Expected event:%d after flush, got %v, %v", i, exists, err)
+			}
+		}
+
+		if err := buffered.Add([]byte("timed")); err != nil {
+			t.Fatalf("Failed to enqueue: %v", err)
+		}
+		time.Sleep(60 * time.Millisecond)
+		if exists, _ := buffered.Exists([]byte("timed")); !exists {
+			t.Error("Expected the flush interval to write the queue")
+		}
+
+		if err := buffered.Add([]byte("last")); err != nil {
+			t.Fatalf("Failed to enqueue: %v", err)
+		}
+		if err := buffered.Close(ctx); err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+		if exists, _ := buffered.Exists([]byte("last")); !exists {
+			t.Error("Expected Close to write the queue")
+		}
+		if err := buffered.Add([]byte("late")); !errors.Is(err, ErrFilterClosed) {
+			t.Errorf("Expected ErrFilterClosed after Close, got %v", err)
+		}
+	})
+
+	t.Run("WriteBehindPause", func(t *testing.T) {
+		key := "integration:test:write-behind-pause"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		buffered, err := NewBufferedFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 10000,
+			FalsePositiveRate:  0.01,
+		}, WriteBehindOptions{MaxBatch: 10, FlushInterval: 10 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("Failed to create buffered filter: %v", err)
+		}
+		defer buffered.Close(ctx)
+
+		buffered.Pause()
+		for i := 0; i < 50; i++ {
+			if err := buffered.Add([]byte(fmt.Sprintf("paused:%d", i))); err != nil {
+				t.Fatalf("Failed to enqueue: %v", err)
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		if exists, _ := buffered.Exists([]byte("paused:0")); exists {
+			t.Error("Expected no writes while paused")
+		}
+		if n := buffered.Queued(); n != 50 {
+			t.Errorf("Expected 50 queued items while paused, got %d", n)
+		}
+		if age := buffered.QueueAge(); age < 40*time.Millisecond {
+			t.Errorf("Expected the queue age to cover the pause, got %s", age)
+		}
+
+		buffered.Resume()
+		time.Sleep(50 * time.Millisecond)
+		if exists, _ := buffered.Exists([]byte("paused:49")); !exists {
+			t.Error("Expected Resume to write the queue")
+		}
+		if n, age := buffered.Queued(), buffered.QueueAge(); n != 0 || age != 0 {
+			t.Errorf("Expected an empty queue after Resume, got %d items aged %s", n, age)
+		}
+
+		buffered.Pause()
+		if err := buffered.Add([]byte("drained")); err != nil {
+			t.Fatalf("Failed to enqueue: %v", err)
+		}
+		if err := buffered.Drain(ctx); err != nil {
+			t.Fatalf("Failed to drain: %v", err)
+		}
+		if exists, _ := buffered.Exists([]byte("drained")); !exists {
+			t.Error("Expected Drain to write the queue while paused")
+		}
+		if n := buffered.Queued(); n != 0 {
+			t.Errorf("Expected an empty queue after Drain, got %d", n)
+		}
+	})
+
+	t.Run("WriteBehindCloseWhilePaused", func(t *testing.T) {
+		key := "integration:test:write-behind-close-paused"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		buffered, err := NewBufferedFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 10000,
+			FalsePositiveRate:  0.01,
+		}, WriteBehindOptions{MaxBatch: 5, MaxQueued: 10, FlushInterval: 10 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("Failed to create buffered filter: %v", err)
+		}
+
+		// Paused, the worker holds one batch of 5 and the queue fills with 10 more
+		buffered.Pause()
+		for i := 0; i < 15; i++ {
+			if err := buffered.Add([]byte(fmt.Sprintf("held:%d", i))); err != nil {
+				t.Fatalf("Failed to enqueue: %v", err)
+			}
+		}
+		blocked := make(chan error, 1)
+		go func() { blocked <- buffered.Add([]byte("blocked")) }()
+		time.Sleep(50 * time.Millisecond)
+		select {
+		case err := <-blocked:
+			t.Fatalf("Expected Add to block on the full queue, got %v", err)
+		default:
+		}
+
+		closeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := buffered.Close(closeCtx); err != nil {
+			t.Fatalf("Close of a paused filter with a full queue failed: %v", err)
+		}
+		if err := <-blocked; !errors.Is(err, ErrFilterClosed) {
+			t.Errorf("Expected the blocked Add to fail with ErrFilterClosed, got %v", err)
+		}
+		for i := 0; i < 15; i++ {
+			if exists, _ := buffered.Exists([]byte(fmt.Sprintf("held:%d", i))); !exists {
+				t.Errorf("Expected Close to write queued item %d", i)
+			}
+		}
+	})
+
+	t.Run("ParallelBulkLoad", func(t *testing.T) {
+		key := "integration:test:bulk-parallel"
+		checkpoint := key + ":checkpoint"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, checkpoint)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 20000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := make([][]byte, 10007)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("bulk:%d", i))
+		}
+		var last BulkLoadProgress
+		loaded, err := bf.BulkLoad(ctx, NewSliceIterator(items), BulkLoadOptions{
+			BatchSize:     500,
+			PipelineSize:  700,
+			Workers:       4,
+			Total:         int64(len(items)),
+			CheckpointKey: checkpoint,
+			Progress:      func(p BulkLoadProgress) { last = p },
+		})
+		if err != nil || loaded != int64(len(items)) {
+			t.Fatalf("Expected %d items loaded, got %d, %v", len(items), loaded, err)
+		}
+		if last.Items != loaded {
+			t.Errorf("Expected final progress of %d items, got %d", loaded, last.Items)
+		}
+		if n := client.Exists(ctx, checkpoint).Val(); n != 0 {
+			t.Error("Expected the checkpoint to be deleted after the load")
+		}
+		for _, item := range items {
+			if exists, err := bf.Exists(item); err != nil || !exists {
+				t.Fatalf("Expected %s after the load, got %v, %v", item, exists, err)
+			}
+		}
+	})
+
+	t.Run("AddStream", func(t *testing.T) {
+		key := "integration:test:stream"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 10000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		events := make(chan []byte, 64)
+		errs := bf.AddStream(ctx, events)
+		go func() {
+			for i := 0; i < 3000; i++ {
+				events <- []byte(fmt.Sprintf("stream:%d", i))
+			}
+			close(events)
+		}()
+		for err := range errs {
+			t.Errorf("Stream write failed: %v", err)
+		}
+		for i := 0; i < 3000; i++ {
+			if exists, err := bf.Exists([]byte(fmt.Sprintf("stream:%d", i))); err != nil || !exists {
+				t.Fatalf("Expected stream:%d after the stream ended, got %v, %v", i, exists, err)
+			}
+		}
+
+		streamCtx, cancel := context.WithCancel(ctx)
+		errs = bf.AddStream(streamCtx, make(chan []byte))
+		cancel()
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if _, open := <-errs; open {
+			t.Error("Expected the error channel to be closed")
+		}
+	})
+
+	t.Run("ExportImport", func(t *testing.T) {
+		key, dest, other := "integration:test:export", "integration:test:export:dest", "integration:test:export:other"
+		for _, k := range []string{key, dest, other, metadataKey(key), metadataKey(dest), metadataKey(other)} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		for i := 0; i < 100; i++ {
+			if err := bf.Add([]byte(fmt.Sprintf("exported:%d", i))); err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+		}
+		var buf bytes.Buffer
+		if err := bf.Export(ctx, &buf); err != nil {
+			t.Fatalf("Failed to export filter: %v", err)
+		}
+
+		cfg.RedisKey = dest
+		restored, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := restored.Import(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatalf("Failed to import filter: %v", err)
+		}
+		for i := 0; i < 100; i++ {
+			if exists, err := restored.Exists([]byte(fmt.Sprintf("exported:%d", i))); err != nil || !exists {
+				t.Fatalf("Expected element %d after import, got %v, %v", i, exists, err)
+			}
+		}
+
+		cfg.RedisKey, cfg.ExpectedInsertions = other, 10000
+		larger, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		var mismatch *ParameterMismatchError
+		if err := larger.Import(ctx, bytes.NewReader(buf.Bytes())); !errors.As(err, &mismatch) {
+			t.Errorf("Expected a parameter mismatch, got %v", err)
+		}
+		if err := restored.Import(ctx, bytes.NewReader(buf.Bytes()[:20])); !errors.Is(err, ErrInvalidSerializedFilter) {
+			t.Errorf("Expected ErrInvalidSerializedFilter for a truncated export, got %v", err)
+		}
+	})
+
+	t.Run("GuavaSharedKey", func(t *testing.T) {
+		key := "integration:test:guava"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+
+		strategy := NewGuavaStrategy(GuavaMurmur128Mitz64)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1_000_000,
+			FalsePositiveRate:  0.01,
+			HashStrategy:       strategy,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		bitSize, hashCount := EstimateGuavaParameters(1_000_000, 0.01)
+		if bitSize != 9585088 || hashCount != 7 {
+			t.Fatalf("Expected Guava's 9585088 bits and 7 hashes, got %d and %d", bitSize, hashCount)
+		}
+
+		// A JVM writer sets Guava's indices with SETBIT
+		for _, pos := range strategy.(PositionHasher).Positions([]byte("from-jvm"), hashCount, bitSize) {
+			if err := client.SetBit(ctx, key, int64(pos), 1).Err(); err != nil {
+				t.Fatalf("Failed to set bit: %v", err)
+			}
+		}
+		if exists, err := bf.Exists([]byte("from-jvm")); err != nil || !exists {
+			t.Errorf("Expected the JVM-written element, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("MigrateEngine", func(t *testing.T) {
+		key := "integration:test:migrate"
+		for _, k := range []string{key, metadataKey(key), companionKey(key, auditKeySuffix)} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		caps, err := ProbeCapabilities(ctx, client)
+		if err != nil {
+			t.Fatalf("Failed to probe capabilities: %v", err)
+		}
+		if !caps.RedisBloom {
+			t.Skip("RedisBloom module not loaded")
+		}
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			Audit:              &Audit{RawItems: true},
+			Capabilities:       caps,
+		}
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		for i := 0; i < 100; i++ {
+			if err := bf.Add([]byte(fmt.Sprintf("migrated:%d", i))); err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+		}
+
+		module, err := MigrateEngine(ctx, cfg, MigrateOptions{Engine: EngineRedisBloom})
+		if err != nil {
+			t.Fatalf("Failed to migrate to RedisBloom: %v", err)
+		}
+		if kind, _ := client.Type(ctx, key).Result(); kind != "MBbloom--" {
+			t.Errorf("Expected a module filter, got type %s", kind)
+		}
+		if exists, err := module.Exists([]byte("migrated:0")); err != nil || !exists {
+			t.Errorf("Expected element in the module filter, got %v, %v", exists, err)
+		}
+		cfg.Engine = EngineRedisBloom
+		bitmap, err := MigrateEngine(ctx, cfg, MigrateOptions{Engine: EngineBitmap})
+		if err != nil {
+			t.Fatalf("Failed to migrate back to a bitmap: %v", err)
+		}
+		for i := 0; i < 100; i++ {
+			if exists, err := bitmap.Exists([]byte(fmt.Sprintf("migrated:%d", i))); err != nil || !exists {
+				t.Fatalf("Expected element %d after migrating back, got %v, %v", i, exists, err)
+			}
+		}
+	})
+
+	t.Run("AddBatchRollback", func(t *testing.T) {
+		key := "integration:test:addbatch"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := [][]byte{[]byte("batch:a"), []byte("batch:b"), []byte("batch:c")}
+		if err := bf.AddBatch(items); err != nil {
+			t.Fatalf("Failed to add batch: %v", err)
+		}
+		for _, item := range items {
+			if exists, err := bf.Exists(item); err != nil || !exists {
+				t.Errorf("Expected %s after AddBatch, got %v, %v", item, exists, err)
+			}
+		}
+
+		// A failing write reverts the bits the batch set, but not bits set before it
+		if err := client.SetBit(ctx, key, 7, 1).Err(); err != nil {
+			t.Fatalf("Failed to set bit: %v", err)
+		}
+		keys := []string{key, metadataKey(key)}
+		err = addBatchScript.Run(ctx, client, keys, "0", "7", "9", "11", "4294967296").Err()
+		if err == nil {
+			t.Fatal("Expected the out-of-range offset to fail the batch")
+		}
+		for offset, want := range map[int64]int64{7: 1, 9: 0, 11: 0} {
+			if bit, _ := client.GetBit(ctx, key, offset).Result(); bit != want {
+				t.Errorf("Expected bit %d to be %d after the rollback, got %d", offset, want, bit)
+			}
+		}
+	})
+
+	t.Run("AddBatchPartial", func(t *testing.T) {
+		key := "integration:test:addbatch:partial"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := [][]byte{[]byte("partial:a"), []byte("partial:b")}
+		result, err := bf.AddBatchPartial(items)
+		if err != nil {
+			t.Fatalf("Failed to add batch: %v", err)
+		}
+		if n := result.Count(BatchApplied); n != len(items) || len(result.Failed()) != 0 {
+			t.Errorf("Expected %d applied items, got %+v", len(items), result.Items)
+		}
+
+		// The second item fails on its last offset: only its own bits are reverted
+		cleanupKey(client, key)
+		keys := []string{key, metadataKey(key)}
+		replies, err := addBatchItemsScript.Run(ctx, client, keys, "0", "2", "3", "5", "2", "9", "4294967296").Slice()
+		if err != nil {
+			t.Fatalf("Failed to run the batch script: %v", err)
+		}
+		if len(replies) != 2 || replies[0] != int64(1) {
+			t.Fatalf("Expected the first item applied and the second failed, got %v", replies)
+		}
+		if _, failed := replies[1].(string); !failed {
+			t.Errorf("Expected an error message for the second item, got %v", replies[1])
+		}
+		for offset, want := range map[int64]int64{3: 1, 5: 1, 9: 0} {
+			if bit, _ := client.GetBit(ctx, key, offset).Result(); bit != want {
+				t.Errorf("Expected bit %d to be %d, got %d", offset, want, bit)
+			}
+		}
+	})
+
+	t.Run("Policy", func(t *testing.T) {
+		allowKey, denyKey := "integration:test:policy:allow", "integration:test:policy:deny"
+		for _, key := range []string{allowKey, denyKey} {
+			cleanupKey(client, key)
+			cleanupKey(client, metadataKey(key))
+			defer cleanupKey(client, key)
+			defer cleanupKey(client, metadataKey(key))
+		}
+		cache := &countingCache{}
+		allow, err := NewBloomFilter(Config{
+			RedisKey:           allowKey,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			ResultCache:        &ResultCache{Cache: cache, NegativeTTL: time.Minute},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create allowlist: %v", err)
+		}
+		deny, err := NewBloomFilter(Config{
+			RedisKey:           denyKey,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			CircuitBreaker: &CircuitBreaker{
+				FailureThreshold: 1,
+				OpenTimeout:      time.Minute,
+				Policy:           FailClosed,
+				IsFailure:        func(error) bool { return true },
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create denylist: %v", err)
+		}
+		if err := allow.AddMany([][]byte{[]byte("alice"), []byte("mallory")}); err != nil {
+			t.Fatalf("Failed to fill allowlist: %v", err)
+		}
+		if err := deny.AddMany([][]byte{[]byte("mallory"), []byte("eve")}); err != nil {
+			t.Fatalf("Failed to fill denylist: %v", err)
+		}
+
+		sets := cache.sets
+		policy, err := NewPolicy(allow, deny, DenyOverridesAllow)
+		if err != nil {
+			t.Fatalf("Failed to create policy: %v", err)
+		}
+		for item, want := range map[string]Decision{
+			"alice":   DecisionAllow,
+			"eve":     DecisionDeny,
+			"mallory": DecisionDeny,
+			"bob":     DecisionNoMatch,
+		} {
+			if got, err := policy.Evaluate([]byte(item)); err != nil || got != want {
+				t.Errorf("Evaluate(%s) = %s, %v; want %s", item, got, err, want)
+			}
+		}
+		if cache.sets == sets {
+			t.Error("Expected policy lookups to fill the allowlist's result cache")
+		}
+
+		// The denylist's circuit breaker answers once its key is unusable
+		client.Del(ctx, denyKey)
+		client.HSet(ctx, denyKey, "f", "v")
+		if _, err := policy.Evaluate([]byte("trudy")); err == nil {
+			t.Error("Expected the first lookup of a broken denylist to fail")
+		}
+		if got, err := policy.Evaluate([]byte("trudy")); err != nil || got != DecisionDeny {
+			t.Errorf("Expected the open circuit to fail closed, got %s, %v", got, err)
+		}
+	})
+
+	t.Run("PolicySharedPipeline", func(t *testing.T) {
+		allowKey, denyKey := "integration:test:policy-shared:allow", "integration:test:policy-shared:deny"
+		for _, key := range []string{allowKey, denyKey} {
+			cleanupKey(client, key)
+			cleanupKey(client, metadataKey(key))
+			defer cleanupKey(client, key)
+			defer cleanupKey(client, metadataKey(key))
+		}
+		counter := newCommandCounter()
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer hooked.Close()
+		hooked.AddHook(counter)
+		shared := NewSingleNodeRedisClient(hooked)
+		allow, err := NewBloomFilter(Config{
+			RedisKey:           allowKey,
+			RedisClient:        shared,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			ResultCache:        &ResultCache{Cache: &mapCache{answers: make(map[string]bool)}, NegativeTTL: time.Minute},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create allowlist: %v", err)
+		}
+		deny, err := NewBloomFilter(Config{
+			RedisKey:           denyKey,
+			RedisClient:        shared,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.001,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create denylist: %v", err)
+		}
+		if err := deny.Add([]byte("eve")); err != nil {
+			t.Fatalf("Failed to fill denylist: %v", err)
+		}
+		policy, err := NewPolicy(allow, deny, DenyOverridesAllow)
+		if err != nil {
+			t.Fatalf("Failed to create policy: %v", err)
+		}
+		k := int(allow.(*bloomFilter).hashCount + deny.(*bloomFilter).hashCount)
+
+		// Both lists are read in one pipeline without direct commands
+		direct, pipelined := counter.counts("getbit")
+		if got, err := policy.Evaluate([]byte("eve")); err != nil || got != DecisionDeny {
+			t.Fatalf("Evaluate(eve) = %s, %v; want deny", got, err)
+		}
+		d, p := counter.counts("getbit")
+		if d != direct || p-pipelined != k {
+			t.Errorf("Expected %d pipelined GETBITs and no direct ones, got %d and %d", k, p-pipelined, d-direct)
+		}
+
+		// The allowlist answers from its cache, so only the denylist's bits are read
+		if got, err := policy.Evaluate([]byte("eve")); err != nil || got != DecisionDeny {
+			t.Fatalf("Evaluate(eve) = %s, %v; want deny", got, err)
+		}
+		_, p2 := counter.counts("getbit")
+		if want := int(deny.(*bloomFilter).hashCount); p2-p != want {
+			t.Errorf("Expected %d GETBITs with a cached allowlist, got %d", want, p2-p)
+		}
+	})
+
+	t.Run("FPRProbe", func(t *testing.T) {
+		key := "integration:test:probe"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		cache := &countingCache{}
+		filter, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			TTL:                time.Hour,
+			TTLMode:            TTLSliding,
+			ResultCache:        &ResultCache{Cache: cache, NegativeTTL: time.Minute},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := filter.Add([]byte("real")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		client.Expire(ctx, key, 30*time.Second)
+		sets := cache.sets
+
+		probe := NewFPRProbe(filter, FPRProbeOptions{Window: 20})
+		for i := 0; i < 20; i++ {
+			probe.sample()
+		}
+		if rate, samples := probe.Rate(); samples != 20 || rate > 0.5 {
+			t.Errorf("Expected 20 samples at a low rate, got %d at %f", samples, rate)
+		}
+		if cache.sets != sets {
+			t.Errorf("Expected probe lookups to bypass the cache, %d answers were stored", cache.sets-sets)
+		}
+		if ttl := client.TTL(ctx, key).Val(); ttl > 30*time.Second {
+			t.Errorf("Expected probe lookups not to slide the TTL, got %s", ttl)
+		}
+	})
+
+	t.Run("SlidingTTLLookups", func(t *testing.T) {
+		key := "integration:test:sliding"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		filter, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			TTL:                time.Hour,
+			TTLMode:            TTLSliding,
+			ResultCache:        &ResultCache{},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := filter.Add([]byte("used")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		unslid := func(path string) {
+			if ttl := client.TTL(ctx, key).Val(); ttl > 30*time.Second {
+				t.Errorf("Expected %s not to slide the TTL, got %s", path, ttl)
+			}
+		}
+
+		client.Expire(ctx, key, 30*time.Second)
+		if err := filter.PrefetchExists([][]byte{[]byte("prefetched")}); err != nil {
+			t.Fatalf("Failed to prefetch: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		unslid("PrefetchExists")
+		NewFPRProbe(filter, FPRProbeOptions{}).sample()
+		unslid("the FPR probe")
+		if _, err := filter.ExistsExplain([]byte("explained")); err != nil {
+			t.Fatalf("Failed to explain: %v", err)
+		}
+		unslid("ExistsExplain")
+		other, err := NewBloomFilter(Config{RedisKey: key + ":other", RedisClient: redisClient, ExpectedInsertions: 1000, FalsePositiveRate: 0.01})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		policy, _ := NewPolicy(filter, other, DenyOverridesAllow)
+		if _, err := policy.Evaluate([]byte("evaluated")); err != nil {
+			t.Fatalf("Failed to evaluate: %v", err)
+		}
+		unslid("Policy")
+
+		if _, err := filter.Exists([]byte("looked-up")); err != nil {
+			t.Fatalf("Failed to check existence: %v", err)
+		}
+		if ttl := client.TTL(ctx, key).Val(); ttl <= 30*time.Second {
+			t.Errorf("Expected Exists to slide the TTL, got %s", ttl)
+		}
+	})
+
+	t.Run("DirectCommandsForSmallHashCount", func(t *testing.T) {
+		key := "integration:test:direct"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		counter := newCommandCounter()
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer hooked.Close()
+		hooked.AddHook(counter)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:    key,
+			RedisClient: NewSingleNodeRedisClient(hooked),
+			BitSize:     1024,
+			HashCount:   2,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("direct")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if exists, err := bf.Exists([]byte("direct")); err != nil || !exists {
+			t.Errorf("Expected element to exist, got %v, %v", exists, err)
+		}
+		for _, name := range []string{"setbit", "getbit"} {
+			direct, pipelined := counter.counts(name)
+			if direct != 2 || pipelined != 0 {
+				t.Errorf("%s sent %d times directly and %d times pipelined, want 2 and 0", name, direct, pipelined)
+			}
+		}
+	})
+
+	t.Run("ProbeCapabilities", func(t *testing.T) {
+		caps, err := ProbeCapabilities(ctx, client)
+		if err != nil {
+			t.Fatalf("Failed to probe capabilities: %v", err)
+		}
+		if caps.Server != ServerRedis || caps.Version == "" {
+			t.Errorf("Expected a Redis server with a version, got %q %q", caps.Server, caps.Version)
+		}
+		for _, f := range []Feature{FeatureLua, FeatureBitfield} {
+			if !caps.Supports(f) {
+				t.Errorf("Expected feature %d to be supported", f)
+			}
+		}
+		if n := client.Exists(ctx, capabilityProbeKey).Val(); n != 0 {
+			t.Error("Probing should not create keys")
+		}
+
+		// Features reported missing are refused before anything is sent
+		_, err = NewBloomFilter(Config{
+			RedisKey:           "integration:test:capabilities",
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			Engine:             EngineBitfield,
+			Capabilities:       &Capabilities{Server: ServerDragonfly},
+		})
+		if !errors.Is(err, ErrBitfieldUnsupported) {
+			t.Errorf("Expected ErrBitfieldUnsupported, got %v", err)
+		}
+	})
+
+	t.Run("AdapterHooks", func(t *testing.T) {
+		key := "integration:test:hooks"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer hooked.Close()
+		adapter := NewRedisAdapter(hooked).(*RedisAdapter)
+		counter := newCommandCounter()
+		if err := adapter.AddHook(counter); err != nil {
+			t.Fatalf("Failed to add hook: %v", err)
+		}
+		bf, err := NewBloomFilter(Config{
+			RedisKey:    key,
+			RedisClient: adapter,
+			BitSize:     9586,
+			HashCount:   7,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("hooked")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if _, err := bf.Exists([]byte("hooked")); err != nil {
+			t.Fatalf("Failed to check existence: %v", err)
+		}
+		for _, name := range []string{"setbit", "getbit"} {
+			if _, pipelined := counter.counts(name); pipelined != 7 {
+				t.Errorf("Hook observed %d pipelined %s commands, want 7", pipelined, name)
+			}
+		}
+	})
+
+	t.Run("HashStrategyRegistry", func(t *testing.T) {
+		key := "integration:test:registry"
+		keys := make([][]byte, 2000)
+		for i := range keys {
+			keys[i] = []byte(fmt.Sprintf("bench_%d", i))
+		}
+		for _, name := range HashStrategyNames() {
+			cleanupKey(client, key)
+			cleanupKey(client, metadataKey(key))
+			strategy, err := NewHashStrategy(name)
+			if err != nil {
+				t.Fatalf("Failed to create registered strategy %q: %v", name, err)
+			}
+			result, err := BenchmarkHashStrategy(strategy, keys, HashBenchmarkOptions{
+				ExpectedInsertions: 1000,
+				FalsePositiveRate:  0.01,
+				Buckets:            64,
+			})
+			if err != nil {
+				t.Fatalf("Failed to benchmark %q: %v", name, err)
+			}
+			if result.Keys != len(keys) || result.DegreesOfFreedom != 63 || result.ChiSquare <= 0 {
+				t.Errorf("Strategy %q: unexpected benchmark result %+v", name, result)
+			}
+			// FNV spreads similar keys poorly, which is what the benchmark is meant to show
+			if name == HashXXHash && result.NormalizedChiSquare() > 2 {
+				t.Errorf("Expected xxhash to be uniform, normalized chi-square %.2f", result.NormalizedChiSquare())
+			}
+			bf, err := NewBloomFilter(Config{
+				RedisKey:           key,
+				RedisClient:        redisClient,
+				ExpectedInsertions: 1000,
+				FalsePositiveRate:  0.01,
+				HashStrategy:       strategy,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create Bloom Filter with %q: %v", name, err)
+			}
+			if err := bf.Add(keys[0]); err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+			if exists, err := bf.Exists(keys[0]); err != nil || !exists {
+				t.Errorf("Strategy %q: expected element to exist, got %v, %v", name, exists, err)
+			}
+		}
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		if _, err := NewHashStrategy("unregistered"); !errors.Is(err, ErrUnknownHashStrategy) {
+			t.Errorf("Expected ErrUnknownHashStrategy, got %v", err)
+		}
+	})
+
+	t.Run("CreationAndRebuildTime", func(t *testing.T) {
+		key := "integration:test:age"
+		for _, k := range []string{key, metadataKey(key), "{" + key + "}:removed"} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		df, err := NewDeletableFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create deletable filter: %v", err)
+		}
+		before := time.Now().Truncate(time.Millisecond)
+		if err := df.Add([]byte("aged")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		stats, err := df.Stats()
+		if err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+		if stats.CreatedAt.Before(before) || stats.CreatedAt.After(time.Now()) || !stats.RebuiltAt.IsZero() {
+			t.Errorf("Expected creation at %v and no rebuild, got %v and %v", before, stats.CreatedAt, stats.RebuiltAt)
+		}
+		created := stats.CreatedAt
+
+		time.Sleep(10 * time.Millisecond)
+		if err := df.Compact(ctx, NewSliceIterator([][]byte{[]byte("aged")})); err != nil {
+			t.Fatalf("Failed to compact: %v", err)
+		}
+		if stats, err = df.Stats(); err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+		if !stats.CreatedAt.Equal(created) || !stats.RebuiltAt.After(created) {
+			t.Errorf("Expected creation at %v and a later rebuild, got %v and %v", created, stats.CreatedAt, stats.RebuiltAt)
+		}
+		if stats.Age() < stats.SinceRebuild() {
+			t.Errorf("Age %v should not be shorter than the time since the rebuild %v", stats.Age(), stats.SinceRebuild())
+		}
+	})
+
+	t.Run("ImportGuava", func(t *testing.T) {
+		key := "integration:test:guava:import"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+
+		// Serialize as Guava's writeTo does: strategy ordinal, hash count, word count, words
+		bitSize, hashCount := EstimateGuavaParameters(1000, 0.01)
+		data := make([]uint64, bitSize/64)
+		strategy := NewGuavaStrategy(GuavaMurmur128Mitz64).(PositionHasher)
+		for _, pos := range strategy.Positions([]byte("from-jvm"), hashCount, bitSize) {
+			data[pos/64] |= 1 << (pos % 64)
+		}
+		var buf bytes.Buffer
+		buf.Write([]byte{byte(GuavaMurmur128Mitz64), byte(hashCount)})
+		binary.Write(&buf, binary.BigEndian, int32(len(data)))
+		binary.Write(&buf, binary.BigEndian, data)
+		serialized := buf.Bytes()
+
+		bf, err := ImportGuava(ctx, bytes.NewReader(serialized), Config{RedisKey: key, RedisClient: redisClient})
+		if err != nil {
+			t.Fatalf("Failed to import Guava filter: %v", err)
+		}
+		found, err := bf.ExistsMany([][]byte{[]byte("from-jvm"), []byte("never-added")})
+		if err != nil || !found[0] || found[1] {
+			t.Errorf("Expected only the JVM element, got %v, %v", found, err)
+		}
+		if _, err := ImportGuava(ctx, bytes.NewReader(serialized[:10]), Config{RedisKey: key, RedisClient: redisClient}); !errors.Is(err, ErrInvalidSerializedFilter) {
+			t.Errorf("Expected ErrInvalidSerializedFilter for a truncated serialization, got %v", err)
+		}
+	})
+
+	t.Run("PybloomRoundTrip", func(t *testing.T) {
+		key := "integration:test:pybloom"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+
+		// A pybloom filter of capacity 1000 at 1% with the Python item's bits set
+		py := &PybloomFilter{ErrorRate: 0.01, NumSlices: 7, BitsPerSlice: 1367, Capacity: 1000, Count: 1}
+		py.Bits = make([]byte, (py.BitSize()+7)/8)
+		strategy := NewPybloomStrategy().(PositionHasher)
+		for _, pos := range strategy.Positions([]byte("from-python"), uint(py.NumSlices), py.BitSize()) {
+			py.Bits[pos/8] |= 1 << (pos % 8)
+		}
+		var buf bytes.Buffer
+		if err := py.Encode(&buf); err != nil {
+			t.Fatalf("Failed to encode pybloom filter: %v", err)
+		}
+
+		bf, err := ImportPybloom(ctx, &buf, Config{RedisKey: key, RedisClient: redisClient})
+		if err != nil {
+			t.Fatalf("Failed to import pybloom filter: %v", err)
+		}
+		found, err := bf.ExistsMany([][]byte{[]byte("from-python"), []byte("never-added")})
+		if err != nil || !found[0] || found[1] {
+			t.Errorf("Expected only the Python element, got %v, %v", found, err)
+		}
+		if err := bf.Add([]byte("from-go")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+
+		buf.Reset()
+		if err := ExportPybloom(ctx, &buf, bf); err != nil {
+			t.Fatalf("Failed to export pybloom filter: %v", err)
+		}
+		exported, err := DecodePybloom(&buf)
+		if err != nil {
+			t.Fatalf("Failed to decode the export: %v", err)
+		}
+		if exported.NumSlices != py.NumSlices || exported.BitsPerSlice != py.BitsPerSlice || exported.Capacity != py.Capacity {
+			t.Errorf("Expected the imported parameters, got %+v", exported)
+		}
+		for _, pos := range strategy.Positions([]byte("from-go"), uint(py.NumSlices), py.BitSize()) {
+			if exported.Bits[pos/8]&(1<<(pos%8)) == 0 {
+				t.Errorf("Expected bit %d of the Go element in the export", pos)
+			}
+		}
+	})
+
+	t.Run("BitLayout", func(t *testing.T) {
+		key := "integration:test:layout"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}
+		msb, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		logical := msb.Positions([]byte("little-endian"))
+		cfg.BitLayout = BitLayoutLSBFirst
+		lsb, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := lsb.Add([]byte("little-endian")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+
+		// A little-endian reader finds logical bit i in bit i%8 of byte i/8
+		raw, err := client.Get(ctx, key).Bytes()
+		if err != nil {
+			t.Fatalf("Failed to read bitmap: %v", err)
+		}
+		for _, pos := range logical {
+			if raw[pos/8]&(1<<(pos%8)) == 0 {
+				t.Errorf("Expected logical bit %d least significant first", pos)
+			}
+		}
+		if exists, err := lsb.Exists([]byte("little-endian")); err != nil || !exists {
+			t.Errorf("Expected element to exist, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("Positions", func(t *testing.T) {
+		key := "integration:test:positions"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:    key,
+			RedisClient: redisClient,
+			BitSize:     4096,
+			HashCount:   5,
+			BitLayout:   BitLayoutBigEndian64,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		positions := bf.Positions([]byte("located"))
+		if len(positions) != 5 {
+			t.Fatalf("Expected 5 positions, got %v", positions)
+		}
+		if err := bf.Add([]byte("located")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		// The offsets are exactly the bits the filter set in Redis
+		set, err := client.BitCount(ctx, key, nil).Result()
+		if err != nil {
+			t.Fatalf("Failed to count bits: %v", err)
+		}
+		distinct := make(map[uint64]bool)
+		for _, pos := range positions {
+			distinct[pos] = true
+			if bit, err := client.GetBit(ctx, key, int64(pos)).Result(); err != nil || bit != 1 {
+				t.Errorf("Expected offset %d to be set, got %d, %v", pos, bit, err)
+			}
+		}
+		if int(set) != len(distinct) {
+			t.Errorf("Expected %d set bits, got %d", len(distinct), set)
+		}
+	})
+
+	t.Run("HashSelfTest", func(t *testing.T) {
+		key := "integration:test:selftest"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			VerifyHashStrategy: true,
+		}
+		for _, name := range HashStrategyNames() {
+			cfg.HashStrategy, _ = NewHashStrategy(name)
+			if _, err := NewBloomFilter(cfg); err != nil {
+				t.Errorf("Strategy %q failed its self-test: %v", name, err)
+			}
+		}
+		cfg.HashStrategy = &brokenStrategy{}
+		if _, err := NewBloomFilter(cfg); !errors.Is(err, ErrHashStrategyMismatch) {
+			t.Errorf("Expected ErrHashStrategyMismatch, got %v", err)
+		}
+		if n := client.Exists(ctx, key, metadataKey(key)).Val(); n != 0 {
+			t.Errorf("Construction wrote %d keys", n)
+		}
+	})
+
+	t.Run("CustomClientTTL", func(t *testing.T) {
+		key := "integration:test:custom:ttl"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        &customClient{client: client},
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			TTL:                time.Minute,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("expiring")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if ttl := client.PTTL(ctx, key).Val(); ttl <= 0 || ttl > time.Minute {
+			t.Errorf("Expected a TTL of at most a minute through the custom client, got %v", ttl)
+		}
+	})
+
+	t.Run("CustomPipeliner", func(t *testing.T) {
+		key := "integration:test:custom:pipeliner"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		var execs int64
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        &customClient{client: client, execs: &execs},
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.AddMany([][]byte{[]byte("piped_1"), []byte("piped_2")}); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		if exists, err := bf.Exists([]byte("piped_2")); err != nil || !exists {
+			t.Errorf("Expected element to exist, got %v, %v", exists, err)
+		}
+		if n := atomic.LoadInt64(&execs); n != 2 {
+			t.Errorf("Expected AddMany and Exists to execute 2 custom pipelines, got %d", n)
+		}
+	})
+
+	t.Run("RateLimit", func(t *testing.T) {
+		key := "integration:test:ratelimit"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			RateLimit:          &RateLimit{OpsPerSecond: 20, Burst: 1},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		start := time.Now()
+		for i := 0; i < 6; i++ {
+			if err := bf.Add([]byte(fmt.Sprintf("limited_%d", i))); err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+		}
+		// The first operation uses the burst, the other five wait 50ms each
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Errorf("Expected 6 operations at 20/s to take at least 200ms, took %v", elapsed)
+		}
+		if exists, err := bf.Exists([]byte("limited_5")); err != nil || !exists {
+			t.Errorf("Expected element to exist, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("Degradation", func(t *testing.T) {
+		key := "integration:test:degrade"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		counter := newCommandCounter()
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer hooked.Close()
+		hooked.AddHook(counter)
+		metrics := newRecordingMetrics()
+		bf, err := NewBloomFilter(Config{
+			RedisKey:    key,
+			RedisClient: NewSingleNodeRedisClient(hooked),
+			BitSize:     9586,
+			HashCount:   7,
+			Metrics:     metrics,
+			// Every lookup is slower than a nanosecond, so the first one degrades the filter
+			Degradation: &Degradation{LatencyThreshold: time.Nanosecond, DegradedHashes: 3},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("degraded")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if exists, err := bf.Exists([]byte("degraded")); err != nil || !exists {
+				t.Errorf("Expected element to exist, got %v, %v", exists, err)
+			}
+		}
+		if _, pipelined := counter.counts("getbit"); pipelined != 7+3 {
+			t.Errorf("Expected a full and a degraded lookup reading 10 bits, read %d", pipelined)
+		}
+		if metrics.gauge(MetricDegraded) != 1 || metrics.counter(MetricDegradedLookups) != 1 {
+			t.Errorf("Expected the degraded gauge and one degraded lookup, got %v and %d",
+				metrics.gauge(MetricDegraded), metrics.counter(MetricDegradedLookups))
+		}
+	})
+
+	t.Run("ResultCache", func(t *testing.T) {
+		key := "integration:test:resultcache"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		counter := newCommandCounter()
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer hooked.Close()
+		hooked.AddHook(counter)
+		metrics := newRecordingMetrics()
+		bf, err := NewBloomFilter(Config{
+			RedisKey:    key,
+			RedisClient: NewSingleNodeRedisClient(hooked),
+			BitSize:     9586,
+			HashCount:   7,
+			Metrics:     metrics,
+			ResultCache: &ResultCache{Size: 100, TTL: time.Minute},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("cached")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			if exists, err := bf.Exists([]byte("cached")); err != nil || !exists {
+				t.Errorf("Expected element to exist, got %v, %v", exists, err)
+			}
+			if exists, err := bf.Exists([]byte("uncached")); err != nil || exists {
+				t.Errorf("Expected absent element, got %v, %v", exists, err)
+			}
+		}
+		// Positive answers are served from the cache; negative ones always go to Redis
+		if _, pipelined := counter.counts("getbit"); pipelined != 3*7 {
+			t.Errorf("Expected only the 3 negative lookups to reach Redis, read %d bits", pipelined)
+		}
+		if hits := metrics.counter(MetricCacheHits); hits != 3 {
+			t.Errorf("Expected repeated positive lookups to hit the cache, got %d hits", hits)
+		}
+	})
+
+	t.Run("ExternalCache", func(t *testing.T) {
+		keyA, keyB := "integration:test:extcache:a", "integration:test:extcache:b"
+		for _, k := range []string{keyA, keyB, metadataKey(keyA), metadataKey(keyB)} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		shared := &mapCache{answers: make(map[string]bool)}
+		newFilter := func(key string, redisClient RedisClient) BloomFilter {
+			bf, err := NewBloomFilter(Config{
+				RedisKey:           key,
+				RedisClient:        redisClient,
+				ExpectedInsertions: 1000,
+				FalsePositiveRate:  0.01,
+				ResultCache:        &ResultCache{Cache: shared},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create Bloom Filter: %v", err)
+			}
+			return bf
+		}
+		a := newFilter(keyA, redisClient)
+		if err := a.Add([]byte("shared")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if exists, err := a.Exists([]byte("shared")); err != nil || !exists {
+			t.Fatalf("Expected element to exist, got %v, %v", exists, err)
+		}
+
+		// Another instance of the same filter answers from the shared cache
+		counter := newCommandCounter()
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer hooked.Close()
+		hooked.AddHook(counter)
+		if exists, err := newFilter(keyA, NewSingleNodeRedisClient(hooked)).Exists([]byte("shared")); err != nil || !exists {
+			t.Errorf("Expected element to exist, got %v, %v", exists, err)
+		}
+		if direct, pipelined := counter.counts("getbit"); direct+pipelined != 0 {
+			t.Errorf("Expected the cached answer, read %d bits", direct+pipelined)
+		}
+		// Answers are namespaced by filter
+		if exists, err := newFilter(keyB, redisClient).Exists([]byte("shared")); err != nil || exists {
+			t.Errorf("Expected the other filter not to share the answer, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("FilterChain", func(t *testing.T) {
+		fastKey, exactKey := "{integration:chain}:fast", "{integration:chain}:exact"
+		for _, k := range []string{fastKey, exactKey, metadataKey(fastKey), metadataKey(exactKey)} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		fast, err := NewBloomFilter(Config{
+			RedisKey:           fastKey,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.1,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		counter := newCommandCounter()
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer hooked.Close()
+		hooked.AddHook(counter)
+		exact, err := NewBloomFilter(Config{
+			RedisKey:    exactKey,
+			RedisClient: NewSingleNodeRedisClient(hooked),
+			BitSize:     9586,
+			HashCount:   7,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		chain, err := NewFilterChain(fast, exact)
+		if err != nil {
+			t.Fatalf("Failed to create filter chain: %v", err)
+		}
+		if err := chain.Add([]byte("chained")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if exists, err := exact.Exists([]byte("chained")); err != nil || !exists {
+			t.Errorf("Expected Add to reach every tier, got %v, %v", exists, err)
+		}
+		if exists, err := chain.Exists([]byte("chained")); err != nil || !exists {
+			t.Errorf("Expected element to exist, got %v, %v", exists, err)
+		}
+		_, before := counter.counts("getbit")
+		if exists, err := chain.Exists([]byte("unchained")); err != nil || exists {
+			t.Errorf("Expected absent element, got %v, %v", exists, err)
+		}
+		// The first tier rules out the absent item without consulting the second
+		if _, after := counter.counts("getbit"); after != before {
+			t.Errorf("Expected the exact tier to be skipped, it read %d bits", after-before)
+		}
+		if _, err := NewFilterChain(); !errors.Is(err, ErrNilFilter) {
+			t.Errorf("Expected ErrNilFilter for an empty chain, got %v", err)
+		}
+	})
+
+	t.Run("BlockedLayout", func(t *testing.T) {
+		key := "integration:test:blocked"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		counter := newCommandCounter()
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer hooked.Close()
+		hooked.AddHook(counter)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        NewSingleNodeRedisClient(hooked),
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			Blocked:            true,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := [][]byte{[]byte("block_1"), []byte("block_2"), []byte("block_3")}
+		for _, item := range items {
+			positions := bf.Positions(item)
+			for _, pos := range positions {
+				if pos/512 != positions[0]/512 {
+					t.Errorf("Positions %v of %q span several 512-bit blocks", positions, item)
+					break
+				}
+			}
+			if err := bf.Add(item); err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+		}
+		for _, item := range append(items, []byte("block_absent")) {
+			exists, err := bf.Exists(item)
+			if err != nil {
+				t.Fatalf("Failed to check existence: %v", err)
+			}
+			if want := !bytes.Equal(item, []byte("block_absent")); exists != want {
+				t.Errorf("Exists(%q) = %v, want %v", item, exists, want)
+			}
+		}
+		// Each lookup reads its block with one GETRANGE instead of k GETBITs
+		if getrange, _ := counter.counts("getrange"); getrange != 4 {
+			t.Errorf("Expected 4 GETRANGE lookups, got %d", getrange)
+		}
+		if direct, pipelined := counter.counts("getbit"); direct+pipelined != 0 {
+			t.Errorf("Expected no GETBIT, got %d", direct+pipelined)
+		}
+	})
+
+	t.Run("DerivedSeeds", func(t *testing.T) {
+		keyA, keyB := "integration:test:seed:a", "integration:test:seed:b"
+		for _, k := range []string{keyA, keyB, metadataKey(keyA), metadataKey(keyB)} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		newFilter := func(key, salt string) BloomFilter {
+			bf, err := NewBloomFilter(Config{
+				RedisKey:           key,
+				RedisClient:        redisClient,
+				ExpectedInsertions: 1000,
+				FalsePositiveRate:  0.01,
+				Seed:               42,
+				Salt:               salt,
+				KeySeeded:          true,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create Bloom Filter: %v", err)
+			}
+			return bf
+		}
+		item := []byte("seeded")
+		a, b := newFilter(keyA, ""), newFilter(keyB, "")
+		if reflect.DeepEqual(a.Positions(item), b.Positions(item)) {
+			t.Error("Expected filters seeded from different keys to use different positions")
+		}
+		// An explicit salt takes precedence over the key, so both keys hash alike
+		if !reflect.DeepEqual(newFilter(keyA, "shared").Positions(item), newFilter(keyB, "shared").Positions(item)) {
+			t.Error("Expected filters with the same seed and salt to use the same positions")
+		}
+		for _, bf := range []BloomFilter{a, b} {
+			if err := bf.Add(item); err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+		}
+		// A reopened filter derives the same seed
+		if exists, err := newFilter(keyB, "").Exists(item); err != nil || !exists {
+			t.Errorf("Expected element to exist, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("RotatableFilter", func(t *testing.T) {
+		gen1, gen2 := "integration:test:rotate:1", "integration:test:rotate:2"
+		for _, k := range []string{gen1, gen2, metadataKey(gen1), metadataKey(gen2)} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		rf, err := NewRotatableFilter(Config{
+			RedisKey:           gen1,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}, time.Minute)
+		if err != nil {
+			t.Fatalf("Failed to create rotatable filter: %v", err)
+		}
+		if err := rf.Add([]byte("before_rotation")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if err := rf.Rotate(ctx, gen2); err != nil {
+			t.Fatalf("Failed to rotate: %v", err)
+		}
+		if current, previous := rf.Keys(); current != gen2 || previous != gen1 {
+			t.Errorf("Expected keys %q and %q, got %q and %q", gen2, gen1, current, previous)
+		}
+		if ttl := client.TTL(ctx, gen1).Val(); ttl <= 0 || ttl > time.Minute {
+			t.Errorf("Expected the previous generation to expire with the overlap window, TTL %v", ttl)
+		}
+		if err := rf.Add([]byte("after_rotation")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		// Both generations are read during the overlap window
+		for _, item := range []string{"before_rotation", "after_rotation"} {
+			if exists, err := rf.Exists([]byte(item)); err != nil || !exists {
+				t.Errorf("Expected %q during the overlap window, got %v, %v", item, exists, err)
+			}
+		}
+		if err := rf.Retire(ctx); err != nil {
+			t.Fatalf("Failed to retire: %v", err)
+		}
+		if exists, err := rf.Exists([]byte("before_rotation")); err != nil || exists {
+			t.Errorf("Expected the retired generation not to be read, got %v, %v", exists, err)
+		}
+		if n := client.Exists(ctx, gen1, metadataKey(gen1)).Val(); n != 0 {
+			t.Errorf("Expected the retired generation to be deleted, %d keys remain", n)
+		}
+	})
+
+	t.Run("AuditStream", func(t *testing.T) {
+		key := "integration:test:audit"
+		stream := companionKey(key, auditKeySuffix)
+		for _, k := range []string{key, metadataKey(key), stream} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			Audit:              &Audit{},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("audited_1")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if err := bf.AddMany([][]byte{[]byte("audited_2"), []byte("audited_3")}); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		entries, err := client.XRange(ctx, stream, "-", "+").Result()
+		if err != nil {
+			t.Fatalf("Failed to read audit stream: %v", err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("Expected 3 audit entries, got %d", len(entries))
+		}
+		// Without RawItems only the 128-bit hash of each item is logged
+		for _, entry := range entries {
+			hash, ok := entry.Values[auditFieldHash].(string)
+			if !ok || len(hash) != 32 || entry.Values[auditFieldItem] != nil {
+				t.Errorf("Expected only a hex hash in entry %v", entry.Values)
+			}
+		}
+		if _, err := NewAuditIterator(ctx, client, stream).Next(); !errors.Is(err, ErrAuditNotReplayable) {
+			t.Errorf("Expected ErrAuditNotReplayable for hashed entries, got %v", err)
+		}
+	})
+
+	t.Run("RebuildFromAudit", func(t *testing.T) {
+		key := "integration:test:audit:rebuild"
+		stream := companionKey(key, auditKeySuffix)
+		for _, k := range []string{key, metadataKey(key), stream} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 100,
+			FalsePositiveRate:  0.01,
+			Audit:              &Audit{RawItems: true},
+		}
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := [][]byte{[]byte("replayed_1"), []byte("replayed_2"), []byte("replayed_3")}
+		if err := bf.AddMany(items); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+
+		// Re-size the filter from its log after the source data is gone
+		cfg.ExpectedInsertions = 10_000
+		cfg.Audit = nil
+		rebuilt, err := RebuildFromAudit(ctx, stream, cfg)
+		if err != nil {
+			t.Fatalf("Failed to rebuild from audit stream: %v", err)
+		}
+		found, err := rebuilt.ExistsMany(append(items, []byte("never_logged")))
+		if err != nil {
+			t.Fatalf("Failed to check elements: %v", err)
+		}
+		for i, item := range items {
+			if !found[i] {
+				t.Errorf("Expected %q after the rebuild", item)
+			}
+		}
+		if found[len(items)] {
+			t.Error("Expected an element that was never logged to be absent")
+		}
+		// The rebuilt parameters are recorded, so the old sizing no longer matches
+		cfg.ExpectedInsertions = 100
+		if _, err := NewBloomFilter(cfg); !errors.As(err, new(*ParameterMismatchError)) {
+			t.Errorf("Expected a parameter mismatch for the old sizing, got %v", err)
+		}
+	})
+
+	t.Run("ReusePipelines", func(t *testing.T) {
+		key := "integration:test:reuse"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		for _, reuse := range []bool{false, true} {
+			custom := &customClient{client: client}
+			bf, err := NewBloomFilter(Config{
+				RedisKey:           key,
+				RedisClient:        custom,
+				ExpectedInsertions: 1000,
+				FalsePositiveRate:  0.01,
+				ReusePipelines:     reuse,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create Bloom Filter: %v", err)
+			}
+			for i := 0; i < 10; i++ {
+				item := []byte(fmt.Sprintf("reused_%d", i))
+				if err := bf.Add(item); err != nil {
+					t.Fatalf("Failed to add element: %v", err)
+				}
+				if exists, err := bf.Exists(item); err != nil || !exists {
+					t.Errorf("Expected element to exist, got %v, %v", exists, err)
+				}
+			}
+			want := int64(20)
+			if reuse {
+				want = 1
+			}
+			if created := atomic.LoadInt64(&custom.created); created != want {
+				t.Errorf("ReusePipelines=%v: expected %d pipelines for 20 sequential calls, created %d", reuse, want, created)
+			}
+		}
+	})
+
+	t.Run("DedicatedPool", func(t *testing.T) {
+		key := "integration:test:pool"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		dedicated, err := NewDedicatedPoolClient(client, PoolOptions{Size: 2})
+		if err != nil {
+			t.Fatalf("Failed to create dedicated pool client: %v", err)
+		}
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        dedicated,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if err := bf.Add([]byte(fmt.Sprintf("pooled_%d", i))); err != nil {
+					t.Errorf("Failed to add element: %v", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+		if conns := dedicated.client.PoolStats().TotalConns; conns == 0 || conns > 2 {
+			t.Errorf("Expected at most 2 dedicated connections, got %d", conns)
+		}
+		if exists, err := bf.Exists([]byte("pooled_19")); err != nil || !exists {
+			t.Errorf("Expected element to exist, got %v, %v", exists, err)
+		}
+		if err := dedicated.Close(); err != nil {
+			t.Fatalf("Failed to close dedicated pool: %v", err)
+		}
+		// Closing the dedicated pool leaves the application's client usable
+		if err := client.Ping(ctx).Err(); err != nil {
+			t.Errorf("Expected the original client to stay open, got %v", err)
+		}
+		if _, err := bf.Exists([]byte("pooled_19")); err == nil {
+			t.Error("Expected lookups to fail after the dedicated pool is closed")
+		}
+	})
+
+	t.Run("ReplicaRouter", func(t *testing.T) {
+		key := "integration:test:replicas"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+
+		// The same server stands in for the replicas; only their latency and health differ
+		newReplica := func(addr string, hooks ...redis.Hook) (*redis.Client, *commandCounter) {
+			replica := redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+			counter := newCommandCounter()
+			for _, hook := range append(hooks, counter) {
+				replica.AddHook(hook)
+			}
+			return replica, counter
+		}
+		broken, _ := newReplica("127.0.0.1:1")
+		slow, slowCounter := newReplica("redis:6379", delayHook(20*time.Millisecond))
+		fast, fastCounter := newReplica("redis:6379")
+		for _, c := range []*redis.Client{broken, slow, fast} {
+			defer c.Close()
+		}
+		router, err := NewReplicaRouter(client, []redis.Cmdable{broken, slow, fast}, ReplicaRouterOptions{
+			ErrorThreshold: 1,
+			EjectFor:       time.Minute,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create replica router: %v", err)
+		}
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        router,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("routed")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if _, pipelined := fastCounter.counts("setbit"); pipelined != 0 {
+			t.Errorf("Expected writes to go to the primary, a replica received %d", pipelined)
+		}
+
+		// The first lookups eject the broken replica and measure the others
+		for i := 0; i < 3; i++ {
+			bf.Exists([]byte("routed"))
+		}
+		_, slowBefore := slowCounter.counts("getbit")
+		_, fastBefore := fastCounter.counts("getbit")
+		for i := 0; i < 5; i++ {
+			if exists, err := bf.Exists([]byte("routed")); err != nil || !exists {
+				t.Errorf("Expected element to exist, got %v, %v", exists, err)
+			}
+		}
+		_, slowAfter := slowCounter.counts("getbit")
+		_, fastAfter := fastCounter.counts("getbit")
+		if slowAfter != slowBefore || fastAfter == fastBefore {
+			t.Errorf("Expected reads on the fastest replica only, slow read %d bits and fast %d",
+				slowAfter-slowBefore, fastAfter-fastBefore)
+		}
+	})
+
+	t.Run("LastSeenFilter", func(t *testing.T) {
+		key := "integration:test:lastseen"
+		cleanup := func() {
+			slices, _ := client.Keys(ctx, companionKey(key, lastSeenKeyPrefix+"*")).Result()
+			client.Del(ctx, append(slices, key, metadataKey(key))...)
+		}
+		cleanup()
+		defer cleanup()
+		ls, err := NewLastSeenFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}, LastSeenOptions{Slice: time.Second, Retention: 5 * time.Second})
+		if err != nil {
+			t.Fatalf("Failed to create last-seen filter: %v", err)
+		}
+		if err := ls.Add([]byte("early")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		time.Sleep(time.Second)
+		if err := ls.Add([]byte("late")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+
+		seenEarly, early, err := ls.LastSeen([]byte("early"))
+		if err != nil {
+			t.Fatalf("Failed to get last-seen time: %v", err)
+		}
+		seenLate, late, err := ls.LastSeen([]byte("late"))
+		if err != nil {
+			t.Fatalf("Failed to get last-seen time: %v", err)
+		}
+		if !seenEarly || !seenLate || !early.Before(late) {
+			t.Errorf("Expected the earlier sighting in an older slice, got %v at %v and %v at %v", seenEarly, early, seenLate, late)
+		}
+		if since := time.Since(late); since < 0 || since > time.Second {
+			t.Errorf("Expected the latest sighting in the current slice, got %v ago", since)
+		}
+		if seen, at, err := ls.LastSeen([]byte("never")); err != nil || seen || !at.IsZero() {
+			t.Errorf("Expected an unseen element, got %v at %v, %v", seen, at, err)
+		}
+
+		// Slices expire after the retention period
+		slices, _ := client.Keys(ctx, companionKey(key, lastSeenKeyPrefix+"*")).Result()
+		for _, slice := range slices {
+			if ttl := client.TTL(ctx, slice).Val(); ttl <= 0 || ttl > 6*time.Second {
+				t.Errorf("Expected slice %q to expire within the retention period, TTL %v", slice, ttl)
+			}
+		}
+		if len(slices) != 2 {
+			t.Errorf("Expected 2 time slices, got %v", slices)
+		}
+	})
+
+	t.Run("BulkLoadCancel", func(t *testing.T) {
+		key := "integration:test:bulk-cancel"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 10000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := make([][]byte, 1000)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("cancel:%d", i))
+		}
+		loadCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var reports []BulkLoadProgress
+		loaded, err := bf.BulkLoad(loadCtx, NewSliceIterator(items), BulkLoadOptions{
+			BatchSize: 100,
+			Total:     int64(len(items)),
+			Progress: func(p BulkLoadProgress) {
+				reports = append(reports, p)
+				if p.Items >= 300 {
+					cancel()
+				}
+			},
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected the load to stop with context.Canceled, got %v", err)
+		}
+		if loaded < 300 || loaded >= int64(len(items)) || loaded%100 != 0 {
+			t.Errorf("Expected the load to stop after whole batches past 300 items, loaded %d", loaded)
+		}
+		for i, p := range reports {
+			if p.Items != int64(i+1)*100 || p.Rate <= 0 {
+				t.Errorf("Unexpected progress report %d: %+v", i, p)
+			}
+			if p.Items < int64(len(items)) && p.ETA <= 0 {
+				t.Errorf("Expected an ETA while items remain, got %+v", p)
+			}
+		}
+		// With one worker the batches are written in source order
+		found, err := bf.ExistsMany(items[:loaded])
+		if err != nil {
+			t.Fatalf("Failed to check elements: %v", err)
+		}
+		for i, ok := range found {
+			if !ok {
+				t.Fatalf("Expected loaded item %q to exist", items[i])
+			}
+		}
+	})
+
+	t.Run("BulkLoadResume", func(t *testing.T) {
+		key := "integration:test:bulk-resume"
+		checkpoint := companionKey(key, "checkpoint")
+		for _, k := range []string{key, metadataKey(key), checkpoint} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 10000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := make([][]byte, 1000)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("resume:%d", i))
+		}
+
+		// Interrupt the first run after a few batches
+		loadCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		interrupted, err := bf.BulkLoad(loadCtx, NewSliceIterator(items), BulkLoadOptions{
+			BatchSize:     100,
+			CheckpointKey: checkpoint,
+			Progress: func(p BulkLoadProgress) {
+				if p.Items >= 300 {
+					cancel()
+				}
+			},
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected the first run to be cancelled, got %v", err)
+		}
+		offset, err := client.HGet(ctx, checkpoint, checkpointFieldOffset).Int64()
+		if err != nil || offset != interrupted {
+			t.Fatalf("Expected a checkpoint at %d items, got %d, %v", interrupted, offset, err)
+		}
+
+		// The second run skips the written items and counts them in its progress
+		var first BulkLoadProgress
+		loaded, err := bf.BulkLoad(ctx, NewSliceIterator(items), BulkLoadOptions{
+			BatchSize:     100,
+			CheckpointKey: checkpoint,
+			Progress: func(p BulkLoadProgress) {
+				if first.Items == 0 {
+					first = p
+				}
+			},
+		})
+		if err != nil || loaded != int64(len(items)) {
+			t.Fatalf("Expected %d items loaded after resuming, got %d, %v", len(items), loaded, err)
+		}
+		if first.Items != interrupted+100 {
+			t.Errorf("Expected the first report of the resumed run at %d items, got %d", interrupted+100, first.Items)
+		}
+		if n := client.Exists(ctx, checkpoint).Val(); n != 0 {
+			t.Error("Expected the checkpoint to be deleted after the load")
+		}
+		found, err := bf.ExistsMany(items)
+		if err != nil {
+			t.Fatalf("Failed to check elements: %v", err)
+		}
+		for i, ok := range found {
+			if !ok {
+				t.Fatalf("Expected %q after the resumed load", items[i])
+			}
+		}
+	})
+
+	t.Run("TypedFilter", func(t *testing.T) {
+		key := "integration:test:typed"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		enc, err := LookupEncoder[uint64](EncoderUint64)
+		if err != nil {
+			t.Fatalf("Failed to look up encoder: %v", err)
+		}
+		ids, err := NewTypedFilter(bf, enc)
+		if err != nil {
+			t.Fatalf("Failed to create typed filter: %v", err)
+		}
+		if err := ids.Add(42); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if exists, err := ids.Exists(42); err != nil || !exists {
+			t.Errorf("Expected 42 to exist, got %v, %v", exists, err)
+		}
+		if exists, err := ids.Exists(43); err != nil || exists {
+			t.Errorf("Expected 43 to be absent, got %v, %v", exists, err)
+		}
+
+		// Tooling encodes the text form identically, so it finds items added by services
+		encoded, err := EncodeText(EncoderUint64, "42")
+		if err != nil {
+			t.Fatalf("Failed to encode text: %v", err)
+		}
+		if exists, err := bf.Exists(encoded); err != nil || !exists {
+			t.Errorf("Expected the text-encoded item to exist, got %v, %v", exists, err)
+		}
+		if _, err := LookupEncoder[string](EncoderUint64); !errors.Is(err, ErrEncoderType) {
+			t.Errorf("Expected ErrEncoderType for the wrong item type, got %v", err)
+		}
+		if err := RegisterEncoder[uint64](EncoderUint64, enc); !errors.Is(err, ErrDuplicateEncoder) {
+			t.Errorf("Expected ErrDuplicateEncoder, got %v", err)
+		}
+	})
+
+	t.Run("ExistsExplain", func(t *testing.T) {
+		key := "integration:test:explain"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			BitLayout:          BitLayoutLSBFirst,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("explained")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		explanation, err := bf.ExistsExplain([]byte("explained"))
+		if err != nil {
+			t.Fatalf("Failed to explain lookup: %v", err)
+		}
+		if !explanation.Exists || explanation.Key != key || len(explanation.Unset()) != 0 {
+			t.Errorf("Expected every bit of the element set, got %+v", explanation)
+		}
+		for _, bit := range explanation.Bits {
+			if bit.Offset != BitLayoutLSBFirst.Offset(bit.Position) {
+				t.Errorf("Expected offset %d for position %d, got %d", BitLayoutLSBFirst.Offset(bit.Position), bit.Position, bit.Offset)
+			}
+		}
+
+		// Clearing one bit behind the filter's back shows up as the reason for a miss
+		cleared := explanation.Bits[len(explanation.Bits)/2]
+		if err := client.SetBit(ctx, key, int64(cleared.Offset), 0).Err(); err != nil {
+			t.Fatalf("Failed to clear bit: %v", err)
+		}
+		if explanation, err = bf.ExistsExplain([]byte("explained")); err != nil {
+			t.Fatalf("Failed to explain lookup: %v", err)
+		}
+		unset := explanation.Unset()
+		if explanation.Exists || len(unset) != 1 || unset[0].Offset != cleared.Offset {
+			t.Errorf("Expected only offset %d unset, got %+v", cleared.Offset, unset)
+		}
+	})
+
+	t.Run("AnalyzeHashStrategy", func(t *testing.T) {
+		key := "integration:test:analyze"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		// Sequential IDs are the structured keys the analysis is meant for
+		keys := make([][]byte, 1000)
+		for i := range keys {
+			keys[i] = []byte(fmt.Sprintf("user:%08d", i))
+		}
+		report, err := AnalyzeHashStrategy(NewXXHashStrategy(), keys, AnalyzeOptions{
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			Buckets:            64,
+		})
+		if err != nil {
+			t.Fatalf("Failed to analyze strategy: %v", err)
+		}
+		if report.NormalizedChiSquare() > 2 || math.Abs(report.HashCorrelation) > 0.1 || report.IdenticalPositionSets != 0 {
+			t.Errorf("Expected xxhash to handle sequential IDs well, got %+v", report)
+		}
+		if math.Abs(report.FillRatio-report.ExpectedFillRatio) > 0.05 {
+			t.Errorf("Expected a fill ratio near %.3f, got %.3f", report.ExpectedFillRatio, report.FillRatio)
+		}
+
+		// The simulated filter sets exactly the bits a real filter of that sizing sets
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.AddMany(keys); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		set, err := client.BitCount(ctx, key, nil).Result()
+		if err != nil {
+			t.Fatalf("Failed to count bits: %v", err)
+		}
+		if fill := float64(set) / float64(report.BitSize); fill != report.FillRatio {
+			t.Errorf("Expected the real filter's fill ratio %.4f to match the analysis %.4f", fill, report.FillRatio)
+		}
+	})
+
+	t.Run("Advisor", func(t *testing.T) {
+		key := "integration:test:advisor"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 100,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		advisor := NewAdvisor(AdvisorOptions{})
+
+		// Within its expected insertions the filter needs no resize
+		items := make([][]byte, 1000)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("advisor-%d", i))
+		}
+		if err := bf.AddMany(items[:50]); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		plan, err := advisor.Plan(ctx, bf)
+		if err != nil {
+			t.Fatalf("Failed to plan: %v", err)
+		}
+		if plan.NeedsResize || plan.Migration != MigrationNone {
+			t.Errorf("Expected no resize for a filter at half capacity, got %s", plan)
+		}
+		if plan.EstimatedInsertions < 40 || plan.EstimatedInsertions > 60 {
+			t.Errorf("Expected about 50 estimated insertions, got %d", plan.EstimatedInsertions)
+		}
+
+		// Three times overfilled, it is resized for the estimate with headroom
+		if err := bf.AddMany(items[50:300]); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		plan, err = advisor.Plan(ctx, bf)
+		if err != nil {
+			t.Fatalf("Failed to plan: %v", err)
+		}
+		if !plan.NeedsResize || plan.CurrentFalsePositiveRate <= plan.TargetFalsePositiveRate {
+			t.Fatalf("Expected an overfilled filter to need a resize, got %s", plan)
+		}
+		if plan.NewExpectedInsertions < 2*plan.EstimatedInsertions || plan.NewBitSize <= plan.BitSize {
+			t.Errorf("Expected a larger filter with headroom, got %s", plan)
+		}
+		wantBits, wantHashes := EstimateParameters(plan.NewExpectedInsertions, 0.01)
+		if plan.NewBitSize != wantBits || plan.NewHashCount != wantHashes {
+			t.Errorf("Expected %d bits and %d hashes, got %d and %d", wantBits, wantHashes, plan.NewBitSize, plan.NewHashCount)
+		}
+		if plan.MemoryDeltaBytes <= 0 || plan.NeedsChunking() || plan.Migration != MigrationRebuildFromSource {
+			t.Errorf("Unexpected resize details: %s", plan)
+		}
+
+		// A saturated filter is sized from the fewest insertions that could have filled it
+		if err := bf.AddMany(items[300:]); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		plan, err = advisor.Plan(ctx, bf)
+		if err != nil {
+			t.Fatalf("Failed to plan: %v", err)
+		}
+		if plan.FillRatio != 1 || plan.NeedsChunking() || plan.NewExpectedInsertions < 2*plan.ExpectedInsertions || plan.NewExpectedInsertions > 10000 {
+			t.Errorf("Expected a bounded plan for a saturated filter, got %s", plan)
+		}
+
+		// Filters without a bitmap cannot be planned
+		if _, err := advisor.Plan(ctx, nil); !errors.Is(err, ErrIncompatibleFilter) {
+			t.Errorf("Expected ErrIncompatibleFilter, got %v", err)
+		}
+	})
+
+	t.Run("ResizableFilter", func(t *testing.T) {
+		key := "integration:test:resizable"
+		staging := companionKey(key, resizeKeySuffix)
+		for _, k := range []string{key, metadataKey(key), staging} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		rf, err := NewResizableFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 100,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create resizable filter: %v", err)
+		}
+		items := make([][]byte, 300)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("resize-%d", i))
+			if err := rf.Add(items[i]); err != nil {
+				t.Fatalf("Failed to add data: %v", err)
+			}
+		}
+
+		// Without a source the audit stream must record raw items
+		params := ResizeParams{ExpectedInsertions: 1000, FalsePositiveRate: 0.01}
+		if _, err := rf.Resize(ctx, params, ResizeOptions{}); !errors.Is(err, ErrAuditNotReplayable) {
+			t.Errorf("Expected ErrAuditNotReplayable, got %v", err)
+		}
+
+		// An item added during the backfill is written to both filters
+		added := []byte("resize-during-backfill")
+		source := &hookIterator{
+			Iterator: NewSliceIterator(items),
+			hook: func() {
+				if err := rf.Add(added); err != nil {
+					t.Errorf("Add during resize failed: %v", err)
+				}
+				if _, err := rf.Resize(ctx, params, ResizeOptions{Source: NewSliceIterator(nil)}); !errors.Is(err, ErrResizeInProgress) {
+					t.Errorf("Nested Resize = %v, want ErrResizeInProgress", err)
+				}
+			},
+		}
+		report, err := rf.Resize(ctx, params, ResizeOptions{Source: source, BatchSize: 64, ValidationItems: 50})
+		if err != nil {
+			t.Fatalf("Failed to resize: %v", err)
+		}
+		if report.Backfilled != int64(len(items)) || report.Validated != 50 {
+			t.Errorf("Expected 300 backfilled and 50 validated items, got %+v", report)
+		}
+		for _, data := range append(items, added) {
+			if exists, err := rf.Exists(data); err != nil || !exists {
+				t.Fatalf("%q should exist after the resize (exists=%v, err=%v)", data, exists, err)
+			}
+		}
+
+		// The new parameters are recorded, so reopening with them succeeds
+		wantBits, _ := EstimateParameters(params.ExpectedInsertions, params.FalsePositiveRate)
+		if bits := client.HGet(ctx, metadataKey(key), metaFieldBits).Val(); bits != fmt.Sprint(wantBits) {
+			t.Errorf("Expected %d bits recorded, got %q", wantBits, bits)
+		}
+		if _, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: params.ExpectedInsertions,
+			FalsePositiveRate:  params.FalsePositiveRate,
+		}); err != nil {
+			t.Errorf("Failed to reopen the resized filter: %v", err)
+		}
+		if n := client.Exists(ctx, staging).Val(); n != 0 {
+			t.Error("Staging key left after the resize")
+		}
+	})
+
+	t.Run("AliasedFilter", func(t *testing.T) {
+		alias := "integration:test:alias"
+		gen1, gen2 := "integration:test:alias:gen1", "integration:test:alias:gen2"
+		for _, k := range []string{alias, gen1, metadataKey(gen1), gen2, metadataKey(gen2)} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		cfg := Config{
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}
+		writer, err := NewAliasedFilter(cfg, alias, 0)
+		if err != nil {
+			t.Fatalf("Failed to create aliased filter: %v", err)
+		}
+		// The reader caches its resolution for longer than the test runs
+		reader, err := NewAliasedFilter(cfg, alias, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to create aliased filter: %v", err)
+		}
+		if _, err := reader.Exists([]byte("item")); !errors.Is(err, ErrAliasNotFound) {
+			t.Errorf("Expected ErrAliasNotFound before the alias is set, got %v", err)
+		}
+
+		previous, err := writer.SwapAlias(ctx, gen1)
+		if err != nil || previous != "" {
+			t.Fatalf("SwapAlias = %q, %v; want an empty previous key", previous, err)
+		}
+		old := []byte("first-generation")
+		if err := writer.Add(old); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		if exists, err := reader.Exists(old); err != nil || !exists {
+			t.Errorf("Reader should see the first generation (exists=%v, err=%v)", exists, err)
+		}
+		if n := client.Exists(ctx, gen1).Val(); n != 1 {
+			t.Error("Expected the item to be written under the generation key")
+		}
+
+		// Publishing a new generation is seen by the writer at once and by the reader
+		// once its cached resolution is dropped
+		previous, err = writer.SwapAlias(ctx, gen2)
+		if err != nil || previous != gen1 {
+			t.Fatalf("SwapAlias = %q, %v; want %q", previous, err, gen1)
+		}
+		if key, err := writer.Key(ctx); err != nil || key != gen2 {
+			t.Errorf("Writer key = %q, %v; want %q", key, err, gen2)
+		}
+		if key, _ := reader.Key(ctx); key != gen1 {
+			t.Errorf("Reader key before invalidation = %q, want the cached %q", key, gen1)
+		}
+		reader.Invalidate()
+		if key, _ := reader.Key(ctx); key != gen2 {
+			t.Errorf("Reader key after invalidation = %q, want %q", key, gen2)
+		}
+		if exists, err := reader.Exists(old); err != nil || exists {
+			t.Errorf("The new generation should be empty (exists=%v, err=%v)", exists, err)
+		}
+	})
+
+	t.Run("PipelineError", func(t *testing.T) {
+		key, wrongType := "integration:test:pipeerror", "integration:test:pipeerror:list"
+		for _, k := range []string{key, metadataKey(key), wrongType} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		if err := client.RPush(ctx, wrongType, "not a bitmap").Err(); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer hooked.Close()
+		hooked.AddHook(misrouteHook(wrongType))
+		cfg := Config{
+			RedisKey:    key,
+			RedisClient: NewSingleNodeRedisClient(hooked),
+			BitSize:     9586,
+			HashCount:   7,
+		}
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+
+		data := []byte("half-written")
+		err = bf.Add(data)
+		var pe *PipelineError
+		if !errors.As(err, &pe) {
+			t.Fatalf("Expected a *PipelineError, got %v", err)
+		}
+		if !pe.Partial() {
+			t.Errorf("Expected a partial failure, got %v", pe)
+		}
+		written := make(map[int64]bool)
+		for _, cmd := range pe.Succeeded() {
+			if cmd.Op == "setbit" {
+				if cmd.Key != key {
+					t.Errorf("Succeeded SETBIT addressed %q, want %q", cmd.Key, key)
+				}
+				written[cmd.Offset] = true
+			}
+		}
+		failed := pe.Failed()
+		for _, cmd := range failed {
+			if cmd.Op != "setbit" || cmd.Key != wrongType || cmd.Slot != KeySlot(wrongType) {
+				t.Errorf("Unexpected failed command %+v", cmd)
+			}
+			if cmd.Err == nil || !strings.HasPrefix(cmd.Err.Error(), "WRONGTYPE") {
+				t.Errorf("Expected a WRONGTYPE error, got %v", cmd.Err)
+			}
+		}
+		if len(written) == 0 || len(failed) != 3 {
+			t.Errorf("Expected 4 written and 3 failed bits, got %d and %d", len(written), len(failed))
+		}
+		// Exactly the reported bits are set
+		for _, pos := range bf.Positions(data) {
+			set := client.GetBit(ctx, key, int64(pos)).Val() == 1
+			if set != written[int64(pos)] {
+				t.Errorf("Bit %d set=%v, but the error reported written=%v", pos, set, written[int64(pos)])
+			}
+		}
+		if len(pe.Unwrap()) != 1 || !strings.Contains(pe.Error(), "3 of") {
+			t.Errorf("Expected one distinct cause and a summary of 3 failures, got %v", pe)
+		}
+		if IsTransientError(err) {
+			t.Error("A WRONGTYPE failure should not be transient")
+		}
+
+		// Bit writes are idempotent, so retrying completes the half-written element
+		cfg.RedisClient = redisClient
+		retry, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := retry.Add(data); err != nil {
+			t.Fatalf("Retried Add failed: %v", err)
+		}
+		if exists, err := retry.Exists(data); err != nil || !exists {
+			t.Errorf("Retried element should exist (exists=%v, err=%v)", exists, err)
+		}
+	})
+
+	t.Run("ExportSpec", func(t *testing.T) {
+		key := "integration:test:spec"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			BitLayout:          BitLayoutLSBFirst,
+			TTL:                time.Hour,
+			Audit:              &Audit{},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		raw, err := bf.ExportSpec()
+		if err != nil {
+			t.Fatalf("Failed to export spec: %v", err)
+		}
+		var spec FilterSpec
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			t.Fatalf("Spec is not valid JSON: %v", err)
+		}
+		cleanupKey(client, spec.Keys.Audit)
+		defer cleanupKey(client, spec.Keys.Audit)
+		if spec.Version != SpecVersion || spec.Hash.Strategy != HashXXHash || spec.Hash.Positions != SpecPositionsDoubleHashing ||
+			spec.Layout.BitOrder != "lsb-first" || spec.TTLMillis != time.Hour.Milliseconds() || spec.Keys.Audit == "" {
+			t.Errorf("Unexpected spec:\n%s", raw)
+		}
+
+		data := []byte("described")
+		if err := bf.Add(data); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		// A reader built from the spec alone finds the item's bits under the named keys
+		strategy, err := NewHashStrategy(spec.Hash.Strategy)
+		if err != nil {
+			t.Fatalf("Spec names an unknown strategy: %v", err)
+		}
+		h1, h2 := strategy.Hash(data, 0), strategy.Hash(data, 1)
+		if h2%2 == 0 {
+			h2++
+		}
+		for i := uint64(0); i < uint64(spec.Hashes); i++ {
+			pos := (h1 + i*h2) % spec.Bits
+			if spec.Layout.BitOrder == "lsb-first" {
+				pos = pos/8*8 + 7 - pos%8
+			}
+			if client.GetBit(ctx, spec.Keys.Bitmap, int64(pos)).Val() != 1 {
+				t.Errorf("Bit %d derived from the spec is not set", pos)
+			}
+		}
+		if bits := client.HGet(ctx, spec.Keys.Metadata, metaFieldBits).Val(); bits != fmt.Sprint(spec.Bits) {
+			t.Errorf("Metadata records %q bits, spec says %d", bits, spec.Bits)
+		}
+		if n := client.XLen(ctx, spec.Keys.Audit).Val(); n != 1 {
+			t.Errorf("Expected one audit entry in %q, got %d", spec.Keys.Audit, n)
+		}
+		if ttl := client.TTL(ctx, spec.Keys.Bitmap).Val(); ttl <= 0 || ttl > time.Hour {
+			t.Errorf("Expected the bitmap to expire within the spec's TTL, got %s", ttl)
+		}
+	})
+
+	t.Run("FuseFilter", func(t *testing.T) {
+		key8, key16 := "integration:test:fuse8", "integration:test:fuse16"
+		for _, k := range []string{key8, key16} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		members := make([][]byte, 10000)
+		for i := range members {
+			members[i] = []byte(fmt.Sprintf("fuse-member-%d", i))
+		}
+		// Duplicates are allowed
+		source := NewSliceIterator(append(members, members[:100]...))
+		f8, err := BuildFuseFilter(ctx, FuseConfig{RedisKey: key8, RedisClient: redisClient}, source)
+		if err != nil {
+			t.Fatalf("Failed to build fuse filter: %v", err)
+		}
+		f16, err := BuildFuseFilter(ctx, FuseConfig{RedisKey: key16, RedisClient: redisClient, FingerprintBits: 16}, NewSliceIterator(members))
+		if err != nil {
+			t.Fatalf("Failed to build fuse filter: %v", err)
+		}
+
+		// A reopened filter reads its parameters from the stored header
+		reopened, err := OpenFuseFilter(ctx, FuseConfig{RedisKey: key8, RedisClient: redisClient})
+		if err != nil {
+			t.Fatalf("Failed to open fuse filter: %v", err)
+		}
+		for _, f := range []*FuseFilter{f8, reopened, f16} {
+			for _, data := range members {
+				if exists, err := f.Exists(data); err != nil || !exists {
+					t.Fatalf("%q should exist (exists=%v, err=%v)", data, exists, err)
+				}
+			}
+		}
+
+		falsePositives := func(f *FuseFilter) int {
+			n := 0
+			for i := 0; i < 10000; i++ {
+				if exists, _ := f.Exists([]byte(fmt.Sprintf("fuse-absent-%d", i))); exists {
+					n++
+				}
+			}
+			return n
+		}
+		// 8-bit fingerprints give about 1/256, 16-bit ones about 1/65536
+		if n := falsePositives(f8); n > 80 {
+			t.Errorf("Expected about 40 false positives in 10000 with 8-bit fingerprints, got %d", n)
+		}
+		if n := falsePositives(f16); n > 3 {
+			t.Errorf("Expected almost no false positives with 16-bit fingerprints, got %d", n)
+		}
+
+		// The stored size is what SizeBytes reports, and less than a Bloom filter at 1/256
+		if n := client.StrLen(ctx, key8).Val(); n != int64(f8.SizeBytes()) {
+			t.Errorf("Stored %d bytes, SizeBytes reports %d", n, f8.SizeBytes())
+		}
+		bloomBits, _ := EstimateParameters(uint64(len(members)), 1.0/256)
+		if uint64(f8.SizeBytes())*8 >= bloomBits {
+			t.Errorf("Fuse filter takes %d bytes, a Bloom filter %d", f8.SizeBytes(), bloomBits/8)
+		}
+		if f16.SizeBytes() != 2*f8.SizeBytes()-fuseHeaderSize {
+			t.Errorf("Expected 16-bit fingerprints to double the array, got %d and %d bytes", f16.SizeBytes(), f8.SizeBytes())
+		}
+
+		client.Set(ctx, key8, "not a fuse filter", 0)
+		if _, err := OpenFuseFilter(ctx, FuseConfig{RedisKey: key8, RedisClient: redisClient}); !errors.Is(err, ErrInvalidSerializedFilter) {
+			t.Errorf("Expected ErrInvalidSerializedFilter, got %v", err)
+		}
+	})
+
+	t.Run("MoveTo", func(t *testing.T) {
+		target := redis.NewClient(&redis.Options{Addr: "redis-ring:6379"})
+		defer target.Close()
+		if err := target.Ping(ctx).Err(); err != nil {
+			t.Skipf("Second Redis instance not available, skipping move test: %v", err)
+		}
+		key := "integration:test:move"
+		keys := []string{key, metadataKey(key), companionKey(key, auditKeySuffix)}
+		for _, k := range keys {
+			cleanupKey(client, k)
+			cleanupKey(target, k)
+			defer cleanupKey(client, k)
+			defer cleanupKey(target, k)
+		}
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 10000,
+			FalsePositiveRate:  0.01,
+			Audit:              &Audit{},
+		}
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := make([][]byte, 500)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("move-%d", i))
+		}
+		if err := bf.AddMany(items); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		bitmap := client.Get(ctx, key).Val()
+
+		// A chunked copy reassembles the bitmap on the target and leaves the source intact
+		if err := bf.MoveTo(ctx, "redis-ring:6379", MoveOptions{Copy: true, ChunkSize: 1024}); !errors.Is(err, ErrMoveTargetRequired) {
+			t.Errorf("Expected ErrMoveTargetRequired, got %v", err)
+		}
+		if err := bf.MoveTo(ctx, "redis-ring:6379", MoveOptions{Copy: true, ChunkSize: 1024, Target: target}); err != nil {
+			t.Fatalf("Failed to copy filter: %v", err)
+		}
+		if got := target.Get(ctx, key).Val(); got != bitmap {
+			t.Errorf("Copied bitmap differs: %d bytes, want %d", len(got), len(bitmap))
+		}
+		if n := client.Exists(ctx, keys...).Val(); n != int64(len(keys)) {
+			t.Errorf("Expected a copy to keep all %d keys on the source, found %d", len(keys), n)
+		}
+		if n := target.Exists(ctx, keys...).Val(); n != int64(len(keys)) {
+			t.Errorf("Expected all %d keys on the target, found %d", len(keys), n)
+		}
+		for _, suffix := range []string{moveChunkSuffix, moveStagingSuffix} {
+			if client.Exists(ctx, companionKey(key, suffix)).Val()+target.Exists(ctx, companionKey(key, suffix)).Val() != 0 {
+				t.Errorf("Companion key %q left behind", suffix)
+			}
+		}
+
+		// Moving again needs Replace, and then removes the keys from the source
+		if err := bf.MoveTo(ctx, "redis-ring:6379", MoveOptions{}); !errors.Is(err, ErrTargetKeyExists) {
+			t.Errorf("Expected ErrTargetKeyExists, got %v", err)
+		}
+		if err := bf.MoveTo(ctx, "redis-ring:6379", MoveOptions{Replace: true}); err != nil {
+			t.Fatalf("Failed to move filter: %v", err)
+		}
+		if n := client.Exists(ctx, keys...).Val(); n != 0 {
+			t.Errorf("Expected no keys left on the source, found %d", n)
+		}
+		cfg.RedisClient = NewSingleNodeRedisClient(target)
+		moved, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to open moved filter: %v", err)
+		}
+		for _, data := range items {
+			if exists, err := moved.Exists(data); err != nil || !exists {
+				t.Fatalf("%q should exist on the target (exists=%v, err=%v)", data, exists, err)
+			}
+		}
+	})
+
+	t.Run("AdmissionControl", func(t *testing.T) {
+		key := "integration:test:admission"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		metrics := newRecordingMetrics()
+		cfg := Config{
+			RedisKey:    key,
+			RedisClient: redisClient,
+			BitSize:     1024,
+			HashCount:   7,
+			Metrics:     metrics,
+			// Refresh the fill ratio on every insert
+			Admission: &Admission{MaxFill: 0.3, CheckInterval: time.Nanosecond},
+		}
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+
+		var first []byte
+		var saturation *SaturationError
+		for i := 0; i < 1000; i++ {
+			data := []byte(fmt.Sprintf("admission-%d", i))
+			if err := bf.Add(data); err != nil {
+				if !errors.As(err, &saturation) || !errors.Is(err, ErrFilterSaturated) {
+					t.Fatalf("Expected a *SaturationError, got %v", err)
+				}
+				break
+			}
+			if first == nil {
+				first = data
+			}
+		}
+		if saturation == nil {
+			t.Fatal("Expected inserts to be refused once the filter is saturated")
+		}
+		set := client.BitCount(ctx, key, nil).Val()
+		if fill := float64(set) / 1024; saturation.Fill != fill || fill <= 0.3 || fill > 0.32 {
+			t.Errorf("Expected inserts refused just past the ceiling, got fill %.3f (error %v)", fill, saturation)
+		}
+		if err := bf.AddMany([][]byte{[]byte("refused")}); !errors.Is(err, ErrFilterSaturated) {
+			t.Errorf("Expected AddMany to be refused, got %v", err)
+		}
+		if client.BitCount(ctx, key, nil).Val() != set {
+			t.Error("A refused insert changed the bitmap")
+		}
+		if exists, err := bf.Exists(first); err != nil || !exists {
+			t.Errorf("Reads should still be served (exists=%v, err=%v)", exists, err)
+		}
+		if n := metrics.counter(MetricAdmissionRejections); n != 2 {
+			t.Errorf("Expected 2 rejections counted, got %d", n)
+		}
+		if g := metrics.gauge(MetricFillRatio); g != saturation.Fill {
+			t.Errorf("Expected the fill ratio gauge at %.3f, got %.3f", saturation.Fill, g)
+		}
+
+		// The override admits inserts beyond the ceiling and counts them
+		cfg.Admission = &Admission{MaxFill: 0.3, CheckInterval: time.Nanosecond, Override: true}
+		overridden, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := overridden.Add([]byte("admitted")); err != nil {
+			t.Fatalf("Expected the override to admit the insert, got %v", err)
+		}
+		if exists, _ := overridden.Exists([]byte("admitted")); !exists {
+			t.Error("Overridden insert should exist")
+		}
+		if n := metrics.counter(MetricAdmissionOverrides); n != 1 {
+			t.Errorf("Expected 1 override counted, got %d", n)
+		}
+	})
+
+	t.Run("MirrorWarmup", func(t *testing.T) {
+		key := "integration:test:mirror:warmup"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 10000,
+			FalsePositiveRate:  0.01,
+		}
+		writer, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := make([][]byte, 1000)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("warmup-%d", i))
+		}
+		if err := writer.AddMany(items); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+
+		counter := newCommandCounter()
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer hooked.Close()
+		hooked.AddHook(counter)
+		cfg.RedisClient = NewSingleNodeRedisClient(hooked)
+		mirror, err := NewMirroredFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create mirrored filter: %v", err)
+		}
+
+		// An interrupted warmup keeps its chunks, and the next one resumes after them
+		warmupCtx, cancel := context.WithCancel(ctx)
+		var progress []WarmupProgress
+		err = mirror.Warmup(warmupCtx, WarmupOptions{ChunkSize: 1024, Progress: func(p WarmupProgress) {
+			progress = append(progress, p)
+			if len(progress) == 3 {
+				cancel()
+			}
+		}})
+		cancel()
+		if !errors.Is(err, context.Canceled) || mirror.Ready() {
+			t.Fatalf("Expected a cancelled, incomplete warmup, got %v (ready=%v)", err, mirror.Ready())
+		}
+		total := progress[0].Total
+		if len(progress) != 3 || progress[2].Bytes != 3072 || total <= 3072 {
+			t.Fatalf("Unexpected progress of the interrupted warmup: %+v", progress)
+		}
+		progress = nil
+		if err := mirror.Warmup(ctx, WarmupOptions{ChunkSize: 1024, Progress: func(p WarmupProgress) {
+			progress = append(progress, p)
+		}}); err != nil {
+			t.Fatalf("Failed to warm up: %v", err)
+		}
+		if progress[0].Bytes != 4096 || progress[len(progress)-1].Bytes != total || !mirror.Ready() {
+			t.Errorf("Expected the warmup to resume at 4096 bytes and finish at %d, got %+v", total, progress)
+		}
+		if direct, pipelined := counter.counts("getrange"); direct != int((total+1023)/1024) || pipelined != 0 {
+			t.Errorf("Expected one GETRANGE per chunk, got %d direct and %d pipelined", direct, pipelined)
+		}
+
+		// Once ready, lookups are answered locally
+		for _, data := range items {
+			if exists, err := mirror.Exists(data); err != nil || !exists {
+				t.Fatalf("%q should exist in the mirror (exists=%v, err=%v)", data, exists, err)
+			}
+		}
+		if direct, pipelined := counter.counts("getbit"); direct+pipelined != 0 {
+			t.Errorf("Expected no GETBIT once warm, got %d", direct+pipelined)
+		}
+
+		// Bits set by another writer appear after the next download
+		late := []byte("warmup-late")
+		if err := writer.Add(late); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if exists, _ := mirror.Exists(late); exists {
+			t.Skip("The late item is a false positive of the mirror")
+		}
+		if err := mirror.Warmup(ctx, WarmupOptions{}); err != nil {
+			t.Fatalf("Failed to refresh: %v", err)
+		}
+		if exists, err := mirror.Exists(late); err != nil || !exists {
+			t.Errorf("Expected the late item after a refresh (exists=%v, err=%v)", exists, err)
+		}
+	})
+
+	t.Run("InsertRateAnomaly", func(t *testing.T) {
+		key := "integration:test:insertrate"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		metrics := newRecordingMetrics()
+		var mu sync.Mutex
+		var anomalies []InsertRateAnomaly
+		window := 100 * time.Millisecond
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 10000,
+			FalsePositiveRate:  0.01,
+			Metrics:            metrics,
+			InsertRateAlert: &InsertRateAlert{
+				Window:        window,
+				WarmupWindows: 3,
+				OnAnomaly: func(a InsertRateAnomaly) {
+					mu.Lock()
+					anomalies = append(anomalies, a)
+					mu.Unlock()
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := make([][]byte, 0, 300)
+		for i := 0; i < cap(items); i++ {
+			items = append(items, []byte(fmt.Sprintf("rate-%d", i)))
+		}
+
+		// Establish a baseline of 5 inserts per window
+		for w := 0; w < 4; w++ {
+			if err := bf.AddMany(items[w*5 : w*5+5]); err != nil {
+				t.Fatalf("Failed to add elements: %v", err)
+			}
+			time.Sleep(window)
+		}
+		if baseline := metrics.gauge(MetricInsertRateBaseline); baseline <= 0 || baseline > 50 {
+			t.Fatalf("Expected a baseline of about 50 inserts per second, got %.1f", baseline)
+		}
+		mu.Lock()
+		if len(anomalies) != 0 {
+			t.Errorf("Expected no anomaly at a steady rate, got %+v", anomalies)
+		}
+		mu.Unlock()
+
+		// A burst beyond ten times the baseline is reported once per window
+		if err := bf.AddMany(items[20:200]); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		for _, data := range items[200:] {
+			if err := bf.Add(data); err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(anomalies) != 1 {
+			t.Fatalf("Expected exactly one anomaly, got %+v", anomalies)
+		}
+		if a := anomalies[0]; a.Key != key || a.Rate <= 10*a.Baseline {
+			t.Errorf("Unexpected anomaly %+v", a)
+		}
+		if n := metrics.counter(MetricInsertRateAnomalies); n != 1 {
+			t.Errorf("Expected 1 anomaly counted, got %d", n)
+		}
+	})
+
+	t.Run("TimingBreakdown", func(t *testing.T) {
+		key := "integration:test:timing"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		// Every round trip takes at least 20ms, so the Redis phase must account for it
+		delay := 20 * time.Millisecond
+		slow := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer slow.Close()
+		slow.AddHook(delayHook(delay))
+		metrics := newTimingMetrics()
+		bf, err := NewBloomFilter(Config{
+			RedisKey:    key,
+			RedisClient: NewSingleNodeRedisClient(slow),
+			BitSize:     9586,
+			HashCount:   7,
+			Metrics:     metrics,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("timed")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if _, err := bf.Exists([]byte("timed")); err != nil {
+			t.Fatalf("Failed to check element: %v", err)
+		}
+
+		for _, names := range []phaseNames{addPhases, existsPhases} {
+			hash, pipeline, wait := metrics.observed(names.hash), metrics.observed(names.pipeline), metrics.observed(names.redis)
+			if len(hash) != 1 || len(pipeline) != 1 || len(wait) != 1 {
+				t.Fatalf("Expected one observation per phase, got %d, %d and %d", len(hash), len(pipeline), len(wait))
+			}
+			if wait[0] < delay {
+				t.Errorf("%s = %s, want at least the %s round trip", names.redis, wait[0], delay)
+			}
+			if hash[0] >= delay || pipeline[0] >= delay {
+				t.Errorf("Expected the round trip outside %s (%s) and %s (%s)", names.hash, hash[0], names.pipeline, pipeline[0])
+			}
+		}
+	})
+
+	t.Run("IdentifierEncoders", func(t *testing.T) {
+		key := "integration:test:identifiers"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		uuidEnc, err := LookupEncoder[[16]byte](EncoderUUID)
+		if err != nil {
+			t.Fatalf("Failed to look up encoder: %v", err)
+		}
+		ulidEnc, err := LookupEncoder[[16]byte](EncoderULID)
+		if err != nil {
+			t.Fatalf("Failed to look up encoder: %v", err)
+		}
+		uuids, err := NewTypedFilter(bf, uuidEnc)
+		if err != nil {
+			t.Fatalf("Failed to create typed filter: %v", err)
+		}
+		ulids, err := NewTypedFilter(bf, ulidEnc)
+		if err != nil {
+			t.Fatalf("Failed to create typed filter: %v", err)
+		}
+
+		// Both identifiers are stored as their 16 binary bytes
+		id := [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+		if err := uuids.Add(id); err != nil {
+			t.Fatalf("Failed to add UUID: %v", err)
+		}
+		if exists, err := bf.Exists(id[:]); err != nil || !exists {
+			t.Errorf("Expected the binary UUID in the filter, got %v, %v", exists, err)
+		}
+		for _, text := range []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", "6BA7B8109DAD11D180B400C04FD430C8"} {
+			encoded, err := EncodeText(EncoderUUID, text)
+			if err != nil {
+				t.Fatalf("Failed to encode %q: %v", text, err)
+			}
+			if exists, err := bf.Exists(encoded); err != nil || !exists {
+				t.Errorf("Expected %q to find the UUID, got %v, %v", text, exists, err)
+			}
+		}
+
+		ulid := [16]byte{0x01, 0x56, 0x3e, 0x3a, 0xb5, 0xd3, 0xd6, 0x76, 0x4c, 0x61, 0xef, 0xb9, 0x93, 0x02, 0xbd, 0x5b}
+		if err := ulids.Add(ulid); err != nil {
+			t.Fatalf("Failed to add ULID: %v", err)
+		}
+		for _, text := range []string{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "01arz3ndektsv4rrffq69g5fav"} {
+			encoded, err := EncodeText(EncoderULID, text)
+			if err != nil {
+				t.Fatalf("Failed to encode %q: %v", text, err)
+			}
+			if !bytes.Equal(encoded, ulid[:]) {
+				t.Errorf("%q encoded to %x, want %x", text, encoded, ulid)
+			}
+			if exists, err := bf.Exists(encoded); err != nil || !exists {
+				t.Errorf("Expected %q to find the ULID, got %v, %v", text, exists, err)
+			}
+		}
+
+		for _, tc := range []struct{ encoder, text string }{
+			{EncoderUUID, "6ba7b810-9dad-11d1-80b4"},
+			{EncoderUUID, "6ba7b810x9dad-11d1-80b4-00c04fd430c8"},
+			{EncoderULID, "81ARZ3NDEKTSV4RRFFQ69G5FAV"},
+			{EncoderULID, "01ARZ3NDEKTSV4RRFFQ69G5FAU!"},
+			{EncoderULID, "01ARZ3NDEKTSV4RRFFQ69G5FAI"},
+		} {
+			if _, err := EncodeText(tc.encoder, tc.text); !errors.Is(err, ErrInvalidIdentifier) {
+				t.Errorf("EncodeText(%s, %q) = %v, want ErrInvalidIdentifier", tc.encoder, tc.text, err)
+			}
+		}
+	})
+
+	t.Run("PrefetchExists", func(t *testing.T) {
+		key := "integration:test:prefetch"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		counter := newCommandCounter()
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379"})
+		defer hooked.Close()
+		hooked.AddHook(counter)
+		cfg := Config{
+			RedisKey:    key,
+			RedisClient: NewSingleNodeRedisClient(hooked),
+			BitSize:     9586,
+			HashCount:   7,
+		}
+		uncached, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := uncached.PrefetchExists([][]byte{[]byte("x")}); !errors.Is(err, ErrCacheDisabled) {
+			t.Errorf("Expected ErrCacheDisabled without a result cache, got %v", err)
+		}
+
+		cfg.ResultCache = &ResultCache{Size: 100, TTL: time.Minute, NegativeTTL: time.Minute}
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		items := make([][]byte, 10)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("prefetch-%d", i))
+		}
+		if err := bf.AddMany(items[:5]); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		_, before := counter.counts("getbit")
+
+		if err := bf.PrefetchExists(items); err != nil {
+			t.Fatalf("Failed to prefetch: %v", err)
+		}
+		// Items added through the filter are cached already; the answers for the others
+		// arrive in the background, all in one pipeline
+		fetched := 5 * 7
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if _, pipelined := counter.counts("getbit"); pipelined-before == fetched {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Prefetch did not complete")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		// Give the goroutine time to store the answers after the pipeline returns
+		time.Sleep(10 * time.Millisecond)
+
+		for i, data := range items {
+			if exists, err := bf.Exists(data); err != nil || exists != (i < 5) {
+				t.Errorf("%q: exists=%v, err=%v; want %v", data, exists, err, i < 5)
+			}
+		}
+		if _, pipelined := counter.counts("getbit"); pipelined-before != fetched {
+			t.Errorf("Expected prefetched lookups to be answered locally, read %d more bits", pipelined-before-fetched)
+		}
+
+		// Cached items are not fetched again
+		if err := bf.PrefetchExists(items); err != nil {
+			t.Fatalf("Failed to prefetch: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if _, pipelined := counter.counts("getbit"); pipelined-before != fetched {
+			t.Errorf("Expected no lookups for cached items, read %d more bits", pipelined-before-fetched)
+		}
+	})
+
+	t.Run("TestAndAdd", func(t *testing.T) {
+		key := "integration:test:testandadd"
+		stream := companionKey(key, auditKeySuffix)
+		for _, k := range []string{key, metadataKey(key), stream} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		counter := newCommandCounter()
+		hooked := redis.NewClient(&redis.Options{Addr: "redis:6379", PoolSize: 20})
+		defer hooked.Close()
+		hooked.AddHook(counter)
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        NewSingleNodeRedisClient(hooked),
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			TTL:                time.Hour,
+			Audit:              &Audit{},
+		}
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+
+		// Of concurrent callers adding the same element, exactly one sees it as new
+		var wg sync.WaitGroup
+		var firsts int32
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				seen, err := bf.TestAndAdd([]byte("contended"))
+				if err != nil {
+					t.Errorf("TestAndAdd failed: %v", err)
+					return
+				}
+				if !seen {
+					atomic.AddInt32(&firsts, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		if firsts != 1 {
+			t.Errorf("%d callers saw the element as new, want exactly one", firsts)
+		}
+		if exists, err := bf.Exists([]byte("contended")); err != nil || !exists {
+			t.Errorf("Expected the element to exist, got %v, %v", exists, err)
+		}
+		// Each call is a single script call, and only the first insert is audited
+		if direct, pipelined := counter.counts("setbit"); direct+pipelined != 0 {
+			t.Errorf("Expected the bits to be set by the script, got %d SETBIT commands", direct+pipelined)
+		}
+		if direct, _ := counter.counts("evalsha"); direct < 20 {
+			t.Errorf("Expected a script call per TestAndAdd, got %d EVALSHA", direct)
+		}
+		if n := client.XLen(ctx, stream).Val(); n != 1 {
+			t.Errorf("Expected one audit entry, got %d", n)
+		}
+		if ttl := client.PTTL(ctx, key).Val(); ttl <= 0 || ttl > time.Hour {
+			t.Errorf("Expected the script to apply the TTL, got %s", ttl)
+		}
+		if ttl := client.PTTL(ctx, metadataKey(key)).Val(); ttl <= 0 {
+			t.Errorf("Expected the script to expire the metadata, got %s", ttl)
+		}
+
+		// Servers without scripting are refused up front
+		cfg.Capabilities = &Capabilities{}
+		noLua, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if _, err := noLua.TestAndAdd([]byte("unscripted")); !errors.Is(err, ErrLuaUnsupported) {
+			t.Errorf("Expected ErrLuaUnsupported, got %v", err)
+		}
+	})
+
+	t.Run("StableFilter", func(t *testing.T) {
+		key := "integration:test:stable"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}
+		if _, err := NewStableFilter(cfg, StableOptions{CellBits: 9}); !errors.Is(err, ErrInvalidStableCells) {
+			t.Errorf("Expected ErrInvalidStableCells, got %v", err)
+		}
+		noBitfield := cfg
+		noBitfield.Capabilities = &Capabilities{Lua: true}
+		if _, err := NewStableFilter(noBitfield, StableOptions{}); !errors.Is(err, ErrBitfieldUnsupported) {
+			t.Errorf("Expected ErrBitfieldUnsupported, got %v", err)
+		}
+		sf, err := NewStableFilter(cfg, StableOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create stable filter: %v", err)
+		}
+
+		// An insert sets the element's cells to the maximum
+		early := make([][]byte, 100)
+		for i := range early {
+			early[i] = []byte(fmt.Sprintf("stable-early-%d", i))
+			if err := sf.Add(early[i]); err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+		}
+		if exists, err := sf.Exists(early[99]); err != nil || !exists {
+			t.Fatalf("Expected the latest element to exist, got %v, %v", exists, err)
+		}
+		for _, pos := range sf.filter.getHashPositions(early[99]) {
+			cell, err := client.BitField(ctx, key, "GET", "u3", fmt.Sprintf("#%d", pos)).Result()
+			if err != nil || cell[0] != 7 {
+				t.Errorf("Cell %d = %v, %v; want 7", pos, cell, err)
+			}
+		}
+
+		// After a long stream, old elements have faded while recent ones remain
+		var recent []byte
+		for i := 0; i < 10000; i++ {
+			recent = []byte(fmt.Sprintf("stable-stream-%d", i))
+			if err := sf.Add(recent); err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+		}
+		if exists, err := sf.Exists(recent); err != nil || !exists {
+			t.Errorf("Expected the latest element to exist, got %v, %v", exists, err)
+		}
+		remembered := 0
+		for _, data := range early {
+			if exists, _ := sf.Exists(data); exists {
+				remembered++
+			}
+		}
+		if remembered > 10 {
+			t.Errorf("Expected early elements to fade out, %d of 100 remain", remembered)
+		}
+
+		// The false-positive rate settles near the configured rate instead of growing
+		falsePositives := 0
+		for i := 0; i < 2000; i++ {
+			if exists, _ := sf.Exists([]byte(fmt.Sprintf("stable-absent-%d", i))); exists {
+				falsePositives++
+			}
+		}
+		if rate := float64(falsePositives) / 2000; rate > 0.03 {
+			t.Errorf("Expected a stable false-positive rate near 0.01, got %.4f", rate)
+		}
+	})
+
+	t.Run("PartitionedLayout", func(t *testing.T) {
+		key := "integration:test:partitioned"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			Partitioned:        true,
+		}
+		filter, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		bf := filter.(*bloomFilter)
+		k := uint64(bf.hashCount)
+		if bf.bitSize%(k*partitionAlign) != 0 {
+			t.Fatalf("Expected %d bits to split into %d aligned partitions", bf.bitSize, k)
+		}
+		size := bf.bitSize / k
+
+		items := make([][]byte, 1000)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("partitioned-%d", i))
+			// Each hash function addresses its own partition
+			for j, pos := range bf.getHashPositions(items[i]) {
+				if pos/size != uint64(j) {
+					t.Fatalf("Position %d of %q is %d, outside partition %d", j, items[i], pos, j)
+				}
+			}
+		}
+		if err := filter.AddMany(items); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		for _, data := range items {
+			if exists, err := filter.Exists(data); err != nil || !exists {
+				t.Fatalf("%q should exist (exists=%v, err=%v)", data, exists, err)
+			}
+		}
+
+		// At the expected insertions every partition is about half full
+		fill, err := PartitionFill(ctx, filter)
+		if err != nil {
+			t.Fatalf("Failed to measure partitions: %v", err)
+		}
+		if uint64(len(fill)) != k {
+			t.Fatalf("Expected %d partitions, got %d", k, len(fill))
+		}
+		var set float64
+		for i, f := range fill {
+			if f < 0.4 || f > 0.6 {
+				t.Errorf("Partition %d is %.3f full, want about 0.5", i, f)
+			}
+			set += f * float64(size)
+		}
+		if total := client.BitCount(ctx, key, nil).Val(); int64(math.Round(set)) != total {
+			t.Errorf("Partitions hold %.0f set bits, the bitmap %d", set, total)
+		}
+
+		// Reopening without the layout is reported rather than reading the wrong bits
+		cfg.Partitioned = false
+		if _, err := NewBloomFilter(cfg); err == nil {
+			t.Error("Expected a mismatch when reopening without the partitioned layout")
+		}
+		cfg.RedisKey = "integration:test:partitioned:plain"
+		plain, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if _, err := PartitionFill(ctx, plain); !errors.Is(err, ErrIncompatibleFilter) {
+			t.Errorf("Expected ErrIncompatibleFilter for a plain filter, got %v", err)
+		}
+	})
+
+	t.Run("RotatingBloomFilter", func(t *testing.T) {
+		key := "integration:test:rotating"
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}
+		if _, err := NewRotatingBloomFilter(cfg, RotatingOptions{Bucket: time.Millisecond, Buckets: 2}); !errors.Is(err, ErrInvalidRotatingWindow) {
+			t.Errorf("Expected ErrInvalidRotatingWindow, got %v", err)
+		}
+		rf, err := NewRotatingBloomFilter(cfg, RotatingOptions{Bucket: time.Second, Buckets: 2})
+		if err != nil {
+			t.Fatalf("Failed to create rotating filter: %v", err)
+		}
+		var used []string
+		defer func() {
+			for _, k := range used {
+				cleanupKey(client, k)
+				cleanupKey(client, metadataKey(k))
+			}
+		}()
+		// Start just after a bucket boundary, so each step lands in the intended bucket
+		nextBucket := func() {
+			time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second + 50*time.Millisecond)))
+		}
+		nextBucket()
+
+		old, recent := []byte("rotating-old"), []byte("rotating-recent")
+		if err := rf.Add(old); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		first := rf.Keys()[0]
+		used = append(used, first)
+		if ttl := client.PTTL(ctx, first).Val(); ttl <= time.Second || ttl > 2*time.Second {
+			t.Errorf("Expected the bucket to expire with the 2s window, got %s", ttl)
+		}
+
+		nextBucket()
+		if err := rf.Add(recent); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		keys := rf.Keys()
+		used = append(used, keys[0])
+		if keys[1] != first || keys[0] == first {
+			t.Fatalf("Expected a new current bucket after %q, got %v", first, keys)
+		}
+		for _, data := range [][]byte{old, recent} {
+			if exists, err := rf.Exists(data); err != nil || !exists {
+				t.Errorf("%q should be in the window (exists=%v, err=%v)", data, exists, err)
+			}
+		}
+
+		// One bucket later the first bucket has left the window
+		nextBucket()
+		if exists, err := rf.Exists(old); err != nil || exists {
+			t.Errorf("%q should have left the window (exists=%v, err=%v)", old, exists, err)
+		}
+		if exists, err := rf.Exists(recent); err != nil || !exists {
+			t.Errorf("%q should still be in the window (exists=%v, err=%v)", recent, exists, err)
+		}
+	})
+
+	t.Run("BitsAndBloomsInterop", func(t *testing.T) {
+		key := "integration:test:bitsandblooms"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+
+		// Serialize a filter the way bits-and-blooms' BloomFilter.WriteTo does, placing
+		// each item with the library's own location function
+		const m, k = 1000, 4
+		words := make([]uint64, (m+63)/64)
+		locate := func(data []byte) []uint64 {
+			h1, h2 := murmur3.Sum128(data)
+			h3, h4 := murmur3.Sum128(append(append([]byte(nil), data...), 1))
+			h := [4]uint64{h1, h2, h3, h4}
+			locations := make([]uint64, k)
+			for i := uint64(0); i < k; i++ {
+				locations[i] = (h[i%2] + i*h[2+((i+i%2)%4)/2]) % m
+			}
+			return locations
+		}
+		items := [][]byte{[]byte("alpha"), []byte("beta"), []byte("gamma")}
+		for _, data := range items {
+			for _, loc := range locate(data) {
+				words[loc/64] |= 1 << (loc % 64)
+			}
+		}
+		var serialized bytes.Buffer
+		for _, v := range append([]uint64{m, k, m}, words...) {
+			binary.Write(&serialized, binary.BigEndian, v)
+		}
+
+		bf, err := ImportBitsAndBlooms(ctx, bytes.NewReader(serialized.Bytes()), Config{RedisKey: key, RedisClient: redisClient})
+		if err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+		for _, data := range items {
+			if exists, err := bf.Exists(data); err != nil || !exists {
+				t.Errorf("%q should exist after the import (exists=%v, err=%v)", data, exists, err)
+			}
+		}
+		if exists, err := bf.Exists([]byte("delta")); err != nil || exists {
+			t.Errorf("Expected an absent item, got %v, %v", exists, err)
+		}
+
+		// Exporting gives back the library's serialization byte for byte
+		var exported bytes.Buffer
+		if err := ExportBitsAndBlooms(ctx, &exported, bf); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		if !bytes.Equal(exported.Bytes(), serialized.Bytes()) {
+			t.Errorf("Exported %x, want %x", exported.Bytes(), serialized.Bytes())
+		}
+
+		// Items added in Redis land where the library looks for them
+		if err := bf.Add([]byte("delta")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		exported.Reset()
+		if err := ExportBitsAndBlooms(ctx, &exported, bf); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		decoded, err := DecodeBitsAndBlooms(&exported)
+		if err != nil {
+			t.Fatalf("Failed to decode the export: %v", err)
+		}
+		for _, loc := range locate([]byte("delta")) {
+			word := binary.BigEndian.Uint64(decoded.Bits[loc/64*8:])
+			if word&(1<<(loc%64)) == 0 {
+				t.Errorf("Location %d of an item added in Redis is not set in the export", loc)
+			}
+		}
+
+		// Corrupt serializations and filters hashed differently are refused
+		corrupt := append([]byte(nil), serialized.Bytes()...)
+		binary.BigEndian.PutUint64(corrupt[16:], m+1)
+		if _, err := DecodeBitsAndBlooms(bytes.NewReader(corrupt)); !errors.Is(err, ErrInvalidSerializedFilter) {
+			t.Errorf("Expected ErrInvalidSerializedFilter for a mismatched bitset length, got %v", err)
+		}
+		if _, err := DecodeBitsAndBlooms(bytes.NewReader(serialized.Bytes()[:40])); !errors.Is(err, ErrInvalidSerializedFilter) {
+			t.Errorf("Expected ErrInvalidSerializedFilter for truncated data, got %v", err)
+		}
+		plain, err := NewBloomFilter(Config{RedisKey: key + ":plain", RedisClient: redisClient, ExpectedInsertions: 100, FalsePositiveRate: 0.01})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := ExportBitsAndBlooms(ctx, io.Discard, plain); !errors.Is(err, ErrIncompatibleFilter) {
+			t.Errorf("Expected ErrIncompatibleFilter for an xxhash filter, got %v", err)
+		}
+	})
+
+	t.Run("UnionAndMergeInto", func(t *testing.T) {
+		keyA, keyB, global := "{integration:merge}:a", "{integration:merge}:b", "{integration:merge}:global"
+		for _, key := range []string{keyA, keyB, global} {
+			cleanupKey(client, key)
+			defer cleanupKey(client, key)
+		}
+		newFilter := func(key string) BloomFilter {
+			bf, err := NewBloomFilter(Config{
+				RedisKey:           key,
+				RedisClient:        redisClient,
+				ExpectedInsertions: 1000,
+				FalsePositiveRate:  0.01,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create Bloom Filter: %v", err)
+			}
+			return bf
+		}
+		a, b := newFilter(keyA), newFilter(keyB)
+		if err := a.Add([]byte("shard_a")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if err := b.Add([]byte("shard_b")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if err := a.Union(ctx, b); err != nil {
+			t.Fatalf("Failed to union filters: %v", err)
+		}
+		if exists, err := a.Exists([]byte("shard_b")); err != nil || !exists {
+			t.Errorf("Expected element of the other filter after Union, got %v, %v", exists, err)
+		}
+		for _, shard := range []BloomFilter{a, b} {
+			if err := shard.MergeInto(ctx, global); err != nil {
+				t.Fatalf("Failed to merge filter: %v", err)
+			}
+		}
+		merged := newFilter(global)
+		found, err := merged.ExistsMany([][]byte{[]byte("shard_a"), []byte("shard_b")})
+		if err != nil || !found[0] || !found[1] {
+			t.Errorf("Expected both elements in the merged filter, got %v, %v", found, err)
+		}
+	})
+
+	t.Run("CopyTo", func(t *testing.T) {
+		key, dest := "integration:test:copy", "integration:test:copy:dest"
+		for _, k := range []string{key, dest, metadataKey(key), metadataKey(dest)} {
+			cleanupKey(client, k)
+			defer cleanupKey(client, k)
+		}
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("copied")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if err := bf.CopyTo(ctx, dest, CopyOptions{}); err != nil {
+			t.Fatalf("Failed to copy filter: %v", err)
+		}
+		if err := bf.CopyTo(ctx, dest, CopyOptions{}); err != ErrTargetKeyExists {
+			t.Errorf("Expected ErrTargetKeyExists, got %v", err)
+		}
+		cfg.RedisKey = dest
+		copied, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to open the copy: %v", err)
+		}
+		if exists, err := copied.Exists([]byte("copied")); err != nil || !exists {
+			t.Errorf("Expected element in the copy, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("ClearAndDrop", func(t *testing.T) {
+		key := "integration:test:clear"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("cleared")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if err := bf.Clear(ctx, DeleteOptions{Unlink: true}); err != nil {
+			t.Fatalf("Failed to clear filter: %v", err)
+		}
+		if exists, err := bf.Exists([]byte("cleared")); err != nil || exists {
+			t.Errorf("Expected element to be gone after Clear, got %v, %v", exists, err)
+		}
+		if err := bf.Drop(ctx, DeleteOptions{}); err != nil {
+			t.Fatalf("Failed to drop filter: %v", err)
+		}
+		if n := client.Exists(ctx, key, metadataKey(key)).Val(); n != 0 {
+			t.Errorf("Expected no keys after Drop, got %d", n)
+		}
+	})
+
+	t.Run("PermissionsCheck", func(t *testing.T) {
+		key := "integration:test:acl"
+		user := "bloom-readonly"
+		if err := client.Do(ctx, "ACL", "SETUSER", user, "on", "nopass", "~integration:*", "~{integration:*", "+getbit", "+hgetall", "+multi", "+discard").Err(); err != nil {
+			t.Skipf("ACL not available: %v", err)
+		}
+		defer client.Do(ctx, "ACL", "DELUSER", user)
+		restricted := redis.NewClient(&redis.Options{Addr: "redis:6379", Username: user})
+		defer restricted.Close()
+
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        NewSingleNodeRedisClient(restricted),
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		report, err := bf.PermissionsCheck(ctx)
+		if err != nil {
+			t.Fatalf("Failed to check permissions: %v", err)
+		}
+		if report.User != user {
+			t.Errorf("Expected user %s, got %s", user, report.User)
+		}
+		missing := make(map[string]bool)
+		for _, check := range report.Missing() {
+			missing[check.Command[0]] = true
+		}
+		if !missing["setbit"] || missing["getbit"] {
+			t.Errorf("Expected only write commands to be missing, got %v", missing)
+		}
+	})
+
+	t.Run("BitfieldEngine", func(t *testing.T) {
+		key := "integration:test:bitfield"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.0001,
+			Engine:             EngineBitfield,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("a")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if err := bf.AddMany([][]byte{[]byte("b"), []byte("c")}); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		found, err := bf.ExistsMany([][]byte{[]byte("a"), []byte("b"), []byte("c")})
+		if err != nil {
+			t.Fatalf("Failed to check elements: %v", err)
+		}
+		for i, exists := range found {
+			if !exists {
+				t.Errorf("Expected element %d to exist", i)
+			}
+		}
+		explanation, err := bf.ExistsExplain([]byte("a"))
+		if err != nil || !explanation.Exists {
+			t.Errorf("Expected GETBIT to see the bits set with BITFIELD, got %v", err)
+		}
+	})
+
+	t.Run("LuaEngine", func(t *testing.T) {
+		key := "integration:test:lua"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			TTL:                time.Hour,
+			Engine:             EngineLua,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("a")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		if ttl := client.TTL(ctx, key).Val(); ttl <= 0 {
+			t.Errorf("Expected the script to set a TTL, got %v", ttl)
+		}
+		// The script is reloaded after the server forgets it
+		if err := client.ScriptFlush(ctx).Err(); err != nil {
+			t.Fatalf("Failed to flush scripts: %v", err)
+		}
+		exists, err := bf.Exists([]byte("a"))
+		if err != nil || !exists {
+			t.Errorf("Expected element to exist after SCRIPT FLUSH, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("FunctionsEngine", func(t *testing.T) {
+		key := "integration:test:functions"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			Engine:             EngineFunctions,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("a")); err != nil {
+			t.Fatalf("Failed to add element: %v", err)
+		}
+		// The library is reloaded after the server forgets it
+		if err := client.FunctionDelete(ctx, functionLibraryName).Err(); err != nil {
+			t.Fatalf("Failed to delete the function library: %v", err)
+		}
+		seen, err := bf.TestAndAdd([]byte("a"))
+		if err != nil || !seen {
+			t.Errorf("Expected element to be present after FUNCTION DELETE, got %v, %v", seen, err)
+		}
+		exists, err := bf.Exists([]byte("b"))
+		if err != nil || exists {
+			t.Errorf("Expected absent element, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("RedisBloomEngine", func(t *testing.T) {
+		key := "integration:test:redisbloom"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		caps, err := ProbeCapabilities(ctx, client)
+		if err != nil {
+			t.Fatalf("Failed to probe capabilities: %v", err)
+		}
+		cfg := Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			Engine:             EngineRedisBloom,
+			Capabilities:       caps,
+		}
+		if !caps.RedisBloom {
+			if _, err := NewBloomFilter(cfg); err != ErrRedisBloomUnsupported {
+				t.Fatalf("Expected ErrRedisBloomUnsupported, got %v", err)
+			}
+			t.Skip("RedisBloom module not loaded")
+		}
+
+		bf, err := NewBloomFilter(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.AddMany([][]byte{[]byte("a"), []byte("b")}); err != nil {
+			t.Fatalf("Failed to add elements: %v", err)
+		}
+		exists, err := bf.Exists([]byte("a"))
+		if err != nil || !exists {
+			t.Errorf("Expected element to exist, got %v, %v", exists, err)
+		}
+		if kind, _ := client.Type(ctx, key).Result(); kind != "MBbloom--" {
+			t.Errorf("Expected a module filter, got type %s", kind)
+		}
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		key := "integration:test:ttl"
+		cleanupKey(client, key)
+		defer cleanupKey(client, key)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			TTL:                2 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		testData := []byte("integration_ttl_test")
+		err = bf.Add(testData)
+		if err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		exists, err := bf.Exists(testData)
+		if err != nil {
+			t.Fatalf("Failed to check data: %v", err)
+		}
+		if !exists {
+			t.Error("Data should exist immediately after adding")
+		}
+		t.Log("Waiting for TTL to expire...")
+		time.Sleep(3 * time.Second)
+		exists, err = bf.Exists(testData)
+		if err != nil {
+			t.Fatalf("Failed to check data after TTL: %v", err)
+		}
+		if exists {
+			t.Error("Data should not exist after TTL expiration")
+		}
+	})
+
+	t.Run("FixedTTL", func(t *testing.T) {
+		key := "integration:test:fixedttl"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			TTL:                time.Hour,
+			TTLMode:            TTLFixed,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("first")); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		if err := client.Expire(ctx, key, time.Minute).Err(); err != nil {
+			t.Fatalf("Failed to shorten TTL: %v", err)
+		}
+		if err := bf.Add([]byte("second")); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		if ttl := client.TTL(ctx, key).Val(); ttl > time.Minute {
+			t.Errorf("Expected a fixed TTL not to be refreshed, got %v", ttl)
+		}
+	})
+
+	t.Run("MetadataAfterExpiry", func(t *testing.T) {
+		key := "integration:test:metaexpiry"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			TTL:                time.Second,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("first")); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		time.Sleep(1500 * time.Millisecond)
+		if n := client.Exists(ctx, key, metadataKey(key)).Val(); n != 0 {
+			t.Fatalf("Expected the filter and its metadata to expire, %d keys left", n)
+		}
+		if err := bf.Add([]byte("second")); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		if bits, err := client.HGet(ctx, metadataKey(key), metaFieldBits).Result(); err != nil || bits == "" {
+			t.Errorf("Expected the recreated filter to record its parameters, got %q, %v", bits, err)
+		}
+	})
+
+	t.Run("PipelinedTTL", func(t *testing.T) {
+		key := "integration:test:pipelinedttl"
+		cleanupKey(client, key)
+		cleanupKey(client, metadataKey(key))
+		defer cleanupKey(client, key)
+		defer cleanupKey(client, metadataKey(key))
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+			TTL:                time.Hour,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.AddMany([][]byte{[]byte("a"), []byte("b")}); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		for _, k := range []string{key, metadataKey(key)} {
+			if ttl := client.TTL(ctx, k).Val(); ttl <= 0 || ttl > time.Hour {
+				t.Errorf("Expected %s to expire within an hour, got %v", k, ttl)
+			}
+		}
+		if err := client.Persist(ctx, key).Err(); err != nil {
+			t.Fatalf("Failed to clear TTL: %v", err)
+		}
+		if err := bf.Add([]byte("c")); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		if ttl := client.TTL(ctx, key).Val(); ttl <= 0 {
+			t.Errorf("Expected Add to refresh the TTL, got %v", ttl)
+		}
+	})
+}
+
+func TestIntegrationWithRedisCluster(t *testing.T) {
+	clusterClient := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs: []string{
+			"redis-cluster:7000",
+			"redis-cluster:7001",
+			"redis-cluster:7002",
+			"redis-cluster:7003",
+			"redis-cluster:7004",
+			"redis-cluster:7005",
+		},
+		RouteRandomly:  true,
+		RouteByLatency: true,
+	})
+	ctx := context.Background()
+	if err := clusterClient.Ping(ctx).Err(); err != nil {
+		t.Skipf("Redis Cluster not available, skipping cluster test: %v", err)
+	}
+	defer clusterClient.Close()
+	redisClient := NewClusterRedisClient(clusterClient)
+	t.Run("ClusterBasicFunctionality", func(t *testing.T) {
+		key := "bloom:{integration:cluster:test}"
+		clusterClient.Del(ctx, key)
+		defer clusterClient.Del(ctx, key)
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		testData := []byte("integration_cluster_test_data")
+		exists, err := bf.Exists(testData)
+		if err != nil {
+			t.Fatalf("Failed to check existence: %v", err)
+		}
+		if exists {
+			t.Error("Data should not exist initially")
+		}
+		err = bf.Add(testData)
+		if err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		exists, err = bf.Exists(testData)
+		if err != nil {
+			t.Fatalf("Failed to check existence after adding: %v", err)
+		}
+		if !exists {
+			t.Error("Data should exist after adding")
+		}
+	})
+
+	t.Run("ClusterReplicaReads", func(t *testing.T) {
+		key := "bloom:{integration:cluster:replicas}"
+		clusterClient.Del(ctx, key)
+		defer clusterClient.Del(ctx, key)
+		replicaClient, err := NewClusterReplicaClient(clusterClient, ClusterReplicaOptions{}, ReplicaRouterOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create replica client: %v", err)
+		}
+		defer replicaClient.Close()
+		bf, err := NewBloomFilter(Config{
+			RedisKey:           key,
+			RedisClient:        replicaClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create Bloom Filter: %v", err)
+		}
+		if err := bf.Add([]byte("a")); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		// Replication is asynchronous; give the replica a moment
+		deadline := time.Now().Add(2 * time.Second)
+		exists, err := bf.Exists([]byte("a"))
+		for err == nil && !exists && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+			exists, err = bf.Exists([]byte("a"))
+		}
+		if err != nil || !exists {
+			t.Errorf("Expected element to exist on the replica, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("ClusterPlacement", func(t *testing.T) {
+		keys := []string{"bloom:{integration:a}", "bloom:{integration:b}", "bloom:{integration:c}"}
+		report, err := ClusterPlacement(ctx, clusterClient, keys...)
+		if err != nil {
+			t.Fatalf("Failed to build placement report: %v", err)
+		}
+		if len(report.Keys) != len(keys) {
+			t.Fatalf("Expected %d placements, got %d", len(keys), len(report.Keys))
+		}
+		for _, placement := range report.Keys {
+			if placement.Addr == "" {
+				t.Errorf("Key %s has no serving node (slot %d)", placement.Key, placement.Slot)
+			}
+		}
+	})
+}
+
+func TestIntegrationWithRedisSentinel(t *testing.T) {
+	failoverClient := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:    "mymaster",
+		SentinelAddrs: []string{"redis-sentinel:26379"},
+	})
+	ctx := context.Background()
+	if err := failoverClient.Ping(ctx).Err(); err != nil {
+		t.Skipf("Redis Sentinel not available, skipping sentinel test: %v", err)
+	}
+	defer failoverClient.Close()
+
+	key := "integration:test:sentinel"
+	failoverClient.Del(ctx, key)
+	defer failoverClient.Del(ctx, key)
+	bf, err := NewBloomFilter(Config{
+		RedisKey:           key,
+		RedisClient:        NewUniversalRedisClient(failoverClient),
+		ExpectedInsertions: 1000,
+		FalsePositiveRate:  0.01,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Bloom Filter: %v", err)
+	}
+	if err := bf.Add([]byte("a")); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	if exists, err := bf.Exists([]byte("a")); err != nil || !exists {
+		t.Errorf("Expected element to exist, got %v, %v", exists, err)
+	}
+}
+
+func TestIntegrationWithRedisRing(t *testing.T) {
+	ring := redis.NewRing(&redis.RingOptions{
+		Addrs: map[string]string{"shard1": "redis:6379", "shard2": "redis-ring:6379"},
+	})
+	ctx := context.Background()
+	if err := ring.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+		return shard.Ping(ctx).Err()
+	}); err != nil {
+		t.Skipf("Redis Ring not available, skipping ring test: %v", err)
+	}
+	defer ring.Close()
+	redisClient := NewUniversalRedisClient(ring)
+
+	for _, engine := range []Engine{EngineBitmap, EngineFunctions} {
+		t.Run(string(engine), func(t *testing.T) {
+			keys := []string{"{integration:ring:a}:" + string(engine), "{integration:ring:b}:" + string(engine)}
+			for _, key := range keys {
+				ring.Del(ctx, key, metadataKey(key))
+				defer ring.Del(ctx, key, metadataKey(key))
+				bf, err := NewBloomFilter(Config{
+					RedisKey:           key,
+					RedisClient:        redisClient,
+					ExpectedInsertions: 1000,
+					FalsePositiveRate:  0.01,
+					Engine:             engine,
+				})
+				if err != nil {
+					t.Fatalf("Failed to create Bloom Filter: %v", err)
+				}
+				if err := bf.Add([]byte("a")); err != nil {
+					t.Fatalf("Failed to add data: %v", err)
+				}
+				if exists, err := bf.Exists([]byte("a")); err != nil || !exists {
+					t.Errorf("Expected element to exist, got %v, %v", exists, err)
+				}
+				if exists, err := bf.Exists([]byte("b")); err != nil || exists {
+					t.Errorf("Expected element not to exist, got %v, %v", exists, err)
+				}
+			}
+		})
+	}
 }
 
 // Benchmark tests for performance
@@ -0,0 +1,367 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Bulk load defaults and checkpoint fields
+const (
+	defaultBulkLoadBatchSize = 1000
+	checkpointFieldOffset    = "offset"
+	checkpointFieldUpdatedAt = "updated_at"
+)
+
+// BulkLoadOptions configures BulkLoad
+type BulkLoadOptions struct {
+	// BatchSize is the number of items read from the source per batch (defaults to 1000)
+	BatchSize int
+	// PipelineSize caps the number of commands per pipeline, splitting larger batches;
+	// zero writes every batch in one pipeline
+	PipelineSize int
+	// Workers is the number of batches written concurrently (defaults to 1)
+	Workers int
+	// Total is the expected number of items, used to estimate the remaining time; zero if unknown
+	Total int64
+	// Progress is called after every batch, one call at a time; nil disables progress reporting
+	Progress func(BulkLoadProgress)
+	// CheckpointKey is a Redis key recording how many source items have been written, so
+	// an interrupted load resumes after them; it is deleted once the load completes
+	CheckpointKey string
+}
+
+// Skipper is implemented by iterators that can skip items without reading them, which
+// lets a resumed bulk load jump to its checkpoint. Skip returns io.EOF if fewer than n
+// items remain.
+type Skipper interface {
+	Skip(n int64) error
+}
+
+// BulkLoadProgress describes how far a bulk load has come
+type BulkLoadProgress struct {
+	// Items is the number of items written so far
+	Items int64
+	// Elapsed is the time since the load started
+	Elapsed time.Duration
+	// Rate is the average number of items written per second
+	Rate float64
+	// ETA is the estimated remaining time; zero when Total is unknown
+	ETA time.Duration
+}
+
+// BulkLoad adds every item yielded by source, writing batches of items in one pipeline
+// each, or in pipelines of PipelineSize commands. With several Workers, batches are
+// written concurrently while the source is read. The context is checked between batches,
+// so a cancelled load stops cleanly after the batches in flight; the number of items
+// written is returned together with any error. With a CheckpointKey, the load resumes
+// after the items a previous run wrote; counts and progress include them.
+func (bf *bloomFilter) BulkLoad(ctx context.Context, source Iterator, opts BulkLoadOptions) (int64, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBulkLoadBatchSize
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+
+	loaded, err := bf.resumeBulkLoad(ctx, source, opts.CheckpointKey)
+	if err != nil {
+		return 0, err
+	}
+	load := &bulkLoad{
+		filter:     bf,
+		opts:       opts,
+		start:      time.Now(),
+		resumed:    loaded,
+		loaded:     loaded,
+		checkpoint: loaded,
+		completed:  make(map[int64]int),
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	load.cancel = cancel
+
+	batches := make(chan bulkBatch)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				load.complete(ctx, batch, bf.writeBatch(ctx, batch.items, opts.PipelineSize))
+			}
+		}()
+	}
+
+	readErr := func() error {
+		var seq int64
+		batch := make([][]byte, 0, opts.BatchSize)
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			item, err := source.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			batch = append(batch, item)
+			if len(batch) == opts.BatchSize {
+				select {
+				case batches <- bulkBatch{seq: seq, items: batch}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				seq++
+				batch = make([][]byte, 0, opts.BatchSize)
+			}
+		}
+		if len(batch) > 0 {
+			select {
+			case batches <- bulkBatch{seq: seq, items: batch}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}()
+	close(batches)
+	wg.Wait()
+
+	if load.err != nil {
+		return load.loaded, load.err
+	}
+	if readErr != nil {
+		return load.loaded, readErr
+	}
+	if opts.CheckpointKey != "" {
+		client, _ := bf.cmdable()
+		if err := client.Del(ctx, opts.CheckpointKey).Err(); err != nil {
+			return load.loaded, err
+		}
+	}
+	return load.loaded, nil
+}
+
+// bulkBatch is a batch of a bulk load, numbered in source order
+type bulkBatch struct {
+	seq   int64
+	items [][]byte
+}
+
+// bulkLoad tracks the batches of a running bulk load. Batches may complete out of order;
+// the checkpoint only advances over the batches written without a gap.
+type bulkLoad struct {
+	filter  *bloomFilter
+	opts    BulkLoadOptions
+	start   time.Time
+	resumed int64
+	cancel  context.CancelFunc
+
+	mu sync.Mutex
+	// loaded is the number of items written, including the resumed ones
+	loaded int64
+	// checkpoint is the number of source items before the first unwritten batch
+	checkpoint int64
+	// next is the first batch not yet covered by the checkpoint, and completed holds the
+	// sizes of later batches already written
+	next      int64
+	completed map[int64]int
+	// err is the first error, which stops the load
+	err error
+}
+
+// complete records the outcome of a batch, saves the checkpoint and reports progress
+func (l *bulkLoad) complete(ctx context.Context, batch bulkBatch, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	if err != nil {
+		l.fail(err)
+		return
+	}
+
+	l.loaded += int64(len(batch.items))
+	l.completed[batch.seq] = len(batch.items)
+	advanced := false
+	for n, ok := l.completed[l.next]; ok; n, ok = l.completed[l.next] {
+		delete(l.completed, l.next)
+		l.checkpoint += int64(n)
+		l.next++
+		advanced = true
+	}
+	if advanced {
+		if err := l.filter.saveCheckpoint(ctx, l.opts.CheckpointKey, l.checkpoint); err != nil {
+			l.fail(err)
+			return
+		}
+	}
+	if l.opts.Progress != nil {
+		p := bulkLoadProgress(l.loaded-l.resumed, l.opts.Total-l.resumed, time.Since(l.start))
+		p.Items = l.loaded
+		l.opts.Progress(p)
+	}
+}
+
+// fail stops the load with err; the caller holds l.mu
+func (l *bulkLoad) fail(err error) {
+	l.err = err
+	l.cancel()
+}
+
+// writeBatch adds a batch of items, split into pipelines of at most pipelineSize commands
+// when it is set
+func (bf *bloomFilter) writeBatch(ctx context.Context, items [][]byte, pipelineSize int) error {
+	per := 1
+	if bf.usesBitmap() {
+		per = int(bf.hashCount)
+	}
+	step := len(items)
+	if pipelineSize > 0 && pipelineSize/per < step {
+		step = pipelineSize / per
+		if step == 0 {
+			step = 1
+		}
+	}
+	for i := 0; i < len(items); i += step {
+		end := i + step
+		if end > len(items) {
+			end = len(items)
+		}
+		if err := bf.addItems(ctx, items[i:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resumeBulkLoad positions source after the items recorded in the checkpoint and
+// returns their number
+func (bf *bloomFilter) resumeBulkLoad(ctx context.Context, source Iterator, key string) (int64, error) {
+	if key == "" {
+		return 0, nil
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := client.HGet(ctx, key, checkpointFieldOffset).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidCheckpoint
+	}
+
+	skip := func() error {
+		if skipper, ok := source.(Skipper); ok {
+			return skipper.Skip(offset)
+		}
+		for i := int64(0); i < offset; i++ {
+			if _, err := source.Next(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := skip(); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, ErrInvalidCheckpoint
+		}
+		return 0, err
+	}
+	return offset, nil
+}
+
+// saveCheckpoint records the number of source items written so far
+func (bf *bloomFilter) saveCheckpoint(ctx context.Context, key string, offset int64) error {
+	if key == "" {
+		return nil
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	return client.HSet(ctx, key,
+		checkpointFieldOffset, offset,
+		checkpointFieldUpdatedAt, formatTimestamp(time.Now()),
+	).Err()
+}
+
+// bulkLoadProgress computes the rate and remaining time of a load
+func bulkLoadProgress(items, total int64, elapsed time.Duration) BulkLoadProgress {
+	p := BulkLoadProgress{Items: items, Elapsed: elapsed}
+	if elapsed > 0 {
+		p.Rate = float64(items) / elapsed.Seconds()
+	}
+	if total > items && p.Rate > 0 {
+		p.ETA = time.Duration(float64(total-items) / p.Rate * float64(time.Second))
+	}
+	return p
+}
+
+// addItems writes the bits of several items in one pipeline and performs the
+// bookkeeping of Add (metadata, audit log, result cache and TTL) once for all of them
+func (bf *bloomFilter) addItems(ctx context.Context, items [][]byte) error {
+	if !bf.usesBitmap() {
+		if _, err := bf.addModuleItems(ctx, items); err != nil {
+			return err
+		}
+		return bf.afterAdd(ctx, items...)
+	}
+
+	var positions []uint64
+	for _, data := range items {
+		positions = append(positions, bf.getHashPositions(data)...)
+	}
+	if err := bf.limiter.wait(ctx, bf.bitCommands(len(positions))); err != nil {
+		return err
+	}
+	if err := bf.admit(ctx); err != nil {
+		return err
+	}
+
+	if err := bf.call(ctx, func() error { return bf.writePositions(ctx, positions) }); err != nil {
+		if bf.bufferAdd(err, positions, items...) {
+			return nil
+		}
+		return err
+	}
+	return bf.afterAdd(ctx, items...)
+}
+
+// writePositions sets the bits of several items with the configured engine, pipelining
+// them for the plain bitmap engine
+func (bf *bloomFilter) writePositions(ctx context.Context, positions []uint64) error {
+	switch bf.config.Engine {
+	case EngineBitfield:
+		return bf.setBitfield(ctx, positions)
+	case EngineLua, EngineFunctions:
+		return bf.setBitsLua(ctx, positions)
+	}
+
+	pipe, err := bf.pipeline()
+	if err != nil {
+		return err
+	}
+	defer bf.releasePipeline(pipe)
+	for _, pos := range positions {
+		pipe.SetBit(ctx, bf.config.RedisKey, bf.offset(pos), 1)
+	}
+	bf.pipelineTTL(ctx, pipe)
+	return execPipeline(ctx, pipe)
+}
@@ -0,0 +1,249 @@
+package bloom
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spaolacci/murmur3"
+)
+
+// Default result cache settings
+const (
+	defaultResultCacheSize = 10_000
+	defaultResultCacheTTL  = time.Minute
+)
+
+// Cache stores Exists answers in front of the Redis lookups. Implementations can wrap
+// ristretto, groupcache or any other layer; they must be safe for concurrent use and may
+// evict entries early. Keys are namespaced by filter, so one Cache can serve many filters.
+type Cache interface {
+	// Get returns the cached answer for key and whether one was found
+	Get(key string) (exists bool, ok bool)
+	// Set stores an answer for key that should be dropped after ttl
+	Set(key string, exists bool, ttl time.Duration)
+}
+
+// ResultCache configures caching of Exists answers that absorbs bursts of
+// repeated checks. Positive answers are safe to cache because membership never reverts
+// (until the filter is cleared or expires); negative answers go stale as soon as another
+// process adds the item, so they are only cached when NegativeTTL is set. Clears and
+// rebuilds through the filter drop the cached answers; ValidateInterval also detects
+// clears by other processes and expired keys.
+type ResultCache struct {
+	// Cache is an external cache to use; nil selects a built-in in-process LRU cache
+	Cache Cache
+	// Size is the maximum number of answers in the built-in cache (defaults to 10000)
+	Size int
+	// TTL is how long positive answers are kept (defaults to one minute)
+	TTL time.Duration
+	// NegativeTTL is how long negative answers are kept; zero disables caching them
+	NegativeTTL time.Duration
+	// ValidateInterval is how often a lookup first checks whether the filter was cleared,
+	// rebuilt or expired since the last check, dropping all answers if so; zero disables it
+	ValidateInterval time.Duration
+}
+
+// resultCaching applies the configured lifetimes to a Cache
+type resultCaching struct {
+	cache       Cache
+	namespace   string
+	ttl         time.Duration
+	negativeTTL time.Duration
+	// generation is part of every key; bumping it invalidates all cached answers
+	generation *uint64
+	// validateEvery is the ValidateInterval, and validation the state of the checks
+	validateEvery time.Duration
+	validation    *cacheValidation
+}
+
+// cacheValidation tracks the state of the filter's keys seen by the last check
+type cacheValidation struct {
+	mu          sync.Mutex
+	next        time.Time
+	fingerprint string
+}
+
+// newResultCaching sets up caching for a filter key, or returns nil if caching is disabled
+func newResultCaching(cfg *ResultCache, namespace string) *resultCaching {
+	if cfg == nil {
+		return nil
+	}
+	c := &resultCaching{
+		cache:       cfg.Cache,
+		namespace:   namespace + ":",
+		ttl:         cfg.TTL,
+		negativeTTL: cfg.NegativeTTL,
+		generation:  new(uint64),
+
+		validateEvery: cfg.ValidateInterval,
+		validation:    new(cacheValidation),
+	}
+	if c.ttl <= 0 {
+		c.ttl = defaultResultCacheTTL
+	}
+	if c.cache == nil {
+		c.cache = newLRUCache(cfg.Size)
+	}
+	return c
+}
+
+// withNamespace returns caching for another filter key sharing the same cache
+func (c *resultCaching) withNamespace(namespace string) *resultCaching {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.namespace = namespace + ":"
+	clone.generation = new(uint64)
+	clone.validation = new(cacheValidation)
+	return &clone
+}
+
+// invalidate drops every answer cached so far, for changes that can turn answers around
+func (c *resultCaching) invalidate() {
+	if c == nil {
+		return
+	}
+	atomic.AddUint64(c.generation, 1)
+}
+
+// validateCache drops all cached answers if the filter's bitmap appeared or disappeared,
+// or its metadata records another creation or rebuild time, since the last check. It
+// checks at most once per ValidateInterval; lookups arriving during a check skip it, and
+// failed checks are retried at the next interval.
+func (bf *bloomFilter) validateCache(ctx context.Context) {
+	c := bf.cache
+	if c == nil || c.validateEvery <= 0 {
+		return
+	}
+	v := c.validation
+	if !v.mu.TryLock() {
+		return
+	}
+	defer v.mu.Unlock()
+	now := time.Now()
+	if now.Before(v.next) {
+		return
+	}
+	v.next = now.Add(c.validateEvery)
+
+	client, err := bf.cmdable()
+	if err != nil {
+		return
+	}
+	pipe := client.Pipeline()
+	exists := pipe.Exists(ctx, bf.config.RedisKey)
+	meta := pipe.HMGet(ctx, metadataKey(bf.config.RedisKey), metaFieldCreatedAt, metaFieldRebuiltAt)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return
+	}
+	fingerprint := fmt.Sprint(exists.Val(), meta.Val())
+	if fingerprint != v.fingerprint {
+		// The first check also drops answers cached before it
+		c.invalidate()
+		v.fingerprint = fingerprint
+	}
+}
+
+// key identifies an item by the filter key, the cache generation and the item's 128-bit
+// Murmur3 hash
+func (c *resultCaching) key(data []byte) string {
+	h1, h2 := murmur3.Sum128(data)
+	var sum [16]byte
+	putUint128(sum[:], h1, h2)
+	return c.namespace + strconv.FormatUint(atomic.LoadUint64(c.generation), 36) + ":" + hex.EncodeToString(sum[:])
+}
+
+// putUint128 writes a 128-bit hash big-endian into b
+func putUint128(b []byte, h1, h2 uint64) {
+	binary.BigEndian.PutUint64(b[:8], h1)
+	binary.BigEndian.PutUint64(b[8:], h2)
+}
+
+// get returns a cached answer
+func (c *resultCaching) get(key string) (exists, ok bool) {
+	return c.cache.Get(key)
+}
+
+// set caches an answer for as long as its polarity allows
+func (c *resultCaching) set(key string, exists bool) {
+	ttl := c.ttl
+	if !exists {
+		ttl = c.negativeTTL
+	}
+	if ttl > 0 {
+		c.cache.Set(key, exists, ttl)
+	}
+}
+
+// cacheEntry is a cached answer
+type cacheEntry struct {
+	key     string
+	exists  bool
+	expires time.Time
+}
+
+// lruCache is the built-in Cache: a bounded LRU cache with per-entry expiry
+type lruCache struct {
+	size    int
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// newLRUCache creates a built-in cache holding at most size answers
+func newLRUCache(size int) *lruCache {
+	if size <= 0 {
+		size = defaultResultCacheSize
+	}
+	return &lruCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns a cached answer that has not expired
+func (c *lruCache) Get(key string) (exists, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return false, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return false, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.exists, true
+}
+
+// Set caches an answer, evicting the least recently used ones beyond the size limit
+func (c *lruCache) Set(key string, exists bool, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, exists: exists, expires: time.Now().Add(ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,155 @@
+package bloom
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ServerKind identifies the Redis-compatible server implementation
+type ServerKind string
+
+const (
+	ServerRedis     ServerKind = "redis"
+	ServerValkey    ServerKind = "valkey"
+	ServerKeyDB     ServerKind = "keydb"
+	ServerDragonfly ServerKind = "dragonfly"
+)
+
+// Feature is an optional server feature the library can take advantage of
+type Feature int
+
+const (
+	// FeatureExpireNX is EXPIRE with the NX/XX/GT/LT flags (Redis 7.0+)
+	FeatureExpireNX Feature = iota
+	// FeatureLua is server-side scripting with EVAL/EVALSHA
+	FeatureLua
+	// FeatureFunctions is FUNCTION LOAD/FCALL (Redis 7.0+)
+	FeatureFunctions
+	// FeatureBitfield is BITFIELD with u1 GET/SET subcommands
+	FeatureBitfield
+	// FeatureRedisBloom is the RedisBloom module's BF.* commands
+	FeatureRedisBloom
+)
+
+// capabilityProbeKey is the key touched by read-only capability probes
+const capabilityProbeKey = "__bloom:capability:probe__"
+
+// Capabilities describes what a connected server supports.
+// Server is empty when the implementation could not be identified.
+type Capabilities struct {
+	Server     ServerKind
+	Version    string
+	ExpireNX   bool
+	Lua        bool
+	Functions  bool
+	Bitfield   bool
+	RedisBloom bool
+}
+
+// Supports reports whether the feature is available.
+// A nil Capabilities assumes a full-featured Redis server.
+func (c *Capabilities) Supports(f Feature) bool {
+	if c == nil {
+		return true
+	}
+	switch f {
+	case FeatureExpireNX:
+		return c.ExpireNX
+	case FeatureLua:
+		return c.Lua
+	case FeatureFunctions:
+		return c.Functions
+	case FeatureBitfield:
+		return c.Bitfield
+	case FeatureRedisBloom:
+		return c.RedisBloom
+	default:
+		return false
+	}
+}
+
+// ProbeCapabilities identifies the server and probes the optional features the library uses.
+// Each feature is detected by issuing a harmless command rather than by version number,
+// so Redis-compatible servers such as Valkey, KeyDB and Dragonfly are judged by behavior.
+// The result can be passed in Config.Capabilities to gate optional features.
+func ProbeCapabilities(ctx context.Context, client redis.Cmdable) (*Capabilities, error) {
+	if client == nil {
+		return nil, ErrNilRedisClient
+	}
+
+	// Some compatible servers reject INFO sections; identify them as unknown
+	caps := &Capabilities{}
+	info, err := client.Info(ctx, "server").Result()
+	if err == nil {
+		caps = parseServerInfo(info)
+	} else if _, err := probeCommand(err); err != nil {
+		return nil, err
+	}
+
+	if caps.ExpireNX, err = probeCommand(client.ExpireNX(ctx, capabilityProbeKey, time.Second).Err()); err != nil {
+		return nil, err
+	}
+	if caps.Lua, err = probeCommand(client.Eval(ctx, "return 1", nil).Err()); err != nil {
+		return nil, err
+	}
+	if caps.Functions, err = probeCommand(client.FunctionList(ctx, redis.FunctionListQuery{}).Err()); err != nil {
+		return nil, err
+	}
+	if caps.Bitfield, err = probeCommand(client.BitField(ctx, capabilityProbeKey, "GET", "u1", 0).Err()); err != nil {
+		return nil, err
+	}
+	if caps.RedisBloom, err = probeCommand(client.BFExists(ctx, capabilityProbeKey, "").Err()); err != nil {
+		return nil, err
+	}
+
+	return caps, nil
+}
+
+// probeCommand interprets the result of a probe command.
+// Server error replies mean the feature is unsupported; anything else is a real failure.
+func probeCommand(err error) (bool, error) {
+	if err == nil || err == redis.Nil {
+		return true, nil
+	}
+	var replyErr redis.Error
+	if errors.As(err, &replyErr) {
+		return false, nil
+	}
+	return false, fmt.Errorf("probe capabilities: %w", err)
+}
+
+// parseServerInfo extracts the server kind and version from an INFO server reply
+func parseServerInfo(info string) *Capabilities {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			fields[name] = value
+		}
+	}
+
+	caps := &Capabilities{Server: ServerRedis, Version: fields["redis_version"]}
+	switch {
+	case fields["dragonfly_version"] != "":
+		caps.Server = ServerDragonfly
+		caps.Version = fields["dragonfly_version"]
+	case fields["valkey_version"] != "" || fields["server_name"] == "valkey":
+		caps.Server = ServerValkey
+		if v := fields["valkey_version"]; v != "" {
+			caps.Version = v
+		}
+	case strings.Contains(strings.ToLower(fields["executable"]), "keydb"):
+		caps.Server = ServerKeyDB
+	}
+	return caps
+}
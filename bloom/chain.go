@@ -0,0 +1,49 @@
+package bloom
+
+// FilterChain evaluates a sequence of filters with short-circuiting, typically a small
+// filter with a high false-positive rate in front of a large accurate one. An item
+// exists only when every filter reports it, so the chain's false-positive rate is at
+// most the product of the individual rates while most negatives are answered by the
+// cheap first tier.
+type FilterChain struct {
+	filters []BloomFilter
+}
+
+// NewFilterChain creates a chain that consults filters in the given order
+func NewFilterChain(filters ...BloomFilter) (*FilterChain, error) {
+	if len(filters) == 0 {
+		return nil, ErrNilFilter
+	}
+	for _, f := range filters {
+		if f == nil {
+			return nil, ErrNilFilter
+		}
+	}
+	return &FilterChain{filters: append([]BloomFilter(nil), filters...)}, nil
+}
+
+// Add adds an element to every filter in the chain
+func (c *FilterChain) Add(data []byte) error {
+	for _, f := range c.filters {
+		if err := f.Add(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Exists checks the filters in order and stops at the first one that rules the element out
+func (c *FilterChain) Exists(data []byte) (bool, error) {
+	for _, f := range c.filters {
+		exists, err := f.Exists(data)
+		if err != nil || !exists {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Filters returns the filters of the chain in evaluation order
+func (c *FilterChain) Filters() []BloomFilter {
+	return append([]BloomFilter(nil), c.filters...)
+}
@@ -0,0 +1,167 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Default circuit breaker settings
+const (
+	defaultCircuitThreshold = 5
+	defaultCircuitTimeout   = 10 * time.Second
+)
+
+// FailurePolicy selects how Exists answers while the circuit breaker is open
+type FailurePolicy int
+
+const (
+	// FailWithError returns ErrCircuitOpen
+	FailWithError FailurePolicy = iota
+	// FailOpen answers false ("not seen"), for filters that deduplicate: items are
+	// processed again rather than dropped
+	FailOpen
+	// FailClosed answers true ("seen"), for filters that gate security checks: items are
+	// rejected rather than let through
+	FailClosed
+)
+
+// CircuitBreaker stops sending bit reads and writes to Redis after consecutive failures,
+// so callers get an immediate answer instead of waiting on timeouts while Redis is down.
+// Once OpenTimeout has passed, a single trial call goes through: success closes the
+// circuit, another failure keeps it open.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failures that open the circuit (defaults to 5)
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before a trial call (defaults to 10s)
+	OpenTimeout time.Duration
+	// Policy is the answer of Exists while the circuit is open (defaults to FailWithError)
+	Policy FailurePolicy
+	// IsFailure reports whether an error counts towards opening (defaults to IsTransientError)
+	IsFailure func(error) bool
+}
+
+// circuit is the state of a CircuitBreaker
+type circuit struct {
+	threshold int
+	timeout   time.Duration
+	policy    FailurePolicy
+	isFailure func(error) bool
+	mu        sync.Mutex
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+// newCircuit creates the circuit for a CircuitBreaker, or nil if it is disabled
+func newCircuit(cfg *CircuitBreaker) *circuit {
+	if cfg == nil {
+		return nil
+	}
+	c := &circuit{
+		threshold: cfg.FailureThreshold,
+		timeout:   cfg.OpenTimeout,
+		policy:    cfg.Policy,
+		isFailure: cfg.IsFailure,
+	}
+	if c.threshold <= 0 {
+		c.threshold = defaultCircuitThreshold
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultCircuitTimeout
+	}
+	if c.isFailure == nil {
+		c.isFailure = IsTransientError
+	}
+	return c
+}
+
+// allow reports whether a call may go to Redis: always while the circuit is closed, and
+// one trial call at a time once an open circuit's timeout has passed
+func (c *circuit) allow() bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.openedAt.IsZero() {
+		return true
+	}
+	if c.probing || time.Since(c.openedAt) < c.timeout {
+		return false
+	}
+	c.probing = true
+	return true
+}
+
+// closed reports whether the circuit is closed, without claiming a trial call
+func (c *circuit) closed() bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.openedAt.IsZero()
+}
+
+// record records the outcome of an allowed call and reports whether the circuit is open
+// and whether that changed
+func (c *circuit) record(err error) (open, changed bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	was := !c.openedAt.IsZero()
+	c.probing = false
+	if err != nil && c.isFailure(err) {
+		c.failures++
+		if was || c.failures >= c.threshold {
+			c.openedAt = time.Now()
+		}
+	} else {
+		c.failures = 0
+		c.openedAt = time.Time{}
+	}
+	open = !c.openedAt.IsZero()
+	return open, open != was
+}
+
+// fallback returns the answer of a lookup that failed with err: the policy's answer if
+// the circuit is open, otherwise the error
+func (c *circuit) fallback(err error) (bool, error) {
+	if c == nil || !errors.Is(err, ErrCircuitOpen) {
+		return false, err
+	}
+	switch c.policy {
+	case FailOpen:
+		return false, nil
+	case FailClosed:
+		return true, nil
+	default:
+		return false, err
+	}
+}
+
+// call runs fn against Redis through the circuit breaker and the retry policy, failing
+// with ErrCircuitOpen without calling fn while the circuit is open
+func (bf *bloomFilter) call(ctx context.Context, fn func() error) error {
+	if !bf.circuit.allow() {
+		bf.metrics.IncCounter(MetricCircuitRejections, 1)
+		return ErrCircuitOpen
+	}
+	err := bf.retry(ctx, fn)
+	if open, changed := bf.circuit.record(err); changed {
+		gauge := 0.0
+		if open {
+			gauge = 1
+		}
+		bf.metrics.SetGauge(MetricCircuitOpen, gauge)
+	}
+	if err == nil {
+		bf.replayFallback()
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyPlacement describes the cluster slot and nodes serving a single Redis key
+type KeyPlacement struct {
+	Key      string
+	Slot     int64
+	NodeID   string
+	Addr     string
+	Replicas []string
+}
+
+// PlacementReport describes how a set of filter keys is distributed across a Redis Cluster
+type PlacementReport struct {
+	Keys []KeyPlacement
+	// KeysPerNode maps a master address to the number of reported keys it serves
+	KeysPerNode map[string]int
+}
+
+// clusterRange is a slot range together with the nodes serving it
+type clusterRange struct {
+	start, end int64
+	master     clusterNode
+	replicas   []clusterNode
+}
+
+// clusterNode identifies a single cluster node
+type clusterNode struct {
+	id   string
+	addr string
+}
+
+// ClusterPlacement reports the slot and serving nodes of each given key so operators
+// can verify how filter keys are distributed and plan resharding.
+// Slots are resolved with CLUSTER KEYSLOT and ownership with CLUSTER SHARDS,
+// falling back to CLUSTER SLOTS on servers older than Redis 7.
+func ClusterPlacement(ctx context.Context, client *redis.ClusterClient, keys ...string) (*PlacementReport, error) {
+	if client == nil {
+		return nil, ErrNilRedisClient
+	}
+
+	ranges, err := clusterRanges(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PlacementReport{
+		Keys:        make([]KeyPlacement, 0, len(keys)),
+		KeysPerNode: make(map[string]int),
+	}
+	for _, key := range keys {
+		slot, err := client.ClusterKeySlot(ctx, key).Result()
+		if err != nil {
+			return nil, fmt.Errorf("cluster keyslot %q: %w", key, err)
+		}
+
+		placement := KeyPlacement{Key: key, Slot: slot}
+		for _, r := range ranges {
+			if slot < r.start || slot > r.end {
+				continue
+			}
+			placement.NodeID = r.master.id
+			placement.Addr = r.master.addr
+			for _, replica := range r.replicas {
+				placement.Replicas = append(placement.Replicas, replica.addr)
+			}
+			break
+		}
+
+		report.Keys = append(report.Keys, placement)
+		report.KeysPerNode[placement.Addr]++
+	}
+
+	return report, nil
+}
+
+// clusterRanges loads the slot ownership table of the cluster
+func clusterRanges(ctx context.Context, client *redis.ClusterClient) ([]clusterRange, error) {
+	shards, err := client.ClusterShards(ctx).Result()
+	if err == nil {
+		return rangesFromShards(shards), nil
+	}
+
+	slots, slotsErr := client.ClusterSlots(ctx).Result()
+	if slotsErr != nil {
+		return nil, fmt.Errorf("cluster shards: %v; cluster slots: %w", err, slotsErr)
+	}
+	return rangesFromSlots(slots), nil
+}
+
+// rangesFromShards converts a CLUSTER SHARDS reply into slot ranges
+func rangesFromShards(shards []redis.ClusterShard) []clusterRange {
+	var ranges []clusterRange
+	for _, shard := range shards {
+		var master clusterNode
+		var replicas []clusterNode
+		for _, node := range shard.Nodes {
+			host := node.Endpoint
+			if host == "" {
+				host = node.IP
+			}
+			n := clusterNode{id: node.ID, addr: net.JoinHostPort(host, strconv.FormatInt(node.Port, 10))}
+			if node.Role == "master" {
+				master = n
+			} else {
+				replicas = append(replicas, n)
+			}
+		}
+		for _, slotRange := range shard.Slots {
+			ranges = append(ranges, clusterRange{
+				start:    slotRange.Start,
+				end:      slotRange.End,
+				master:   master,
+				replicas: replicas,
+			})
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+	return ranges
+}
+
+// rangesFromSlots converts a CLUSTER SLOTS reply into slot ranges
+func rangesFromSlots(slots []redis.ClusterSlot) []clusterRange {
+	ranges := make([]clusterRange, 0, len(slots))
+	for _, slot := range slots {
+		r := clusterRange{start: int64(slot.Start), end: int64(slot.End)}
+		for i, node := range slot.Nodes {
+			n := clusterNode{id: node.ID, addr: node.Addr}
+			if i == 0 {
+				r.master = n
+			} else {
+				r.replicas = append(r.replicas, n)
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+	return ranges
+}
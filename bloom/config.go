@@ -11,15 +11,83 @@ type Config struct {
 	RedisClient        RedisClient
 	ExpectedInsertions uint64
 	FalsePositiveRate  float64
-	TTL                time.Duration
-	HashStrategy       HashStrategy
+	// BitSize and HashCount override the sizes derived from ExpectedInsertions and
+	// FalsePositiveRate, for example to match a filter built elsewhere; with both set,
+	// zero ExpectedInsertions and FalsePositiveRate are derived from them
+	BitSize   uint64
+	HashCount uint
+	TTL       time.Duration
+	// TTLMode selects when TTL is applied (defaults to TTLRefresh, on every write)
+	TTLMode      TTLMode
+	HashStrategy HashStrategy
+	// Seed is mixed into every hash; filters with different seeds set unrelated bits for the same item
+	Seed uint64
+	// Salt is combined with Seed to derive the effective per-filter seed
+	Salt string
+	// KeySeeded uses RedisKey as the salt when Salt is empty
+	KeySeeded bool
+	// VerifyHashStrategy checks the hash strategy against known-answer vectors at construction
+	VerifyHashStrategy bool
+	// BitLayout maps logical bit indices to Redis offsets (defaults to MSB-first, the SETBIT convention)
+	BitLayout BitLayout
+	// Blocked confines each item's bits to one 64-byte block so Exists needs a single GETRANGE
+	Blocked bool
+	// Partitioned gives each hash function its own disjoint region of the bitmap
+	Partitioned bool
+	// ReusePipelines keeps executed pipelines for later calls instead of creating one per call;
+	// custom Pipeliner implementations must be reusable after Exec
+	ReusePipelines bool
+	// Metrics receives measurements emitted by the filter; nil discards them
+	Metrics Metrics
+	// Audit appends every Add to a capped Redis Stream; nil disables the audit log
+	Audit *Audit
+	// ResultCache caches Exists answers in front of Redis; nil disables caching
+	ResultCache *ResultCache
+	// Degradation checks fewer bits while Redis is slow; nil disables it
+	Degradation *Degradation
+	// Admission refuses inserts once the filter is too full; nil disables admission control
+	Admission *Admission
+	// InsertRateAlert reports insert-rate spikes; nil disables anomaly detection
+	InsertRateAlert *InsertRateAlert
+	// SaturationAlert reports fill and capacity thresholds being crossed; nil disables monitoring
+	SaturationAlert *SaturationAlert
+	// Retry retries bit reads and writes that fail with transient errors; nil disables retries
+	Retry *RetryPolicy
+	// CircuitBreaker stops calling Redis after consecutive failures; nil disables it
+	CircuitBreaker *CircuitBreaker
+	// LocalFallback buffers Adds locally while Redis is unreachable; nil disables it
+	LocalFallback *LocalFallback
+	// UpdateNotifications publishes every Add for mirrors to pick up; nil disables it
+	UpdateNotifications *UpdateNotifications
+	// RateLimit throttles the filter's Redis operations and commands; nil disables limiting
+	RateLimit *RateLimit
+	// Capabilities gates optional server features; nil assumes a full-featured Redis
+	Capabilities *Capabilities
+	// Engine selects how the filter is stored and accessed (defaults to EngineBitmap)
+	Engine Engine
 }
 
-// calculateOptimalParameters calculates the optimal number of bits and hash functions
-// using the standard Bloom Filter formulas:
+// impliedSizing fills in zero ExpectedInsertions and FalsePositiveRate with the values
+// for which bitSize and hashCount are optimal: n = m ln2 / k at a rate of 2^-k
+func impliedSizing(cfg *Config, bitSize uint64, hashCount uint) {
+	if cfg.ExpectedInsertions == 0 {
+		cfg.ExpectedInsertions = uint64(math.Round(float64(bitSize) * math.Ln2 / float64(hashCount)))
+	}
+	if cfg.FalsePositiveRate == 0 {
+		cfg.FalsePositiveRate = math.Pow(0.5, float64(hashCount))
+	}
+}
+
+// EstimateParameters calculates the optimal number of bits and hash functions for n
+// items at a false-positive rate p using the standard Bloom Filter formulas:
 // m = -(n * ln(p)) / (ln(2)^2)  // total bits
 // k = (m / n) * ln(2)           // number of hash functions
-func calculateOptimalParameters(n uint64, p float64) (uint64, uint) {
+// It returns zeros for n = 0 or p outside (0, 1).
+func EstimateParameters(n uint64, p float64) (uint64, uint) {
+	if n == 0 || p <= 0 || p >= 1 {
+		return 0, 0
+	}
+
 	// Calculate optimal bit size
 	ln2 := math.Ln2
 	ln2Squared := ln2 * ln2
@@ -35,3 +103,24 @@ func calculateOptimalParameters(n uint64, p float64) (uint64, uint) {
 
 	return bitSize, hashCount
 }
+
+// EstimateFPR returns the expected false-positive rate of m bits and k hash functions
+// holding n items: p = (1 - e^(-k*n/m))^k
+func EstimateFPR(m uint64, k uint, n uint64) float64 {
+	if m == 0 {
+		return 1
+	}
+	return math.Pow(1-math.Exp(-float64(k)*float64(n)/float64(m)), float64(k))
+}
+
+// EstimateCapacity returns the number of items m bits and k hash functions hold before
+// the false-positive rate exceeds p: n = -(m / k) * ln(1 - p^(1/k))
+func EstimateCapacity(m uint64, k uint, p float64) uint64 {
+	if m == 0 || k == 0 || p <= 0 {
+		return 0
+	}
+	if p >= 1 {
+		return math.MaxUint64
+	}
+	return uint64(math.Floor(-float64(m) / float64(k) * math.Log(1-math.Pow(p, 1/float64(k)))))
+}
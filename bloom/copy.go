@@ -0,0 +1,123 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CopyOptions configures how CopyTo duplicates a filter
+type CopyOptions struct {
+	// Target is a client of another instance or database to copy to; nil copies within
+	// the filter's own instance
+	Target redis.Cmdable
+	// Replace overwrites keys that already exist at the destination
+	Replace bool
+}
+
+// CopyTo duplicates the filter and its metadata under destKey, keeping their TTLs. Within
+// one instance the keys are copied server-side with COPY (Redis 6.2+); copies to another
+// instance, across cluster slots or on servers without COPY fall back to DUMP and
+// RESTORE, which pass the data through the client. The copy is a filter with the same
+// parameters: open it with the filter's configuration and destKey as RedisKey.
+func (bf *bloomFilter) CopyTo(ctx context.Context, destKey string, opts CopyOptions) error {
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	key := bf.config.RedisKey
+	if destKey == "" {
+		return ErrEmptyRedisKey
+	}
+	if destKey == key && opts.Target == nil {
+		return ErrTargetKeyExists
+	}
+
+	pairs := [][2]string{{key, destKey}, {metadataKey(key), metadataKey(destKey)}}
+	if opts.Target == nil && copiesInPlace(client, key, destKey) {
+		if err := copyKeys(ctx, client, pairs, opts.Replace); !copyUnsupported(err) {
+			return err
+		}
+	}
+
+	target := opts.Target
+	if target == nil {
+		target = client
+	}
+	for _, pair := range pairs {
+		if err := dumpRestore(ctx, client, target, pair[0], pair[1], opts.Replace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copiesInPlace reports whether COPY can copy key to destKey: outside a cluster or ring,
+// or within one slot
+func copiesInPlace(client redis.Cmdable, key, destKey string) bool {
+	switch client.(type) {
+	case *redis.ClusterClient, *redis.Ring:
+		return KeySlot(key) == KeySlot(destKey)
+	default:
+		return true
+	}
+}
+
+// copyKeys copies each source key to its destination with COPY in one pipeline. Missing
+// sources are skipped; an existing destination is ErrTargetKeyExists unless replace is set.
+func copyKeys(ctx context.Context, client redis.Cmdable, pairs [][2]string, replace bool) error {
+	pipe := client.Pipeline()
+	exists := make([]*redis.IntCmd, len(pairs))
+	copies := make([]*redis.IntCmd, len(pairs))
+	for i, pair := range pairs {
+		exists[i] = pipe.Exists(ctx, pair[0])
+		copies[i] = pipe.Copy(ctx, pair[0], pair[1], 0, replace)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	// COPY replies 0 both for a missing source and for an existing destination
+	for i := range pairs {
+		if exists[i].Val() == 1 && copies[i].Val() == 0 {
+			return ErrTargetKeyExists
+		}
+	}
+	return nil
+}
+
+// copyUnsupported reports whether err is the reply of a server without COPY
+func copyUnsupported(err error) bool {
+	var replyErr redis.Error
+	return errors.As(err, &replyErr) && strings.Contains(strings.ToLower(err.Error()), "unknown command")
+}
+
+// dumpRestore copies key from src to destKey on dst with DUMP and RESTORE, keeping the
+// remaining TTL. A missing key is skipped.
+func dumpRestore(ctx context.Context, src, dst redis.Cmdable, key, destKey string, replace bool) error {
+	data, err := src.Dump(ctx, key).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ttl, err := src.PTTL(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	if replace {
+		err = dst.RestoreReplace(ctx, destKey, ttl, data).Err()
+	} else {
+		err = dst.Restore(ctx, destKey, ttl, data).Err()
+	}
+	if redis.HasErrorPrefix(err, "BUSYKEY") {
+		return ErrTargetKeyExists
+	}
+	return err
+}
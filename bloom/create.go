@@ -0,0 +1,109 @@
+package bloom
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// createScript records the metadata fields ARGV[2..], given as field-value pairs, in the
+// hash KEYS[2] unless the filter KEYS[1] or its metadata already exists, and returns 1 if
+// it did and 0 otherwise. ARGV[1] is a TTL in milliseconds, applied as described for
+// luaExpireSource.
+var createScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 or redis.call('EXISTS', KEYS[2]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[2], unpack(ARGV, 2))
+` + luaExpireSource + `
+return 1
+`)
+
+// CreateBloomFilter creates a new filter at cfg.RedisKey and records its parameters.
+// It fails with ErrFilterExists if the key or its metadata already exists; the check and
+// the parameters are written by one Lua script, so of several processes creating the
+// same filter concurrently, exactly one succeeds and no process sees a claimed filter
+// without parameters. It requires Lua scripting (ErrLuaUnsupported otherwise), and
+// module filters are not supported.
+func CreateBloomFilter(ctx context.Context, cfg Config) (BloomFilter, error) {
+	bf, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !bf.usesBitmap() {
+		return nil, ErrIncompatibleFilter
+	}
+	if !cfg.Capabilities.Supports(FeatureLua) {
+		return nil, ErrLuaUnsupported
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := bf.creationFields(time.Now())
+	args := make([]interface{}, 1, 1+len(fields))
+	args[0] = bf.scriptTTL()
+	args = append(args, fields...)
+	created, err := createScript.Run(ctx, client, []string{cfg.RedisKey, metadataKey(cfg.RedisKey)}, args...).Int()
+	if err != nil {
+		return nil, err
+	}
+	if created == 0 {
+		return nil, ErrFilterExists
+	}
+	bf.metadataRecorded = 1
+	return bf, nil
+}
+
+// OpenBloomFilter opens the filter at cfg.RedisKey with the bit size, hash count and hash
+// strategy recorded in its metadata, failing with ErrFilterNotFound if none are
+// recorded. A cfg.HashStrategy is only needed for custom strategies and must match the
+// recorded one. Zero ExpectedInsertions and FalsePositiveRate are derived from the
+// recorded parameters.
+func OpenBloomFilter(ctx context.Context, cfg Config) (BloomFilter, error) {
+	if cfg.Engine == EngineRedisBloom {
+		return nil, ErrIncompatibleFilter
+	}
+	if err := validateStorage(cfg); err != nil {
+		return nil, err
+	}
+	provider, ok := cfg.RedisClient.(CmdableProvider)
+	if !ok {
+		return nil, ErrCommandsUnsupported
+	}
+
+	recorded, err := provider.Cmdable().HMGet(ctx, metadataKey(cfg.RedisKey), metaFieldBits, metaFieldHashes, metaFieldHash).Result()
+	if err != nil {
+		return nil, err
+	}
+	bitsField, _ := recorded[0].(string)
+	hashesField, _ := recorded[1].(string)
+	hash, _ := recorded[2].(string)
+	bitSize, err := strconv.ParseUint(bitsField, 10, 64)
+	if err != nil {
+		return nil, ErrFilterNotFound
+	}
+	hashCount, err := strconv.ParseUint(hashesField, 10, 32)
+	if err != nil || bitSize == 0 || hashCount == 0 {
+		return nil, ErrFilterNotFound
+	}
+
+	if cfg.HashStrategy == nil {
+		if cfg.HashStrategy, err = NewHashStrategy(hash); err != nil {
+			return nil, err
+		}
+	} else if name := hashStrategyName(cfg.HashStrategy); name != hash {
+		return nil, &ParameterMismatchError{Key: cfg.RedisKey, Field: metaFieldHash, Recorded: hash, Configured: name}
+	}
+	impliedSizing(&cfg, bitSize, uint(hashCount))
+
+	bf, err := buildBloomFilter(cfg, bitSize, uint(hashCount))
+	if err != nil {
+		return nil, err
+	}
+	bf.metadataRecorded = 1
+	return bf, nil
+}
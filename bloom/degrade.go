@@ -0,0 +1,84 @@
+package bloom
+
+import (
+	"sync"
+	"time"
+)
+
+// latencyEWMAWeight is the weight of the newest sample in the latency moving average
+const latencyEWMAWeight = 0.2
+
+// Degradation configures adaptive degraded lookups. While the moving average of Exists
+// latency exceeds LatencyThreshold, Exists checks only the first DegradedHashes of the
+// k bits, trading a temporarily higher false-positive rate for bounded latency. False
+// negatives are impossible either way, since every checked bit of a member is set.
+type Degradation struct {
+	// LatencyThreshold is the average Exists latency above which lookups degrade
+	LatencyThreshold time.Duration
+	// DegradedHashes is the number of bits checked while degraded (defaults to half of k, at least one)
+	DegradedHashes uint
+}
+
+// degrader tracks lookup latency and decides when to degrade
+type degrader struct {
+	threshold time.Duration
+	hashes    uint
+	mu        sync.Mutex
+	ewma      time.Duration
+	degraded  bool
+}
+
+// newDegrader creates the degrader for a Degradation, or nil if it is disabled
+func newDegrader(cfg *Degradation, hashCount uint) *degrader {
+	if cfg == nil || cfg.LatencyThreshold <= 0 {
+		return nil
+	}
+	hashes := cfg.DegradedHashes
+	if hashes == 0 || hashes >= hashCount {
+		hashes = (hashCount + 1) / 2
+	}
+	return &degrader{threshold: cfg.LatencyThreshold, hashes: hashes}
+}
+
+// limit returns how many positions a lookup should check right now
+func (d *degrader) limit(hashCount uint) uint {
+	if d == nil {
+		return hashCount
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.degraded {
+		return d.hashes
+	}
+	return hashCount
+}
+
+// observe records a lookup latency and reports whether the degraded state changed
+func (d *degrader) observe(latency time.Duration) (degraded, changed bool) {
+	if d == nil {
+		return false, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.ewma == 0 {
+		d.ewma = latency
+	} else {
+		d.ewma = time.Duration(latencyEWMAWeight*float64(latency) + (1-latencyEWMAWeight)*float64(d.ewma))
+	}
+
+	was := d.degraded
+	d.degraded = d.ewma > d.threshold
+	return d.degraded, d.degraded != was
+}
+
+// observeLatency feeds a lookup latency to the degrader and publishes state changes
+func (bf *bloomFilter) observeLatency(latency time.Duration) {
+	if degraded, changed := bf.degrader.observe(latency); changed {
+		gauge := 0.0
+		if degraded {
+			gauge = 1
+		}
+		bf.metrics.SetGauge(MetricDegraded, gauge)
+	}
+}
@@ -0,0 +1,195 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Key suffixes used by DeletableFilter
+const (
+	removedKeySuffix        = "removed"
+	compactKeySuffix        = "compact"
+	compactRemovedKeySuffix = "compact:removed"
+)
+
+// DeletableFilter pairs an inclusion filter with a "removed" filter to give practical
+// delete semantics without counting overhead. An item exists when it is present in the
+// inclusion filter and absent from the removed filter.
+//
+// A removed item cannot be re-added until the next Compact, and every removal raises the
+// chance that an unrelated item is reported as removed. Compact periodically rebuilds the
+// pair from the live data set to reset both effects.
+type DeletableFilter struct {
+	mu      sync.RWMutex
+	added   *bloomFilter
+	removed *bloomFilter
+	// stagedAdded and stagedRemoved receive every Add and Remove while Compact rebuilds
+	// the pair; nil outside a compaction
+	stagedAdded   *bloomFilter
+	stagedRemoved *bloomFilter
+}
+
+// NewDeletableFilter creates a deletable filter. The inclusion bits are stored under
+// cfg.RedisKey and the removed bits under a companion key in the same cluster slot.
+func NewDeletableFilter(cfg Config) (*DeletableFilter, error) {
+	added, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &DeletableFilter{
+		added:   added,
+		removed: added.auxiliary(companionKey(cfg.RedisKey, removedKeySuffix)),
+	}, nil
+}
+
+// Add adds an element to the filter, also writing it to the rebuilt filter while a
+// compaction is in progress
+func (df *DeletableFilter) Add(data []byte) error {
+	df.mu.RLock()
+	defer df.mu.RUnlock()
+	if err := df.added.Add(data); err != nil {
+		return err
+	}
+	if df.stagedAdded != nil {
+		return df.stagedAdded.Add(data)
+	}
+	return nil
+}
+
+// Remove marks an element as deleted. A removal during a compaction is also recorded for
+// the rebuilt pair, so it survives the compaction even if the source yields the item.
+func (df *DeletableFilter) Remove(data []byte) error {
+	df.mu.RLock()
+	defer df.mu.RUnlock()
+	if err := df.removed.Add(data); err != nil {
+		return err
+	}
+	if df.stagedRemoved != nil {
+		return df.stagedRemoved.Add(data)
+	}
+	return nil
+}
+
+// Stats returns the state of the inclusion filter
+func (df *DeletableFilter) Stats() (*Stats, error) {
+	return df.added.Stats()
+}
+
+// Exists checks if an element was added and has not been removed
+func (df *DeletableFilter) Exists(data []byte) (bool, error) {
+	present, err := df.added.Exists(data)
+	if err != nil || !present {
+		return false, err
+	}
+	removed, err := df.removed.Exists(data)
+	if err != nil {
+		return false, err
+	}
+	return !removed, nil
+}
+
+// Compact rebuilds the pair from the live items yielded by source. The new inclusion
+// filter is built under a temporary key and swapped in atomically together with
+// replacing the removed filter, so readers never observe a partially rebuilt filter.
+// While it runs, Add and Remove on this DeletableFilter are written to both the current
+// and the rebuilt pair, like ResizableFilter.Resize does, and are paused only for the
+// swap: items added meanwhile are kept and items removed meanwhile stay removed. Writers
+// in other processes are not seen, so stop them or route their writes through this
+// instance for the duration of the compaction.
+func (df *DeletableFilter) Compact(ctx context.Context, source Iterator) error {
+	client, err := df.added.cmdable()
+	if err != nil {
+		return err
+	}
+
+	key := df.added.config.RedisKey
+	tmp := df.added.auxiliary(companionKey(key, compactKeySuffix))
+	removals := df.added.auxiliary(companionKey(key, compactRemovedKeySuffix))
+	tmp.cache, removals.cache = nil, nil
+
+	// Clear leftovers of an interrupted compaction before writes are staged
+	df.mu.Lock()
+	if df.stagedAdded != nil {
+		df.mu.Unlock()
+		return ErrCompactionInProgress
+	}
+	if err := client.Del(ctx, tmp.config.RedisKey, removals.config.RedisKey).Err(); err != nil {
+		df.mu.Unlock()
+		return err
+	}
+	df.stagedAdded, df.stagedRemoved = tmp, removals
+	df.mu.Unlock()
+
+	swapped := false
+	defer func() {
+		if !swapped {
+			df.mu.Lock()
+			df.stagedAdded, df.stagedRemoved = nil, nil
+			df.mu.Unlock()
+			client.Del(context.Background(), tmp.config.RedisKey, removals.config.RedisKey)
+		}
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		item, err := source.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := tmp.Add(item); err != nil {
+			return err
+		}
+	}
+
+	// Swap while Add and Remove are paused; concurrent writes may have created either key
+	df.mu.Lock()
+	defer df.mu.Unlock()
+	var staged [2]*redis.IntCmd
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		staged[0] = pipe.Exists(ctx, tmp.config.RedisKey)
+		staged[1] = pipe.Exists(ctx, removals.config.RedisKey)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ttl, err := df.added.swapTTL(ctx, client)
+	if err != nil {
+		return err
+	}
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, pair := range [][2]string{
+			{tmp.config.RedisKey, key},
+			{removals.config.RedisKey, df.removed.config.RedisKey},
+		} {
+			if staged[i].Val() == 0 {
+				pipe.Del(ctx, pair[1])
+				continue
+			}
+			pipe.Rename(ctx, pair[0], pair[1])
+			if ttl > 0 {
+				pipe.PExpire(ctx, pair[1], ttl)
+			}
+		}
+		queueMarkRebuilt(ctx, pipe, key, time.Now())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	df.stagedAdded, df.stagedRemoved = nil, nil
+	swapped = true
+	df.added.cache.invalidate()
+	df.removed.cache.invalidate()
+	return nil
+}
@@ -0,0 +1,86 @@
+package bloom
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DeleteOptions configures how Clear and Drop delete keys
+type DeleteOptions struct {
+	// Unlink deletes with UNLINK, which frees the memory in a background thread instead of
+	// blocking Redis while a large bitmap is released
+	Unlink bool
+}
+
+// Clear empties the filter by deleting its bitmap and records the time as the rebuild
+// time in its metadata, in one transaction. Cached answers are discarded.
+func (bf *bloomFilter) Clear(ctx context.Context, opts DeleteOptions) error {
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	if err := bf.limiter.wait(ctx, 3); err != nil {
+		return err
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleteKeys(ctx, pipe, opts, bf.config.RedisKey)
+		queueMarkRebuilt(ctx, pipe, bf.config.RedisKey, time.Now())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	atomic.StoreUint32(&bf.reserved, 0)
+	bf.cache.invalidate()
+	return nil
+}
+
+// Drop deletes the filter's bitmap, metadata and audit stream. The filter can still be
+// used afterwards and starts over empty. Cached answers are discarded. Filters on a
+// BitStoreClient delete their bitmap with BitStore.Delete.
+func (bf *bloomFilter) Drop(ctx context.Context, opts DeleteOptions) error {
+	if bc, ok := bf.config.RedisClient.(*BitStoreClient); ok {
+		if err := bc.store.Delete(ctx, bf.config.RedisKey); err != nil {
+			return err
+		}
+		bf.cache.invalidate()
+		return nil
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	keys := []string{bf.config.RedisKey, metadataKey(bf.config.RedisKey)}
+	if bf.config.Audit != nil {
+		keys = append(keys, bf.auditStream())
+	}
+	if err := bf.limiter.wait(ctx, len(keys)); err != nil {
+		return err
+	}
+
+	// One command per key, as the audit stream may live in another slot
+	pipe := client.Pipeline()
+	for _, key := range keys {
+		deleteKeys(ctx, pipe, opts, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	atomic.StoreUint32(&bf.metadataRecorded, 0)
+	atomic.StoreUint32(&bf.reserved, 0)
+	bf.cache.invalidate()
+	return nil
+}
+
+// deleteKeys queues a DEL or UNLINK of keys
+func deleteKeys(ctx context.Context, pipe redis.Pipeliner, opts DeleteOptions, keys ...string) {
+	if opts.Unlink {
+		pipe.Unlink(ctx, keys...)
+	} else {
+		pipe.Del(ctx, keys...)
+	}
+}
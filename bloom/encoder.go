@@ -0,0 +1,271 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Encoder turns items of type T into the bytes stored in a filter
+type Encoder[T any] interface {
+	Encode(item T) ([]byte, error)
+}
+
+// EncoderFunc adapts a function to the Encoder interface
+type EncoderFunc[T any] func(item T) ([]byte, error)
+
+// Encode calls f(item)
+func (f EncoderFunc[T]) Encode(item T) ([]byte, error) {
+	return f(item)
+}
+
+// TextEncoder is implemented by encoders that can also parse an item from its text form,
+// which lets command-line tools and configuration files express items of any type
+type TextEncoder interface {
+	EncodeText(text string) ([]byte, error)
+}
+
+// Names of the built-in encoders
+const (
+	EncoderString = "string"
+	EncoderBytes  = "bytes"
+	EncoderUint64 = "uint64"
+	EncoderInt64  = "int64"
+	EncoderUUID   = "uuid"
+	EncoderULID   = "ulid"
+)
+
+// encoders is the registry of named encoders; values are Encoder[T] for some T
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]interface{}{
+		EncoderString: stringEncoder{},
+		EncoderBytes:  bytesEncoder{},
+		EncoderUint64: uint64Encoder{},
+		EncoderInt64:  int64Encoder{},
+		EncoderUUID:   uuidEncoder{},
+		EncoderULID:   ulidEncoder{},
+	}
+)
+
+// RegisterEncoder makes an encoder available by name to typed filters and tooling
+func RegisterEncoder[T any](name string, enc Encoder[T]) error {
+	if name == "" || enc == nil {
+		return ErrInvalidEncoder
+	}
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	if _, exists := encoders[name]; exists {
+		return ErrDuplicateEncoder
+	}
+	encoders[name] = enc
+	return nil
+}
+
+// LookupEncoder returns the registered encoder with the given name for items of type T
+func LookupEncoder[T any](name string) (Encoder[T], error) {
+	encodersMu.RLock()
+	enc, ok := encoders[name]
+	encodersMu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownEncoder
+	}
+	typed, ok := enc.(Encoder[T])
+	if !ok {
+		return nil, ErrEncoderType
+	}
+	return typed, nil
+}
+
+// EncodeText encodes the text form of an item with the named encoder
+func EncodeText(name, text string) ([]byte, error) {
+	encodersMu.RLock()
+	enc, ok := encoders[name]
+	encodersMu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownEncoder
+	}
+	textual, ok := enc.(TextEncoder)
+	if !ok {
+		return nil, ErrEncoderType
+	}
+	return textual.EncodeText(text)
+}
+
+// EncoderNames returns the names of all registered encoders in sorted order
+func EncoderNames() []string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TypedFilter adds and checks items of type T through an Encoder
+type TypedFilter[T any] struct {
+	filter  BloomFilter
+	encoder Encoder[T]
+}
+
+// NewTypedFilter wraps a filter with an encoder for items of type T
+func NewTypedFilter[T any](filter BloomFilter, enc Encoder[T]) (*TypedFilter[T], error) {
+	if filter == nil {
+		return nil, ErrNilFilter
+	}
+	if enc == nil {
+		return nil, ErrInvalidEncoder
+	}
+	return &TypedFilter[T]{filter: filter, encoder: enc}, nil
+}
+
+// Add encodes and adds an item
+func (tf *TypedFilter[T]) Add(item T) error {
+	data, err := tf.encoder.Encode(item)
+	if err != nil {
+		return err
+	}
+	return tf.filter.Add(data)
+}
+
+// Exists encodes an item and checks whether it is in the filter
+func (tf *TypedFilter[T]) Exists(item T) (bool, error) {
+	data, err := tf.encoder.Encode(item)
+	if err != nil {
+		return false, err
+	}
+	return tf.filter.Exists(data)
+}
+
+// Filter returns the underlying filter
+func (tf *TypedFilter[T]) Filter() BloomFilter {
+	return tf.filter
+}
+
+// stringEncoder stores strings as their UTF-8 bytes
+type stringEncoder struct{}
+
+// Encode returns the bytes of the string
+func (stringEncoder) Encode(item string) ([]byte, error) {
+	return []byte(item), nil
+}
+
+// EncodeText returns the bytes of the text
+func (stringEncoder) EncodeText(text string) ([]byte, error) {
+	return []byte(text), nil
+}
+
+// bytesEncoder stores byte slices unchanged
+type bytesEncoder struct{}
+
+// Encode returns the item unchanged
+func (bytesEncoder) Encode(item []byte) ([]byte, error) {
+	return item, nil
+}
+
+// EncodeText returns the bytes of the text
+func (bytesEncoder) EncodeText(text string) ([]byte, error) {
+	return []byte(text), nil
+}
+
+// uint64Encoder stores unsigned integers as 8 big-endian bytes
+type uint64Encoder struct{}
+
+// Encode returns the 8-byte big-endian form of the item
+func (uint64Encoder) Encode(item uint64) ([]byte, error) {
+	return binary.BigEndian.AppendUint64(nil, item), nil
+}
+
+// EncodeText parses a decimal integer and encodes it
+func (e uint64Encoder) EncodeText(text string) ([]byte, error) {
+	v, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return e.Encode(v)
+}
+
+// int64Encoder stores signed integers as 8 big-endian two's complement bytes
+type int64Encoder struct{}
+
+// Encode returns the 8-byte big-endian form of the item
+func (int64Encoder) Encode(item int64) ([]byte, error) {
+	return binary.BigEndian.AppendUint64(nil, uint64(item)), nil
+}
+
+// EncodeText parses a decimal integer and encodes it
+func (e int64Encoder) EncodeText(text string) ([]byte, error) {
+	v, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return e.Encode(v)
+}
+
+// uuidEncoder stores UUIDs as their 16 binary bytes. Types such as uuid.UUID convert
+// to [16]byte directly, so no string form is built or hashed.
+type uuidEncoder struct{}
+
+// Encode returns the 16 bytes of the UUID
+func (uuidEncoder) Encode(item [16]byte) ([]byte, error) {
+	return item[:], nil
+}
+
+// EncodeText parses a UUID in canonical hyphenated or plain hexadecimal form
+func (uuidEncoder) EncodeText(text string) ([]byte, error) {
+	if len(text) == 36 && text[8] == '-' && text[13] == '-' && text[18] == '-' && text[23] == '-' {
+		text = text[:8] + text[9:13] + text[14:18] + text[19:23] + text[24:]
+	}
+	if len(text) != 32 {
+		return nil, ErrInvalidIdentifier
+	}
+	return hex.DecodeString(text)
+}
+
+// crockford maps Crockford base32 characters to their values, or 0xff if invalid
+var crockford = func() [256]byte {
+	var table [256]byte
+	for i := range table {
+		table[i] = 0xff
+	}
+	const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	for i := 0; i < len(alphabet); i++ {
+		table[alphabet[i]] = byte(i)
+		table[alphabet[i]|0x20] = byte(i)
+	}
+	return table
+}()
+
+// ulidEncoder stores ULIDs as their 16 binary bytes, like uuidEncoder; ulid.ULID
+// converts to [16]byte directly
+type ulidEncoder struct{}
+
+// Encode returns the 16 bytes of the ULID
+func (ulidEncoder) Encode(item [16]byte) ([]byte, error) {
+	return item[:], nil
+}
+
+// EncodeText parses a ULID in its 26-character Crockford base32 form
+func (ulidEncoder) EncodeText(text string) ([]byte, error) {
+	// 26 characters carry 130 bits, so the first may only hold the top 3 bits
+	if len(text) != 26 || crockford[text[0]] > 7 {
+		return nil, ErrInvalidIdentifier
+	}
+	var hi, lo uint64
+	for i := 0; i < len(text); i++ {
+		v := crockford[text[i]]
+		if v == 0xff {
+			return nil, ErrInvalidIdentifier
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+	out := make([]byte, 16)
+	binary.BigEndian.PutUint64(out, hi)
+	binary.BigEndian.PutUint64(out[8:], lo)
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package bloom
+
+// Engine selects how a filter is stored in Redis
+type Engine string
+
+const (
+	// EngineBitmap stores the filter in a Redis string written with SETBIT and read with
+	// GETBIT. It is the default.
+	EngineBitmap Engine = "bitmap"
+	// EngineRedisBloom stores the filter as a RedisBloom module filter, reserved with
+	// BF.RESERVE and accessed with BF.ADD and BF.EXISTS. The module hashes items itself,
+	// so features that work on bit positions are unavailable.
+	EngineRedisBloom Engine = "redisbloom"
+	// EngineBitfield stores the filter in a bitmap like EngineBitmap, but writes and reads
+	// all of an item's bits with one BITFIELD command of u1 SET or GET subcommands
+	// instead of one SETBIT or GETBIT per bit
+	EngineBitfield Engine = "bitfield"
+	// EngineLua stores the filter in a bitmap like EngineBitmap, but runs Add and Exists
+	// as cached Lua scripts that perform all bit operations, the metadata update and the
+	// EXPIRE of a TTL atomically in one round trip
+	EngineLua Engine = "lua"
+	// EngineFunctions is EngineLua for Redis 7: the scripts are registered once as a
+	// function library with FUNCTION LOAD and called with FCALL, so they survive in the
+	// server's function store instead of its per-node script cache
+	EngineFunctions Engine = "functions"
+)
+
+// validateEngine checks the engine and the options that depend on it
+func validateEngine(cfg Config) error {
+	switch cfg.Engine {
+	case "", EngineBitmap:
+		return nil
+	case EngineBitfield:
+		if !cfg.Capabilities.Supports(FeatureBitfield) {
+			return ErrBitfieldUnsupported
+		}
+		if _, ok := cfg.RedisClient.(CmdableProvider); !ok {
+			return ErrCommandsUnsupported
+		}
+		return nil
+	case EngineLua:
+		if !cfg.Capabilities.Supports(FeatureLua) {
+			return ErrLuaUnsupported
+		}
+		if _, ok := cfg.RedisClient.(CmdableProvider); !ok {
+			return ErrCommandsUnsupported
+		}
+		return nil
+	case EngineFunctions:
+		if !cfg.Capabilities.Supports(FeatureFunctions) {
+			return ErrFunctionsUnsupported
+		}
+		if _, ok := cfg.RedisClient.(CmdableProvider); !ok {
+			return ErrCommandsUnsupported
+		}
+		return nil
+	case EngineRedisBloom:
+	default:
+		return ErrUnknownEngine
+	}
+
+	if !cfg.Capabilities.Supports(FeatureRedisBloom) {
+		return ErrRedisBloomUnsupported
+	}
+	if _, ok := cfg.RedisClient.(CmdableProvider); !ok {
+		return ErrCommandsUnsupported
+	}
+	// Layouts, seeds and position strategies choose bits, which the module does itself;
+	// admission control counts set bits and the local fallback buffers them
+	if _, ok := cfg.HashStrategy.(PositionHasher); ok || cfg.Blocked || cfg.Partitioned || cfg.Admission != nil || cfg.LocalFallback != nil {
+		return ErrIncompatibleFilter
+	}
+	if _, ok := effectiveSeed(cfg); ok {
+		return ErrIncompatibleFilter
+	}
+	return nil
+}
+
+// usesBitmap reports whether the filter is stored as a bitmap of the computed positions
+func (bf *bloomFilter) usesBitmap() bool {
+	return bf.config.Engine != EngineRedisBloom
+}
+
+// bitCommands returns the number of Redis commands that read or write n bits
+func (bf *bloomFilter) bitCommands(n int) int {
+	if (bf.config.Engine == EngineBitfield || bf.scripted()) && n > 0 {
+		return 1
+	}
+	return n
+}
+
+// scripted reports whether the engine performs reads and writes in server-side scripts
+func (bf *bloomFilter) scripted() bool {
+	return bf.config.Engine == EngineLua || bf.config.Engine == EngineFunctions
+}
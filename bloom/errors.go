@@ -8,4 +8,55 @@ var (
 	ErrInvalidFalsePositiveRate  = errors.New("false positive rate must be between 0 and 1")
 	ErrEmptyRedisKey             = errors.New("redis key cannot be empty")
 	ErrNilRedisClient            = errors.New("redis client cannot be nil")
+	ErrHooksUnsupported          = errors.New("redis client does not support hooks")
+	ErrInvalidHashStrategy       = errors.New("hash strategy name and factory are required")
+	ErrDuplicateHashStrategy     = errors.New("hash strategy is already registered")
+	ErrUnknownHashStrategy       = errors.New("unknown hash strategy")
+	ErrCommandsUnsupported       = errors.New("redis client does not expose the full command set")
+	ErrNilFilter                 = errors.New("filter cannot be nil")
+	ErrInvalidSerializedFilter   = errors.New("invalid serialized filter")
+	ErrIncompatibleFilter        = errors.New("filter is incompatible with the requested operation")
+	ErrHashVectorsUnavailable    = errors.New("hash strategy has no known-answer vectors")
+	ErrHashStrategyMismatch      = errors.New("hash strategy output does not match its known answers")
+	ErrInvalidRotationOverlap    = errors.New("rotation overlap must be greater than 0")
+	ErrPoolUnsupported           = errors.New("redis client type does not support a dedicated pool")
+	ErrInvalidLastSeenWindow     = errors.New("last-seen slice must be positive and no longer than the retention")
+	ErrInvalidRotatingWindow     = errors.New("rotating filter buckets must span at least one second and number at least one")
+	ErrInvalidSketchParameters   = errors.New("sketch epsilon and delta must be between 0 and 1")
+	ErrLuaUnsupported            = errors.New("server does not support Lua scripting")
+	ErrBatchIncomplete           = errors.New("some batch items could not be added")
+	ErrInvalidCheckpoint         = errors.New("bulk load checkpoint does not match the source")
+	ErrInvalidEncoder            = errors.New("encoder name and implementation are required")
+	ErrDuplicateEncoder          = errors.New("encoder is already registered")
+	ErrUnknownEncoder            = errors.New("unknown encoder")
+	ErrEncoderType               = errors.New("encoder does not handle the requested item type")
+	ErrInvalidIdentifier         = errors.New("invalid UUID or ULID")
+	ErrResizeInProgress          = errors.New("a resize is already in progress")
+	ErrResizeDiverged            = errors.New("resized filter is missing backfilled items")
+	ErrAliasNotFound             = errors.New("alias key does not exist")
+	ErrAuditNotReplayable        = errors.New("audit entry records a hash instead of the item")
+	ErrInvalidFingerprintBits    = errors.New("fingerprint bits must be 8 or 16")
+	ErrFuseConstruction          = errors.New("binary fuse filter construction did not converge")
+	ErrMoveTargetRequired        = errors.New("chunked moves require a target client")
+	ErrTargetKeyExists           = errors.New("key already exists on the target instance")
+	ErrCacheDisabled             = errors.New("filter has no result cache")
+	ErrPermissionDenied          = errors.New("missing ACL permissions")
+	ErrFilterSaturated           = errors.New("filter is saturated")
+	ErrInvalidOffset             = errors.New("bit offset is not an integer or out of range")
+	ErrBitfieldUnsupported       = errors.New("server does not support BITFIELD")
+	ErrInvalidStableCells        = errors.New("stable filter cell bits must be between 1 and 8")
+	ErrUnknownEngine             = errors.New("unknown storage engine")
+	ErrRedisBloomUnsupported     = errors.New("server does not have the RedisBloom module")
+	ErrFunctionsUnsupported      = errors.New("server does not support functions")
+	ErrParameterMismatch         = errors.New("filters were created with different parameters")
+	ErrFilterExists              = errors.New("filter already exists")
+	ErrFilterNotFound            = errors.New("filter does not exist or has no recorded parameters")
+	ErrUnknownTTLMode            = errors.New("unknown TTL mode")
+	ErrExpireNXUnsupported       = errors.New("server does not support EXPIRE NX")
+	ErrCircuitOpen               = errors.New("circuit breaker is open")
+	ErrBitClearUnsupported       = errors.New("bit store cannot clear bits")
+	ErrPubSubUnsupported         = errors.New("redis client does not support Pub/Sub")
+	ErrFilterClosed              = errors.New("filter is closed")
+	ErrCompactionInProgress      = errors.New("a compaction is already in progress")
+	ErrSaturationAlertDisabled   = errors.New("filter has no saturation alert")
 )
@@ -0,0 +1,57 @@
+package bloom
+
+import (
+	"context"
+	"math"
+)
+
+// fillRatio measures the fraction of the filter's bits set with BITCOUNT
+func (bf *bloomFilter) fillRatio(ctx context.Context) (float64, error) {
+	client, err := bf.cmdable()
+	if err != nil {
+		return 0, err
+	}
+	set, err := client.BitCount(ctx, bf.config.RedisKey, nil).Result()
+	if err != nil {
+		return 0, err
+	}
+	return float64(set) / float64(bf.bitSize), nil
+}
+
+// EstimateCardinality estimates the number of distinct items inserted from the
+// fraction X/m of bits set: n = -(m / k) * ln(1 - X/m). A saturated filter returns
+// math.MaxUint64. RedisBloom filters report their item count with BF.CARD.
+func (bf *bloomFilter) EstimateCardinality(ctx context.Context) (uint64, error) {
+	if !bf.usesBitmap() {
+		client, err := bf.cmdable()
+		if err != nil {
+			return 0, err
+		}
+		n, err := client.BFCard(ctx, bf.config.RedisKey).Result()
+		return uint64(n), err
+	}
+	fill, err := bf.fillRatio(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return estimateInsertions(bf.bitSize, bf.hashCount, fill), nil
+}
+
+// CurrentFalsePositiveRate returns the filter's effective false-positive probability
+// (X/m)^k for the observed fraction X/m of bits set, for comparison with the configured
+// FalsePositiveRate. RedisBloom filters do not expose their bits.
+func (bf *bloomFilter) CurrentFalsePositiveRate(ctx context.Context) (float64, error) {
+	if !bf.usesBitmap() {
+		return 0, ErrIncompatibleFilter
+	}
+	fill, err := bf.fillRatio(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return falsePositiveRate(fill, bf.hashCount), nil
+}
+
+// falsePositiveRate is the chance that k bits chosen at random are all set
+func falsePositiveRate(fill float64, hashCount uint) float64 {
+	return math.Pow(fill, float64(hashCount))
+}
@@ -0,0 +1,83 @@
+package bloom
+
+import (
+	"context"
+	"time"
+)
+
+// BitProbe is the state of one of an item's bits
+type BitProbe struct {
+	// Position is the logical bit index produced by the hash strategy
+	Position uint64
+	// Offset is the Redis bit offset after applying the bit layout
+	Offset uint64
+	// Set reports whether the bit is set
+	Set bool
+}
+
+// Explanation describes how the filter decided whether an item exists
+type Explanation struct {
+	// Key is the Redis key holding the bits
+	Key string
+	// Slot is the Redis Cluster hash slot of Key
+	Slot int
+	// Bits lists every one of the item's k bits in hash order
+	Bits []BitProbe
+	// Exists reports whether all bits are set
+	Exists bool
+	// Duration is how long reading the bits took
+	Duration time.Duration
+}
+
+// Unset returns the bits that are not set, which are the reason an item does not exist
+func (e *Explanation) Unset() []BitProbe {
+	var unset []BitProbe
+	for _, bit := range e.Bits {
+		if !bit.Set {
+			unset = append(unset, bit)
+		}
+	}
+	return unset
+}
+
+// ExistsExplain checks an item like Exists but reads all k bits, bypassing the result
+// cache and degraded lookups, and reports the state of each one
+func (bf *bloomFilter) ExistsExplain(data []byte) (*Explanation, error) {
+	ctx := context.Background()
+	if !bf.usesBitmap() {
+		return nil, ErrIncompatibleFilter
+	}
+	positions := bf.getHashPositions(data)
+	if err := bf.limiter.wait(ctx, len(positions)); err != nil {
+		return nil, err
+	}
+
+	pipe, err := bf.pipeline()
+	if err != nil {
+		return nil, err
+	}
+	defer bf.releasePipeline(pipe)
+
+	start := time.Now()
+	explanation := &Explanation{
+		Key:  bf.config.RedisKey,
+		Slot: KeySlot(bf.config.RedisKey),
+		Bits: make([]BitProbe, len(positions)),
+	}
+	cmds := make([]interface{ Val() int64 }, len(positions))
+	for i, pos := range positions {
+		explanation.Bits[i] = BitProbe{Position: pos, Offset: uint64(bf.offset(pos))}
+		cmds[i] = pipe.GetBit(ctx, bf.config.RedisKey, bf.offset(pos))
+	}
+	if err := execPipeline(ctx, pipe); err != nil {
+		return nil, err
+	}
+	explanation.Duration = time.Since(start)
+
+	explanation.Exists = true
+	for i, cmd := range cmds {
+		explanation.Bits[i].Set = cmd.Val() == 1
+		explanation.Exists = explanation.Exists && explanation.Bits[i].Set
+	}
+	return explanation, nil
+}
@@ -0,0 +1,205 @@
+package bloom
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync/atomic"
+)
+
+// Export format
+const (
+	exportMagic   = "RBBF"
+	exportVersion = 1
+)
+
+// exportHeader describes the bitmap that follows it in an export
+type exportHeader struct {
+	bits      uint64
+	hashes    uint32
+	strategy  string
+	positions string
+	seed      string
+	bitOrder  string
+	// size is the number of bitmap bytes that follow
+	size uint64
+}
+
+// exportHeader returns the header describing the filter's bitmap
+func (bf *bloomFilter) exportHeader() *exportHeader {
+	spec := bf.spec()
+	return &exportHeader{
+		bits:      spec.Bits,
+		hashes:    uint32(spec.Hashes),
+		strategy:  spec.Hash.Strategy,
+		positions: spec.Hash.Positions,
+		seed:      spec.Hash.Seed,
+		bitOrder:  spec.Layout.BitOrder,
+		size:      bf.config.BitLayout.byteSize(bf.bitSize),
+	}
+}
+
+// encode writes the header: the magic "RBBF", a version byte, m and k as big-endian
+// uint64 and uint32, the hash strategy, position scheme, seed and bit order as strings
+// with a big-endian uint16 length, and the bitmap size as a big-endian uint64
+func (h *exportHeader) encode(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString(exportMagic)
+	buf.WriteByte(exportVersion)
+	_ = binary.Write(&buf, binary.BigEndian, h.bits)
+	_ = binary.Write(&buf, binary.BigEndian, h.hashes)
+	for _, s := range []string{h.strategy, h.positions, h.seed, h.bitOrder} {
+		_ = binary.Write(&buf, binary.BigEndian, uint16(len(s)))
+		buf.WriteString(s)
+	}
+	_ = binary.Write(&buf, binary.BigEndian, h.size)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// decodeExportHeader reads a header written by encode
+func decodeExportHeader(r io.Reader) (*exportHeader, error) {
+	fail := func(err error) (*exportHeader, error) {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidSerializedFilter, err)
+	}
+	var prefix [len(exportMagic) + 1]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return fail(err)
+	}
+	if string(prefix[:len(exportMagic)]) != exportMagic || prefix[len(exportMagic)] != exportVersion {
+		return fail(errors.New("not an export of version " + strconv.Itoa(exportVersion)))
+	}
+
+	h := &exportHeader{}
+	if err := binary.Read(r, binary.BigEndian, &h.bits); err != nil {
+		return fail(err)
+	}
+	if err := binary.Read(r, binary.BigEndian, &h.hashes); err != nil {
+		return fail(err)
+	}
+	for _, s := range []*string{&h.strategy, &h.positions, &h.seed, &h.bitOrder} {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return fail(err)
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return fail(err)
+		}
+		*s = string(b)
+	}
+	if err := binary.Read(r, binary.BigEndian, &h.size); err != nil {
+		return fail(err)
+	}
+	return h, nil
+}
+
+// Export writes the filter's bitmap to w behind a small header recording m, k, the hash
+// strategy and the bit layout, reading it from Redis in chunks with GETRANGE. A missing
+// key exports as an empty filter. Module filters cannot be exported.
+func (bf *bloomFilter) Export(ctx context.Context, w io.Writer) error {
+	if !bf.usesBitmap() {
+		return ErrIncompatibleFilter
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	header := bf.exportHeader()
+	if err := header.encode(w); err != nil {
+		return err
+	}
+
+	size := int64(header.size)
+	zero := make([]byte, int(math.Min(float64(size), bitmapChunkSize)))
+	for offset := int64(0); offset < size; offset += bitmapChunkSize {
+		end := offset + bitmapChunkSize
+		if end > size {
+			end = size
+		}
+		if err := bf.limiter.wait(ctx, 1); err != nil {
+			return err
+		}
+		chunk, err := client.GetRange(ctx, bf.config.RedisKey, offset, end-1).Result()
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, chunk); err != nil {
+			return err
+		}
+		// Bytes beyond the end of the stored string are zero
+		if pad := int(end-offset) - len(chunk); pad > 0 {
+			if _, err := w.Write(zero[:pad]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Import replaces the filter's bitmap with one written by Export, staging it in chunks
+// with SETRANGE and swapping it in atomically. The export must have been taken from a
+// filter with the same parameters, hashing and bit layout; a difference is reported as
+// a *ParameterMismatchError before anything is written. Cached answers are discarded.
+func (bf *bloomFilter) Import(ctx context.Context, r io.Reader) error {
+	if !bf.usesBitmap() {
+		return ErrIncompatibleFilter
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	header, err := decodeExportHeader(r)
+	if err != nil {
+		return err
+	}
+	own := bf.exportHeader()
+	if err := bf.checkImport(header, own); err != nil {
+		return err
+	}
+	if header.size != own.size {
+		return fmt.Errorf("%w: %d bitmap bytes for %d bits", ErrInvalidSerializedFilter, header.size, header.bits)
+	}
+
+	err = replaceBitmapFrom(ctx, client, bf.config.RedisKey, r, int64(header.size), bf.config.TTL, bf.parameterFields())
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: bit data: %v", ErrInvalidSerializedFilter, err)
+	}
+	if err != nil {
+		return err
+	}
+	atomic.StoreUint32(&bf.metadataRecorded, 1)
+	bf.cache.invalidate()
+	return nil
+}
+
+// checkImport compares an export's header with the filter's own
+func (bf *bloomFilter) checkImport(header, own *exportHeader) error {
+	fields := []struct {
+		name               string
+		recorded, expected string
+	}{
+		{metaFieldBits, strconv.FormatUint(header.bits, 10), strconv.FormatUint(own.bits, 10)},
+		{metaFieldHashes, strconv.FormatUint(uint64(header.hashes), 10), strconv.FormatUint(uint64(own.hashes), 10)},
+		{metaFieldHash, header.strategy, own.strategy},
+		{"positions", header.positions, own.positions},
+		{"seed", header.seed, own.seed},
+		{"bit_order", header.bitOrder, own.bitOrder},
+	}
+	for _, f := range fields {
+		if f.recorded != f.expected {
+			return &ParameterMismatchError{
+				Key:        bf.config.RedisKey,
+				Field:      f.name,
+				Recorded:   f.recorded,
+				Configured: f.expected,
+			}
+		}
+	}
+	return nil
+}
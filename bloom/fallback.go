@@ -0,0 +1,206 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Default local fallback settings
+const (
+	defaultFallbackItems = 100_000
+	// replayBatchPositions is the number of bits replayed in one pipeline
+	replayBatchPositions = 10_000
+)
+
+// LocalFallback keeps a filter usable through short Redis outages. Adds that fail because
+// Redis is unreachable, or because the circuit breaker is open, are buffered in a local
+// bitmap instead of failing, and Exists answers from that bitmap best-effort. Once a call
+// reaches Redis again, the buffered bits are replayed in the background.
+type LocalFallback struct {
+	// MaxItems caps the number of buffered items (defaults to 100,000); Adds beyond it
+	// fail with the Redis error
+	MaxItems int
+}
+
+// bufferedWrite holds the positions of one buffered Add or batch
+type bufferedWrite struct {
+	positions []uint64
+	items     int
+}
+
+// localFallback is the local bitmap of a LocalFallback
+type localFallback struct {
+	max       int
+	mu        sync.Mutex
+	bits      map[uint64]struct{}
+	pending   []bufferedWrite
+	items     int
+	replaying bool
+}
+
+// newLocalFallback creates the local bitmap for a LocalFallback, or nil if it is disabled
+func newLocalFallback(cfg *LocalFallback) *localFallback {
+	if cfg == nil {
+		return nil
+	}
+	max := cfg.MaxItems
+	if max <= 0 {
+		max = defaultFallbackItems
+	}
+	return &localFallback{max: max, bits: make(map[uint64]struct{})}
+}
+
+// unavailable reports whether err means Redis could not be reached
+func unavailable(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || IsTransientError(err)
+}
+
+// buffer records the positions of items that could not be written after err, and
+// reports whether they were buffered
+func (f *localFallback) buffer(err error, positions []uint64, items int) bool {
+	if f == nil || !unavailable(err) {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.items+items > f.max {
+		return false
+	}
+	for _, pos := range positions {
+		f.bits[pos] = struct{}{}
+	}
+	f.pending = append(f.pending, bufferedWrite{positions: positions, items: items})
+	f.items += items
+	return true
+}
+
+// contains reports whether all positions are set in the local bitmap
+func (f *localFallback) contains(positions []uint64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, pos := range positions {
+		if _, ok := f.bits[pos]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// buffered returns the number of items waiting to be replayed
+func (f *localFallback) buffered() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.items
+}
+
+// startReplay returns the buffered writes and marks a replay as running, or returns nil
+// if nothing is buffered or a replay is already running
+func (f *localFallback) startReplay() []bufferedWrite {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.replaying || len(f.pending) == 0 {
+		return nil
+	}
+	f.replaying = true
+	return f.pending
+}
+
+// finishReplay drops the first n replayed writes and rebuilds the local bitmap from the
+// writes still pending
+func (f *localFallback) finishReplay(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replaying = false
+	if n == 0 {
+		return
+	}
+	f.pending = append([]bufferedWrite(nil), f.pending[n:]...)
+	f.bits = make(map[uint64]struct{})
+	f.items = 0
+	for _, w := range f.pending {
+		for _, pos := range w.positions {
+			f.bits[pos] = struct{}{}
+		}
+		f.items += w.items
+	}
+}
+
+// lookupFallback answers a lookup of positions that failed with err from the local
+// bitmap: true if the item was buffered, otherwise the circuit breaker's answer or false
+func (bf *bloomFilter) lookupFallback(err error, positions []uint64) (bool, error) {
+	if bf.fallback == nil || !unavailable(err) {
+		return bf.circuit.fallback(err)
+	}
+	if bf.fallback.contains(positions) {
+		return true, nil
+	}
+	if exists, err := bf.circuit.fallback(err); err == nil {
+		return exists, nil
+	}
+	return false, nil
+}
+
+// bufferAdd buffers the positions of items whose write failed with err and performs the
+// local part of the Add bookkeeping, reporting whether they were buffered
+func (bf *bloomFilter) bufferAdd(err error, positions []uint64, items ...[]byte) bool {
+	if !bf.fallback.buffer(err, positions, len(items)) {
+		return false
+	}
+	if bf.cache != nil {
+		for _, data := range items {
+			bf.cache.set(bf.cache.key(data), true)
+		}
+	}
+	bf.recordInserts(len(items))
+	bf.metrics.SetGauge(MetricFallbackBuffered, float64(bf.fallback.buffered()))
+	return true
+}
+
+// replayFallback writes buffered positions to Redis in the background, unless nothing is
+// buffered or a replay is already running. Writes that fail stay buffered for the next
+// replay.
+func (bf *bloomFilter) replayFallback() {
+	pending := bf.fallback.startReplay()
+	if pending == nil {
+		return
+	}
+	go func() {
+		ctx := context.Background()
+		done := 0
+		for done < len(pending) {
+			end := done
+			var positions []uint64
+			for end < len(pending) && (end == done || len(positions)+len(pending[end].positions) <= replayBatchPositions) {
+				positions = append(positions, pending[end].positions...)
+				end++
+			}
+			if err := bf.call(ctx, func() error { return bf.writePositions(ctx, positions) }); err != nil {
+				bf.metrics.IncCounter(MetricFallbackReplayErrors, 1)
+				break
+			}
+			done = end
+		}
+		if done > 0 {
+			var replayed []uint64
+			for _, w := range pending[:done] {
+				replayed = append(replayed, w.positions...)
+			}
+			err := bf.recordCreation(ctx)
+			if err == nil {
+				err = bf.publishUpdate(ctx, replayed)
+			}
+			if err == nil {
+				err = bf.refreshTTL(ctx)
+			}
+			if err != nil {
+				bf.metrics.IncCounter(MetricFallbackReplayErrors, 1)
+			}
+		}
+		bf.fallback.finishReplay(done)
+		bf.metrics.SetGauge(MetricFallbackBuffered, float64(bf.fallback.buffered()))
+	}()
+}
@@ -0,0 +1,185 @@
+package bloom
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Count-Min Sketch defaults and layout
+const (
+	sketchKeySuffix       = "cms"
+	defaultSketchEpsilon  = 0.001
+	defaultSketchDelta    = 0.01
+	sketchCounterBitWidth = "u32"
+)
+
+// FrequencyOptions sizes the Count-Min Sketch of a FrequencyFilter. Counts overestimate
+// the true frequency by at most Epsilon times the total number of additions, with
+// probability 1-Delta.
+type FrequencyOptions struct {
+	// Epsilon is the relative error bound (defaults to 0.001)
+	Epsilon float64
+	// Delta is the probability of exceeding the error bound (defaults to 0.01)
+	Delta float64
+}
+
+// FrequencyFilter combines a Bloom filter with a Count-Min Sketch: Add updates both in one
+// MULTI/EXEC transaction and Query answers membership and approximate frequency in one
+// round trip. Servers without BITFIELD store the counters in a hash instead.
+type FrequencyFilter struct {
+	filter    *bloomFilter
+	sketchKey string
+	width     uint64
+	depth     uint
+	bitfield  bool
+}
+
+// NewFrequencyFilter creates a frequency filter. The sketch lives in a companion key in
+// the same cluster slot as the filter.
+func NewFrequencyFilter(cfg Config, opts FrequencyOptions) (*FrequencyFilter, error) {
+	if opts.Epsilon == 0 {
+		opts.Epsilon = defaultSketchEpsilon
+	}
+	if opts.Delta == 0 {
+		opts.Delta = defaultSketchDelta
+	}
+	if opts.Epsilon < 0 || opts.Epsilon >= 1 || opts.Delta < 0 || opts.Delta >= 1 {
+		return nil, ErrInvalidSketchParameters
+	}
+
+	filter, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !filter.usesBitmap() {
+		return nil, ErrIncompatibleFilter
+	}
+	if _, err := filter.cmdable(); err != nil {
+		return nil, err
+	}
+	return &FrequencyFilter{
+		filter:    filter,
+		sketchKey: companionKey(cfg.RedisKey, sketchKeySuffix),
+		width:     uint64(math.Ceil(math.E / opts.Epsilon)),
+		depth:     uint(math.Ceil(math.Log(1 / opts.Delta))),
+		bitfield:  cfg.Capabilities.Supports(FeatureBitfield),
+	}, nil
+}
+
+// Add records an occurrence of the element in the filter and the sketch atomically, then
+// updates the result cache, audit log and update notifications like BloomFilter.Add
+func (ff *FrequencyFilter) Add(data []byte) error {
+	ctx := context.Background()
+	client, _ := ff.filter.cmdable()
+	positions := ff.filter.getHashPositions(data)
+	counters := ff.counters(data)
+	ttl := ff.filter.config.TTL
+
+	if err := ff.filter.limiter.wait(ctx, len(positions)+1); err != nil {
+		return err
+	}
+	if err := ff.filter.recordCreation(ctx); err != nil {
+		return err
+	}
+
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, pos := range positions {
+			pipe.SetBit(ctx, ff.filter.config.RedisKey, ff.filter.offset(pos), 1)
+		}
+		if ff.bitfield {
+			args := []interface{}{"OVERFLOW", "SAT"}
+			for _, c := range counters {
+				args = append(args, "INCRBY", sketchCounterBitWidth, "#"+strconv.FormatUint(c, 10), 1)
+			}
+			pipe.BitField(ctx, ff.sketchKey, args...)
+		} else {
+			for _, c := range counters {
+				pipe.HIncrBy(ctx, ff.sketchKey, strconv.FormatUint(c, 10), 1)
+			}
+		}
+		if ttl > 0 {
+			ff.filter.queueExpire(ctx, pipe, ff.filter.config.RedisKey, ff.sketchKey, metadataKey(ff.filter.config.RedisKey))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// The transaction has applied the TTL
+	return ff.filter.recordAdd(ctx, data)
+}
+
+// Exists checks if an element is in the filter
+func (ff *FrequencyFilter) Exists(data []byte) (bool, error) {
+	return ff.filter.Exists(data)
+}
+
+// Query reports whether the element was probably seen and its approximate count.
+// The count is zero whenever the filter rules the element out.
+func (ff *FrequencyFilter) Query(data []byte) (seen bool, count uint64, err error) {
+	ctx := context.Background()
+	client, _ := ff.filter.cmdable()
+	positions := ff.filter.getHashPositions(data)
+	counters := ff.counters(data)
+
+	if err := ff.filter.limiter.wait(ctx, len(positions)+1); err != nil {
+		return false, 0, err
+	}
+
+	pipe := client.Pipeline()
+	inFilter := ff.filter.queueCheckBits(ctx, pipe, positions)
+	var read func() []int64
+	if ff.bitfield {
+		args := make([]interface{}, 0, 3*len(counters))
+		for _, c := range counters {
+			args = append(args, "GET", sketchCounterBitWidth, "#"+strconv.FormatUint(c, 10))
+		}
+		cmd := pipe.BitField(ctx, ff.sketchKey, args...)
+		read = cmd.Val
+	} else {
+		fields := make([]string, len(counters))
+		for i, c := range counters {
+			fields[i] = strconv.FormatUint(c, 10)
+		}
+		cmd := pipe.HMGet(ctx, ff.sketchKey, fields...)
+		read = func() []int64 {
+			values := make([]int64, len(fields))
+			for i, v := range cmd.Val() {
+				if s, ok := v.(string); ok {
+					values[i], _ = strconv.ParseInt(s, 10, 64)
+				}
+			}
+			return values
+		}
+	}
+	if err := execPipeline(ctx, pipe); err != nil {
+		return false, 0, err
+	}
+
+	if !inFilter() {
+		return false, 0, nil
+	}
+	// The estimate is the smallest counter across the rows
+	values := read()
+	count = uint64(values[0])
+	for _, v := range values[1:] {
+		if uint64(v) < count {
+			count = uint64(v)
+		}
+	}
+	return true, count, nil
+}
+
+// counters returns the sketch counter index of the element in each row
+func (ff *FrequencyFilter) counters(data []byte) []uint64 {
+	h1 := ff.filter.hashStrategy.Hash(data, 0)
+	h2 := ff.filter.hashStrategy.Hash(data, 1) | 1
+	counters := make([]uint64, ff.depth)
+	for i := range counters {
+		counters[i] = uint64(i)*ff.width + (h1+uint64(i)*h2)%ff.width
+	}
+	return counters
+}
@@ -0,0 +1,98 @@
+package bloom
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Function library registered by the functions engine
+const (
+	functionLibraryName = "bloomfilter"
+	functionAdd         = "bloomfilter_add"
+	functionExists      = "bloomfilter_exists"
+	functionTestAndAdd  = "bloomfilter_testandadd"
+	errFunctionNotFound = "Function not found"
+)
+
+// functionLibrary holds the engine's scripts as Redis 7 functions. The script bodies take
+// KEYS and ARGV as parameters, so they run unchanged as functions.
+const functionLibrary = "#!lua name=" + functionLibraryName + `
+local function add(KEYS, ARGV)
+` + luaAddSource + `
+end
+
+local function exists(KEYS, ARGV)
+` + luaExistsSource + `
+end
+
+local function testandadd(KEYS, ARGV)
+` + testAndAddSource + `
+end
+
+redis.register_function('` + functionAdd + `', add)
+redis.register_function{function_name = '` + functionExists + `', callback = exists, flags = {'no-writes'}}
+redis.register_function('` + functionTestAndAdd + `', testandadd)
+`
+
+// fcall calls a function of the library with FCALL, loading the library first if needed
+// and reloading it once if the server does not know the function
+func (m *scriptManager) fcall(ctx context.Context, client redis.Cmdable, function string, keys []string, args ...interface{}) *redis.Cmd {
+	if !m.isLoadedLibrary() {
+		if err := m.loadLibrary(ctx, client); err != nil {
+			cmd := redis.NewCmd(ctx)
+			cmd.SetErr(err)
+			return cmd
+		}
+	}
+	cmd := client.FCall(ctx, function, keys, args...)
+	if err := cmd.Err(); err != nil && strings.Contains(err.Error(), errFunctionNotFound) {
+		if err := m.loadLibrary(ctx, client); err != nil {
+			cmd.SetErr(err)
+			return cmd
+		}
+		cmd = client.FCall(ctx, function, keys, args...)
+	}
+	return cmd
+}
+
+// isLoadedLibrary reports whether the library has been loaded
+func (m *scriptManager) isLoadedLibrary() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.loaded[functionLibraryName]
+}
+
+// loadLibrary loads the library with FUNCTION LOAD REPLACE, on every primary of a cluster
+// and every shard of a ring
+func (m *scriptManager) loadLibrary(ctx context.Context, client redis.Cmdable) error {
+	load := func(ctx context.Context, node *redis.Client) error {
+		return node.FunctionLoadReplace(ctx, functionLibrary).Err()
+	}
+	var err error
+	switch c := client.(type) {
+	case *redis.ClusterClient:
+		err = c.ForEachMaster(ctx, load)
+	case *redis.Ring:
+		err = c.ForEachShard(ctx, load)
+	default:
+		err = client.FunctionLoadReplace(ctx, functionLibrary).Err()
+	}
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.loaded[functionLibraryName] = true
+	m.mu.Unlock()
+	return nil
+}
+
+// runScript runs one of the engine's scripts: as a function of the library with the
+// functions engine, or with EVALSHA otherwise
+func (bf *bloomFilter) runScript(ctx context.Context, client redis.Cmdable, script *redis.Script, function string, keys []string, args ...interface{}) *redis.Cmd {
+	if bf.config.Engine == EngineFunctions {
+		return bf.scripts.fcall(ctx, client, function, keys, args...)
+	}
+	return bf.scripts.run(ctx, client, script, keys, args...)
+}
@@ -0,0 +1,177 @@
+package bloom
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+
+	"github.com/spaolacci/murmur3"
+)
+
+// GuavaStrategy identifies the index derivation of a Guava BloomFilter,
+// matching the ordinal of Guava's BloomFilterStrategies enum
+type GuavaStrategy uint8
+
+const (
+	// GuavaMurmur128Mitz32 is Guava's MURMUR128_MITZ_32 strategy
+	GuavaMurmur128Mitz32 GuavaStrategy = 0
+	// GuavaMurmur128Mitz64 is Guava's MURMUR128_MITZ_64 strategy (the default since Guava 12)
+	GuavaMurmur128Mitz64 GuavaStrategy = 1
+)
+
+// Registered names of the Guava-compatible hash strategies
+const (
+	HashGuavaMitz32 = "guava-murmur128-mitz32"
+	HashGuavaMitz64 = "guava-murmur128-mitz64"
+)
+
+func init() {
+	RegisterHashStrategy(HashGuavaMitz32, func() HashStrategy { return NewGuavaStrategy(GuavaMurmur128Mitz32) })
+	RegisterHashStrategy(HashGuavaMitz64, func() HashStrategy { return NewGuavaStrategy(GuavaMurmur128Mitz64) })
+}
+
+// guavaStrategy reproduces Guava's murmur3_128 hashing and bit index derivation
+type guavaStrategy struct {
+	strategy GuavaStrategy
+}
+
+var _ PositionHasher = (*guavaStrategy)(nil)
+
+// NewGuavaStrategy creates a hash strategy that computes the same bit indices as a Guava
+// BloomFilter using the given strategy. Items must be passed as the bytes Guava's funnel
+// feeds to the hasher, e.g. the UTF-8 bytes for Funnels.stringFunnel(UTF_8).
+func NewGuavaStrategy(strategy GuavaStrategy) HashStrategy {
+	return &guavaStrategy{strategy: strategy}
+}
+
+// Hash returns the lower (even i) or upper (odd i) half of the 128-bit Murmur3 hash
+func (g *guavaStrategy) Hash(data []byte, i uint) uint64 {
+	lower, upper := murmur3.Sum128(data)
+	if i%2 == 0 {
+		return lower
+	}
+	return upper
+}
+
+// Positions derives the bit indices exactly as Guava's BloomFilterStrategies do
+func (g *guavaStrategy) Positions(data []byte, hashCount uint, bitSize uint64) []uint64 {
+	lower, upper := murmur3.Sum128(data)
+	positions := make([]uint64, hashCount)
+
+	if g.strategy == GuavaMurmur128Mitz32 {
+		hash1 := int32(lower)
+		hash2 := int32(lower >> 32)
+		for i := uint(1); i <= hashCount; i++ {
+			combined := hash1 + int32(i)*hash2
+			if combined < 0 {
+				combined = ^combined
+			}
+			positions[i-1] = uint64(combined) % bitSize
+		}
+		return positions
+	}
+
+	combined := int64(lower)
+	for i := uint(0); i < hashCount; i++ {
+		positions[i] = uint64(combined&math.MaxInt64) % bitSize
+		combined += int64(upper)
+	}
+	return positions
+}
+
+// EstimateGuavaParameters returns the bit size and hash count Guava's BloomFilter.create
+// picks for n items at a false-positive rate p: m = -n*ln(p)/ln(2)^2 truncated,
+// k = round(m/n*ln(2)), and m rounded up to whole 64-bit words as Guava's bit array
+// does. Filters configured with a Guava strategy are sized this way, so a Go and a JVM
+// filter created with the same n and p address the same bits. It returns zeros for
+// p outside (0, 1).
+func EstimateGuavaParameters(n uint64, p float64) (uint64, uint) {
+	if p <= 0 || p >= 1 {
+		return 0, 0
+	}
+	if n == 0 {
+		n = 1
+	}
+	numBits := uint64(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	hashCount := uint(math.Max(1, math.Round(float64(numBits)/float64(n)*math.Ln2)))
+	return (numBits + 63) / 64 * 64, hashCount
+}
+
+// GuavaFilter is a Guava BloomFilter decoded from its writeTo serialization
+type GuavaFilter struct {
+	Strategy         GuavaStrategy
+	NumHashFunctions uint
+	// Data holds the bit array; bit i is bit (i % 64) of Data[i / 64]
+	Data []uint64
+}
+
+// BitSize returns the number of bits in the filter, which Guava uses as the modulus
+func (g *GuavaFilter) BitSize() uint64 {
+	return uint64(len(g.Data)) * 64
+}
+
+// DecodeGuava reads a filter written by Guava's BloomFilter.writeTo: a strategy ordinal
+// byte, an unsigned hash count byte, a big-endian int32 word count and the words as
+// big-endian int64 values
+func DecodeGuava(r io.Reader) (*GuavaFilter, error) {
+	var header [6]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidSerializedFilter, err)
+	}
+
+	strategy := GuavaStrategy(header[0])
+	if strategy != GuavaMurmur128Mitz32 && strategy != GuavaMurmur128Mitz64 {
+		return nil, fmt.Errorf("%w: unknown guava strategy %d", ErrInvalidSerializedFilter, header[0])
+	}
+	hashCount := uint(header[1])
+	words := int32(binary.BigEndian.Uint32(header[2:]))
+	if hashCount == 0 || words <= 0 {
+		return nil, fmt.Errorf("%w: %d hash functions over %d words", ErrInvalidSerializedFilter, hashCount, words)
+	}
+
+	data := make([]uint64, words)
+	if err := binary.Read(r, binary.BigEndian, data); err != nil {
+		return nil, fmt.Errorf("%w: bit data: %v", ErrInvalidSerializedFilter, err)
+	}
+
+	return &GuavaFilter{Strategy: strategy, NumHashFunctions: hashCount, Data: data}, nil
+}
+
+// ImportGuava decodes a Guava BloomFilter serialization and stores its bits under
+// cfg.RedisKey, replacing any existing filter. The returned filter uses the Guava
+// strategy, hash count and bit size, so it answers exactly like the JVM filter;
+// ExpectedInsertions, FalsePositiveRate and HashStrategy in cfg are ignored.
+func ImportGuava(ctx context.Context, r io.Reader, cfg Config) (BloomFilter, error) {
+	guava, err := DecodeGuava(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.HashStrategy = NewGuavaStrategy(guava.Strategy)
+	bf, err := buildBloomFilter(cfg, guava.BitSize(), guava.NumHashFunctions)
+	if err != nil {
+		return nil, err
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	bitmap := make([]byte, cfg.BitLayout.byteSize(bf.bitSize))
+	for w, word := range guava.Data {
+		for word != 0 {
+			b := bits.TrailingZeros64(word)
+			setBitmapBit(bitmap, cfg.BitLayout.Offset(uint64(w)*64+uint64(b)))
+			word &= word - 1
+		}
+	}
+
+	if err := replaceBitmap(ctx, client, cfg.RedisKey, bitmap, cfg.TTL, bf.parameterFields()); err != nil {
+		return nil, err
+	}
+	bf.metadataRecorded = 1
+	return bf, nil
+}
@@ -2,6 +2,8 @@ package bloom
 
 import (
 	"hash/fnv"
+	"sort"
+	"sync"
 
 	"github.com/cespare/xxhash/v2"
 	"github.com/spaolacci/murmur3"
@@ -12,6 +14,66 @@ type HashStrategy interface {
 	Hash(data []byte, i uint) uint64
 }
 
+// PositionHasher is implemented by hash strategies that derive bit positions themselves
+// instead of using the default double hashing, e.g. to stay compatible with another library
+type PositionHasher interface {
+	Positions(data []byte, hashCount uint, bitSize uint64) []uint64
+}
+
+// Names of the built-in hash strategies
+const (
+	HashXXHash  = "xxhash"
+	HashMurmur3 = "murmur3"
+	HashFNV     = "fnv"
+)
+
+// hashStrategies is the registry of named hash strategy constructors
+var (
+	hashStrategiesMu sync.RWMutex
+	hashStrategies   = map[string]func() HashStrategy{
+		HashXXHash:  NewXXHashStrategy,
+		HashMurmur3: NewMurmur3Strategy,
+		HashFNV:     NewFNVStrategy,
+	}
+)
+
+// RegisterHashStrategy makes a hash strategy available by name to tooling and configuration
+func RegisterHashStrategy(name string, factory func() HashStrategy) error {
+	if name == "" || factory == nil {
+		return ErrInvalidHashStrategy
+	}
+	hashStrategiesMu.Lock()
+	defer hashStrategiesMu.Unlock()
+	if _, exists := hashStrategies[name]; exists {
+		return ErrDuplicateHashStrategy
+	}
+	hashStrategies[name] = factory
+	return nil
+}
+
+// NewHashStrategy creates the registered hash strategy with the given name
+func NewHashStrategy(name string) (HashStrategy, error) {
+	hashStrategiesMu.RLock()
+	factory, ok := hashStrategies[name]
+	hashStrategiesMu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownHashStrategy
+	}
+	return factory(), nil
+}
+
+// HashStrategyNames returns the names of all registered hash strategies in sorted order
+func HashStrategyNames() []string {
+	hashStrategiesMu.RLock()
+	defer hashStrategiesMu.RUnlock()
+	names := make([]string, 0, len(hashStrategies))
+	for name := range hashStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // XXHashStrategy implements HashStrategy using xxhash (fastest)
 type XXHashStrategy struct{}
 
@@ -0,0 +1,55 @@
+package bloom
+
+// Known-answer vectors of the built-in hash strategies. A mismatch means a dependency
+// upgrade or code change altered hash outputs, which would silently corrupt filters
+// shared with processes still using the old outputs.
+var (
+	fnvVectors = []HashTestVector{
+		{Input: []byte("a"), Index: 0, Want: 0xe4bbeed9252b447c},
+		{Input: []byte("a"), Index: 1, Want: 0xd80d0daea7dbdd7f},
+		{Input: []byte("a"), Index: 7, Want: 0xbeaf8759ad3d7579},
+		{Input: []byte("user@example.com"), Index: 0, Want: 0x21fe82ed4e74598b},
+		{Input: []byte("user@example.com"), Index: 1, Want: 0x83834d22360be186},
+		{Input: []byte("user@example.com"), Index: 7, Want: 0xa3e73606c471bc08},
+	}
+	murmur3Vectors = []HashTestVector{
+		{Input: []byte("a"), Index: 0, Want: 0x000000003c2569b2},
+		{Input: []byte("a"), Index: 1, Want: 0x000000006c28bcc4},
+		{Input: []byte("a"), Index: 7, Want: 0x000000009afa16dd},
+		{Input: []byte("user@example.com"), Index: 0, Want: 0x0000000041cf9c5c},
+		{Input: []byte("user@example.com"), Index: 1, Want: 0x0000000058d76406},
+		{Input: []byte("user@example.com"), Index: 7, Want: 0x000000004b0e19d0},
+	}
+	xxhashVectors = []HashTestVector{
+		{Input: []byte("a"), Index: 0, Want: 0x77757daa21f86a1e},
+		{Input: []byte("a"), Index: 1, Want: 0x0ef8d19887c443d2},
+		{Input: []byte("a"), Index: 7, Want: 0x54a0c22feb3cf989},
+		{Input: []byte("user@example.com"), Index: 0, Want: 0xebbe45cd1542e159},
+		{Input: []byte("user@example.com"), Index: 1, Want: 0x46afb2dfbbed5531},
+		{Input: []byte("user@example.com"), Index: 7, Want: 0xcc8475cc7923a18c},
+	}
+	guavaVectors = []HashTestVector{
+		{Input: []byte("a"), Index: 0, Want: 0x85555565f6597889},
+		{Input: []byte("a"), Index: 1, Want: 0xe6b53a48510e895a},
+		{Input: []byte("a"), Index: 7, Want: 0xe6b53a48510e895a},
+		{Input: []byte("user@example.com"), Index: 0, Want: 0xe11718d678db26ff},
+		{Input: []byte("user@example.com"), Index: 1, Want: 0x0af2175eb94f3e12},
+		{Input: []byte("user@example.com"), Index: 7, Want: 0x0af2175eb94f3e12},
+	}
+	pybloomVectors = []HashTestVector{
+		{Input: []byte("a"), Index: 0, Want: 0x4ec5840f0820f087},
+		{Input: []byte("a"), Index: 1, Want: 0x58bdf5e5446c3501},
+		{Input: []byte("a"), Index: 7, Want: 0x494bd9bc9a1ce837},
+		{Input: []byte("user@example.com"), Index: 0, Want: 0x63042c8bf1c03afe},
+		{Input: []byte("user@example.com"), Index: 1, Want: 0x5d0dc618545428d4},
+		{Input: []byte("user@example.com"), Index: 7, Want: 0x467b9ce71e6d6cea},
+	}
+	bitsAndBloomsVectors = []HashTestVector{
+		{Input: []byte("a"), Index: 0, Want: 0x85555565f6597889},
+		{Input: []byte("a"), Index: 1, Want: 0x62cd487813625c3a},
+		{Input: []byte("a"), Index: 7, Want: 0x0a8e0097c37dec43},
+		{Input: []byte("user@example.com"), Index: 0, Want: 0xe11718d678db26ff},
+		{Input: []byte("user@example.com"), Index: 1, Want: 0xae073e8db5506f66},
+		{Input: []byte("user@example.com"), Index: 7, Want: 0x9c604ecbc7362512},
+	}
+)
@@ -0,0 +1,117 @@
+package bloom
+
+import (
+	"runtime"
+	"time"
+)
+
+// defaultBenchmarkBuckets is the number of buckets used for the distribution test
+const defaultBenchmarkBuckets = 1024
+
+// HashBenchmarkOptions configures BenchmarkHashStrategy
+type HashBenchmarkOptions struct {
+	// ExpectedInsertions and FalsePositiveRate size the simulated filter
+	ExpectedInsertions uint64
+	FalsePositiveRate  float64
+	// Buckets is the number of equal-width buckets positions are counted in (defaults to 1024)
+	Buckets int
+	// Rounds is the number of passes over the keys for the throughput measurement (defaults to 1)
+	Rounds int
+}
+
+// HashBenchmarkResult reports the speed and distribution quality of a hash strategy
+type HashBenchmarkResult struct {
+	Keys         int
+	Positions    int
+	Duration     time.Duration
+	OpsPerSecond float64
+	AllocsPerOp  float64
+	BytesPerOp   float64
+	// ChiSquare is the chi-square statistic of the bucket counts against a uniform distribution
+	ChiSquare float64
+	// DegreesOfFreedom is the number of buckets minus one; for a uniform hash
+	// ChiSquare stays close to it
+	DegreesOfFreedom int
+}
+
+// NormalizedChiSquare returns ChiSquare divided by its degrees of freedom,
+// which is close to 1 for a well-distributed strategy
+func (r HashBenchmarkResult) NormalizedChiSquare() float64 {
+	if r.DegreesOfFreedom == 0 {
+		return 0
+	}
+	return r.ChiSquare / float64(r.DegreesOfFreedom)
+}
+
+// BenchmarkHashStrategy measures throughput, allocations and bit-position distribution of a
+// strategy over a sample of real keys, using the positions a filter with the given sizing would touch
+func BenchmarkHashStrategy(strategy HashStrategy, keys [][]byte, opts HashBenchmarkOptions) (HashBenchmarkResult, error) {
+	if opts.ExpectedInsertions == 0 {
+		return HashBenchmarkResult{}, ErrInvalidExpectedInsertions
+	}
+	if opts.FalsePositiveRate <= 0 || opts.FalsePositiveRate >= 1 {
+		return HashBenchmarkResult{}, ErrInvalidFalsePositiveRate
+	}
+	if opts.Buckets <= 1 {
+		opts.Buckets = defaultBenchmarkBuckets
+	}
+	if opts.Rounds <= 0 {
+		opts.Rounds = 1
+	}
+
+	bitSize, hashCount := EstimateParameters(opts.ExpectedInsertions, opts.FalsePositiveRate)
+	bf := &bloomFilter{bitSize: bitSize, hashCount: hashCount, hashStrategy: strategy}
+
+	// Throughput and allocations
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for round := 0; round < opts.Rounds; round++ {
+		for _, key := range keys {
+			bf.getHashPositions(key)
+		}
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	ops := len(keys) * opts.Rounds
+	result := HashBenchmarkResult{
+		Keys:             len(keys),
+		Duration:         elapsed,
+		DegreesOfFreedom: opts.Buckets - 1,
+	}
+	if ops > 0 {
+		result.AllocsPerOp = float64(after.Mallocs-before.Mallocs) / float64(ops)
+		result.BytesPerOp = float64(after.TotalAlloc-before.TotalAlloc) / float64(ops)
+		if elapsed > 0 {
+			result.OpsPerSecond = float64(ops) / elapsed.Seconds()
+		}
+	}
+
+	// Bit-position distribution
+	counts := make([]uint64, opts.Buckets)
+	for _, key := range keys {
+		for _, pos := range bf.getHashPositions(key) {
+			counts[pos*uint64(opts.Buckets)/bitSize]++
+			result.Positions++
+		}
+	}
+	result.ChiSquare = chiSquareUniform(counts, result.Positions)
+
+	return result, nil
+}
+
+// chiSquareUniform computes the chi-square statistic of counts against a uniform distribution
+func chiSquareUniform(counts []uint64, total int) float64 {
+	if total == 0 || len(counts) == 0 {
+		return 0
+	}
+	expected := float64(total) / float64(len(counts))
+	var chi float64
+	for _, c := range counts {
+		d := float64(c) - expected
+		chi += d * d / expected
+	}
+	return chi
+}
@@ -0,0 +1,40 @@
+package bloom
+
+import "io"
+
+// Iterator yields items from a data source, for example when rebuilding a filter.
+// Next returns io.EOF once the source is exhausted.
+type Iterator interface {
+	Next() ([]byte, error)
+}
+
+// sliceIterator iterates over an in-memory slice of items
+type sliceIterator struct {
+	items [][]byte
+	pos   int
+}
+
+// NewSliceIterator creates an Iterator over the given items
+func NewSliceIterator(items [][]byte) Iterator {
+	return &sliceIterator{items: items}
+}
+
+// Next returns the next item or io.EOF
+func (it *sliceIterator) Next() ([]byte, error) {
+	if it.pos >= len(it.items) {
+		return nil, io.EOF
+	}
+	item := it.items[it.pos]
+	it.pos++
+	return item, nil
+}
+
+// Skip advances past the next n items, or returns io.EOF if fewer remain
+func (it *sliceIterator) Skip(n int64) error {
+	if n > int64(len(it.items)-it.pos) {
+		it.pos = len(it.items)
+		return io.EOF
+	}
+	it.pos += int(n)
+	return nil
+}
@@ -0,0 +1,52 @@
+package bloom
+
+import "strings"
+
+// companionKey derives the name of a key stored alongside key.
+// The result always hashes to the same cluster slot as key, so multi-key
+// commands such as RENAME work on Redis Cluster.
+func companionKey(key, suffix string) string {
+	if hasHashTag(key) {
+		return key + ":" + suffix
+	}
+	return "{" + key + "}:" + suffix
+}
+
+// hasHashTag reports whether key contains a non-empty cluster hash tag
+func hasHashTag(key string) bool {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return false
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	return end > 0
+}
+
+// clusterSlotCount is the number of hash slots in a Redis Cluster
+const clusterSlotCount = 16384
+
+// KeySlot returns the Redis Cluster hash slot of key, honoring hash tags
+func KeySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlotCount)
+}
+
+// crc16 computes the CRC-16/XMODEM checksum Redis Cluster uses for key slots
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
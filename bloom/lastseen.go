@@ -0,0 +1,103 @@
+package bloom
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// lastSeenKeyPrefix prefixes the companion keys of the per-slice filters
+const lastSeenKeyPrefix = "seen:"
+
+// LastSeenOptions configures the time slices of a LastSeenFilter
+type LastSeenOptions struct {
+	// Slice is the width of a time slice, which bounds the precision of last-seen times
+	Slice time.Duration
+	// Retention is how far back last-seen times are kept; older sightings report a zero time
+	Retention time.Duration
+}
+
+// LastSeenFilter answers "seen before, and roughly when?" in a single round trip. Next to
+// the main filter it writes each item into a small filter per time slice that expires
+// after the retention period; the newest slice containing an item gives its approximate
+// last-seen time. Like membership, last-seen times can be overestimated by false positives.
+type LastSeenFilter struct {
+	main *bloomFilter
+	opts LastSeenOptions
+}
+
+// NewLastSeenFilter creates a last-seen filter. The per-slice filters share the main
+// filter's parameters and live in companion keys in the same cluster slot.
+func NewLastSeenFilter(cfg Config, opts LastSeenOptions) (*LastSeenFilter, error) {
+	if opts.Slice <= 0 || opts.Retention < opts.Slice {
+		return nil, ErrInvalidLastSeenWindow
+	}
+	main, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !main.usesBitmap() {
+		return nil, ErrIncompatibleFilter
+	}
+	return &LastSeenFilter{main: main, opts: opts}, nil
+}
+
+// Add records the element in the main filter and the current time slice
+func (ls *LastSeenFilter) Add(data []byte) error {
+	if err := ls.main.Add(data); err != nil {
+		return err
+	}
+	return ls.slice(time.Now().Truncate(ls.opts.Slice)).Add(data)
+}
+
+// Exists checks if an element is in the main filter
+func (ls *LastSeenFilter) Exists(data []byte) (bool, error) {
+	return ls.main.Exists(data)
+}
+
+// LastSeen reports whether the element was seen and the start of the newest time slice
+// it was seen in. The time is zero when the element was seen before the retention period.
+func (ls *LastSeenFilter) LastSeen(data []byte) (seen bool, at time.Time, err error) {
+	ctx := context.Background()
+	positions := ls.main.getHashPositions(data)
+
+	newest := time.Now().Truncate(ls.opts.Slice)
+	slices := int(ls.opts.Retention / ls.opts.Slice)
+	if err := ls.main.limiter.wait(ctx, len(positions)*(slices+1)); err != nil {
+		return false, time.Time{}, err
+	}
+
+	pipe, err := ls.main.pipeline()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	defer ls.main.releasePipeline(pipe)
+
+	inMain := ls.main.queueCheckBits(ctx, pipe, positions)
+	inSlice := make([]func() bool, slices)
+	for i := range inSlice {
+		inSlice[i] = ls.slice(newest.Add(-time.Duration(i)*ls.opts.Slice)).queueCheckBits(ctx, pipe, positions)
+	}
+	if err := execPipeline(ctx, pipe); err != nil {
+		return false, time.Time{}, err
+	}
+
+	if !inMain() {
+		return false, time.Time{}, nil
+	}
+	for i, found := range inSlice {
+		if found() {
+			return true, newest.Add(-time.Duration(i) * ls.opts.Slice), nil
+		}
+	}
+	return true, time.Time{}, nil
+}
+
+// slice returns the filter of the time slice starting at start
+func (ls *LastSeenFilter) slice(start time.Time) *bloomFilter {
+	key := companionKey(ls.main.config.RedisKey, lastSeenKeyPrefix+strconv.FormatInt(start.Unix(), 10))
+	f := ls.main.auxiliary(key)
+	f.config.TTL = ls.opts.Retention + ls.opts.Slice
+	f.cache = nil
+	return f
+}
@@ -0,0 +1,59 @@
+package bloom
+
+// BitLayout maps a logical bit index of the filter to the SETBIT/GETBIT offset it is
+// stored at in Redis. Choosing the layout used by another writer lets filters built with
+// client-side bitmap libraries be queried in place.
+type BitLayout int
+
+const (
+	// BitLayoutMSBFirst stores bit 0 in the most significant bit of byte 0,
+	// which is the native Redis SETBIT convention and the default
+	BitLayoutMSBFirst BitLayout = iota
+	// BitLayoutLSBFirst stores bit 0 in the least significant bit of byte 0, as used by
+	// little-endian word arrays, Python bitarray(endian='little') and Java BitSet.toByteArray
+	BitLayoutLSBFirst
+	// BitLayoutBigEndian64 packs bits least significant first into 64-bit words that are
+	// stored big-endian, as produced by writing a Java long[] with DataOutputStream
+	BitLayoutBigEndian64
+)
+
+// Offset returns the Redis bit offset of the logical bit index.
+// Every layout is its own inverse, so Offset also maps an offset back to the logical index.
+func (l BitLayout) Offset(pos uint64) uint64 {
+	switch l {
+	case BitLayoutLSBFirst:
+		return pos&^7 | (7 - pos&7)
+	case BitLayoutBigEndian64:
+		word, bit := pos/64, pos%64
+		return (word*8+7-bit/8)*8 + (7 - bit%8)
+	default:
+		return pos
+	}
+}
+
+// byteSize returns the number of bytes needed to store bitSize logical bits
+func (l BitLayout) byteSize(bitSize uint64) uint64 {
+	if l == BitLayoutBigEndian64 {
+		return (bitSize + 63) / 64 * 8
+	}
+	return (bitSize + 7) / 8
+}
+
+// convertBitmap re-encodes a bitmap from one bit layout to another. The result is
+// rounded up to whole 64-bit words so every layout fits; callers trim as needed.
+func convertBitmap(src []byte, from, to BitLayout) []byte {
+	dst := make([]byte, (len(src)+7)/8*8)
+	if from == to {
+		copy(dst, src)
+		return dst
+	}
+	for i, b := range src {
+		for j := uint64(0); b != 0; j++ {
+			if b&0x80 != 0 {
+				setBitmapBit(dst, to.Offset(from.Offset(uint64(i)*8+j)))
+			}
+			b <<= 1
+		}
+	}
+	return dst
+}
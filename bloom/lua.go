@@ -0,0 +1,115 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// luaAddScript sets bits of KEYS[1]. ARGV[2] is the number n of field/value pairs that
+// follow, stored with HSETNX in the metadata hash KEYS[2], and the offsets come after
+// them. The TTL in ARGV[1] is applied to both keys as described for luaExpireSource.
+var luaAddScript = redis.NewScript(luaAddSource)
+
+// luaAddSource is the body of luaAddScript, shared with the function library
+const luaAddSource = `
+local first = 3 + 2 * tonumber(ARGV[2])
+for i = 3, first - 1, 2 do
+	redis.call('HSETNX', KEYS[2], ARGV[i], ARGV[i + 1])
+end
+for i = first, #ARGV do
+	redis.call('SETBIT', KEYS[1], ARGV[i], 1)
+end
+` + luaExpireSource + `
+return 1
+`
+
+// luaExistsScript checks several items against KEYS[1]. ARGV holds, for every item, its
+// number of offsets followed by the offsets; the reply has 1 for each item whose bits
+// are all set and 0 otherwise.
+var luaExistsScript = redis.NewScript(luaExistsSource)
+
+// luaExistsSource is the body of luaExistsScript, shared with the function library
+const luaExistsSource = `
+local results = {}
+local i = 1
+while i <= #ARGV do
+	local n = tonumber(ARGV[i])
+	local found = 1
+	for j = i + 1, i + n do
+		if redis.call('GETBIT', KEYS[1], ARGV[j]) == 0 then
+			found = 0
+			break
+		end
+	end
+	results[#results + 1] = found
+	i = i + n + 1
+end
+return results
+`
+
+// setBitsLua sets the bits at the given positions, records the creation time and
+// parameters and applies the TTL in a single script call
+func (bf *bloomFilter) setBitsLua(ctx context.Context, positions []uint64) error {
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+
+	var fields []interface{}
+	start := time.Now()
+	if !bf.creationRecorded() {
+		fields = bf.creationFields(start)
+	}
+	args := make([]interface{}, 2, 2+len(fields)+len(positions))
+	args[0] = bf.scriptTTL()
+	args[1] = len(fields) / 2
+	args = append(args, fields...)
+	for _, pos := range positions {
+		args = append(args, bf.offset(pos))
+	}
+	keys := []string{bf.config.RedisKey, metadataKey(bf.config.RedisKey)}
+	if err := bf.runScript(ctx, client, luaAddScript, functionAdd, keys, args...).Err(); err != nil {
+		return err
+	}
+	if fields != nil {
+		// The script refreshed the TTL, unless it only applies to keys without one
+		if bf.config.TTLMode != TTLFixed {
+			atomic.StoreInt64(&bf.metadataExpiry, start.Add(bf.config.TTL).UnixNano())
+		}
+		atomic.StoreUint32(&bf.metadataRecorded, 1)
+	}
+	return nil
+}
+
+// checkItemsLua checks the positions of several items in a single script call
+func (bf *bloomFilter) checkItemsLua(ctx context.Context, positions [][]uint64) ([]bool, error) {
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	var args []interface{}
+	for _, p := range positions {
+		args = append(args, len(p))
+		for _, pos := range p {
+			args = append(args, bf.offset(pos))
+		}
+	}
+	replies, err := bf.runScript(ctx, client, luaExistsScript, functionExists, []string{bf.config.RedisKey}, args...).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(replies) != len(positions) {
+		return nil, fmt.Errorf("exists script returned %d results for %d items", len(replies), len(positions))
+	}
+
+	results := make([]bool, len(replies))
+	for i, reply := range replies {
+		results[i] = reply == 1
+	}
+	return results, nil
+}
@@ -0,0 +1,149 @@
+package bloom
+
+import "context"
+
+// AddMany adds several elements with a single pipeline for all of their bits, instead of
+// one round trip per element
+func (bf *bloomFilter) AddMany(items [][]byte) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return bf.addItems(context.Background(), items)
+}
+
+// ExistsMany checks several elements with a single pipeline and returns one answer per
+// element, in order. Answers in the result cache are used and new answers are cached.
+func (bf *bloomFilter) ExistsMany(items [][]byte) ([]bool, error) {
+	return bf.existsMany(context.Background(), items, true)
+}
+
+// existsMany looks items up like ExistsMany; slide selects whether the lookup extends a
+// sliding TTL
+func (bf *bloomFilter) existsMany(ctx context.Context, items [][]byte, slide bool) ([]bool, error) {
+	results := make([]bool, len(items))
+	var keys []string
+	pending := make([]int, 0, len(items))
+	if bf.cache != nil {
+		bf.validateCache(ctx)
+		keys = make([]string, len(items))
+	}
+	for i, data := range items {
+		if bf.cache != nil {
+			keys[i] = bf.cache.key(data)
+			if exists, ok := bf.cache.get(keys[i]); ok {
+				bf.metrics.IncCounter(MetricCacheHits, 1)
+				results[i] = exists
+				continue
+			}
+			bf.metrics.IncCounter(MetricCacheMisses, 1)
+		}
+		pending = append(pending, i)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	lookup := make([][]byte, len(pending))
+	for j, i := range pending {
+		lookup[j] = items[i]
+	}
+	found, err := bf.checkItems(ctx, lookup)
+	if err != nil {
+		// Answer pending items from the local fallback or the circuit breaker, uncached
+		for j, i := range pending {
+			var positions []uint64
+			if bf.fallback != nil {
+				positions = bf.getHashPositions(lookup[j])
+			}
+			exists, err := bf.lookupFallback(err, positions)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = exists
+		}
+		return results, nil
+	}
+	for j, i := range pending {
+		results[i] = found[j]
+		if bf.cache != nil {
+			bf.cache.set(keys[i], found[j])
+		}
+	}
+	if !slide {
+		return results, nil
+	}
+	return results, bf.slideTTL(ctx)
+}
+
+// checkItems checks the bits of several items in one pipeline
+func (bf *bloomFilter) checkItems(ctx context.Context, items [][]byte) ([]bool, error) {
+	if !bf.usesBitmap() {
+		return bf.checkModuleItems(ctx, items)
+	}
+
+	positions := make([][]uint64, len(items))
+	var commands int
+	for i, data := range items {
+		positions[i] = bf.getHashPositions(data)
+		commands += len(positions[i])
+	}
+	if err := bf.limiter.wait(ctx, bf.bitCommands(commands)); err != nil {
+		return nil, err
+	}
+	var results []bool
+	err := bf.call(ctx, func() error {
+		var err error
+		results, err = bf.readPositions(ctx, positions)
+		return err
+	})
+	return results, err
+}
+
+// readPositions checks the bits of several items with the configured engine, pipelining
+// the reads for the plain bitmap engine
+func (bf *bloomFilter) readPositions(ctx context.Context, positions [][]uint64) ([]bool, error) {
+	switch bf.config.Engine {
+	case EngineBitfield:
+		return bf.checkItemsBitfield(ctx, positions)
+	case EngineLua, EngineFunctions:
+		return bf.checkItemsLua(ctx, positions)
+	}
+
+	pipe, err := bf.pipeline()
+	if err != nil {
+		return nil, err
+	}
+	defer bf.releasePipeline(pipe)
+	checks := make([]func() bool, len(positions))
+	for i := range positions {
+		checks[i] = bf.queueCheckBits(ctx, pipe, positions[i])
+	}
+	if err := execPipeline(ctx, pipe); err != nil {
+		return nil, err
+	}
+
+	results := make([]bool, len(positions))
+	for i, check := range checks {
+		results[i] = check()
+	}
+	return results, nil
+}
+
+// checkItemsBitfield checks the bits of several items with a single BITFIELD command
+func (bf *bloomFilter) checkItemsBitfield(ctx context.Context, positions [][]uint64) ([]bool, error) {
+	var all []uint64
+	for _, p := range positions {
+		all = append(all, p...)
+	}
+	bits, err := bf.getBitfield(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]bool, len(positions))
+	for i, p := range positions {
+		results[i] = allOnes(bits[:len(p)])
+		bits = bits[len(p):]
+	}
+	return results, nil
+}
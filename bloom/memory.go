@@ -0,0 +1,176 @@
+package bloom
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryClient is a RedisClient that keeps bitmaps in process memory. It serves tests,
+// local tools and embedded deployments that do not need Redis; features that require the
+// full command set (audit, admission, metadata) are unavailable.
+type MemoryClient struct {
+	mu      sync.Mutex
+	bitmaps map[string][]byte
+	expires map[string]time.Time
+}
+
+var _ RedisClient = (*MemoryClient)(nil)
+
+// NewMemoryRedisClient creates an empty in-memory client
+func NewMemoryRedisClient() *MemoryClient {
+	return &MemoryClient{bitmaps: make(map[string][]byte), expires: make(map[string]time.Time)}
+}
+
+// SetBit sets a bit at the specified offset
+func (mc *MemoryClient) SetBit(ctx context.Context, key string, offset int64, value int) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "setbit", key, offset, value)
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.setBit(cmd, key, offset, value)
+	return cmd
+}
+
+// GetBit gets a bit at the specified offset
+func (mc *MemoryClient) GetBit(ctx context.Context, key string, offset int64) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "getbit", key, offset)
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.getBit(cmd, key, offset)
+	return cmd
+}
+
+// Expire sets a timeout on the key
+func (mc *MemoryClient) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx, "expire", key, expiration)
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.expire(cmd, key, expiration)
+	return cmd
+}
+
+// Pipeline returns a pipeline that applies its commands under a single lock on Exec
+func (mc *MemoryClient) Pipeline() Pipeliner {
+	return &memoryPipeline{client: mc}
+}
+
+// Bytes returns a copy of the bitmap stored under key, or nil if there is none
+func (mc *MemoryClient) Bytes(key string) []byte {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	bitmap, _ := mc.bitmap(key)
+	return append([]byte(nil), bitmap...)
+}
+
+// Delete removes key
+func (mc *MemoryClient) Delete(key string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	delete(mc.bitmaps, key)
+	delete(mc.expires, key)
+}
+
+// bitmap returns the bitmap under key, dropping it first if it has expired
+func (mc *MemoryClient) bitmap(key string) ([]byte, bool) {
+	if deadline, ok := mc.expires[key]; ok && !time.Now().Before(deadline) {
+		delete(mc.bitmaps, key)
+		delete(mc.expires, key)
+	}
+	bitmap, ok := mc.bitmaps[key]
+	return bitmap, ok
+}
+
+// setBit applies a SETBIT, growing the bitmap like Redis does
+func (mc *MemoryClient) setBit(cmd *redis.IntCmd, key string, offset int64, value int) {
+	if offset < 0 || value&^1 != 0 {
+		cmd.SetErr(ErrInvalidOffset)
+		return
+	}
+	bitmap, _ := mc.bitmap(key)
+	if need := int(offset>>3) + 1; need > len(bitmap) {
+		bitmap = append(bitmap, make([]byte, need-len(bitmap))...)
+	}
+	mask := byte(0x80 >> (offset & 7))
+	if bitmap[offset>>3]&mask != 0 {
+		cmd.SetVal(1)
+	}
+	if value == 1 {
+		bitmap[offset>>3] |= mask
+	} else {
+		bitmap[offset>>3] &^= mask
+	}
+	mc.bitmaps[key] = bitmap
+}
+
+// expire applies an EXPIRE; like Redis, it does nothing for a missing key
+func (mc *MemoryClient) expire(cmd *redis.BoolCmd, key string, expiration time.Duration) {
+	if _, ok := mc.bitmap(key); ok {
+		mc.expires[key] = time.Now().Add(expiration)
+		cmd.SetVal(true)
+	}
+}
+
+// getBit applies a GETBIT; bits beyond the end of the bitmap read as zero
+func (mc *MemoryClient) getBit(cmd *redis.IntCmd, key string, offset int64) {
+	if offset < 0 {
+		cmd.SetErr(ErrInvalidOffset)
+		return
+	}
+	bitmap, _ := mc.bitmap(key)
+	if offset>>3 < int64(len(bitmap)) && bitmap[offset>>3]&(0x80>>(offset&7)) != 0 {
+		cmd.SetVal(1)
+	}
+}
+
+// memoryPipeline queues commands for a MemoryClient
+type memoryPipeline struct {
+	client *MemoryClient
+	cmds   []redis.Cmder
+	apply  []func()
+}
+
+// SetBit queues a SETBIT
+func (p *memoryPipeline) SetBit(ctx context.Context, key string, offset int64, value int) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "setbit", key, offset, value)
+	p.cmds = append(p.cmds, cmd)
+	p.apply = append(p.apply, func() { p.client.setBit(cmd, key, offset, value) })
+	return cmd
+}
+
+// GetBit queues a GETBIT
+func (p *memoryPipeline) GetBit(ctx context.Context, key string, offset int64) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "getbit", key, offset)
+	p.cmds = append(p.cmds, cmd)
+	p.apply = append(p.apply, func() { p.client.getBit(cmd, key, offset) })
+	return cmd
+}
+
+// Expire queues an EXPIRE
+func (p *memoryPipeline) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx, "expire", key, expiration)
+	p.cmds = append(p.cmds, cmd)
+	p.apply = append(p.apply, func() { p.client.expire(cmd, key, expiration) })
+	return cmd
+}
+
+// Exec applies the queued commands and empties the pipeline so it can be reused
+func (p *memoryPipeline) Exec(ctx context.Context) ([]redis.Cmder, error) {
+	cmds, apply := p.cmds, p.apply
+	p.cmds, p.apply = nil, nil
+	if err := ctx.Err(); err != nil {
+		return cmds, err
+	}
+
+	p.client.mu.Lock()
+	defer p.client.mu.Unlock()
+	var firstErr error
+	for i, fn := range apply {
+		fn()
+		if err := cmds[i].Err(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return cmds, firstErr
+}
@@ -0,0 +1,180 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Metadata key layout
+const (
+	metadataKeySuffix  = "meta"
+	metaFieldCreatedAt = "created_at"
+	metaFieldRebuiltAt = "rebuilt_at"
+	metaFieldBits      = "bits"
+	metaFieldHashes    = "hashes"
+	metaFieldHash      = "hash"
+	metaFieldVersion   = "version"
+)
+
+// metadata is the decoded content of a filter's metadata hash
+type metadata struct {
+	CreatedAt time.Time
+	RebuiltAt time.Time
+}
+
+// ParameterMismatchError is returned when a filter is constructed for a key whose
+// metadata records different parameters, or an import was exported by another filter
+type ParameterMismatchError struct {
+	Key string
+	// Field is the mismatching metadata field: "bits", "hashes" or "hash", and for
+	// imports also "positions", "seed" or "bit_order"
+	Field      string
+	Recorded   string
+	Configured string
+}
+
+func (e *ParameterMismatchError) Error() string {
+	return fmt.Sprintf("filter %s was created with %s %s, configured %s", e.Key, e.Field, e.Recorded, e.Configured)
+}
+
+// Unwrap returns ErrParameterMismatch
+func (e *ParameterMismatchError) Unwrap() error {
+	return ErrParameterMismatch
+}
+
+// metadataKey returns the name of the hash holding the filter's metadata
+func metadataKey(key string) string {
+	return companionKey(key, metadataKeySuffix)
+}
+
+// parameterFields returns the metadata field/value pairs describing how the filter
+// hashes, or nil for module filters, which hash items themselves
+func (bf *bloomFilter) parameterFields() []interface{} {
+	if !bf.usesBitmap() {
+		return nil
+	}
+	return []interface{}{
+		metaFieldBits, strconv.FormatUint(bf.bitSize, 10),
+		metaFieldHashes, strconv.FormatUint(uint64(bf.hashCount), 10),
+		metaFieldHash, hashStrategyName(bf.config.HashStrategy),
+		metaFieldVersion, Version,
+	}
+}
+
+// creationFields returns the field/value pairs stored when the filter is first written
+func (bf *bloomFilter) creationFields(at time.Time) []interface{} {
+	return append([]interface{}{metaFieldCreatedAt, formatTimestamp(at)}, bf.parameterFields()...)
+}
+
+// creationRecorded reports whether the creation time and parameters are known to be
+// stored. With a TTL they are only trusted until the metadata key may have expired
+// together with the filter; the next write then records them again for the recreated
+// filter.
+func (bf *bloomFilter) creationRecorded() bool {
+	if atomic.LoadUint32(&bf.metadataRecorded) == 0 {
+		return false
+	}
+	return bf.config.TTL <= 0 || time.Now().UnixNano() < atomic.LoadInt64(&bf.metadataExpiry)
+}
+
+// recordCreation stores the creation time and parameters the first time this filter
+// instance writes, and with a TTL again once the metadata may have expired. HSETNX keeps
+// the earliest values when several processes share the filter.
+func (bf *bloomFilter) recordCreation(ctx context.Context) error {
+	if bf.creationRecorded() {
+		return nil
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		// Metadata is optional for clients without the full command set
+		return nil
+	}
+
+	meta := metadataKey(bf.config.RedisKey)
+	start := time.Now()
+	fields := bf.creationFields(start)
+	var ttl *redis.DurationCmd
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := 0; i < len(fields); i += 2 {
+			pipe.HSetNX(ctx, meta, fields[i].(string), fields[i+1])
+		}
+		// The write pipeline may have expired the key before it existed
+		if bf.config.TTL > 0 {
+			bf.queueExpire(ctx, pipe, meta)
+			ttl = pipe.PTTL(ctx, meta)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if ttl != nil && ttl.Val() > 0 {
+		atomic.StoreInt64(&bf.metadataExpiry, start.Add(ttl.Val()).UnixNano())
+	}
+	atomic.StoreUint32(&bf.metadataRecorded, 1)
+	return nil
+}
+
+// checkParameters compares the parameters recorded in the metadata with the filter's
+// own, returning a *ParameterMismatchError on a difference. Keys without recorded
+// parameters pass, as do module filters and clients without the full command set.
+func (bf *bloomFilter) checkParameters(ctx context.Context) error {
+	fields := bf.parameterFields()
+	client, err := bf.cmdable()
+	if fields == nil || err != nil {
+		return nil
+	}
+
+	names := []string{metaFieldBits, metaFieldHashes, metaFieldHash}
+	recorded, err := client.HMGet(ctx, metadataKey(bf.config.RedisKey), names...).Result()
+	if err != nil {
+		return err
+	}
+	for i, name := range names {
+		value, ok := recorded[i].(string)
+		if ok && value != fields[2*i+1] {
+			return &ParameterMismatchError{
+				Key:        bf.config.RedisKey,
+				Field:      name,
+				Recorded:   value,
+				Configured: fields[2*i+1].(string),
+			}
+		}
+	}
+	return nil
+}
+
+// queueMarkRebuilt queues an update of the rebuild time on pipe
+func queueMarkRebuilt(ctx context.Context, pipe redis.Pipeliner, key string, at time.Time) {
+	meta := metadataKey(key)
+	now := formatTimestamp(at)
+	pipe.HSetNX(ctx, meta, metaFieldCreatedAt, now)
+	pipe.HSet(ctx, meta, metaFieldRebuiltAt, now)
+}
+
+// decodeMetadata decodes the fields of a metadata hash
+func decodeMetadata(fields map[string]string) *metadata {
+	return &metadata{
+		CreatedAt: parseTimestamp(fields[metaFieldCreatedAt]),
+		RebuiltAt: parseTimestamp(fields[metaFieldRebuiltAt]),
+	}
+}
+
+// formatTimestamp encodes a time as Unix milliseconds
+func formatTimestamp(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+// parseTimestamp decodes Unix milliseconds, returning the zero time for missing values
+func parseTimestamp(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
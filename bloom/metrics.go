@@ -0,0 +1,52 @@
+package bloom
+
+import "time"
+
+// Metric names emitted by the library
+const (
+	MetricProbeFalsePositiveRate = "bloom_probe_false_positive_rate"
+	MetricProbeErrors            = "bloom_probe_errors_total"
+	MetricDegraded               = "bloom_degraded"
+	MetricDegradedLookups        = "bloom_degraded_lookups_total"
+	MetricCacheHits              = "bloom_cache_hits_total"
+	MetricCacheMisses            = "bloom_cache_misses_total"
+	MetricPrefetchErrors         = "bloom_prefetch_errors_total"
+	MetricFillRatio              = "bloom_fill_ratio"
+	MetricAdmissionRejections    = "bloom_admission_rejections_total"
+	MetricAdmissionOverrides     = "bloom_admission_overrides_total"
+	MetricInsertRateBaseline     = "bloom_insert_rate_baseline"
+	MetricInsertRateAnomalies    = "bloom_insert_rate_anomalies_total"
+	MetricSaturationAlerts       = "bloom_saturation_alerts_total"
+	MetricSaturationErrors       = "bloom_saturation_sample_errors_total"
+	MetricRetries                = "bloom_retries_total"
+	MetricCircuitOpen            = "bloom_circuit_open"
+	MetricCircuitRejections      = "bloom_circuit_rejections_total"
+	MetricFallbackBuffered       = "bloom_fallback_buffered_items"
+	MetricFallbackReplayErrors   = "bloom_fallback_replay_errors_total"
+	MetricMirrorRefreshErrors    = "bloom_mirror_refresh_errors_total"
+	MetricWriteBehindQueued      = "bloom_write_behind_queued_items"
+	MetricWriteBehindErrors      = "bloom_write_behind_errors_total"
+	MetricWriteBehindQueueAge    = "bloom_write_behind_queue_age_seconds"
+	MetricWriteBehindPaused      = "bloom_write_behind_paused"
+	MetricAddHashDuration        = "bloom_add_hash_duration"
+	MetricAddPipelineDuration    = "bloom_add_pipeline_duration"
+	MetricAddRedisDuration       = "bloom_add_redis_duration"
+	MetricExistsHashDuration     = "bloom_exists_hash_duration"
+	MetricExistsPipelineDuration = "bloom_exists_pipeline_duration"
+	MetricExistsRedisDuration    = "bloom_exists_redis_duration"
+)
+
+// Metrics receives measurements emitted by the library so they can be
+// forwarded to Prometheus, StatsD or any other monitoring system
+type Metrics interface {
+	IncCounter(name string, delta int64)
+	SetGauge(name string, value float64)
+	ObserveDuration(name string, d time.Duration)
+}
+
+// noopMetrics discards all measurements
+type noopMetrics struct{}
+
+func (noopMetrics) IncCounter(string, int64)              {}
+func (noopMetrics) SetGauge(string, float64)              {}
+func (noopMetrics) ObserveDuration(string, time.Duration) {}
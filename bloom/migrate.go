@@ -0,0 +1,125 @@
+package bloom
+
+import "context"
+
+// Migration staging and checkpoint keys
+const (
+	migrateKeySuffix           = "migrate"
+	migrateCheckpointKeySuffix = "migrate:checkpoint"
+)
+
+// MigrateOptions configures MigrateEngine
+type MigrateOptions struct {
+	// Engine is the engine the filter is migrated to
+	Engine Engine
+	// Source yields every item of the filter, in the same order on every run so an
+	// interrupted migration can resume; nil replays the filter's audit stream, which
+	// must record raw items
+	Source Iterator
+	// BatchSize is the number of items read from the source per batch (defaults to 1000)
+	BatchSize int
+	// Workers is the number of batches written concurrently (defaults to 1)
+	Workers int
+	// Total is the expected number of items, used to estimate the remaining time; zero if unknown
+	Total int64
+	// Progress is called after every batch, one call at a time; nil disables progress reporting
+	Progress func(BulkLoadProgress)
+}
+
+// MigrateEngine moves the filter stored at cfg.RedisKey with cfg.Engine to opts.Engine
+// under the same key, e.g. from a bitmap to a RedisBloom module filter or back. The bits
+// of one cannot be translated into the other, as the module hashes items itself, so the
+// items are replayed from opts.Source or the audit stream into a staging key that is
+// renamed over the filter key in one transaction. Parameters are translated: a module
+// filter gets the bitmap's expected insertions and false-positive rate, and a bitmap is
+// sized for the larger of the configured and the module's current capacity, which grows
+// as the module filter scales. Options that choose bit positions are dropped for the
+// module. Progress is checkpointed in Redis, so calling MigrateEngine again after an
+// interruption resumes where it stopped. Items added during the migration are only
+// included if the source yields them; other processes must reopen the filter with the
+// new engine. Migrating between engines that share the bitmap storage changes nothing
+// in Redis.
+func MigrateEngine(ctx context.Context, cfg Config, opts MigrateOptions) (BloomFilter, error) {
+	current, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := current.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	targetCfg := cfg
+	targetCfg.Engine = opts.Engine
+	if opts.Engine == EngineRedisBloom {
+		// impliedSizing has filled in the sizing of explicit bit sizes and hash counts
+		targetCfg.ExpectedInsertions = current.config.ExpectedInsertions
+		targetCfg.FalsePositiveRate = current.config.FalsePositiveRate
+		targetCfg.BitSize, targetCfg.HashCount = 0, 0
+		targetCfg.HashStrategy, targetCfg.BitLayout = nil, BitLayoutMSBFirst
+		targetCfg.Blocked, targetCfg.Partitioned = false, false
+		targetCfg.Seed, targetCfg.Salt, targetCfg.KeySeeded = 0, "", false
+	}
+	target, err := newBloomFilter(targetCfg)
+	if err != nil {
+		return nil, err
+	}
+	if current.usesBitmap() == target.usesBitmap() {
+		if err := target.checkParameters(ctx); err != nil {
+			return nil, err
+		}
+		return target, nil
+	}
+	if !current.usesBitmap() {
+		info, err := client.BFInfo(ctx, cfg.RedisKey).Result()
+		if err != nil {
+			return nil, err
+		}
+		if capacity := uint64(info.Capacity); capacity > target.config.ExpectedInsertions {
+			targetCfg.ExpectedInsertions = capacity
+			if target, err = newBloomFilter(targetCfg); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	source := opts.Source
+	if source == nil {
+		if cfg.Audit == nil || !cfg.Audit.RawItems {
+			return nil, ErrAuditNotReplayable
+		}
+		source = NewAuditIterator(ctx, client, current.auditStream())
+	}
+
+	// A checkpoint means a previous run was interrupted and its staging key is kept
+	staging := target.auxiliary(companionKey(cfg.RedisKey, migrateKeySuffix))
+	staging.cache = nil
+	checkpoint := companionKey(cfg.RedisKey, migrateCheckpointKeySuffix)
+	resuming, err := client.Exists(ctx, checkpoint).Result()
+	if err != nil {
+		return nil, err
+	}
+	if resuming == 0 {
+		if err := client.Del(ctx, staging.config.RedisKey).Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	loaded, err := staging.BulkLoad(ctx, source, BulkLoadOptions{
+		BatchSize:     opts.BatchSize,
+		Workers:       opts.Workers,
+		Total:         opts.Total,
+		Progress:      opts.Progress,
+		CheckpointKey: checkpoint,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := target.swapIn(ctx, client, staging, loaded > 0); err != nil {
+		return nil, err
+	}
+	current.cache.invalidate()
+	target.metadataRecorded = 1
+	return target, nil
+}
@@ -0,0 +1,252 @@
+package bloom
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// WarmupOptions configures how a mirror downloads the bitmap
+type WarmupOptions struct {
+	// ChunkSize is the number of bytes read per GETRANGE (defaults to 1 MiB)
+	ChunkSize int
+	// BytesPerSecond caps the download rate; zero means unlimited
+	BytesPerSecond int64
+	// Progress is called after every chunk
+	Progress func(WarmupProgress)
+}
+
+// WarmupProgress reports the state of a warmup
+type WarmupProgress struct {
+	// Bytes is the number of bytes downloaded so far, including earlier interrupted warmups
+	Bytes int64
+	// Total is the size of the bitmap in bytes
+	Total   int64
+	Elapsed time.Duration
+}
+
+// MirroredFilter keeps a local copy of a filter's bitmap and answers Exists from it once
+// the copy is complete, so lookups cost no Redis round trip. Adds go to Redis and are
+// applied to the local copy; bits set by other writers appear after the next Warmup, or
+// the next download of Refresh.
+type MirroredFilter struct {
+	filter *bloomFilter
+	mu     sync.RWMutex
+	bitmap []byte
+	// loaded is the number of bytes downloaded; Warmup resumes from there
+	loaded int64
+	// complete is set once the whole bitmap has been downloaded
+	complete bool
+	// refreshing is set while a new copy is downloaded; added collects the positions
+	// added meanwhile, which are applied to the new copy before it is swapped in
+	refreshing bool
+	added      []uint64
+	// warmup serializes downloads
+	warmup sync.Mutex
+}
+
+// Default mirror refresh settings
+const defaultRefreshInterval = time.Minute
+
+// RefreshOptions configures periodic downloads of a mirror's bitmap
+type RefreshOptions struct {
+	// Interval is the time between the starts of two downloads (defaults to one minute)
+	Interval time.Duration
+	// Warmup configures each download
+	Warmup WarmupOptions
+	// OnError is called when a download fails; the next one starts after Interval
+	OnError func(error)
+}
+
+// NewMirroredFilter creates a mirrored filter. Until Warmup completes, Exists is answered
+// by Redis.
+func NewMirroredFilter(cfg Config) (*MirroredFilter, error) {
+	bf, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !bf.usesBitmap() {
+		return nil, ErrIncompatibleFilter
+	}
+	if _, err := bf.cmdable(); err != nil {
+		return nil, err
+	}
+	return &MirroredFilter{filter: bf, bitmap: make([]byte, cfg.BitLayout.byteSize(bf.bitSize))}, nil
+}
+
+// Warmup downloads the bitmap in chunks. If ctx is cancelled, the chunks downloaded so far
+// are kept and the next call resumes after them. Once the copy is complete, further calls
+// download the whole bitmap again into a new copy, which replaces the current one when
+// complete, so bits cleared in Redis are cleared locally too; Exists keeps answering from
+// the current copy meanwhile.
+func (m *MirroredFilter) Warmup(ctx context.Context, opts WarmupOptions) error {
+	client, err := m.filter.cmdable()
+	if err != nil {
+		return err
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = bitmapChunkSize
+	}
+	bucket := newTokenBucket(float64(opts.BytesPerSecond), chunkSize)
+
+	m.warmup.Lock()
+	defer m.warmup.Unlock()
+
+	m.mu.Lock()
+	total := int64(len(m.bitmap))
+	offset := m.loaded
+	var next []byte
+	if m.complete {
+		next = make([]byte, total)
+		offset = 0
+		m.refreshing, m.added = true, nil
+		defer func() {
+			m.mu.Lock()
+			m.refreshing, m.added = false, nil
+			m.mu.Unlock()
+		}()
+	}
+	m.mu.Unlock()
+
+	start := time.Now()
+	for offset < total {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := offset + int64(chunkSize)
+		if end > total {
+			end = total
+		}
+		if err := bucket.wait(ctx, int(end-offset)); err != nil {
+			return err
+		}
+		chunk, err := client.GetRange(ctx, m.filter.config.RedisKey, offset, end-1).Result()
+		if err != nil {
+			return err
+		}
+
+		if next != nil {
+			copy(next[offset:end], chunk)
+		} else {
+			// Merge rather than copy, so bits added locally during the download are kept
+			m.mu.Lock()
+			for i := 0; i < len(chunk); i++ {
+				m.bitmap[offset+int64(i)] |= chunk[i]
+			}
+			m.loaded = end
+			m.complete = m.complete || end == total
+			m.mu.Unlock()
+		}
+
+		offset = end
+		if opts.Progress != nil {
+			opts.Progress(WarmupProgress{Bytes: offset, Total: total, Elapsed: time.Since(start)})
+		}
+	}
+
+	if next != nil {
+		m.mu.Lock()
+		for _, pos := range m.added {
+			setBitmapBit(next, pos)
+		}
+		m.bitmap = next
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// Refresh runs Warmup at once and then every Interval until ctx is done, keeping the local
+// copy a recent snapshot of Redis. Failed downloads are counted in
+// bloom_mirror_refresh_errors_total and retried at the next interval. With
+// UpdateNotifications configured, it also subscribes to them: notified bits are set at
+// once, and other notifications start a download early. It returns ctx's error, or the
+// error of the subscription.
+func (m *MirroredFilter) Refresh(ctx context.Context, opts RefreshOptions) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	pubsub, err := m.subscribeUpdates(ctx)
+	if err != nil {
+		return err
+	}
+	var updates <-chan *redis.Message
+	if pubsub != nil {
+		defer pubsub.Close()
+		updates = pubsub.Channel()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	download := true
+	for {
+		if download {
+			if err := m.Warmup(ctx, opts.Warmup); err != nil && ctx.Err() == nil {
+				m.filter.metrics.IncCounter(MetricMirrorRefreshErrors, 1)
+				if opts.OnError != nil {
+					opts.OnError(err)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			download = true
+		case msg := <-updates:
+			download = !m.applyUpdate(msg.Payload)
+			// One download covers all notifications queued meanwhile
+			for download && len(updates) > 0 {
+				<-updates
+			}
+		}
+	}
+}
+
+// Ready reports whether the local copy is complete and answers Exists
+func (m *MirroredFilter) Ready() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.complete
+}
+
+// Add adds an element in Redis and to the local copy
+func (m *MirroredFilter) Add(data []byte) error {
+	if err := m.filter.Add(data); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setLocal(m.filter.Positions(data))
+	return nil
+}
+
+// setLocal sets bits in the local copy, and in the next one if a download is running; the
+// caller holds m.mu
+func (m *MirroredFilter) setLocal(offsets []uint64) {
+	for _, offset := range offsets {
+		setBitmapBit(m.bitmap, offset)
+	}
+	if m.refreshing {
+		m.added = append(m.added, offsets...)
+	}
+}
+
+// Exists checks the local copy when it is complete, or Redis otherwise
+func (m *MirroredFilter) Exists(data []byte) (bool, error) {
+	m.mu.RLock()
+	if !m.complete {
+		m.mu.RUnlock()
+		return m.filter.Exists(data)
+	}
+	defer m.mu.RUnlock()
+	for _, offset := range m.filter.Positions(data) {
+		if m.bitmap[offset>>3]&(0x80>>(offset&7)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,202 @@
+package bloom
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Move settings
+const (
+	defaultMoveTimeout = 5 * time.Second
+	moveChunkSuffix    = "move:chunk"
+	moveStagingSuffix  = "move:staging"
+)
+
+// MoveOptions configures how MoveTo relocates a filter
+type MoveOptions struct {
+	// DB is the database on the target instance
+	DB int
+	// Timeout is the MIGRATE idle timeout: the longest either server may stall during a
+	// transfer (defaults to 5s)
+	Timeout time.Duration
+	// Copy keeps the filter on the source instance
+	Copy bool
+	// Replace overwrites keys that already exist on the target
+	Replace bool
+	// Username and Password authenticate the source instance to the target
+	Username string
+	Password string
+	// ChunkSize moves bitmaps larger than this many bytes in chunks, so no single MIGRATE
+	// blocks either server for the whole transfer; zero moves the bitmap in one MIGRATE
+	ChunkSize int64
+	// Target is a client of the target instance and database, required to reassemble
+	// chunked bitmaps
+	Target redis.Cmdable
+}
+
+// cutChunkScript copies bytes ARGV[1]..ARGV[2] of KEYS[1] into KEYS[2] on the source
+var cutChunkScript = redis.NewScript(`
+redis.call('SET', KEYS[2], redis.call('GETRANGE', KEYS[1], ARGV[1], ARGV[2]))
+return 1
+`)
+
+// joinChunkScript writes the migrated chunk KEYS[2] at byte ARGV[1] of KEYS[1] on the
+// target and deletes the chunk
+var joinChunkScript = redis.NewScript(`
+redis.call('SETRANGE', KEYS[1], ARGV[1], redis.call('GET', KEYS[2]))
+redis.call('DEL', KEYS[2])
+return 1
+`)
+
+// MoveTo relocates the filter's keys to the Redis instance at targetAddr ("host:port")
+// with MIGRATE, so the data travels directly between the servers. The bitmap, metadata
+// and audit stream are moved; the filter keeps using the source instance afterwards.
+func (bf *bloomFilter) MoveTo(ctx context.Context, targetAddr string, opts MoveOptions) error {
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	host, port, err := net.SplitHostPort(targetAddr)
+	if err != nil {
+		return err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultMoveTimeout
+	}
+
+	key := bf.config.RedisKey
+	keys := []string{key, metadataKey(key)}
+	var separate []string
+	if bf.config.Audit != nil {
+		if stream := bf.auditStream(); KeySlot(stream) == KeySlot(key) {
+			keys = append(keys, stream)
+		} else {
+			separate = append(separate, stream)
+		}
+	}
+
+	// Module filters are not strings and always move whole
+	if opts.ChunkSize > 0 && bf.usesBitmap() {
+		size, err := client.StrLen(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if size > opts.ChunkSize {
+			if err := bf.moveChunked(ctx, client, host, port, size, opts); err != nil {
+				return err
+			}
+			keys = keys[1:]
+		}
+	}
+
+	if err := migrate(ctx, client, host, port, opts, keys...); err != nil {
+		return err
+	}
+	for _, k := range separate {
+		if err := migrate(ctx, client, host, port, opts, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveChunked moves the bitmap in chunks of opts.ChunkSize bytes: each chunk is cut into
+// a companion key on the source, migrated, and appended to a staging key on the target,
+// which finally replaces the filter key there
+func (bf *bloomFilter) moveChunked(ctx context.Context, client redis.Cmdable, host, port string, size int64, opts MoveOptions) error {
+	if opts.Target == nil {
+		return ErrMoveTargetRequired
+	}
+	key := bf.config.RedisKey
+	if !opts.Replace {
+		exists, err := opts.Target.Exists(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			return ErrTargetKeyExists
+		}
+	}
+	ttl, err := client.PTTL(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+
+	chunk, staging := companionKey(key, moveChunkSuffix), companionKey(key, moveStagingSuffix)
+	if err := opts.Target.Del(ctx, staging).Err(); err != nil {
+		return err
+	}
+	chunkOpts := opts
+	chunkOpts.Copy, chunkOpts.Replace = false, true
+	for offset := int64(0); offset < size; offset += opts.ChunkSize {
+		end := offset + opts.ChunkSize - 1
+		if err := cutChunkScript.Run(ctx, client, []string{key, chunk}, offset, end).Err(); err != nil {
+			return err
+		}
+		if err := migrate(ctx, client, host, port, chunkOpts, chunk); err != nil {
+			return err
+		}
+		if err := joinChunkScript.Run(ctx, opts.Target, []string{staging, chunk}, offset).Err(); err != nil {
+			return err
+		}
+	}
+
+	_, err = opts.Target.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Rename(ctx, staging, key)
+		if ttl > 0 {
+			pipe.PExpire(ctx, key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !opts.Copy {
+		return client.Del(ctx, key).Err()
+	}
+	return nil
+}
+
+// migrate moves keys to the target instance with a single MIGRATE. Keys missing on the
+// source are skipped.
+func migrate(ctx context.Context, client redis.Cmdable, host, port string, opts MoveOptions, keys ...string) error {
+	args := []interface{}{"migrate", host, port, "", opts.DB, opts.Timeout.Milliseconds()}
+	if opts.Copy {
+		args = append(args, "copy")
+	}
+	if opts.Replace {
+		args = append(args, "replace")
+	}
+	switch {
+	case opts.Username != "":
+		args = append(args, "auth2", opts.Username, opts.Password)
+	case opts.Password != "":
+		args = append(args, "auth", opts.Password)
+	}
+	args = append(args, "keys")
+	for _, k := range keys {
+		args = append(args, k)
+	}
+
+	// MIGRATE's key argument is empty, so a cluster client cannot route it by slot
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		node, err := cluster.MasterForKey(ctx, keys[0])
+		if err != nil {
+			return err
+		}
+		client = node
+	}
+
+	// A reply of NOKEY means none of the keys exist, which leaves nothing to move
+	if err := doCommand(ctx, client, args...).Err(); err != nil {
+		if strings.HasPrefix(err.Error(), "BUSYKEY") {
+			return ErrTargetKeyExists
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package bloom
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// updatesKeySuffix names the default update notification channel
+const updatesKeySuffix = "updates"
+
+// UpdateNotifications publishes a message on a Pub/Sub channel after every Add, so
+// mirrors refreshed with MirroredFilter.Refresh pick up new items promptly instead of
+// waiting for the next download. Mirrors created with the same setting subscribe to the
+// channel.
+//
+// A message is either empty, meaning "filter updated", or lists the Redis bit offsets the
+// Add set as space-separated decimal numbers. Mirrors download the bitmap again on empty
+// messages and set the listed bits directly otherwise.
+type UpdateNotifications struct {
+	// Channel is the Pub/Sub channel (defaults to a companion key of the filter)
+	Channel string
+	// Offsets lists the set bit offsets in every message; without it, messages are empty
+	Offsets bool
+}
+
+// subscriber is implemented by go-redis clients that support Pub/Sub
+type subscriber interface {
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// updatesChannel returns the channel of the filter's update notifications
+func (bf *bloomFilter) updatesChannel() string {
+	if bf.config.UpdateNotifications.Channel != "" {
+		return bf.config.UpdateNotifications.Channel
+	}
+	return companionKey(bf.config.RedisKey, updatesKeySuffix)
+}
+
+// publishUpdate announces that the bits at the given positions were set
+func (bf *bloomFilter) publishUpdate(ctx context.Context, positions []uint64) error {
+	if bf.config.UpdateNotifications == nil || !bf.usesBitmap() {
+		return nil
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	var msg string
+	if bf.config.UpdateNotifications.Offsets {
+		var b strings.Builder
+		for i, pos := range positions {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(strconv.FormatUint(bf.config.BitLayout.Offset(pos), 10))
+		}
+		msg = b.String()
+	}
+	return client.Publish(ctx, bf.updatesChannel(), msg).Err()
+}
+
+// publishItems announces that items were added
+func (bf *bloomFilter) publishItems(ctx context.Context, items ...[]byte) error {
+	if bf.config.UpdateNotifications == nil || !bf.usesBitmap() {
+		return nil
+	}
+	var positions []uint64
+	if bf.config.UpdateNotifications.Offsets {
+		for _, data := range items {
+			positions = append(positions, bf.getHashPositions(data)...)
+		}
+	}
+	return bf.publishUpdate(ctx, positions)
+}
+
+// parseUpdate returns the bit offsets listed in an update notification, or nil if it
+// lists none or cannot be parsed
+func parseUpdate(msg string) []uint64 {
+	fields := strings.Fields(msg)
+	offsets := make([]uint64, len(fields))
+	for i, field := range fields {
+		offset, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil
+		}
+		offsets[i] = offset
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+	return offsets
+}
+
+// subscribeUpdates subscribes to the filter's update notifications, returning nil if they
+// are disabled
+func (m *MirroredFilter) subscribeUpdates(ctx context.Context) (*redis.PubSub, error) {
+	if m.filter.config.UpdateNotifications == nil {
+		return nil, nil
+	}
+	client, err := m.filter.cmdable()
+	if err != nil {
+		return nil, err
+	}
+	sub, ok := client.(subscriber)
+	if !ok {
+		return nil, ErrPubSubUnsupported
+	}
+	pubsub := sub.Subscribe(ctx, m.filter.updatesChannel())
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	return pubsub, nil
+}
+
+// applyUpdate sets the bits listed in an update notification in the local copy and
+// reports whether it did; notifications without offsets need a download instead
+func (m *MirroredFilter) applyUpdate(msg string) bool {
+	offsets := parseUpdate(msg)
+	if offsets == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, offset := range offsets {
+		if offset>>3 >= uint64(len(m.bitmap)) {
+			return false
+		}
+	}
+	m.setLocal(offsets)
+	return true
+}
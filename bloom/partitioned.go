@@ -0,0 +1,62 @@
+package bloom
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// partitionAlign is the granularity of partition boundaries. Every bit layout maps a
+// 64-bit word onto itself, so each partition occupies whole bytes of the bitmap.
+const partitionAlign = 64
+
+// partitionedBitSize rounds a bit size up so it splits into hashCount aligned partitions
+func partitionedBitSize(bitSize uint64, hashCount uint) uint64 {
+	unit := uint64(hashCount) * partitionAlign
+	return (bitSize + unit - 1) / unit * unit
+}
+
+// partitionedPositions derives position i by double hashing within partition i
+func (bf *bloomFilter) partitionedPositions(data []byte) []uint64 {
+	h1 := bf.hashStrategy.Hash(data, 0)
+	h2 := bf.hashStrategy.Hash(data, 1) | 1
+	size := bf.bitSize / uint64(bf.hashCount)
+
+	positions := make([]uint64, bf.hashCount)
+	for i := uint(0); i < bf.hashCount; i++ {
+		positions[i] = uint64(i)*size + (h1+uint64(i)*h2)%size
+	}
+	return positions
+}
+
+// PartitionFill returns the share of set bits in each partition of a partitioned filter,
+// in hash order, measured with one BITCOUNT per partition in a single pipeline. In a
+// healthy filter all partitions fill at the same rate; one running ahead points at a
+// hash function that distributes the keys poorly.
+func PartitionFill(ctx context.Context, filter BloomFilter) ([]float64, error) {
+	bf, ok := filter.(*bloomFilter)
+	if !ok || !bf.config.Partitioned {
+		return nil, ErrIncompatibleFilter
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	size := bf.bitSize / uint64(bf.hashCount)
+	pipe := client.Pipeline()
+	counts := make([]*redis.IntCmd, bf.hashCount)
+	for i := range counts {
+		start := int64(uint64(i) * size / 8)
+		counts[i] = pipe.BitCount(ctx, bf.config.RedisKey, &redis.BitCount{Start: start, End: start + int64(size/8) - 1})
+	}
+	if err := execPipeline(ctx, pipe); err != nil {
+		return nil, err
+	}
+
+	fill := make([]float64, len(counts))
+	for i, count := range counts {
+		fill[i] = float64(count.Val()) / float64(size)
+	}
+	return fill, nil
+}
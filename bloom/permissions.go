@@ -0,0 +1,190 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PermissionCheck is the outcome of checking one command against the connected user's ACL
+type PermissionCheck struct {
+	// Feature is the part of the configuration that needs the command
+	Feature string
+	// Command is the command and arguments that were checked
+	Command []string
+	Allowed bool
+	// Reason explains why the command is denied
+	Reason string
+	// key is the key the command addresses
+	key string
+}
+
+// PermissionReport lists the commands the filter's configuration needs and whether the
+// connected user may run them
+type PermissionReport struct {
+	User   string
+	Checks []PermissionCheck
+}
+
+// Missing returns the checks of commands the user may not run
+func (r *PermissionReport) Missing() []PermissionCheck {
+	var missing []PermissionCheck
+	for _, check := range r.Checks {
+		if !check.Allowed {
+			missing = append(missing, check)
+		}
+	}
+	return missing
+}
+
+// Err returns ErrPermissionDenied describing every missing permission, or nil
+func (r *PermissionReport) Err() error {
+	missing := r.Missing()
+	if len(missing) == 0 {
+		return nil
+	}
+	reasons := make([]string, len(missing))
+	for i, check := range missing {
+		reasons[i] = fmt.Sprintf("%s (%s): %s", strings.ToUpper(check.Command[0]), check.Feature, check.Reason)
+	}
+	if r.User != "" {
+		return fmt.Errorf("%w for user %s: %s", ErrPermissionDenied, r.User, strings.Join(reasons, "; "))
+	}
+	return fmt.Errorf("%w: %s", ErrPermissionDenied, strings.Join(reasons, "; "))
+}
+
+// PermissionsCheck verifies that the connected user can run every command the configured
+// features need, against the filter's actual keys. The commands are queued in a MULTI
+// transaction that is discarded: Redis checks ACL permissions when queueing, so nothing
+// is executed and the user needs no administrative rights, only MULTI and DISCARD. User
+// is filled in when the user may run ACL WHOAMI.
+func (bf *bloomFilter) PermissionsCheck(ctx context.Context) (*PermissionReport, error) {
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+	report := &PermissionReport{}
+	report.User, _ = doCommand(ctx, client, "acl", "whoami").Text()
+
+	// Transactions are confined to one slot, so checks are queued per slot
+	checks := bf.requiredCommands()
+	bySlot := make(map[int][]int)
+	var slots []int
+	for i, check := range checks {
+		slot := KeySlot(check.key)
+		if _, ok := bySlot[slot]; !ok {
+			slots = append(slots, slot)
+		}
+		bySlot[slot] = append(bySlot[slot], i)
+	}
+	for _, slot := range slots {
+		if err := queuePermissionChecks(ctx, client, checks, bySlot[slot]); err != nil {
+			return nil, err
+		}
+	}
+	report.Checks = checks
+	return report, nil
+}
+
+// queuePermissionChecks queues the checks at the given indexes in a discarded transaction
+// and records which were accepted
+func queuePermissionChecks(ctx context.Context, client redis.Cmdable, checks []PermissionCheck, indexes []int) error {
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		node, err := cluster.MasterForKey(ctx, checks[indexes[0]].key)
+		if err != nil {
+			return err
+		}
+		client = node
+	}
+
+	pipe := client.Pipeline()
+	multi := pipe.Do(ctx, "multi")
+	cmds := make([]*redis.Cmd, len(indexes))
+	for n, i := range indexes {
+		args := make([]interface{}, len(checks[i].Command))
+		for j, arg := range checks[i].Command {
+			args[j] = arg
+		}
+		cmds[n] = pipe.Do(ctx, args...)
+	}
+	pipe.Do(ctx, "discard")
+	_, _ = pipe.Exec(ctx)
+	if err := multi.Err(); err != nil {
+		return err
+	}
+
+	for n, i := range indexes {
+		err := cmds[n].Err()
+		var replyErr redis.Error
+		switch {
+		case err == nil:
+			checks[i].Allowed = true
+		case errors.As(err, &replyErr):
+			checks[i].Reason = err.Error()
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// requiredCommands lists the commands the filter's configuration issues, with
+// representative arguments on the keys it uses
+func (bf *bloomFilter) requiredCommands() []PermissionCheck {
+	key, meta := bf.config.RedisKey, metadataKey(bf.config.RedisKey)
+	checks := []PermissionCheck{
+		{Feature: "Add", Command: []string{"setbit", key, "0", "1"}, key: key},
+		{Feature: "Exists", Command: []string{"getbit", key, "0"}, key: key},
+		{Feature: "metadata", Command: []string{"hsetnx", meta, metaFieldCreatedAt, "0"}, key: meta},
+		{Feature: "Stats", Command: []string{"hgetall", meta}, key: meta},
+	}
+	switch bf.config.Engine {
+	case EngineBitfield:
+		checks[0].Command = []string{"bitfield", key, "set", "u1", "0", "1"}
+		checks[1].Command = []string{"bitfield", key, "get", "u1", "0"}
+	case EngineLua:
+		checks[0].Command = []string{"evalsha", luaAddScript.Hash(), "2", key, meta, "0", ""}
+		checks[1].Command = []string{"evalsha", luaExistsScript.Hash(), "1", key}
+		checks = append(checks, PermissionCheck{Feature: "Engine", Command: []string{"script", "load", "return 1"}, key: key})
+	case EngineFunctions:
+		checks[0].Command = []string{"fcall", functionAdd, "2", key, meta, "0", ""}
+		checks[1].Command = []string{"fcall", functionExists, "1", key}
+		checks = append(checks,
+			PermissionCheck{Feature: "TestAndAdd", Command: []string{"fcall", functionTestAndAdd, "2", key, meta, "0"}, key: key},
+			PermissionCheck{Feature: "Engine", Command: []string{"function", "load", "replace", "#!lua name=" + functionLibraryName}, key: key})
+	case EngineRedisBloom:
+		checks = append([]PermissionCheck{
+			{Feature: "Engine", Command: []string{"bf.reserve", key, "0.01", "100"}, key: key},
+			{Feature: "Add", Command: []string{"bf.add", key, "0"}, key: key},
+			{Feature: "Exists", Command: []string{"bf.exists", key, "0"}, key: key},
+		}, checks[2:]...)
+	}
+	if bf.config.TTL > 0 {
+		checks = append(checks,
+			PermissionCheck{Feature: "TTL", Command: []string{"expire", key, "1"}, key: key},
+			PermissionCheck{Feature: "TTL", Command: []string{"expire", meta, "1"}, key: meta})
+	}
+	if bf.config.Blocked {
+		checks = append(checks, PermissionCheck{Feature: "Blocked", Command: []string{"getrange", key, "0", "63"}, key: key})
+	}
+	if bf.config.Audit != nil {
+		stream := bf.auditStream()
+		checks = append(checks, PermissionCheck{Feature: "Audit", Command: []string{"xadd", stream, "maxlen", "~", "1", "*", auditFieldHash, "0"}, key: stream})
+	}
+	if bf.config.Admission != nil {
+		checks = append(checks, PermissionCheck{Feature: "Admission", Command: []string{"bitcount", key}, key: key})
+	}
+	if bf.config.Capabilities.Supports(FeatureLua) && bf.usesBitmap() {
+		checks = append(checks,
+			PermissionCheck{Feature: "AddBatch", Command: []string{"evalsha", addBatchScript.Hash(), "2", key, meta, "0"}, key: key},
+			PermissionCheck{Feature: "AddBatch", Command: []string{"eval", "return 1", "2", key, meta}, key: key},
+			PermissionCheck{Feature: "AddBatchPartial", Command: []string{"evalsha", addBatchItemsScript.Hash(), "2", key, meta, "0"}, key: key})
+		if bf.config.Engine != EngineFunctions {
+			checks = append(checks, PermissionCheck{Feature: "TestAndAdd", Command: []string{"evalsha", testAndAddScript.Hash(), "2", key, meta, "0"}, key: key})
+		}
+	}
+	return checks
+}
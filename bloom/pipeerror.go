@@ -0,0 +1,119 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CommandResult is the outcome of one command of a pipeline
+type CommandResult struct {
+	// Op is the command name, such as "setbit"
+	Op string
+	// Key is the key the command addressed, and Slot its Redis Cluster hash slot
+	Key  string
+	Slot int
+	// Offset is the bit offset for SETBIT and GETBIT commands
+	Offset int64
+	// Err is the command's error, or nil if it succeeded
+	Err error
+}
+
+// PipelineError reports a pipeline in which at least one command failed, listing the
+// outcome of every command. Since bit writes are idempotent, a partially applied Add can
+// always be retried; Partial tells whether some of its bits were written.
+type PipelineError struct {
+	Commands []CommandResult
+}
+
+// Error summarizes the failed commands
+func (e *PipelineError) Error() string {
+	failed := e.Failed()
+	var b strings.Builder
+	fmt.Fprintf(&b, "pipeline: %d of %d commands failed", len(failed), len(e.Commands))
+	if len(failed) > 0 {
+		first := failed[0]
+		fmt.Fprintf(&b, " (first: %s %s offset %d slot %d: %v)", first.Op, first.Key, first.Offset, first.Slot, first.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns the distinct errors of the failed commands
+func (e *PipelineError) Unwrap() []error {
+	var errs []error
+	seen := make(map[string]bool)
+	for _, cmd := range e.Failed() {
+		if msg := cmd.Err.Error(); !seen[msg] {
+			seen[msg] = true
+			errs = append(errs, cmd.Err)
+		}
+	}
+	return errs
+}
+
+// Failed returns the commands that failed
+func (e *PipelineError) Failed() []CommandResult {
+	return e.filter(true)
+}
+
+// Succeeded returns the commands that succeeded
+func (e *PipelineError) Succeeded() []CommandResult {
+	return e.filter(false)
+}
+
+// Partial reports whether some commands succeeded and others failed
+func (e *PipelineError) Partial() bool {
+	failed := len(e.Failed())
+	return failed > 0 && failed < len(e.Commands)
+}
+
+// filter returns the commands that failed or succeeded
+func (e *PipelineError) filter(failed bool) []CommandResult {
+	var results []CommandResult
+	for _, cmd := range e.Commands {
+		if (cmd.Err != nil) == failed {
+			results = append(results, cmd)
+		}
+	}
+	return results
+}
+
+// execPipeline executes a pipeline and turns a failure into a PipelineError that
+// lists the outcome of every queued command. A failure that none of the commands
+// report, such as a refused connection, is returned as is.
+func execPipeline(ctx context.Context, pipe Pipeliner) error {
+	cmds, err := pipe.Exec(ctx)
+	if err == nil || len(cmds) == 0 {
+		return err
+	}
+	if pe := newPipelineError(cmds); len(pe.Failed()) > 0 {
+		return pe
+	}
+	return err
+}
+
+// newPipelineError describes the outcome of executed pipeline commands
+func newPipelineError(cmds []redis.Cmder) *PipelineError {
+	pe := &PipelineError{Commands: make([]CommandResult, len(cmds))}
+	for i, cmd := range cmds {
+		result := CommandResult{Op: cmd.Name(), Err: cmd.Err()}
+		if errors.Is(result.Err, redis.Nil) {
+			result.Err = nil
+		}
+		args := cmd.Args()
+		if len(args) > 1 {
+			result.Key = fmt.Sprint(args[1])
+			result.Slot = KeySlot(result.Key)
+		}
+		if len(args) > 2 && (result.Op == "setbit" || result.Op == "getbit") {
+			if offset, ok := args[2].(int64); ok {
+				result.Offset = offset
+			}
+		}
+		pe.Commands[i] = result
+	}
+	return pe
+}
@@ -0,0 +1,34 @@
+package bloom
+
+import "sync"
+
+// pipelinePool keeps executed pipelines for reuse. go-redis pipelines are empty again
+// after Exec, so reusing them saves an allocation per call at high operation rates.
+type pipelinePool struct {
+	pool sync.Pool
+}
+
+// newPipelinePool creates a pool when pipeline reuse is enabled, or returns nil
+func newPipelinePool(enabled bool) *pipelinePool {
+	if !enabled {
+		return nil
+	}
+	return &pipelinePool{}
+}
+
+// get returns a pooled pipeline, or nil if the pool is empty or disabled
+func (p *pipelinePool) get() Pipeliner {
+	if p == nil {
+		return nil
+	}
+	pipe, _ := p.pool.Get().(Pipeliner)
+	return pipe
+}
+
+// put returns an executed pipeline to the pool
+func (p *pipelinePool) put(pipe Pipeliner) {
+	if p == nil {
+		return
+	}
+	p.pool.Put(pipe)
+}
@@ -0,0 +1,202 @@
+package bloom
+
+import (
+	"context"
+	"time"
+)
+
+// Decision is the outcome of evaluating an item against a Policy
+type Decision int
+
+const (
+	// DecisionNoMatch means the item is in neither list
+	DecisionNoMatch Decision = iota
+	// DecisionAllow means the item is allowed
+	DecisionAllow
+	// DecisionDeny means the item is denied
+	DecisionDeny
+)
+
+// String returns the name of the decision
+func (d Decision) String() string {
+	switch d {
+	case DecisionAllow:
+		return "allow"
+	case DecisionDeny:
+		return "deny"
+	default:
+		return "no-match"
+	}
+}
+
+// Precedence decides which list wins when an item matches both
+type Precedence int
+
+const (
+	// DenyOverridesAllow denies items present in both lists
+	DenyOverridesAllow Precedence = iota
+	// AllowOverridesDeny allows items present in both lists
+	AllowOverridesDeny
+)
+
+// Policy combines an allowlist and a denylist filter with a defined precedence.
+// When both filters were created by this package and share a RedisClient, an item
+// missing from their result caches is evaluated against both lists in one pipeline.
+type Policy struct {
+	allow      BloomFilter
+	deny       BloomFilter
+	precedence Precedence
+}
+
+// NewPolicy creates a policy from an allowlist and a denylist filter
+func NewPolicy(allow, deny BloomFilter, precedence Precedence) (*Policy, error) {
+	if allow == nil || deny == nil {
+		return nil, ErrNilFilter
+	}
+	return &Policy{allow: allow, deny: deny, precedence: precedence}, nil
+}
+
+// Evaluate checks the item against both lists and applies the precedence
+func (p *Policy) Evaluate(data []byte) (Decision, error) {
+	allowed, denied, err := p.lookup(data)
+	if err != nil {
+		return DecisionNoMatch, err
+	}
+
+	switch {
+	case allowed && denied:
+		if p.precedence == AllowOverridesDeny {
+			return DecisionAllow, nil
+		}
+		return DecisionDeny, nil
+	case denied:
+		return DecisionDeny, nil
+	case allowed:
+		return DecisionAllow, nil
+	default:
+		return DecisionNoMatch, nil
+	}
+}
+
+// lookup checks membership in both lists. Bitmap filters of this package sharing a
+// client consult their caches first and read the remaining bits in one pipeline; other
+// filters, and both lists after a failed pipeline, are looked up one after the other
+// like Exists. Neither extends a sliding TTL.
+func (p *Policy) lookup(data []byte) (bool, bool, error) {
+	ctx := context.Background()
+	allow, okAllow := p.allow.(*bloomFilter)
+	deny, okDeny := p.deny.(*bloomFilter)
+	if okAllow && okDeny && sharesPipeline(allow, deny) {
+		if allowed, denied, ok := sharedLookup(ctx, allow, deny, data); ok {
+			return allowed, denied, nil
+		}
+	}
+
+	allowed, err := policyLookup(ctx, p.allow, data)
+	if err != nil {
+		return false, false, err
+	}
+	denied, err := policyLookup(ctx, p.deny, data)
+	return allowed, denied, err
+}
+
+// sharesPipeline reports whether two filters can be read in one pipeline: both store
+// a bitmap on the same client, and neither is degraded to a prefix of its positions or
+// has a circuit that is not closed
+func sharesPipeline(a, b *bloomFilter) bool {
+	return a.usesBitmap() && b.usesBitmap() &&
+		sameClient(a.config.RedisClient, b.config.RedisClient) &&
+		a.degrader.limit(a.hashCount) >= a.hashCount && b.degrader.limit(b.hashCount) >= b.hashCount &&
+		a.circuit.closed() && b.circuit.closed()
+}
+
+// sharedLookup answers both lists from their caches and one pipeline for the rest,
+// reporting false if the pipeline failed, so the lists are looked up on their own
+// with their retries, circuit breaker and fallback
+func sharedLookup(ctx context.Context, allow, deny *bloomFilter, data []byte) (bool, bool, bool) {
+	filters := [2]*bloomFilter{allow, deny}
+	var found, cached [2]bool
+	var keys [2]string
+	var positions [2][]uint64
+	for i, bf := range filters {
+		found[i], cached[i], keys[i] = cachedLookup(ctx, bf, data)
+		if !cached[i] {
+			positions[i] = bf.getHashPositions(data)
+			if err := bf.limiter.wait(ctx, len(positions[i])); err != nil {
+				return false, false, false
+			}
+		}
+	}
+	if cached[0] && cached[1] {
+		return found[0], found[1], true
+	}
+
+	pipe, err := allow.pipeline()
+	if err != nil {
+		return false, false, false
+	}
+	defer allow.releasePipeline(pipe)
+	var allSet [2]func() bool
+	for i, bf := range filters {
+		if !cached[i] {
+			allSet[i] = bf.queueCheckBits(ctx, pipe, positions[i])
+		}
+	}
+	start := time.Now()
+	err = execPipeline(ctx, pipe)
+	for i, bf := range filters {
+		if !cached[i] {
+			bf.observeLatency(time.Since(start))
+		}
+	}
+	if err != nil {
+		return false, false, false
+	}
+
+	for i, bf := range filters {
+		if cached[i] {
+			continue
+		}
+		bf.circuit.record(nil)
+		bf.replayFallback()
+		found[i] = allSet[i]()
+		if bf.cache != nil {
+			bf.cache.set(keys[i], found[i])
+		}
+	}
+	return found[0], found[1], true
+}
+
+// cachedLookup consults the result cache of bf, returning the cached answer, whether
+// there was one and the key to cache a fetched answer under
+func cachedLookup(ctx context.Context, bf *bloomFilter, data []byte) (bool, bool, string) {
+	if bf.cache == nil {
+		return false, false, ""
+	}
+	bf.validateCache(ctx)
+	key := bf.cache.key(data)
+	if exists, ok := bf.cache.get(key); ok {
+		bf.metrics.IncCounter(MetricCacheHits, 1)
+		return exists, true, key
+	}
+	bf.metrics.IncCounter(MetricCacheMisses, 1)
+	return false, false, key
+}
+
+// policyLookup checks data in one list
+func policyLookup(ctx context.Context, filter BloomFilter, data []byte) (bool, error) {
+	if bf, ok := filter.(*bloomFilter); ok {
+		return bf.exists(ctx, data, false)
+	}
+	return filter.Exists(data)
+}
+
+// sameClient reports whether two clients talk to the same go-redis client
+func sameClient(a, b RedisClient) bool {
+	if a == b {
+		return true
+	}
+	pa, okA := a.(CmdableProvider)
+	pb, okB := b.(CmdableProvider)
+	return okA && okB && pa.Cmdable() == pb.Cmdable()
+}
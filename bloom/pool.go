@@ -0,0 +1,65 @@
+package bloom
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PoolOptions bounds a dedicated connection pool
+type PoolOptions struct {
+	// Size is the maximum number of connections (per node for clusters and rings)
+	Size int
+	// MinIdle is the number of idle connections kept open
+	MinIdle int
+	// Timeout is how long a command waits for a free connection (go-redis default if zero)
+	Timeout time.Duration
+}
+
+// DedicatedPoolClient is a RedisClient with its own connection pool, separate from the
+// application's general client, so heavy filter traffic cannot exhaust the connections
+// that latency-critical application queries need. Close it when the filters using it
+// are no longer needed.
+type DedicatedPoolClient struct {
+	*RedisAdapter
+	client redis.UniversalClient
+}
+
+// NewDedicatedPoolClient opens a client with the same connection settings as client but
+// its own bounded pool. Hooks installed on client are not copied; add them with AddHook.
+func NewDedicatedPoolClient(client redis.UniversalClient, opts PoolOptions) (*DedicatedPoolClient, error) {
+	var dedicated redis.UniversalClient
+	switch c := client.(type) {
+	case *redis.Client:
+		o := *c.Options()
+		o.PoolSize, o.MinIdleConns = opts.Size, opts.MinIdle
+		if opts.Timeout > 0 {
+			o.PoolTimeout = opts.Timeout
+		}
+		dedicated = redis.NewClient(&o)
+	case *redis.ClusterClient:
+		o := *c.Options()
+		o.PoolSize, o.MinIdleConns = opts.Size, opts.MinIdle
+		if opts.Timeout > 0 {
+			o.PoolTimeout = opts.Timeout
+		}
+		dedicated = redis.NewClusterClient(&o)
+	case *redis.Ring:
+		o := *c.Options()
+		o.PoolSize, o.MinIdleConns = opts.Size, opts.MinIdle
+		if opts.Timeout > 0 {
+			o.PoolTimeout = opts.Timeout
+		}
+		dedicated = redis.NewRing(&o)
+	case nil:
+		return nil, ErrNilRedisClient
+	default:
+		return nil, ErrPoolUnsupported
+	}
+	return &DedicatedPoolClient{RedisAdapter: &RedisAdapter{client: dedicated}, client: dedicated}, nil
+}
+
+// Close closes the dedicated pool
+func (c *DedicatedPoolClient) Close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,47 @@
+package bloom
+
+import "context"
+
+// PrefetchExists resolves the membership of items in the background and stores the
+// answers in the result cache, so Exists calls that follow shortly after are answered
+// locally. It returns immediately; answers that fail to resolve are counted in
+// bloom_prefetch_errors_total and looked up again by Exists. Negative answers are only
+// kept when ResultCache.NegativeTTL is set.
+func (bf *bloomFilter) PrefetchExists(items [][]byte) error {
+	if bf.cache == nil {
+		return ErrCacheDisabled
+	}
+
+	// Skip items that are already cached
+	keys := make([]string, 0, len(items))
+	pending := make([][]byte, 0, len(items))
+	for _, data := range items {
+		key := bf.cache.key(data)
+		if _, ok := bf.cache.get(key); !ok {
+			keys = append(keys, key)
+			pending = append(pending, data)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	go func() {
+		if err := bf.prefetch(context.Background(), keys, pending); err != nil {
+			bf.metrics.IncCounter(MetricPrefetchErrors, 1)
+		}
+	}()
+	return nil
+}
+
+// prefetch checks all items in one pipeline and caches the answers
+func (bf *bloomFilter) prefetch(ctx context.Context, keys []string, items [][]byte) error {
+	results, err := bf.checkItems(ctx, items)
+	if err != nil {
+		return err
+	}
+	for i, key := range keys {
+		bf.cache.set(key, results[i])
+	}
+	return nil
+}
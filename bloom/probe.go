@@ -0,0 +1,173 @@
+package bloom
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// Default live probe settings
+const (
+	defaultProbeInterval = time.Second
+	defaultProbeWindow   = 1000
+)
+
+// probeItemPrefix marks generated probe items; combined with 128 random bits
+// they are never inserted by real traffic
+const probeItemPrefix = "\x00bloom:fpr-probe:"
+
+// FPRProbeOptions configures a live false-positive rate probe
+type FPRProbeOptions struct {
+	// Interval between probe queries (defaults to one second)
+	Interval time.Duration
+	// Window is the number of most recent samples the rate is computed over (defaults to 1000)
+	Window int
+	// Metrics receives the measured rate as a gauge
+	Metrics Metrics
+}
+
+// FPRProbe periodically queries a filter with generated never-inserted values
+// and reports the fraction answered as present, which is the live false-positive rate.
+// A rate well above the configured target points at saturation or a hash/config bug.
+// Filters of this package are probed without touching their cache, degradation or
+// sliding TTL; other implementations are probed with Exists.
+type FPRProbe struct {
+	filter  BloomFilter
+	opts    FPRProbeOptions
+	mu      sync.Mutex
+	samples []bool
+	next    int
+	count   int
+	hits    int
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewFPRProbe creates a probe for the given filter; call Start to begin sampling
+func NewFPRProbe(filter BloomFilter, opts FPRProbeOptions) *FPRProbe {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultProbeInterval
+	}
+	if opts.Window <= 0 {
+		opts.Window = defaultProbeWindow
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = noopMetrics{}
+	}
+	return &FPRProbe{
+		filter:  filter,
+		opts:    opts,
+		samples: make([]bool, opts.Window),
+	}
+}
+
+// Start launches the background sampling loop; it runs until Stop is called or ctx is done
+func (p *FPRProbe) Start(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		return
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	go p.run(ctx, p.done)
+}
+
+// Stop halts sampling and waits for the loop to exit
+func (p *FPRProbe) Stop() {
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	p.cancel, p.done = nil, nil
+	p.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Rate returns the measured false-positive rate and the number of samples it is based on
+func (p *FPRProbe) Rate() (float64, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.count == 0 {
+		return 0, 0
+	}
+	return float64(p.hits) / float64(p.count), p.count
+}
+
+// run samples the filter once per interval
+func (p *FPRProbe) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.sample()
+		}
+	}
+}
+
+// sample queries one generated item and records the answer
+func (p *FPRProbe) sample() {
+	item := make([]byte, len(probeItemPrefix)+16)
+	copy(item, probeItemPrefix)
+	if _, err := rand.Read(item[len(probeItemPrefix):]); err != nil {
+		p.opts.Metrics.IncCounter(MetricProbeErrors, 1)
+		return
+	}
+
+	var exists bool
+	var err error
+	if bf, ok := p.filter.(*bloomFilter); ok {
+		exists, err = bf.probe(context.Background(), item)
+	} else {
+		exists, err = p.filter.Exists(item)
+	}
+	if err != nil {
+		p.opts.Metrics.IncCounter(MetricProbeErrors, 1)
+		return
+	}
+
+	rate, _ := p.record(exists)
+	p.opts.Metrics.SetGauge(MetricProbeFalsePositiveRate, rate)
+}
+
+// probe checks a probe item without the side effects of Exists: it bypasses the result
+// cache, which would fill with items that are never queried again, is not truncated by
+// and does not feed the degradation of slow lookups, and does not slide the TTL
+func (bf *bloomFilter) probe(ctx context.Context, data []byte) (bool, error) {
+	found, err := bf.checkItems(ctx, [][]byte{data})
+	if err != nil {
+		return false, err
+	}
+	return found[0], nil
+}
+
+// record adds a sample to the sliding window
+func (p *FPRProbe) record(hit bool) (float64, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.count == len(p.samples) {
+		if p.samples[p.next] {
+			p.hits--
+		}
+	} else {
+		p.count++
+	}
+	p.samples[p.next] = hit
+	if hit {
+		p.hits++
+	}
+	p.next = (p.next + 1) % len(p.samples)
+
+	return float64(p.hits) / float64(p.count), p.count
+}
@@ -0,0 +1,237 @@
+package bloom
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// HashPybloom is the registered name of the pybloom-compatible hash strategy
+const HashPybloom = "pybloom"
+
+// pybloomHeaderSize is the size of pybloom's "<dQQQQ" file header
+const pybloomHeaderSize = 8 + 4*8
+
+func init() {
+	RegisterHashStrategy(HashPybloom, NewPybloomStrategy)
+}
+
+// pybloomStrategy reproduces the salted hashlib hashing and sliced bit layout of
+// pybloom / pybloom-live's BloomFilter: hash function i addresses bits
+// [i*bitsPerSlice, (i+1)*bitsPerSlice)
+type pybloomStrategy struct{}
+
+var _ PositionHasher = (*pybloomStrategy)(nil)
+
+// NewPybloomStrategy creates a hash strategy that computes the same bit indices as a
+// pybloom BloomFilter. Items must be passed as the UTF-8 bytes of the Python str.
+func NewPybloomStrategy() HashStrategy {
+	return &pybloomStrategy{}
+}
+
+// Hash returns the i-th raw 64-bit hash pybloom would derive for a very large filter
+func (p *pybloomStrategy) Hash(data []byte, i uint) uint64 {
+	hashes := pybloomHashes(data, i+1, 1<<63)
+	return hashes[i]
+}
+
+// Positions derives the sliced bit indices exactly as pybloom's make_hashfuncs does
+func (p *pybloomStrategy) Positions(data []byte, hashCount uint, bitSize uint64) []uint64 {
+	bitsPerSlice := bitSize / uint64(hashCount)
+	positions := pybloomHashes(data, hashCount, bitsPerSlice)
+	for i := range positions {
+		positions[i] += uint64(i) * bitsPerSlice
+	}
+	return positions
+}
+
+// pybloomHashes returns one hash per slice, each reduced modulo bitsPerSlice
+func pybloomHashes(data []byte, numSlices uint, bitsPerSlice uint64) []uint64 {
+	chunkSize := 2
+	switch {
+	case bitsPerSlice >= 1<<31:
+		chunkSize = 8
+	case bitsPerSlice >= 1<<15:
+		chunkSize = 4
+	}
+
+	var newHash func() hash.Hash
+	switch totalBits := 8 * int(numSlices) * chunkSize; {
+	case totalBits > 384:
+		newHash = sha512.New
+	case totalBits > 256:
+		newHash = sha512.New384
+	case totalBits > 160:
+		newHash = sha256.New
+	case totalBits > 128:
+		newHash = sha1.New
+	default:
+		newHash = md5.New
+	}
+
+	hashes := make([]uint64, 0, numSlices)
+	var index [4]byte
+	for salt := uint32(0); uint(len(hashes)) < numSlices; salt++ {
+		// salt = hashfn(hashfn(pack('I', i)).digest()), then updated with the key
+		binary.LittleEndian.PutUint32(index[:], salt)
+		inner := newHash()
+		inner.Write(index[:])
+		h := newHash()
+		h.Write(inner.Sum(nil))
+		h.Write(data)
+		digest := h.Sum(nil)
+
+		for off := 0; off+chunkSize <= len(digest) && uint(len(hashes)) < numSlices; off += chunkSize {
+			var v uint64
+			switch chunkSize {
+			case 2:
+				v = uint64(binary.LittleEndian.Uint16(digest[off:]))
+			case 4:
+				v = uint64(binary.LittleEndian.Uint32(digest[off:]))
+			default:
+				v = binary.LittleEndian.Uint64(digest[off:])
+			}
+			hashes = append(hashes, v%bitsPerSlice)
+		}
+	}
+	return hashes
+}
+
+// PybloomFilter is a pybloom BloomFilter decoded from its tofile serialization
+type PybloomFilter struct {
+	ErrorRate    float64
+	NumSlices    uint64
+	BitsPerSlice uint64
+	Capacity     uint64
+	Count        uint64
+	// Bits holds the bit array in pybloom's little-endian bit order
+	Bits []byte
+}
+
+// BitSize returns the total number of bits in the filter
+func (p *PybloomFilter) BitSize() uint64 {
+	return p.NumSlices * p.BitsPerSlice
+}
+
+// DecodePybloom reads a filter written by pybloom's BloomFilter.tofile: a "<dQQQQ" header
+// (error rate, slices, bits per slice, capacity, count) followed by the bit array
+func DecodePybloom(r io.Reader) (*PybloomFilter, error) {
+	var header [pybloomHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidSerializedFilter, err)
+	}
+
+	p := &PybloomFilter{
+		ErrorRate:    math.Float64frombits(binary.LittleEndian.Uint64(header[0:])),
+		NumSlices:    binary.LittleEndian.Uint64(header[8:]),
+		BitsPerSlice: binary.LittleEndian.Uint64(header[16:]),
+		Capacity:     binary.LittleEndian.Uint64(header[24:]),
+		Count:        binary.LittleEndian.Uint64(header[32:]),
+	}
+	if p.NumSlices == 0 || p.BitsPerSlice == 0 || p.NumSlices > math.MaxUint8 {
+		return nil, fmt.Errorf("%w: %d slices of %d bits", ErrInvalidSerializedFilter, p.NumSlices, p.BitsPerSlice)
+	}
+
+	p.Bits = make([]byte, (p.BitSize()+7)/8)
+	if _, err := io.ReadFull(r, p.Bits); err != nil {
+		return nil, fmt.Errorf("%w: bit data: %v", ErrInvalidSerializedFilter, err)
+	}
+	return p, nil
+}
+
+// Encode writes the filter in pybloom's tofile format
+func (p *PybloomFilter) Encode(w io.Writer) error {
+	var header [pybloomHeaderSize]byte
+	binary.LittleEndian.PutUint64(header[0:], math.Float64bits(p.ErrorRate))
+	binary.LittleEndian.PutUint64(header[8:], p.NumSlices)
+	binary.LittleEndian.PutUint64(header[16:], p.BitsPerSlice)
+	binary.LittleEndian.PutUint64(header[24:], p.Capacity)
+	binary.LittleEndian.PutUint64(header[32:], p.Count)
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(p.Bits)
+	return err
+}
+
+// ImportPybloom decodes a pybloom serialization and stores its bits under cfg.RedisKey,
+// replacing any existing filter. The returned filter uses pybloom's hashing and sliced
+// layout, so it answers exactly like the Python filter; ExpectedInsertions,
+// FalsePositiveRate and HashStrategy in cfg are taken from the serialization. rbloom
+// files cannot be imported: rbloom hashes items with a user-supplied Python function.
+func ImportPybloom(ctx context.Context, r io.Reader, cfg Config) (BloomFilter, error) {
+	py, err := DecodePybloom(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ExpectedInsertions = py.Capacity
+	cfg.FalsePositiveRate = py.ErrorRate
+	cfg.HashStrategy = NewPybloomStrategy()
+	bf, err := buildBloomFilter(cfg, py.BitSize(), uint(py.NumSlices))
+	if err != nil {
+		return nil, err
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	// pybloom stores bits least significant first
+	bitmap := convertBitmap(py.Bits, BitLayoutLSBFirst, cfg.BitLayout)
+
+	if err := replaceBitmap(ctx, client, cfg.RedisKey, bitmap, cfg.TTL, bf.parameterFields()); err != nil {
+		return nil, err
+	}
+	bf.metadataRecorded = 1
+	return bf, nil
+}
+
+// ExportPybloom writes a filter that uses the pybloom strategy in pybloom's tofile format,
+// so it can be loaded in Python with BloomFilter.fromfile. The count field is estimated
+// from the number of set bits.
+func ExportPybloom(ctx context.Context, w io.Writer, filter BloomFilter) error {
+	bf, ok := filter.(*bloomFilter)
+	if !ok {
+		return ErrIncompatibleFilter
+	}
+	if _, ok := bf.hashStrategy.(*pybloomStrategy); !ok {
+		return ErrIncompatibleFilter
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+
+	raw, err := readBitmap(ctx, client, bf.config.RedisKey, int(bf.config.BitLayout.byteSize(bf.bitSize)))
+	if err != nil {
+		return err
+	}
+	bitmap := convertBitmap(raw, bf.config.BitLayout, BitLayoutLSBFirst)[:(bf.bitSize+7)/8]
+	setBits := 0
+	for _, b := range bitmap {
+		setBits += bits.OnesCount8(b)
+	}
+
+	py := &PybloomFilter{
+		ErrorRate:    bf.config.FalsePositiveRate,
+		NumSlices:    uint64(bf.hashCount),
+		BitsPerSlice: bf.bitSize / uint64(bf.hashCount),
+		Capacity:     bf.config.ExpectedInsertions,
+		Bits:         bitmap,
+	}
+	if fill := float64(setBits) / float64(bf.bitSize); fill < 1 {
+		py.Count = uint64(math.Round(-float64(bf.bitSize) / float64(bf.hashCount) * math.Log(1-fill)))
+	} else {
+		py.Count = py.Capacity
+	}
+	return py.Encode(w)
+}
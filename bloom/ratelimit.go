@@ -0,0 +1,100 @@
+package bloom
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit caps the Redis traffic a filter generates so a runaway batch job cannot
+// starve a shared Redis. Operations wait for capacity instead of failing.
+type RateLimit struct {
+	// OpsPerSecond limits filter operations such as Add and Exists; zero means unlimited
+	OpsPerSecond float64
+	// CommandsPerSecond limits the Redis commands those operations issue; zero means unlimited
+	CommandsPerSecond float64
+	// Burst is the bucket size of both limits (defaults to one second of traffic)
+	Burst int
+}
+
+// rateLimiter enforces a RateLimit with one token bucket per dimension
+type rateLimiter struct {
+	ops      *tokenBucket
+	commands *tokenBucket
+}
+
+// newRateLimiter creates the limiter for a RateLimit, or nil if it limits nothing
+func newRateLimiter(limit *RateLimit) *rateLimiter {
+	if limit == nil || (limit.OpsPerSecond <= 0 && limit.CommandsPerSecond <= 0) {
+		return nil
+	}
+	return &rateLimiter{
+		ops:      newTokenBucket(limit.OpsPerSecond, limit.Burst),
+		commands: newTokenBucket(limit.CommandsPerSecond, limit.Burst),
+	}
+}
+
+// wait blocks until one operation issuing the given number of commands may proceed
+func (rl *rateLimiter) wait(ctx context.Context, commands int) error {
+	if rl == nil {
+		return nil
+	}
+	if err := rl.ops.wait(ctx, 1); err != nil {
+		return err
+	}
+	return rl.commands.wait(ctx, commands)
+}
+
+// tokenBucket is a token bucket that lets callers go into debt and sleep it off,
+// so requests larger than the burst still make progress at the configured rate
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full bucket, or nil for a non-positive rate (unlimited)
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	size := float64(burst)
+	if size <= 0 {
+		size = math.Max(1, rate)
+	}
+	return &tokenBucket{rate: rate, burst: size, tokens: size, last: time.Now()}
+}
+
+// wait takes n tokens, sleeping until the bucket has refilled enough to cover them
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= float64(n)
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give back the tokens that will not be used
+		b.mu.Lock()
+		b.tokens += float64(n)
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
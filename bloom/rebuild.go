@@ -0,0 +1,51 @@
+package bloom
+
+import "context"
+
+// rebuildKeySuffix names the key a rebuilt filter is staged under
+const rebuildKeySuffix = "rebuild"
+
+// Rebuild replaces the filter with one sized for params. The new filter is built under a
+// companion key, backfilled from source when it is not nil, and renamed over the filter
+// key in a single transaction; without a source the rebuilt filter starts empty. It
+// returns the new filter, which replaces the receiver. Items added through other
+// instances during the backfill are lost; ResizableFilter writes them to both filters.
+func (bf *bloomFilter) Rebuild(ctx context.Context, params ResizeParams, source Iterator) (BloomFilter, error) {
+	if !bf.usesBitmap() {
+		return nil, ErrIncompatibleFilter
+	}
+	cfg := bf.config
+	cfg.ExpectedInsertions = params.ExpectedInsertions
+	cfg.FalsePositiveRate = params.FalsePositiveRate
+	cfg.BitSize, cfg.HashCount = 0, 0
+	target, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := target.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	staging := target.auxiliary(companionKey(cfg.RedisKey, rebuildKeySuffix))
+	staging.cache = nil
+	if err := client.Del(ctx, staging.config.RedisKey).Err(); err != nil {
+		return nil, err
+	}
+	var loaded int64
+	if source != nil {
+		loaded, err = staging.BulkLoad(ctx, source, BulkLoadOptions{})
+		if err != nil {
+			client.Del(context.Background(), staging.config.RedisKey)
+			return nil, err
+		}
+	}
+
+	if err := target.swapIn(ctx, client, staging, loaded > 0); err != nil {
+		return nil, err
+	}
+	bf.cache.invalidate()
+	target.cache = bf.cache
+	target.metadataRecorded = 1
+	return target, nil
+}
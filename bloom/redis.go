@@ -2,6 +2,7 @@ package bloom
 
 import (
 	"context"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -11,7 +12,19 @@ type RedisAdapter struct {
 	client redis.Cmdable
 }
 
-var _ RedisClient = (*RedisAdapter)(nil)
+var (
+	_ RedisClient = (*RedisAdapter)(nil)
+	_ Pipeliner   = (redis.Pipeliner)(nil)
+)
+
+// CmdableProvider is implemented by RedisClient values that expose the full go-redis
+// command set. Operations beyond bit reads and writes (key management, compaction,
+// introspection) require it.
+type CmdableProvider interface {
+	Cmdable() redis.Cmdable
+}
+
+var _ CmdableProvider = (*RedisAdapter)(nil)
 
 // NewRedisAdapter creates a new Redis adapter from a Redis client
 func NewRedisAdapter(client redis.Cmdable) RedisClient {
@@ -28,11 +41,38 @@ func (ra *RedisAdapter) GetBit(ctx context.Context, key string, offset int64) *r
 	return ra.client.GetBit(ctx, key, offset)
 }
 
+// Expire sets a timeout on the key
+func (ra *RedisAdapter) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	return ra.client.Expire(ctx, key, expiration)
+}
+
 // Pipeline returns a new pipeline
-func (ra *RedisAdapter) Pipeline() pipeliner {
+func (ra *RedisAdapter) Pipeline() Pipeliner {
 	return ra.client.Pipeline()
 }
 
+// Cmdable returns the wrapped go-redis client
+func (ra *RedisAdapter) Cmdable() redis.Cmdable {
+	return ra.client
+}
+
+// hookAdder is implemented by go-redis clients that accept hooks
+type hookAdder interface {
+	AddHook(hook redis.Hook)
+}
+
+// AddHook registers a go-redis hook on the underlying client so tracing and metrics
+// middlewares observe the filter's commands and pipelines. The hook is installed on the
+// wrapped client itself and therefore also applies to commands issued outside the filter.
+func (ra *RedisAdapter) AddHook(hook redis.Hook) error {
+	adder, ok := ra.client.(hookAdder)
+	if !ok {
+		return ErrHooksUnsupported
+	}
+	adder.AddHook(hook)
+	return nil
+}
+
 // NewSingleNodeRedisClient creates a Redis adapter for a single-node Redis client
 func NewSingleNodeRedisClient(client *redis.Client) RedisClient {
 	return NewRedisAdapter(client)
@@ -42,3 +82,19 @@ func NewSingleNodeRedisClient(client *redis.Client) RedisClient {
 func NewClusterRedisClient(client *redis.ClusterClient) RedisClient {
 	return NewRedisAdapter(client)
 }
+
+// NewUniversalRedisClient creates a Redis adapter for any go-redis client: the clients
+// of redis.NewUniversalClient, Sentinel-managed clients from redis.NewFailoverClient and
+// redis.NewFailoverClusterClient, and sharded redis.Ring clients
+func NewUniversalRedisClient(client redis.UniversalClient) RedisClient {
+	return NewRedisAdapter(client)
+}
+
+// doCommand sends a command that redis.Cmdable has no method for. The command runs in a
+// single-command pipeline, since Do is only part of the concrete clients and Pipeliner.
+func doCommand(ctx context.Context, client redis.Cmdable, args ...interface{}) *redis.Cmd {
+	pipe := client.Pipeline()
+	cmd := pipe.Do(ctx, args...)
+	_, _ = pipe.Exec(ctx)
+	return cmd
+}
@@ -0,0 +1,84 @@
+package bloom
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+)
+
+// redisBloomExists is the error RedisBloom replies to BF.RESERVE for an existing key
+const redisBloomExists = "item exists"
+
+// reserve creates the module filter with the configured parameters the first time this
+// filter instance writes; a filter that already exists keeps its own. Without it, the
+// first BF.ADD would create a filter with the module's default capacity and error rate.
+func (bf *bloomFilter) reserve(ctx context.Context) error {
+	if atomic.LoadUint32(&bf.reserved) == 1 {
+		return nil
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+
+	err = client.BFReserve(ctx, bf.config.RedisKey, bf.config.FalsePositiveRate, int64(bf.config.ExpectedInsertions)).Err()
+	if err != nil && !strings.Contains(err.Error(), redisBloomExists) {
+		return err
+	}
+	atomic.StoreUint32(&bf.reserved, 1)
+	return nil
+}
+
+// addModuleItems adds items to the module filter with a single BF.ADD or BF.MADD and
+// reports, for each item, whether it was not present before
+func (bf *bloomFilter) addModuleItems(ctx context.Context, items [][]byte) ([]bool, error) {
+	if err := bf.limiter.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	if err := bf.reserve(ctx); err != nil {
+		return nil, err
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 1 {
+		added, err := client.BFAdd(ctx, bf.config.RedisKey, items[0]).Result()
+		if err != nil {
+			return nil, err
+		}
+		return []bool{added}, nil
+	}
+	return client.BFMAdd(ctx, bf.config.RedisKey, moduleArgs(items)...).Result()
+}
+
+// checkModuleItems checks items against the module filter with a single BF.EXISTS or
+// BF.MEXISTS
+func (bf *bloomFilter) checkModuleItems(ctx context.Context, items [][]byte) ([]bool, error) {
+	if err := bf.limiter.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 1 {
+		exists, err := client.BFExists(ctx, bf.config.RedisKey, items[0]).Result()
+		if err != nil {
+			return nil, err
+		}
+		return []bool{exists}, nil
+	}
+	return client.BFMExists(ctx, bf.config.RedisKey, moduleArgs(items)...).Result()
+}
+
+// moduleArgs converts items to command arguments
+func moduleArgs(items [][]byte) []interface{} {
+	args := make([]interface{}, len(items))
+	for i, data := range items {
+		args[i] = data
+	}
+	return args
+}
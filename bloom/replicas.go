@@ -0,0 +1,249 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Default replica health settings
+const (
+	defaultReplicaErrorThreshold = 3
+	defaultReplicaEjectFor       = 10 * time.Second
+)
+
+// ReplicaRouterOptions configures health tracking of replicas
+type ReplicaRouterOptions struct {
+	// ErrorThreshold is the number of consecutive failures that eject a replica (defaults to 3)
+	ErrorThreshold int
+	// EjectFor is how long an ejected replica receives no reads (defaults to 10 seconds)
+	EjectFor time.Duration
+}
+
+// ReplicaRouter is a RedisClient that sends writes to a primary and routes reads to the
+// replica with the lowest moving average latency. Replicas that fail repeatedly are
+// ejected for a while; when no replica is healthy, reads go to the primary.
+//
+// Replication is asynchronous, so an item may briefly read as absent on a replica right
+// after it was added. Redis Cluster deployments can use NewClusterReplicaClient.
+type ReplicaRouter struct {
+	primary   redis.Cmdable
+	replicas  []*replicaNode
+	threshold int
+	ejectFor  time.Duration
+}
+
+var (
+	_ RedisClient     = (*ReplicaRouter)(nil)
+	_ CmdableProvider = (*ReplicaRouter)(nil)
+)
+
+// replicaNode tracks the latency and health of one replica
+type replicaNode struct {
+	client       redis.Cmdable
+	mu           sync.Mutex
+	ewma         time.Duration
+	failures     int
+	ejectedUntil time.Time
+}
+
+// NewReplicaRouter creates a router over a primary and its replicas
+func NewReplicaRouter(primary redis.Cmdable, replicas []redis.Cmdable, opts ReplicaRouterOptions) (*ReplicaRouter, error) {
+	if primary == nil {
+		return nil, ErrNilRedisClient
+	}
+	r := &ReplicaRouter{primary: primary, threshold: opts.ErrorThreshold, ejectFor: opts.EjectFor}
+	if r.threshold <= 0 {
+		r.threshold = defaultReplicaErrorThreshold
+	}
+	if r.ejectFor <= 0 {
+		r.ejectFor = defaultReplicaEjectFor
+	}
+	for _, replica := range replicas {
+		if replica == nil {
+			return nil, ErrNilRedisClient
+		}
+		r.replicas = append(r.replicas, &replicaNode{client: replica})
+	}
+	return r, nil
+}
+
+// SetBit sets a bit on the primary
+func (r *ReplicaRouter) SetBit(ctx context.Context, key string, offset int64, value int) *redis.IntCmd {
+	return r.primary.SetBit(ctx, key, offset, value)
+}
+
+// GetBit reads a bit from the fastest healthy replica
+func (r *ReplicaRouter) GetBit(ctx context.Context, key string, offset int64) *redis.IntCmd {
+	node := r.pick()
+	if node == nil {
+		return r.primary.GetBit(ctx, key, offset)
+	}
+	start := time.Now()
+	cmd := node.client.GetBit(ctx, key, offset)
+	r.observe(node, time.Since(start), cmd.Err())
+	return cmd
+}
+
+// Expire sets a key expiration on the primary
+func (r *ReplicaRouter) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	return r.primary.Expire(ctx, key, expiration)
+}
+
+// Pipeline returns a pipeline that runs on a replica when it only reads
+func (r *ReplicaRouter) Pipeline() Pipeliner {
+	return &routedPipeline{router: r}
+}
+
+// Cmdable returns the primary, which serves all commands beyond bit reads
+func (r *ReplicaRouter) Cmdable() redis.Cmdable {
+	return r.primary
+}
+
+// ClusterReplicaOptions selects the nodes that serve a cluster's reads. With neither
+// option set, reads go to a random replica of the key's slot.
+type ClusterReplicaOptions struct {
+	// RouteByLatency sends reads to the node of the slot with the lowest latency, which may be the primary
+	RouteByLatency bool
+	// RouteRandomly sends reads to a random node of the slot, which may be the primary
+	RouteRandomly bool
+}
+
+// ClusterReplicaClient is a ReplicaRouter for Redis Cluster: writes go through the
+// application's cluster client, and bit reads through a second cluster client with the
+// same settings in ReadOnly mode, which go-redis routes to replicas. Reads fall back to
+// the primaries while the read client is ejected. Close it when the filters using it
+// are no longer needed.
+type ClusterReplicaClient struct {
+	*ReplicaRouter
+	reads *redis.ClusterClient
+}
+
+// NewClusterReplicaClient creates a client that reads from the replicas of client's cluster.
+// Hooks installed on client are not copied to the read client.
+func NewClusterReplicaClient(client *redis.ClusterClient, opts ClusterReplicaOptions, routerOpts ReplicaRouterOptions) (*ClusterReplicaClient, error) {
+	if client == nil {
+		return nil, ErrNilRedisClient
+	}
+	o := *client.Options()
+	o.ReadOnly = true
+	o.RouteByLatency, o.RouteRandomly = opts.RouteByLatency, opts.RouteRandomly
+	reads := redis.NewClusterClient(&o)
+	router, err := NewReplicaRouter(client, []redis.Cmdable{reads}, routerOpts)
+	if err != nil {
+		reads.Close()
+		return nil, err
+	}
+	return &ClusterReplicaClient{ReplicaRouter: router, reads: reads}, nil
+}
+
+// Close closes the read client; the application's cluster client stays open
+func (c *ClusterReplicaClient) Close() error {
+	return c.reads.Close()
+}
+
+// pick returns the healthy replica with the lowest average latency, or nil
+func (r *ReplicaRouter) pick() *replicaNode {
+	now := time.Now()
+	var best *replicaNode
+	var bestLatency time.Duration
+	for _, node := range r.replicas {
+		node.mu.Lock()
+		healthy, latency := !now.Before(node.ejectedUntil), node.ewma
+		node.mu.Unlock()
+		if healthy && (best == nil || latency < bestLatency) {
+			best, bestLatency = node, latency
+		}
+	}
+	return best
+}
+
+// observe records the outcome of a read on a replica
+func (r *ReplicaRouter) observe(node *replicaNode, latency time.Duration, err error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	if err != nil && !errors.Is(err, redis.Nil) {
+		node.failures++
+		if node.failures >= r.threshold {
+			node.failures = 0
+			node.ejectedUntil = time.Now().Add(r.ejectFor)
+			// Start over once the replica is back
+			node.ewma = 0
+		}
+		return
+	}
+
+	node.failures = 0
+	if node.ewma == 0 {
+		node.ewma = latency
+	} else {
+		node.ewma = time.Duration(latencyEWMAWeight*float64(latency) + (1-latencyEWMAWeight)*float64(node.ewma))
+	}
+}
+
+// routedPipeline queues commands and picks the node when executed: the primary if any
+// command writes, otherwise the fastest healthy replica
+type routedPipeline struct {
+	router *ReplicaRouter
+	cmds   []redis.Cmder
+	writes bool
+}
+
+// SetBit queues a SETBIT, which routes the pipeline to the primary
+func (p *routedPipeline) SetBit(ctx context.Context, key string, offset int64, value int) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "setbit", key, offset, value)
+	p.cmds = append(p.cmds, cmd)
+	p.writes = true
+	return cmd
+}
+
+// GetBit queues a GETBIT
+func (p *routedPipeline) GetBit(ctx context.Context, key string, offset int64) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "getbit", key, offset)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// Expire queues an EXPIRE, which routes the pipeline to the primary
+func (p *routedPipeline) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx, "expire", key, expiration)
+	p.cmds = append(p.cmds, cmd)
+	p.writes = true
+	return cmd
+}
+
+// Exec sends the queued commands to the chosen node in one round trip
+func (p *routedPipeline) Exec(ctx context.Context) ([]redis.Cmder, error) {
+	cmds := p.cmds
+	writes := p.writes
+	p.cmds, p.writes = nil, false
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	var node *replicaNode
+	if !writes {
+		node = p.router.pick()
+	}
+	client := p.router.primary
+	if node != nil {
+		client = node.client
+	}
+
+	pipe := client.Pipeline()
+	for _, cmd := range cmds {
+		if err := pipe.Process(ctx, cmd); err != nil {
+			return cmds, err
+		}
+	}
+	start := time.Now()
+	_, err := pipe.Exec(ctx)
+	if node != nil {
+		p.router.observe(node, time.Since(start), err)
+	}
+	return cmds, err
+}
@@ -0,0 +1,255 @@
+package bloom
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Resize defaults and layout
+const (
+	resizeKeySuffix              = "resize"
+	defaultResizeValidationItems = 1000
+)
+
+// ResizeParams are the new sizing parameters of a resized filter
+type ResizeParams struct {
+	ExpectedInsertions uint64
+	FalsePositiveRate  float64
+}
+
+// ResizeOptions configures ResizableFilter.Resize
+type ResizeOptions struct {
+	// Source yields the live items to backfill; nil replays the raw items of the audit stream
+	Source Iterator
+	// BatchSize is the number of items written per pipeline during the backfill (defaults to 1000)
+	BatchSize int
+	// ValidationItems is the number of backfilled items, sampled uniformly, that must be
+	// present in the new filter before it is swapped in (defaults to 1000)
+	ValidationItems int
+}
+
+// ResizeReport describes a completed resize
+type ResizeReport struct {
+	Backfilled int64
+	Validated  int
+	Duration   time.Duration
+}
+
+// ResizableFilter is a filter that can be resized online. While Resize backfills the new
+// filter, every Add is written to both filters, so no insertion is lost in the transition.
+type ResizableFilter struct {
+	mu      sync.RWMutex
+	cfg     Config
+	current *bloomFilter
+	staging *bloomFilter
+}
+
+// NewResizableFilter creates a resizable filter
+func NewResizableFilter(cfg Config) (*ResizableFilter, error) {
+	current, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !current.usesBitmap() {
+		return nil, ErrIncompatibleFilter
+	}
+	return &ResizableFilter{cfg: cfg, current: current}, nil
+}
+
+// Add adds an element, also writing it to the new filter while a resize is in progress
+func (rf *ResizableFilter) Add(data []byte) error {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	if err := rf.current.Add(data); err != nil {
+		return err
+	}
+	if rf.staging != nil {
+		return rf.staging.Add(data)
+	}
+	return nil
+}
+
+// Exists checks if an element exists in the current filter
+func (rf *ResizableFilter) Exists(data []byte) (bool, error) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	return rf.current.Exists(data)
+}
+
+// Filter returns the current filter
+func (rf *ResizableFilter) Filter() BloomFilter {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	return rf.current
+}
+
+// Resize rebuilds the filter with new parameters. The new filter is built under a
+// companion key from opts.Source (or the audit stream) while Adds are written to both
+// filters. A uniform sample of the backfilled items is then checked against the new
+// filter, and only if none is missing is it renamed over the filter key in a single
+// transaction. Other processes using the filter must reopen it with the new parameters.
+func (rf *ResizableFilter) Resize(ctx context.Context, params ResizeParams, opts ResizeOptions) (*ResizeReport, error) {
+	start := time.Now()
+	if opts.ValidationItems <= 0 {
+		opts.ValidationItems = defaultResizeValidationItems
+	}
+
+	cfg := rf.cfg
+	cfg.ExpectedInsertions = params.ExpectedInsertions
+	cfg.FalsePositiveRate = params.FalsePositiveRate
+	cfg.BitSize, cfg.HashCount = 0, 0
+	target, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := target.cmdable()
+	if err != nil {
+		return nil, err
+	}
+	source := opts.Source
+	if source == nil {
+		if cfg.Audit == nil || !cfg.Audit.RawItems {
+			return nil, ErrAuditNotReplayable
+		}
+		source = NewAuditIterator(ctx, client, target.auditStream())
+	}
+
+	// The staging filter hashes like the target, so it can be renamed over the key
+	staging := target.auxiliary(companionKey(cfg.RedisKey, resizeKeySuffix))
+	staging.cache = nil
+
+	// A stale staging key is only deleted once no other resize can be writing to it
+	rf.mu.Lock()
+	if rf.staging != nil {
+		rf.mu.Unlock()
+		return nil, ErrResizeInProgress
+	}
+	if err := client.Del(ctx, staging.config.RedisKey).Err(); err != nil {
+		rf.mu.Unlock()
+		return nil, err
+	}
+	rf.staging = staging
+	rf.mu.Unlock()
+
+	report := &ResizeReport{}
+	swapped := false
+	defer func() {
+		if !swapped {
+			rf.mu.Lock()
+			rf.staging = nil
+			rf.mu.Unlock()
+			client.Del(context.Background(), staging.config.RedisKey)
+		}
+	}()
+
+	// Backfill, keeping a uniform sample of the items for validation
+	sample := make([][]byte, 0, opts.ValidationItems)
+	sampler := &sampledIterator{source: source, sample: &sample, size: opts.ValidationItems, rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	report.Backfilled, err = staging.BulkLoad(ctx, sampler, BulkLoadOptions{BatchSize: opts.BatchSize})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := staging.validateMembers(ctx, sample); err != nil {
+		return nil, err
+	}
+	report.Validated = len(sample)
+
+	// Swap atomically while Adds are paused
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if err := target.swapIn(ctx, client, staging, report.Backfilled > 0); err != nil {
+		return nil, err
+	}
+
+	target.cache = rf.current.cache
+	target.metadataRecorded = 1
+	rf.cfg, rf.current, rf.staging = cfg, target, nil
+	swapped = true
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// swapIn renames staging over the filter's key in one transaction, or deletes the key if
+// nothing was staged, recording the rebuild and the filter's parameters in its metadata
+func (bf *bloomFilter) swapIn(ctx context.Context, client redis.Cmdable, staging *bloomFilter, staged bool) error {
+	key := bf.config.RedisKey
+	ttl, err := bf.swapTTL(ctx, client)
+	if err != nil {
+		return err
+	}
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if staged {
+			pipe.Rename(ctx, staging.config.RedisKey, key)
+		} else {
+			pipe.Del(ctx, key)
+		}
+		queueMarkRebuilt(ctx, pipe, key, time.Now())
+		if fields := bf.parameterFields(); fields != nil {
+			pipe.HSet(ctx, metadataKey(key), fields...)
+		} else {
+			// Module filters hash items themselves and record no bitmap parameters
+			pipe.HDel(ctx, metadataKey(key), metaFieldBits, metaFieldHashes, metaFieldHash)
+		}
+		if ttl > 0 {
+			pipe.Expire(ctx, key, ttl)
+			pipe.Expire(ctx, metadataKey(key), ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// validateMembers checks in one pipeline that every item is present
+func (bf *bloomFilter) validateMembers(ctx context.Context, items [][]byte) error {
+	if len(items) == 0 {
+		return nil
+	}
+	pipe, err := bf.pipeline()
+	if err != nil {
+		return err
+	}
+	defer bf.releasePipeline(pipe)
+
+	checks := make([]func() bool, len(items))
+	for i, data := range items {
+		checks[i] = bf.queueCheckBits(ctx, pipe, bf.getHashPositions(data))
+	}
+	if err := execPipeline(ctx, pipe); err != nil {
+		return err
+	}
+	for _, present := range checks {
+		if !present() {
+			return ErrResizeDiverged
+		}
+	}
+	return nil
+}
+
+// sampledIterator passes items through while reservoir-sampling them
+type sampledIterator struct {
+	source Iterator
+	sample *[][]byte
+	size   int
+	seen   int
+	rng    *rand.Rand
+}
+
+// Next returns the next item of the source
+func (it *sampledIterator) Next() ([]byte, error) {
+	item, err := it.source.Next()
+	if err != nil {
+		return nil, err
+	}
+	it.seen++
+	if len(*it.sample) < it.size {
+		*it.sample = append(*it.sample, append([]byte(nil), item...))
+	} else if j := it.rng.Intn(it.seen); j < it.size {
+		(*it.sample)[j] = append([]byte(nil), item...)
+	}
+	return item, nil
+}
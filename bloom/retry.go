@@ -0,0 +1,144 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Default retry policy settings
+const (
+	defaultRetryAttempts  = 3
+	defaultRetryBaseDelay = 10 * time.Millisecond
+	defaultRetryMaxDelay  = time.Second
+)
+
+// RetryPolicy retries the bit reads and writes of Add, Exists and their batch variants
+// when they fail with a transient error, such as a connection reset or a cluster
+// failover, with exponential backoff between attempts. Bit writes are idempotent, so a
+// partially applied write is safe to repeat.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first (defaults to 3)
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled for every further one (defaults to 10ms)
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts (defaults to one second)
+	MaxDelay time.Duration
+	// Jitter varies every delay randomly by up to this fraction of it, between 0 and 1
+	Jitter float64
+	// Retryable reports whether an error is worth retrying (defaults to IsTransientError)
+	Retryable func(error) bool
+}
+
+// retrier applies a RetryPolicy
+type retrier struct {
+	attempts  int
+	base      time.Duration
+	max       time.Duration
+	jitter    float64
+	retryable func(error) bool
+}
+
+// newRetrier creates the retrier for a RetryPolicy, or nil if it is disabled
+func newRetrier(policy *RetryPolicy) *retrier {
+	if policy == nil || policy.MaxAttempts == 1 {
+		return nil
+	}
+	r := &retrier{
+		attempts:  policy.MaxAttempts,
+		base:      policy.BaseDelay,
+		max:       policy.MaxDelay,
+		jitter:    math.Min(math.Max(policy.Jitter, 0), 1),
+		retryable: policy.Retryable,
+	}
+	if r.attempts <= 0 {
+		r.attempts = defaultRetryAttempts
+	}
+	if r.base <= 0 {
+		r.base = defaultRetryBaseDelay
+	}
+	if r.max <= 0 {
+		r.max = defaultRetryMaxDelay
+	}
+	if r.retryable == nil {
+		r.retryable = IsTransientError
+	}
+	return r
+}
+
+// delay returns the backoff before the given retry, counting from one
+func (r *retrier) delay(retry int) time.Duration {
+	d := r.base
+	for i := 1; i < retry && d < r.max; i++ {
+		d *= 2
+	}
+	if d > r.max {
+		d = r.max
+	}
+	return time.Duration(float64(d) * (1 + r.jitter*(2*rand.Float64()-1)))
+}
+
+// retry runs fn, running it again after a backoff while it fails with a retryable error
+// and attempts remain. It gives up early with the last error when ctx is done.
+func (bf *bloomFilter) retry(ctx context.Context, fn func() error) error {
+	r := bf.retrier
+	err := fn()
+	for attempt := 1; err != nil && r != nil && attempt < r.attempts && r.retryable(err); attempt++ {
+		timer := time.NewTimer(r.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		bf.metrics.IncCounter(MetricRetries, 1)
+		err = fn()
+	}
+	return err
+}
+
+// IsTransientError reports whether err is likely to go away on its own: connection
+// failures and timeouts, and the LOADING, READONLY, CLUSTERDOWN, MASTERDOWN and TRYAGAIN
+// replies Redis sends during restarts and failovers. A PipelineError is transient if all
+// of its failed commands are. Context cancellation and closed clients are not.
+func IsTransientError(err error) bool {
+	var pe *PipelineError
+	if errors.As(err, &pe) {
+		failed := pe.Failed()
+		for _, cmd := range failed {
+			if !IsTransientError(cmd.Err) {
+				return false
+			}
+		}
+		return len(failed) > 0
+	}
+
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded), errors.Is(err, redis.ErrClosed):
+		return false
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		prefix, _, _ := strings.Cut(redisErr.Error(), " ")
+		switch prefix {
+		case "LOADING", "READONLY", "CLUSTERDOWN", "MASTERDOWN", "TRYAGAIN":
+			return true
+		}
+	}
+	return false
+}
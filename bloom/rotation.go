@@ -0,0 +1,126 @@
+package bloom
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RotatableFilter writes to a current generation key and, for an overlap window after
+// each rotation, also reads the previous generation. Resetting a dedupe filter this way
+// avoids the burst of duplicates a plain DEL causes: items seen shortly before the
+// rotation are still reported until the new generation has caught up.
+type RotatableFilter struct {
+	cfg     Config
+	overlap time.Duration
+
+	mu       sync.RWMutex
+	current  *bloomFilter
+	previous *bloomFilter
+	retireAt time.Time
+}
+
+// NewRotatableFilter creates a rotatable filter whose first generation is cfg.RedisKey.
+// Each later generation uses the same parameters under the key passed to Rotate.
+func NewRotatableFilter(cfg Config, overlap time.Duration) (*RotatableFilter, error) {
+	if overlap <= 0 {
+		return nil, ErrInvalidRotationOverlap
+	}
+	current, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &RotatableFilter{cfg: cfg, overlap: overlap, current: current}, nil
+}
+
+// Add adds an element to the current generation
+func (rf *RotatableFilter) Add(data []byte) error {
+	current, _ := rf.generations()
+	return current.Add(data)
+}
+
+// Exists checks the current generation and, during the overlap window, the previous one
+func (rf *RotatableFilter) Exists(data []byte) (bool, error) {
+	current, previous := rf.generations()
+	exists, err := current.Exists(data)
+	if err != nil || exists || previous == nil {
+		return exists, err
+	}
+	return previous.Exists(data)
+}
+
+// Keys returns the current generation key and the previous one while it is still read
+func (rf *RotatableFilter) Keys() (current, previous string) {
+	cur, prev := rf.generations()
+	if prev != nil {
+		previous = prev.config.RedisKey
+	}
+	return cur.config.RedisKey, previous
+}
+
+// Rotate starts writing to a new generation under key. The previous generation is read
+// for the overlap window; when the client supports expiration its keys are set to expire
+// at the end of the window, otherwise call Retire once the window has passed.
+// A generation still in its overlap window is retired immediately.
+func (rf *RotatableFilter) Rotate(ctx context.Context, key string) error {
+	if key == "" {
+		return ErrEmptyRedisKey
+	}
+	cfg := rf.cfg
+	cfg.RedisKey = key
+	next, err := newBloomFilter(cfg)
+	if err != nil {
+		return err
+	}
+
+	rf.mu.Lock()
+	retired := rf.previous
+	old := rf.current
+	rf.current, rf.previous = next, old
+	rf.retireAt = time.Now().Add(rf.overlap)
+	rf.mu.Unlock()
+
+	if retired != nil {
+		if err := deleteGeneration(ctx, retired); err != nil {
+			return err
+		}
+	}
+	for _, k := range []string{old.config.RedisKey, metadataKey(old.config.RedisKey)} {
+		if err := old.config.RedisClient.Expire(ctx, k, rf.overlap).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Retire stops reading the previous generation and deletes its keys
+func (rf *RotatableFilter) Retire(ctx context.Context) error {
+	rf.mu.Lock()
+	previous := rf.previous
+	rf.previous = nil
+	rf.mu.Unlock()
+
+	if previous == nil {
+		return nil
+	}
+	return deleteGeneration(ctx, previous)
+}
+
+// generations returns the current generation and the previous one if it is still read
+func (rf *RotatableFilter) generations() (current, previous *bloomFilter) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	if rf.previous != nil && time.Now().Before(rf.retireAt) {
+		previous = rf.previous
+	}
+	return rf.current, previous
+}
+
+// deleteGeneration removes the bitmap and metadata of a retired generation
+func deleteGeneration(ctx context.Context, bf *bloomFilter) error {
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	return client.Del(ctx, bf.config.RedisKey, metadataKey(bf.config.RedisKey)).Err()
+}
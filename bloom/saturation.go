@@ -0,0 +1,183 @@
+package bloom
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Saturation monitoring defaults
+const defaultSaturationInterval = 10 * time.Second
+
+// defaultCapacityThresholds are used when no thresholds are configured
+var defaultCapacityThresholds = []float64{0.8, 1}
+
+// SaturationAlert configures saturation monitoring. After writes from this instance the
+// filter is sampled in the background, at most once per Interval and at the latest one
+// Interval after the last write, and OnThreshold is called whenever the fill ratio or the
+// estimated insertions rise past a threshold. WatchSaturation also samples every Interval,
+// catching writes from other processes. A threshold fires again only after the filter has
+// dropped back below it, for example after Clear.
+type SaturationAlert struct {
+	// Interval is the time between samples (defaults to 10s)
+	Interval time.Duration
+	// FillThresholds are fill ratios; a filter at its expected insertions is about half full
+	FillThresholds []float64
+	// CapacityThresholds are fractions of ExpectedInsertions compared with the estimated
+	// insertions (defaults to 0.8 and 1 when no thresholds are set)
+	CapacityThresholds []float64
+	// OnThreshold is called once for every threshold crossed
+	OnThreshold func(SaturationEvent)
+}
+
+// SaturationEvent describes a crossed saturation threshold
+type SaturationEvent struct {
+	Key string
+	// Threshold is the crossed threshold, a fraction of ExpectedInsertions when Capacity
+	// is set and a fill ratio otherwise
+	Threshold float64
+	Capacity  bool
+	// FillRatio is zero for RedisBloom filters, whose bits are not observable
+	FillRatio           float64
+	EstimatedInsertions uint64
+	At                  time.Time
+}
+
+// saturation tracks sampling and which thresholds have been crossed
+type saturation struct {
+	cfg SaturationAlert
+	mu  sync.Mutex
+	// scheduled is set while a write-triggered sample is pending
+	scheduled   bool
+	sampled     time.Time
+	fillCrossed []bool
+	capCrossed  []bool
+}
+
+// newSaturation creates the monitor for a SaturationAlert, or nil if it is disabled
+func newSaturation(cfg *SaturationAlert) *saturation {
+	if cfg == nil {
+		return nil
+	}
+	s := &saturation{cfg: *cfg}
+	if s.cfg.Interval <= 0 {
+		s.cfg.Interval = defaultSaturationInterval
+	}
+	if len(s.cfg.FillThresholds) == 0 && len(s.cfg.CapacityThresholds) == 0 {
+		s.cfg.CapacityThresholds = defaultCapacityThresholds
+	}
+	s.fillCrossed = make([]bool, len(s.cfg.FillThresholds))
+	s.capCrossed = make([]bool, len(s.cfg.CapacityThresholds))
+	return s
+}
+
+// watchSaturation schedules a background sample after a write: at once if Interval has
+// passed since the last sample, otherwise when it has, so the last writes before a pause
+// are sampled too
+func (bf *bloomFilter) watchSaturation() {
+	s := bf.saturation
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scheduled {
+		return
+	}
+	s.scheduled = true
+	time.AfterFunc(s.cfg.Interval-time.Since(s.sampled), func() {
+		s.mu.Lock()
+		s.scheduled = false
+		s.mu.Unlock()
+		bf.runSaturationSample(context.Background())
+	})
+}
+
+// WatchSaturation samples the filter every SaturationAlert.Interval until ctx is done, so
+// thresholds crossed by writes from other processes fire too. It returns ctx's error, or
+// ErrSaturationAlertDisabled if the filter has no SaturationAlert. Failed samples are
+// counted in bloom_saturation_sample_errors_total.
+func WatchSaturation(ctx context.Context, filter BloomFilter) error {
+	bf, ok := filter.(*bloomFilter)
+	if !ok || bf.saturation == nil {
+		return ErrSaturationAlertDisabled
+	}
+	ticker := time.NewTicker(bf.saturation.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			bf.runSaturationSample(ctx)
+		}
+	}
+}
+
+// runSaturationSample takes a sample, counting a failure
+func (bf *bloomFilter) runSaturationSample(ctx context.Context) {
+	s := bf.saturation
+	s.mu.Lock()
+	s.sampled = time.Now()
+	s.mu.Unlock()
+	if err := bf.sampleSaturation(ctx); err != nil && ctx.Err() == nil {
+		bf.metrics.IncCounter(MetricSaturationErrors, 1)
+	}
+}
+
+// sampleSaturation measures the filter and reports the thresholds crossed since the
+// previous sample
+func (bf *bloomFilter) sampleSaturation(ctx context.Context) error {
+	s := bf.saturation
+	var fill float64
+	var estimated uint64
+	if bf.usesBitmap() {
+		var err error
+		if fill, err = bf.fillRatio(ctx); err != nil {
+			return err
+		}
+		bf.metrics.SetGauge(MetricFillRatio, fill)
+		estimated = estimateInsertions(bf.bitSize, bf.hashCount, fill)
+	} else {
+		var err error
+		if estimated, err = bf.EstimateCardinality(ctx); err != nil {
+			return err
+		}
+	}
+	capacity := float64(estimated) / float64(bf.config.ExpectedInsertions)
+
+	now := time.Now()
+	var events []SaturationEvent
+	s.mu.Lock()
+	for i, threshold := range s.cfg.FillThresholds {
+		if bf.usesBitmap() && crossed(&s.fillCrossed[i], fill, threshold) {
+			events = append(events, SaturationEvent{Threshold: threshold})
+		}
+	}
+	for i, threshold := range s.cfg.CapacityThresholds {
+		if crossed(&s.capCrossed[i], capacity, threshold) {
+			events = append(events, SaturationEvent{Threshold: threshold, Capacity: true})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, event := range events {
+		event.Key = bf.config.RedisKey
+		event.FillRatio = fill
+		event.EstimatedInsertions = estimated
+		event.At = now
+		bf.metrics.IncCounter(MetricSaturationAlerts, 1)
+		if s.cfg.OnThreshold != nil {
+			s.cfg.OnThreshold(event)
+		}
+	}
+	return nil
+}
+
+// crossed records whether value is at or above threshold and reports a rise past it
+func crossed(state *bool, value, threshold float64) bool {
+	above := value >= threshold
+	rose := above && !*state
+	*state = above
+	return rose
+}
@@ -0,0 +1,70 @@
+package bloom
+
+import (
+	"context"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// scriptManager runs Lua scripts with EVALSHA only, so the script source crosses the
+// network once per server instead of on every cache miss. Scripts are loaded with
+// SCRIPT LOAD on first use, and again when the server answers NOSCRIPT, as it does
+// after a restart, a failover to a replica or SCRIPT FLUSH.
+type scriptManager struct {
+	mu     sync.Mutex
+	loaded map[string]bool
+}
+
+// newScriptManager creates a script manager with no scripts loaded
+func newScriptManager() *scriptManager {
+	return &scriptManager{loaded: make(map[string]bool)}
+}
+
+// run runs the script with EVALSHA, loading it first if needed and reloading it once
+// if the server no longer has it
+func (m *scriptManager) run(ctx context.Context, client redis.Scripter, script *redis.Script, keys []string, args ...interface{}) *redis.Cmd {
+	if !m.isLoaded(script) {
+		if err := m.load(ctx, client, script); err != nil {
+			cmd := redis.NewCmd(ctx)
+			cmd.SetErr(err)
+			return cmd
+		}
+	}
+	cmd := script.EvalSha(ctx, client, keys, args...)
+	if redis.HasErrorPrefix(cmd.Err(), "NOSCRIPT") {
+		if err := m.load(ctx, client, script); err != nil {
+			cmd.SetErr(err)
+			return cmd
+		}
+		cmd = script.EvalSha(ctx, client, keys, args...)
+	}
+	return cmd
+}
+
+// isLoaded reports whether the script has been loaded
+func (m *scriptManager) isLoaded(script *redis.Script) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.loaded[script.Hash()]
+}
+
+// load loads the script with SCRIPT LOAD. Cluster clients load it on every primary, and
+// ring clients are made to load it on every shard.
+func (m *scriptManager) load(ctx context.Context, client redis.Scripter, script *redis.Script) error {
+	var err error
+	if ring, ok := client.(*redis.Ring); ok {
+		err = ring.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return script.Load(ctx, shard).Err()
+		})
+	} else {
+		err = script.Load(ctx, client).Err()
+	}
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.loaded[script.Hash()] = true
+	m.mu.Unlock()
+	return nil
+}
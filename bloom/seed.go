@@ -0,0 +1,49 @@
+package bloom
+
+import (
+	"encoding/binary"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// seededStrategy mixes a per-filter seed into every hash of the wrapped strategy, so the
+// same item sets unrelated bit patterns in filters with different seeds
+type seededStrategy struct {
+	inner HashStrategy
+	seed  uint64
+}
+
+// Hash implements HashStrategy by remixing the wrapped hash with the seed
+func (s *seededStrategy) Hash(data []byte, i uint) uint64 {
+	return mix64(s.inner.Hash(data, i) ^ s.seed)
+}
+
+// effectiveSeed derives the seed a filter hashes with from the configured seed and the
+// salt (the explicit Salt, or the Redis key when KeySeeded is set). It reports false when
+// no seeding is configured, which keeps the strategy's own positions.
+func effectiveSeed(cfg Config) (uint64, bool) {
+	salt := cfg.Salt
+	if salt == "" && cfg.KeySeeded {
+		salt = cfg.RedisKey
+	}
+	if cfg.Seed == 0 && salt == "" {
+		return 0, false
+	}
+
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], cfg.Seed)
+	h := xxhash.New()
+	h.Write(buf[:])
+	h.WriteString(salt)
+	return h.Sum64(), true
+}
+
+// mix64 is the SplitMix64 finalizer, a bijection that spreads every input bit
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,49 @@
+package bloom
+
+import "fmt"
+
+// HashTestVector is a known-answer test case for a hash strategy
+type HashTestVector struct {
+	Input []byte
+	Index uint
+	Want  uint64
+}
+
+// KnownAnswerer is implemented by hash strategies that ship known-answer vectors.
+// Custom strategies implement it to take part in the construction-time self-test.
+type KnownAnswerer interface {
+	KnownAnswers() []HashTestVector
+}
+
+// VerifyHashStrategy checks the strategy against its known-answer vectors and reports
+// the first mismatch. Strategies without vectors cannot be verified.
+func VerifyHashStrategy(strategy HashStrategy) error {
+	answerer, ok := strategy.(KnownAnswerer)
+	if !ok {
+		return ErrHashVectorsUnavailable
+	}
+	for _, v := range answerer.KnownAnswers() {
+		if got := strategy.Hash(v.Input, v.Index); got != v.Want {
+			return fmt.Errorf("%w: Hash(%q, %d) = %#x, want %#x", ErrHashStrategyMismatch, v.Input, v.Index, got, v.Want)
+		}
+	}
+	return nil
+}
+
+// KnownAnswers returns the known-answer vectors of the XXHash strategy
+func (x *XXHashStrategy) KnownAnswers() []HashTestVector { return xxhashVectors }
+
+// KnownAnswers returns the known-answer vectors of the Murmur3 strategy
+func (m *Murmur3Strategy) KnownAnswers() []HashTestVector { return murmur3Vectors }
+
+// KnownAnswers returns the known-answer vectors of the FNV strategy
+func (f *FNVStrategy) KnownAnswers() []HashTestVector { return fnvVectors }
+
+// KnownAnswers returns the known-answer vectors of the Murmur3 128-bit hash Guava uses
+func (g *guavaStrategy) KnownAnswers() []HashTestVector { return guavaVectors }
+
+// KnownAnswers returns the known-answer vectors of pybloom's salted hashing
+func (p *pybloomStrategy) KnownAnswers() []HashTestVector { return pybloomVectors }
+
+// KnownAnswers returns the known-answer vectors of the bits-and-blooms hashing
+func (b *bitsAndBloomsStrategy) KnownAnswers() []HashTestVector { return bitsAndBloomsVectors }
@@ -0,0 +1,96 @@
+package bloom
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Union adds the elements of other to the filter by combining the bitmaps with BITOP OR.
+// The result answers like a filter that every element of both was added to. Both filters
+// must have been created with identical parameters (size, hashing and layout) on the
+// same server; in a cluster their keys must also share a slot.
+func (bf *bloomFilter) Union(ctx context.Context, other BloomFilter) error {
+	src, err := bf.compatible(other)
+	if err != nil {
+		return err
+	}
+	if err := bf.bitop(ctx, "or", bf.config.RedisKey, src.config.RedisKey); err != nil {
+		return err
+	}
+	bf.cache.invalidate()
+	return nil
+}
+
+// Intersect keeps only the bits set in both filters, combining the bitmaps with BITOP
+// AND. Elements added to both filters remain present, and the result reports no more
+// false positives than either filter did. The filters must be compatible as for Union.
+func (bf *bloomFilter) Intersect(ctx context.Context, other BloomFilter) error {
+	src, err := bf.compatible(other)
+	if err != nil {
+		return err
+	}
+	if err := bf.bitop(ctx, "and", bf.config.RedisKey, src.config.RedisKey); err != nil {
+		return err
+	}
+	bf.cache.invalidate()
+	return nil
+}
+
+// MergeInto adds the filter's elements to the filter stored at destKey with BITOP OR,
+// creating it if it does not exist. The destination must have been created with the
+// filter's parameters; in a cluster destKey must share the filter's slot.
+func (bf *bloomFilter) MergeInto(ctx context.Context, destKey string) error {
+	if !bf.usesBitmap() {
+		return ErrIncompatibleFilter
+	}
+	if destKey == "" {
+		return ErrEmptyRedisKey
+	}
+	if err := bf.bitop(ctx, "or", destKey, bf.config.RedisKey); err != nil {
+		return err
+	}
+	return bf.withKey(destKey).recordCreation(ctx)
+}
+
+// compatible checks that other can be combined with the filter and returns it
+func (bf *bloomFilter) compatible(other BloomFilter) (*bloomFilter, error) {
+	src, ok := other.(*bloomFilter)
+	if !ok || !bf.usesBitmap() || !src.usesBitmap() || !sameClient(bf.config.RedisClient, src.config.RedisClient) {
+		return nil, ErrIncompatibleFilter
+	}
+	a, b := bf.spec(), src.spec()
+	if a.Bits != b.Bits || a.Hashes != b.Hashes || a.Hash != b.Hash || a.Layout != b.Layout {
+		return nil, ErrParameterMismatch
+	}
+	return src, nil
+}
+
+// bitop stores the result of BITOP op over dest and src in dest. BITOP replaces dest, so
+// its expiration is read first and restored in the same transaction.
+func (bf *bloomFilter) bitop(ctx context.Context, op, dest, src string) error {
+	client, err := bf.cmdable()
+	if err != nil {
+		return err
+	}
+	if err := bf.limiter.wait(ctx, 2); err != nil {
+		return err
+	}
+
+	ttl, err := client.PTTL(ctx, dest).Result()
+	if err != nil {
+		return err
+	}
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if op == "and" {
+			pipe.BitOpAnd(ctx, dest, dest, src)
+		} else {
+			pipe.BitOpOr(ctx, dest, dest, src)
+		}
+		if ttl > 0 {
+			pipe.PExpire(ctx, dest, ttl)
+		}
+		return nil
+	})
+	return err
+}
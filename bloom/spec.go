@@ -0,0 +1,138 @@
+package bloom
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+)
+
+// SpecVersion is the version of the FilterSpec format produced by ExportSpec
+const SpecVersion = 1
+
+// Position schemes named in a FilterSpec
+const (
+	// SpecPositionsDoubleHashing derives position i as (h1 + i*h2) mod bits, where h1 and h2
+	// are hashes 0 and 1 of the strategy and h2 is incremented when even
+	SpecPositionsDoubleHashing = "double-hashing"
+	// SpecPositionsBlocked picks block h1 mod (bits/512) and derives position i within it
+	// as (lo + i*hi) mod 512 on 32-bit words, where lo and hi|1 are the halves of h2
+	SpecPositionsBlocked = "blocked"
+	// SpecPositionsPartitioned splits the bits into k equal partitions and derives position
+	// i within partition i as (h1 + i*(h2|1)) mod (bits/k)
+	SpecPositionsPartitioned = "partitioned"
+	// SpecPositionsStrategy means the hash strategy derives the positions itself
+	SpecPositionsStrategy = "strategy"
+)
+
+// FilterSpec is a language-agnostic description of how a filter stores items in Redis.
+// It carries everything another implementation needs to read and write the same keys.
+type FilterSpec struct {
+	Version int `json:"version"`
+	// Bits is the number of logical bits m, and Hashes the number of positions k per item
+	Bits   uint64     `json:"bits"`
+	Hashes uint       `json:"hashes"`
+	Hash   HashSpec   `json:"hash"`
+	Layout LayoutSpec `json:"layout"`
+	Keys   KeySpec    `json:"keys"`
+	// TTLMillis is the expiration applied to the keys on every write, zero if none
+	TTLMillis int64 `json:"ttl_ms,omitempty"`
+}
+
+// HashSpec describes how an item's bit positions are computed
+type HashSpec struct {
+	// Strategy is the registered name of the hash strategy, or "custom"
+	Strategy string `json:"strategy"`
+	// Positions is one of the SpecPositions schemes
+	Positions string `json:"positions"`
+	// Seed is the effective seed as a decimal string; when set, every strategy hash h is
+	// replaced by the SplitMix64 finalizer of h XOR seed
+	Seed string `json:"seed,omitempty"`
+}
+
+// LayoutSpec describes how logical bits map to Redis bit offsets
+type LayoutSpec struct {
+	// BitOrder is "msb-first", "lsb-first" or "big-endian-64"
+	BitOrder string `json:"bit_order"`
+	// BlockBits is the block size of a blocked layout, zero otherwise
+	BlockBits int `json:"block_bits,omitempty"`
+}
+
+// KeySpec names the Redis keys of a filter
+type KeySpec struct {
+	// Bitmap is the string key holding the bits
+	Bitmap string `json:"bitmap"`
+	// Metadata is the hash holding creation and rebuild times in Unix milliseconds and
+	// the bits, hashes, hash strategy and library version the filter was created with
+	Metadata string `json:"metadata"`
+	// Audit is the stream receiving an entry per Add, empty if not configured
+	Audit string `json:"audit,omitempty"`
+}
+
+// spec describes the filter's parameters, hashing and key naming
+func (bf *bloomFilter) spec() *FilterSpec {
+	spec := &FilterSpec{
+		Version: SpecVersion,
+		Bits:    bf.bitSize,
+		Hashes:  bf.hashCount,
+		Hash: HashSpec{
+			Strategy:  hashStrategyName(bf.config.HashStrategy),
+			Positions: SpecPositionsDoubleHashing,
+		},
+		Layout: LayoutSpec{BitOrder: bitOrderName(bf.config.BitLayout)},
+		Keys: KeySpec{
+			Bitmap:   bf.config.RedisKey,
+			Metadata: metadataKey(bf.config.RedisKey),
+		},
+		TTLMillis: bf.config.TTL.Milliseconds(),
+	}
+	switch {
+	case bf.config.Blocked:
+		spec.Hash.Positions = SpecPositionsBlocked
+		spec.Layout.BlockBits = blockBits
+	case bf.config.Partitioned:
+		spec.Hash.Positions = SpecPositionsPartitioned
+	default:
+		if _, ok := bf.hashStrategy.(PositionHasher); ok {
+			spec.Hash.Positions = SpecPositionsStrategy
+		}
+	}
+	if seed, ok := effectiveSeed(bf.config); ok {
+		spec.Hash.Seed = strconv.FormatUint(seed, 10)
+	}
+	if bf.config.Audit != nil {
+		spec.Keys.Audit = bf.auditStream()
+	}
+	return spec
+}
+
+// ExportSpec returns the filter's FilterSpec encoded as JSON. Module filters have no
+// spec, as RedisBloom hashes items itself.
+func (bf *bloomFilter) ExportSpec() ([]byte, error) {
+	if !bf.usesBitmap() {
+		return nil, ErrIncompatibleFilter
+	}
+	return json.MarshalIndent(bf.spec(), "", "  ")
+}
+
+// hashStrategyName returns the registered name of a strategy, or "custom" if none of
+// the registered constructors produces an equal strategy
+func hashStrategyName(strategy HashStrategy) string {
+	for _, name := range HashStrategyNames() {
+		if candidate, err := NewHashStrategy(name); err == nil && reflect.DeepEqual(candidate, strategy) {
+			return name
+		}
+	}
+	return "custom"
+}
+
+// bitOrderName returns the FilterSpec name of a bit layout
+func bitOrderName(layout BitLayout) string {
+	switch layout {
+	case BitLayoutLSBFirst:
+		return "lsb-first"
+	case BitLayoutBigEndian64:
+		return "big-endian-64"
+	default:
+		return "msb-first"
+	}
+}
@@ -0,0 +1,169 @@
+package bloom
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultStableCellBits is the default cell width of a StableFilter
+const defaultStableCellBits = 3
+
+// StableOptions sets the stability parameters of a StableFilter
+type StableOptions struct {
+	// CellBits is the width of each cell, between 1 and 8 (defaults to 3); an insert sets
+	// its cells to the maximum value 2^CellBits-1
+	CellBits uint
+	// Decrements is the number of cells decremented on every insert. Zero derives it
+	// from Config.FalsePositiveRate, so the false-positive rate settles at that value.
+	Decrements uint64
+}
+
+// StableFilter is a Stable Bloom filter for deduplicating unbounded streams. Each insert
+// decrements a few cells at random before setting the item's cells to their maximum, so
+// items that are not seen again fade out and the false-positive rate converges to a fixed
+// value instead of growing towards one. In exchange, an item can be forgotten: the longer
+// ago it was added and the more items followed it, the likelier a false negative.
+//
+// Cells are stored with BITFIELD at cfg.RedisKey, one cell per hash position: the number
+// of cells and hashes follows from ExpectedInsertions and FalsePositiveRate as for a
+// regular filter.
+type StableFilter struct {
+	filter     *bloomFilter
+	cellType   string
+	max        uint64
+	decrements uint64
+}
+
+// NewStableFilter creates a stable filter. It requires a server with BITFIELD.
+func NewStableFilter(cfg Config, opts StableOptions) (*StableFilter, error) {
+	if opts.CellBits == 0 {
+		opts.CellBits = defaultStableCellBits
+	}
+	if opts.CellBits > 8 {
+		return nil, ErrInvalidStableCells
+	}
+	if cfg.Blocked || cfg.Engine == EngineRedisBloom {
+		return nil, ErrIncompatibleFilter
+	}
+	if !cfg.Capabilities.Supports(FeatureBitfield) {
+		return nil, ErrBitfieldUnsupported
+	}
+
+	filter, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := filter.cmdable(); err != nil {
+		return nil, err
+	}
+
+	sf := &StableFilter{
+		filter:     filter,
+		cellType:   "u" + strconv.FormatUint(uint64(opts.CellBits), 10),
+		max:        1<<opts.CellBits - 1,
+		decrements: opts.Decrements,
+	}
+	if sf.decrements == 0 {
+		sf.decrements = stableDecrements(filter.bitSize, filter.hashCount, sf.max, cfg.FalsePositiveRate)
+	}
+	if sf.decrements > filter.bitSize {
+		sf.decrements = filter.bitSize
+	}
+	return sf, nil
+}
+
+// stableDecrements returns the number of decrements per insert at which the stable
+// false-positive rate of m cells, k hashes and cell maximum max equals p
+func stableDecrements(m uint64, k uint, max uint64, p float64) uint64 {
+	c := 1/float64(k) - 1/float64(m)
+	x := math.Pow(p, 1/float64(k))
+	decrements := 1 / (c * (math.Pow(1-x, -1/float64(max)) - 1))
+	if decrements < 1 || math.IsNaN(decrements) {
+		return 1
+	}
+	return uint64(math.Ceil(decrements))
+}
+
+// Add decrements the configured number of consecutive cells, starting at a random one,
+// and sets the element's cells to the maximum, in a single BITFIELD command
+func (sf *StableFilter) Add(data []byte) error {
+	ctx := context.Background()
+	client, _ := sf.filter.cmdable()
+	positions := sf.filter.getHashPositions(data)
+	key := sf.filter.config.RedisKey
+	ttl := sf.filter.config.TTL
+
+	if err := sf.filter.limiter.wait(ctx, 1); err != nil {
+		return err
+	}
+
+	m := sf.filter.bitSize
+	start := uint64(rand.Int63n(int64(m)))
+	args := make([]interface{}, 0, 2+3*(int(sf.decrements)+len(positions)))
+	args = append(args, "OVERFLOW", "SAT")
+	for i := uint64(0); i < sf.decrements; i++ {
+		args = append(args, "INCRBY", sf.cellType, "#"+strconv.FormatUint((start+i)%m, 10), -1)
+	}
+	for _, pos := range positions {
+		args = append(args, "SET", sf.cellType, "#"+strconv.FormatUint(pos, 10), sf.max)
+	}
+
+	var err error
+	if ttl > 0 {
+		_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.BitField(ctx, key, args...)
+			pipe.Expire(ctx, key, ttl)
+			return nil
+		})
+	} else {
+		err = client.BitField(ctx, key, args...).Err()
+	}
+	if err != nil {
+		return err
+	}
+	sf.filter.recordInserts(1)
+	return nil
+}
+
+// Exists checks if an element was seen recently enough that none of its cells has
+// decayed to zero
+func (sf *StableFilter) Exists(data []byte) (bool, error) {
+	ctx := context.Background()
+	client, _ := sf.filter.cmdable()
+	positions := sf.filter.getHashPositions(data)
+
+	if err := sf.filter.limiter.wait(ctx, 1); err != nil {
+		return false, err
+	}
+
+	args := make([]interface{}, 0, 3*len(positions))
+	for _, pos := range positions {
+		args = append(args, "GET", sf.cellType, "#"+strconv.FormatUint(pos, 10))
+	}
+	cells, err := client.BitField(ctx, sf.filter.config.RedisKey, args...).Result()
+	if err != nil {
+		return false, err
+	}
+	for _, cell := range cells {
+		if cell == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Decrements returns the number of cells decremented on every insert
+func (sf *StableFilter) Decrements() uint64 {
+	return sf.decrements
+}
+
+// StableFalsePositiveRate returns the false-positive rate the filter converges to
+func (sf *StableFilter) StableFalsePositiveRate() float64 {
+	k := float64(sf.filter.hashCount)
+	c := float64(sf.decrements) * (1/k - 1/float64(sf.filter.bitSize))
+	return math.Pow(1-math.Pow(1/(1+1/c), float64(sf.max)), k)
+}
@@ -0,0 +1,100 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Stats describes the state of a Bloom Filter
+type Stats struct {
+	// CreatedAt is when the filter was first written; zero if unknown
+	CreatedAt time.Time
+	// RebuiltAt is when the filter was last rebuilt; zero if never
+	RebuiltAt time.Time
+	// BitSize and HashCount are the filter's parameters; zero for RedisBloom filters
+	BitSize   uint64
+	HashCount uint
+	// HashStrategy is the registered name of the hash strategy, or "custom"
+	HashStrategy string
+	// SetBits is the BITCOUNT of the bitmap
+	SetBits uint64
+	// FillRatio is the fraction of bits set
+	FillRatio float64
+	// MemoryUsage is the MEMORY USAGE of the key in bytes; zero if missing or unreported
+	MemoryUsage int64
+	// TTL is the key's remaining time to live; zero if the key is missing or persistent
+	TTL time.Duration
+}
+
+// Age returns how long ago the filter was created, or zero if unknown
+func (s *Stats) Age() time.Duration {
+	if s.CreatedAt.IsZero() {
+		return 0
+	}
+	return time.Since(s.CreatedAt)
+}
+
+// SinceRebuild returns how long ago the filter was last rebuilt or created, or zero if unknown
+func (s *Stats) SinceRebuild() time.Duration {
+	if s.RebuiltAt.IsZero() {
+		return s.Age()
+	}
+	return time.Since(s.RebuiltAt)
+}
+
+// Stats returns the current state of the filter, reading the metadata, BITCOUNT,
+// MEMORY USAGE and PTTL of its keys in one pipeline
+func (bf *bloomFilter) Stats() (*Stats, error) {
+	ctx := context.Background()
+	client, err := bf.cmdable()
+	if err != nil {
+		return nil, err
+	}
+
+	key := bf.config.RedisKey
+	pipe := client.Pipeline()
+	fieldsCmd := pipe.HGetAll(ctx, metadataKey(key))
+	var setCmd *redis.IntCmd
+	if bf.usesBitmap() {
+		setCmd = pipe.BitCount(ctx, key, nil)
+	}
+	memoryCmd := pipe.MemoryUsage(ctx, key)
+	ttlCmd := pipe.PTTL(ctx, key)
+	_, _ = pipe.Exec(ctx)
+
+	fields, err := fieldsCmd.Result()
+	if err != nil {
+		return nil, err
+	}
+	meta := decodeMetadata(fields)
+	stats := &Stats{CreatedAt: meta.CreatedAt, RebuiltAt: meta.RebuiltAt}
+	if setCmd != nil {
+		set, err := setCmd.Result()
+		if err != nil {
+			return nil, err
+		}
+		stats.BitSize = bf.bitSize
+		stats.HashCount = bf.hashCount
+		stats.HashStrategy = hashStrategyName(bf.config.HashStrategy)
+		stats.SetBits = uint64(set)
+		stats.FillRatio = float64(set) / float64(bf.bitSize)
+	}
+	// Servers without MEMORY USAGE, or ACLs denying it, leave the field zero
+	memory, err := memoryCmd.Result()
+	var replyErr redis.Error
+	if err != nil && !errors.Is(err, redis.Nil) && !errors.As(err, &replyErr) {
+		return nil, err
+	}
+	stats.MemoryUsage = memory
+	ttl, err := ttlCmd.Result()
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		stats.TTL = ttl
+	}
+	return stats, nil
+}
@@ -0,0 +1,59 @@
+package bloom
+
+import "context"
+
+// streamBatchSize is the largest number of items AddStream writes in one pipeline
+const streamBatchSize = 1000
+
+// AddStream adds the items received from items until it is closed or ctx is done. Each
+// write takes the items already waiting in the channel, up to 1000, so batches grow with
+// the inflow while a lone item is written at once; the next items are only received once
+// the batch is written, which pushes back on the producer. Write errors, then ctx's error
+// if it ends the stream, are sent on the returned channel, which is closed when the
+// stream ends. Receive from it until it is closed, as the stream waits for every error
+// to be received. Items must not be modified after they are sent.
+func (bf *bloomFilter) AddStream(ctx context.Context, items <-chan []byte) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		for {
+			var batch [][]byte
+			select {
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			case data, ok := <-items:
+				if !ok {
+					return
+				}
+				batch = append(batch, data)
+			}
+			batch, open := drainStream(items, batch)
+
+			if err := bf.addItems(ctx, batch); err != nil {
+				errs <- err
+			}
+			if !open {
+				return
+			}
+		}
+	}()
+	return errs
+}
+
+// drainStream appends the items waiting in the channel to batch, up to the batch size,
+// and reports whether the channel is still open
+func drainStream(items <-chan []byte, batch [][]byte) ([][]byte, bool) {
+	for len(batch) < streamBatchSize {
+		select {
+		case data, ok := <-items:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, data)
+		default:
+			return batch, true
+		}
+	}
+	return batch, true
+}
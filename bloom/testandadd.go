@@ -0,0 +1,101 @@
+package bloom
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// testAndAddScript sets the bits at offsets ARGV[2..] of KEYS[1] and returns 1 if all of
+// them were already set, 0 otherwise. ARGV[1] is a TTL in milliseconds, applied to the
+// bitmap and the metadata key KEYS[2] as described for luaExpireSource.
+var testAndAddScript = redis.NewScript(testAndAddSource)
+
+// testAndAddSource is the body of testAndAddScript, shared with the function library
+const testAndAddSource = `
+local present = 1
+for i = 2, #ARGV do
+	if redis.call('SETBIT', KEYS[1], ARGV[i], 1) == 0 then
+		present = 0
+	end
+end
+` + luaExpireSource + `
+return present
+`
+
+// TestAndAdd adds an element and reports whether it was already present, as one atomic
+// Lua script call. Of several concurrent callers adding the same element, exactly one
+// sees false. Like Exists, a true answer may be a false positive. The functions engine
+// calls the same script with FCALL, and module filters use BF.ADD, which is atomic by
+// itself.
+func (bf *bloomFilter) TestAndAdd(data []byte) (bool, error) {
+	ctx := context.Background()
+	if !bf.usesBitmap() {
+		return bf.testAndAddModule(ctx, data)
+	}
+	if !bf.config.Capabilities.Supports(FeatureLua) {
+		return false, ErrLuaUnsupported
+	}
+	client, err := bf.cmdable()
+	if err != nil {
+		return false, err
+	}
+
+	positions := bf.getHashPositions(data)
+	if err := bf.limiter.wait(ctx, len(positions)); err != nil {
+		return false, err
+	}
+	if err := bf.admit(ctx); err != nil {
+		return false, err
+	}
+	if err := bf.recordCreation(ctx); err != nil {
+		return false, err
+	}
+
+	args := make([]interface{}, 1, 1+len(positions))
+	args[0] = bf.scriptTTL()
+	for _, pos := range positions {
+		args = append(args, bf.offset(pos))
+	}
+	keys := []string{bf.config.RedisKey, metadataKey(bf.config.RedisKey)}
+	var present int
+	if bf.config.Engine == EngineFunctions {
+		present, err = bf.scripts.fcall(ctx, client, functionTestAndAdd, keys, args...).Int()
+	} else {
+		present, err = testAndAddScript.Run(ctx, client, keys, args...).Int()
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if bf.cache != nil {
+		bf.cache.set(bf.cache.key(data), true)
+	}
+	if present == 1 {
+		return true, nil
+	}
+	if bf.config.Audit != nil {
+		if err := bf.appendAudit(ctx, data); err != nil {
+			return false, err
+		}
+	}
+	bf.recordInserts(1)
+	bf.watchSaturation()
+	return false, bf.publishUpdate(ctx, positions)
+}
+
+// testAndAddModule adds an element to a module filter with BF.ADD, which reports whether
+// the element was new
+func (bf *bloomFilter) testAndAddModule(ctx context.Context, data []byte) (bool, error) {
+	added, err := bf.addModuleItems(ctx, [][]byte{data})
+	if err != nil {
+		return false, err
+	}
+	if !added[0] {
+		if bf.cache != nil {
+			bf.cache.set(bf.cache.key(data), true)
+		}
+		return true, bf.refreshTTL(ctx)
+	}
+	return false, bf.afterAdd(ctx, data)
+}
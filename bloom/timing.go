@@ -0,0 +1,71 @@
+package bloom
+
+import "time"
+
+// phaseNames names the duration metrics of the phases of one operation
+type phaseNames struct {
+	hash     string
+	pipeline string
+	redis    string
+}
+
+// Phase metric names of Add and Exists
+var (
+	addPhases    = phaseNames{MetricAddHashDuration, MetricAddPipelineDuration, MetricAddRedisDuration}
+	existsPhases = phaseNames{MetricExistsHashDuration, MetricExistsPipelineDuration, MetricExistsRedisDuration}
+)
+
+// phaseTimer attributes the time of an operation to consecutive phases: computing the
+// hash positions, building the pipeline, and waiting on Redis. A nil timer records nothing.
+type phaseTimer struct {
+	metrics Metrics
+	names   phaseNames
+	last    time.Time
+}
+
+// startPhases starts timing an operation, or returns nil when metrics are discarded
+func (bf *bloomFilter) startPhases(names phaseNames) *phaseTimer {
+	if _, ok := bf.metrics.(noopMetrics); ok {
+		return nil
+	}
+	return &phaseTimer{metrics: bf.metrics, names: names, last: time.Now()}
+}
+
+// lap observes the time since the previous lap under the given metric name
+func (t *phaseTimer) lap(name string) {
+	if t == nil {
+		return
+	}
+	now := time.Now()
+	t.metrics.ObserveDuration(name, now.Sub(t.last))
+	t.last = now
+}
+
+// skip restarts the clock without observing, excluding time such as rate limiting
+func (t *phaseTimer) skip() {
+	if t == nil {
+		return
+	}
+	t.last = time.Now()
+}
+
+// hashed ends the hashing phase
+func (t *phaseTimer) hashed() {
+	if t != nil {
+		t.lap(t.names.hash)
+	}
+}
+
+// built ends the pipeline building phase
+func (t *phaseTimer) built() {
+	if t != nil {
+		t.lap(t.names.pipeline)
+	}
+}
+
+// executed ends the Redis phase
+func (t *phaseTimer) executed() {
+	if t != nil {
+		t.lap(t.names.redis)
+	}
+}
@@ -0,0 +1,426 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Client-side caching settings
+const (
+	// trackingBlockBytes is the size of the bitmap ranges cached locally
+	trackingBlockBytes    = 64
+	defaultTrackingBlocks = 4096
+	// trackingInvalidateChannel is the channel Redis sends redirected invalidations to
+	trackingInvalidateChannel = "__redis__:invalidate"
+	// trackingRetryDelay is the pause between attempts to reach Redis after the
+	// invalidation connection failed
+	trackingRetryDelay = 100 * time.Millisecond
+)
+
+// TrackingOptions configures client-side caching
+type TrackingOptions struct {
+	// MaxBlocks caps the number of cached 64-byte bitmap blocks (defaults to 4096, 256 KiB)
+	MaxBlocks int
+}
+
+// TrackingClient is a RedisClient that caches the bitmap blocks read by Exists locally,
+// using Redis 6+ client-side caching: reads go through a connection with CLIENT
+// TRACKING on, and Redis notifies the client when another client changes a cached key,
+// which drops the key's blocks. Repeated lookups of hot elements are then answered
+// without a round trip.
+//
+// Invalidations are redirected to a Pub/Sub connection, the RESP2 form of tracking,
+// because go-redis does not expose RESP3 push messages. They arrive asynchronously, so a
+// write by another client becomes visible after a short delay; writes through the
+// TrackingClient itself are visible immediately. If the invalidation connection drops,
+// the cache is cleared and reads bypass it until tracking is restored. Close the client
+// when the filters using it are no longer needed.
+type TrackingClient struct {
+	client *redis.Client
+	sub    *redis.Client
+	reads  *redis.Client
+	pubsub *redis.PubSub
+	subID  int64
+	max    int
+
+	mu       sync.Mutex
+	blocks   map[string]map[int64][]byte
+	count    int
+	epoch    uint64
+	tracking bool
+
+	closing chan struct{}
+}
+
+var (
+	_ RedisClient     = (*TrackingClient)(nil)
+	_ CmdableProvider = (*TrackingClient)(nil)
+)
+
+// NewTrackingClient opens the invalidation and tracked read connections of a client-side
+// cache in front of client. Writes and all commands beyond bit reads go through client.
+// Hooks installed on client are not copied to the two connections.
+func NewTrackingClient(ctx context.Context, client *redis.Client, opts TrackingOptions) (*TrackingClient, error) {
+	if client == nil {
+		return nil, ErrNilRedisClient
+	}
+	tc := &TrackingClient{
+		client:  client,
+		max:     opts.MaxBlocks,
+		blocks:  make(map[string]map[int64][]byte),
+		closing: make(chan struct{}),
+	}
+	if tc.max <= 0 {
+		tc.max = defaultTrackingBlocks
+	}
+
+	base := *client.Options()
+	onConnect := base.OnConnect
+
+	subOpts := base
+	subOpts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		if onConnect != nil {
+			if err := onConnect(ctx, cn); err != nil {
+				return err
+			}
+		}
+		id, err := cn.ClientID(ctx).Result()
+		if err != nil {
+			return err
+		}
+		atomic.StoreInt64(&tc.subID, id)
+		return nil
+	}
+	tc.sub = redis.NewClient(&subOpts)
+	tc.pubsub = tc.sub.Subscribe(ctx, trackingInvalidateChannel)
+	if _, err := tc.pubsub.Receive(ctx); err != nil {
+		tc.pubsub.Close()
+		tc.sub.Close()
+		return nil, err
+	}
+
+	readOpts := base
+	readOpts.PoolSize, readOpts.MinIdleConns, readOpts.MaxIdleConns = 1, 0, 1
+	readOpts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		if onConnect != nil {
+			if err := onConnect(ctx, cn); err != nil {
+				return err
+			}
+		}
+		if err := tc.track(ctx, cn); err != nil {
+			return err
+		}
+		// Keys tracked by a previous read connection are no longer tracked
+		tc.invalidate(nil)
+		return nil
+	}
+	tc.reads = redis.NewClient(&readOpts)
+	if err := tc.reads.Ping(ctx).Err(); err != nil {
+		tc.Close()
+		return nil, err
+	}
+
+	tc.tracking = true
+	go tc.listen()
+	return tc, nil
+}
+
+// commandProcessor runs arbitrary commands, like a go-redis client or connection
+type commandProcessor interface {
+	Process(ctx context.Context, cmd redis.Cmder) error
+}
+
+// track enables tracking on a read connection, redirecting invalidations to the current
+// invalidation connection
+func (tc *TrackingClient) track(ctx context.Context, cn commandProcessor) error {
+	id := atomic.LoadInt64(&tc.subID)
+	return cn.Process(ctx, redis.NewStatusCmd(ctx, "client", "tracking", "on", "redirect", strconv.FormatInt(id, 10)))
+}
+
+// listen drops cached blocks as invalidations arrive, and restores tracking when go-redis
+// resubscribes on a new invalidation connection
+func (tc *TrackingClient) listen() {
+	ctx := context.Background()
+	for {
+		msg, err := tc.pubsub.Receive(ctx)
+		if errors.Is(err, redis.ErrClosed) {
+			return
+		}
+		if err != nil {
+			// Invalidations may have been lost with the connection
+			tc.setTracking(false)
+			select {
+			case <-tc.closing:
+				return
+			case <-time.After(trackingRetryDelay):
+			}
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			tc.setTracking(tc.track(ctx, tc.reads) == nil)
+		case *redis.Message:
+			keys := msg.PayloadSlice
+			if keys == nil && msg.Payload != "" {
+				keys = []string{msg.Payload}
+			}
+			tc.invalidate(keys)
+		}
+	}
+}
+
+// setTracking clears the cache and enables or disables it
+func (tc *TrackingClient) setTracking(on bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.clear()
+	tc.tracking = on
+}
+
+// invalidate drops the cached blocks of keys, or all blocks if keys is empty
+func (tc *TrackingClient) invalidate(keys []string) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if len(keys) == 0 {
+		tc.clear()
+		return
+	}
+	tc.epoch++
+	for _, key := range keys {
+		tc.count -= len(tc.blocks[key])
+		delete(tc.blocks, key)
+	}
+}
+
+// clear drops all cached blocks; the caller holds tc.mu
+func (tc *TrackingClient) clear() {
+	tc.epoch++
+	tc.blocks = make(map[string]map[int64][]byte)
+	tc.count = 0
+}
+
+// store caches a block fetched in epoch, unless an invalidation arrived since or tracking
+// is off; the caller holds tc.mu
+func (tc *TrackingClient) store(epoch uint64, key string, index int64, block []byte) {
+	if epoch != tc.epoch || !tc.tracking {
+		return
+	}
+	if tc.count >= tc.max {
+		tc.evict()
+	}
+	blocks := tc.blocks[key]
+	if blocks == nil {
+		blocks = make(map[int64][]byte)
+		tc.blocks[key] = blocks
+	}
+	if _, ok := blocks[index]; !ok {
+		tc.count++
+	}
+	blocks[index] = block
+}
+
+// evict drops an arbitrary cached block; the caller holds tc.mu
+func (tc *TrackingClient) evict() {
+	for key, blocks := range tc.blocks {
+		for index := range blocks {
+			delete(blocks, index)
+			tc.count--
+			break
+		}
+		if len(blocks) == 0 {
+			delete(tc.blocks, key)
+		}
+		return
+	}
+}
+
+// blockRef identifies a cached block
+type blockRef struct {
+	key   string
+	index int64
+}
+
+// getBits answers GETBIT commands from cached blocks, fetching missing blocks through
+// the tracked read connection in one pipeline
+func (tc *TrackingClient) getBits(ctx context.Context, cmds []*redis.IntCmd) error {
+	tc.mu.Lock()
+	epoch, tracking := tc.epoch, tc.tracking
+	fetched := make(map[blockRef][]byte)
+	for _, cmd := range cmds {
+		key, offset := cmd.Args()[1].(string), cmd.Args()[2].(int64)
+		ref := blockRef{key: key, index: offset / 8 / trackingBlockBytes}
+		if block, ok := tc.blocks[key][ref.index]; ok {
+			fetched[ref] = block
+		} else {
+			fetched[ref] = nil
+		}
+	}
+	tc.mu.Unlock()
+
+	if !tracking {
+		pipe := tc.client.Pipeline()
+		for _, cmd := range cmds {
+			_ = pipe.Process(ctx, cmd)
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+
+	pipe := tc.reads.Pipeline()
+	ranges := make(map[blockRef]*redis.StringCmd)
+	for ref, block := range fetched {
+		if block == nil {
+			start := ref.index * trackingBlockBytes
+			ranges[ref] = pipe.GetRange(ctx, ref.key, start, start+trackingBlockBytes-1)
+		}
+	}
+	if len(ranges) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		tc.mu.Lock()
+		for ref, rng := range ranges {
+			block := []byte(rng.Val())
+			fetched[ref] = block
+			tc.store(epoch, ref.key, ref.index, block)
+		}
+		tc.mu.Unlock()
+	}
+
+	for _, cmd := range cmds {
+		key, offset := cmd.Args()[1].(string), cmd.Args()[2].(int64)
+		block := fetched[blockRef{key: key, index: offset / 8 / trackingBlockBytes}]
+		i := offset / 8 % trackingBlockBytes
+		if i < int64(len(block)) && block[i]&(0x80>>(offset%8)) != 0 {
+			cmd.SetVal(1)
+		} else {
+			cmd.SetVal(0)
+		}
+	}
+	return nil
+}
+
+// SetBit sets a bit through the application's client and drops the key's cached blocks
+func (tc *TrackingClient) SetBit(ctx context.Context, key string, offset int64, value int) *redis.IntCmd {
+	cmd := tc.client.SetBit(ctx, key, offset, value)
+	tc.invalidate([]string{key})
+	return cmd
+}
+
+// GetBit reads a bit from the local cache
+func (tc *TrackingClient) GetBit(ctx context.Context, key string, offset int64) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "getbit", key, offset)
+	_ = tc.getBits(ctx, []*redis.IntCmd{cmd})
+	return cmd
+}
+
+// Expire sets a timeout on the key through the application's client
+func (tc *TrackingClient) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	return tc.client.Expire(ctx, key, expiration)
+}
+
+// Pipeline returns a pipeline that answers its bit reads from the local cache
+func (tc *TrackingClient) Pipeline() Pipeliner {
+	return &trackingPipeline{client: tc}
+}
+
+// Cmdable returns the application's client, which serves all commands beyond bit reads
+func (tc *TrackingClient) Cmdable() redis.Cmdable {
+	return tc.client
+}
+
+// Close closes the invalidation and read connections; the application's client stays open
+func (tc *TrackingClient) Close() error {
+	select {
+	case <-tc.closing:
+		return nil
+	default:
+	}
+	close(tc.closing)
+	err := tc.pubsub.Close()
+	if tc.reads != nil {
+		if rerr := tc.reads.Close(); err == nil {
+			err = rerr
+		}
+	}
+	if serr := tc.sub.Close(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// trackingPipeline queues commands for a TrackingClient
+type trackingPipeline struct {
+	client *TrackingClient
+	cmds   []redis.Cmder
+}
+
+// SetBit queues a SETBIT
+func (p *trackingPipeline) SetBit(ctx context.Context, key string, offset int64, value int) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "setbit", key, offset, value)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// GetBit queues a GETBIT
+func (p *trackingPipeline) GetBit(ctx context.Context, key string, offset int64) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "getbit", key, offset)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// Expire queues an EXPIRE
+func (p *trackingPipeline) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx, "expire", key, expiration)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// Exec sends the queued writes to the application's client in one round trip, then
+// answers the queued reads, which therefore see the writes
+func (p *trackingPipeline) Exec(ctx context.Context) ([]redis.Cmder, error) {
+	cmds := p.cmds
+	p.cmds = nil
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	var reads []*redis.IntCmd
+	var written []string
+	pipe := p.client.client.Pipeline()
+	for _, cmd := range cmds {
+		if cmd.Name() == "getbit" {
+			reads = append(reads, cmd.(*redis.IntCmd))
+			continue
+		}
+		if cmd.Name() == "setbit" {
+			written = append(written, cmd.Args()[1].(string))
+		}
+		_ = pipe.Process(ctx, cmd)
+	}
+	if len(reads) < len(cmds) {
+		_, err := pipe.Exec(ctx)
+		if len(written) > 0 {
+			p.client.invalidate(written)
+		}
+		if err != nil {
+			return cmds, err
+		}
+	}
+	if len(reads) > 0 {
+		if err := p.client.getBits(ctx, reads); err != nil {
+			return cmds, err
+		}
+	}
+	return cmds, nil
+}
@@ -0,0 +1,157 @@
+package bloom
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TTLMode selects when the configured TTL is applied to a filter's keys
+type TTLMode string
+
+const (
+	// TTLRefresh applies the TTL on every write, so a filter expires TTL after its last
+	// Add. It is the default.
+	TTLRefresh TTLMode = "refresh"
+	// TTLFixed applies the TTL only to keys without one, with EXPIRE NX, so a filter
+	// expires TTL after its first Add however often it is written. Rebuilds keep the
+	// remaining TTL. Outside the scripted engines it requires Redis 7.
+	TTLFixed TTLMode = "fixed"
+	// TTLSliding refreshes the TTL on every write and on every Exists or ExistsMany
+	// answered by Redis, so a filter expires TTL after it was last used. Answers from the
+	// result cache and lookups the library makes itself (FPRProbe, PrefetchExists, Policy
+	// and ExistsExplain) do not extend it.
+	TTLSliding TTLMode = "sliding"
+)
+
+// luaExpireSource applies the TTL in milliseconds in ARGV[1] to KEYS[1] and KEYS[2]. A
+// negative TTL is only applied to keys without one. It is shared by the write scripts.
+const luaExpireSource = `
+local ttl = tonumber(ARGV[1])
+if ttl ~= 0 then
+	for k = 1, 2 do
+		if ttl > 0 or redis.call('PTTL', KEYS[k]) == -1 then
+			redis.call('PEXPIRE', KEYS[k], math.abs(ttl))
+		end
+	end
+end
+`
+
+// validateTTLMode checks the TTL mode and the client features it depends on
+func validateTTLMode(cfg Config) error {
+	switch cfg.TTLMode {
+	case "", TTLRefresh, TTLSliding:
+		return nil
+	case TTLFixed:
+		if cfg.TTL <= 0 || cfg.Engine == EngineLua || cfg.Engine == EngineFunctions {
+			return nil
+		}
+		if !cfg.Capabilities.Supports(FeatureExpireNX) {
+			return ErrExpireNXUnsupported
+		}
+		if _, ok := cfg.RedisClient.(CmdableProvider); !ok {
+			return ErrCommandsUnsupported
+		}
+		return nil
+	default:
+		return ErrUnknownTTLMode
+	}
+}
+
+// refreshTTL applies the TTL after a write, unless a scripted engine has already done so
+// server-side or the write pipeline carried it
+func (bf *bloomFilter) refreshTTL(ctx context.Context) error {
+	if bf.scripted() || bf.pipelinesTTL() {
+		return nil
+	}
+	return bf.applyTTL(ctx)
+}
+
+// pipelinesTTL reports whether the plain bitmap engine sends the TTL in the same pipeline
+// as its SETBITs. TTLFixed mode needs EXPIRE NX, which Pipeliner does not offer.
+func (bf *bloomFilter) pipelinesTTL() bool {
+	return bf.config.TTL > 0 && bf.config.TTLMode != TTLFixed && bf.usesBitmap() && !bf.scripted() && bf.config.Engine != EngineBitfield
+}
+
+// pipelineTTL queues the TTL of the filter and its metadata after the SETBITs on pipe
+func (bf *bloomFilter) pipelineTTL(ctx context.Context, pipe Pipeliner) {
+	if !bf.pipelinesTTL() {
+		return
+	}
+	pipe.Expire(ctx, bf.config.RedisKey, bf.config.TTL)
+	pipe.Expire(ctx, metadataKey(bf.config.RedisKey), bf.config.TTL)
+}
+
+// slideTTL applies the TTL after a lookup in TTLSliding mode. Only the user-facing
+// Exists and ExistsMany call it; internal lookups use exists, existsMany or checkItems
+// without sliding, so they do not keep an unused filter alive.
+func (bf *bloomFilter) slideTTL(ctx context.Context) error {
+	if bf.config.TTLMode != TTLSliding {
+		return nil
+	}
+	return bf.applyTTL(ctx)
+}
+
+// applyTTL sets the configured TTL on the filter and its metadata
+func (bf *bloomFilter) applyTTL(ctx context.Context) error {
+	if bf.config.TTL <= 0 {
+		return nil
+	}
+	keys := []string{bf.config.RedisKey, metadataKey(bf.config.RedisKey)}
+	if bf.config.TTLMode == TTLFixed {
+		client, err := bf.cmdable()
+		if err != nil {
+			return err
+		}
+		_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			bf.queueExpire(ctx, pipe, keys...)
+			return nil
+		})
+		return err
+	}
+	for _, key := range keys {
+		if err := bf.config.RedisClient.Expire(ctx, key, bf.config.TTL).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queueExpire queues the TTL of keys on pipe, with EXPIRE NX in TTLFixed mode
+func (bf *bloomFilter) queueExpire(ctx context.Context, pipe redis.Pipeliner, keys ...string) {
+	for _, key := range keys {
+		if bf.config.TTLMode == TTLFixed {
+			pipe.ExpireNX(ctx, key, bf.config.TTL)
+		} else {
+			pipe.Expire(ctx, key, bf.config.TTL)
+		}
+	}
+}
+
+// scriptTTL returns the TTL argument of the write scripts: the TTL in milliseconds,
+// negated in TTLFixed mode
+func (bf *bloomFilter) scriptTTL() string {
+	ms := bf.config.TTL.Milliseconds()
+	if bf.config.TTLMode == TTLFixed {
+		ms = -ms
+	}
+	return strconv.FormatInt(ms, 10)
+}
+
+// swapTTL returns the TTL of a bitmap swapped in by a rebuild: the configured TTL, or in
+// TTLFixed mode the remaining TTL of the filter key, as SET with KEEPTTL would keep it
+func (bf *bloomFilter) swapTTL(ctx context.Context, client redis.Cmdable) (time.Duration, error) {
+	if bf.config.TTL <= 0 || bf.config.TTLMode != TTLFixed {
+		return bf.config.TTL, nil
+	}
+	ttl, err := client.PTTL(ctx, bf.config.RedisKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl > 0 {
+		return ttl, nil
+	}
+	return bf.config.TTL, nil
+}
@@ -0,0 +1,4 @@
+package bloom
+
+// Version is the library version, recorded in the metadata of the filters it creates
+const Version = "1.0.0"
@@ -0,0 +1,130 @@
+package bloom
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// windowKeyPrefix prefixes the companion keys of the time-bucket filters
+const windowKeyPrefix = "window:"
+
+// RotatingOptions configures the time buckets of a RotatingBloomFilter
+type RotatingOptions struct {
+	// Bucket is the time span covered by each bucket filter, at least one second
+	Bucket time.Duration
+	// Buckets is the number of buckets queried, including the current one; the filter
+	// remembers items for between Buckets-1 and Buckets bucket spans
+	Buckets int
+}
+
+// RotatingBloomFilter answers "seen within the last N buckets" by writing each item to the
+// filter of the current time bucket and querying the last N bucket filters. Bucket filters
+// expire on their own once they leave the window, so nothing has to be deleted.
+type RotatingBloomFilter struct {
+	base *bloomFilter
+	opts RotatingOptions
+
+	mu      sync.Mutex
+	start   time.Time
+	current *bloomFilter
+}
+
+// NewRotatingBloomFilter creates a rotating filter. Each bucket filter is sized by cfg and
+// stored in a companion key of cfg.RedisKey named after the bucket's start time; cfg.TTL
+// is replaced by the window length, and the audit log is not supported.
+func NewRotatingBloomFilter(cfg Config, opts RotatingOptions) (*RotatingBloomFilter, error) {
+	if opts.Bucket < time.Second || opts.Buckets <= 0 {
+		return nil, ErrInvalidRotatingWindow
+	}
+	cfg.TTL = opts.Bucket * time.Duration(opts.Buckets)
+	cfg.Audit = nil
+	base, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !base.usesBitmap() {
+		return nil, ErrIncompatibleFilter
+	}
+	return &RotatingBloomFilter{base: base, opts: opts}, nil
+}
+
+// Add adds an element to the current bucket
+func (rf *RotatingBloomFilter) Add(data []byte) error {
+	return rf.bucket(time.Now()).Add(data)
+}
+
+// Exists checks if an element was added to any bucket of the window, reading all of them
+// in one pipeline
+func (rf *RotatingBloomFilter) Exists(data []byte) (bool, error) {
+	ctx := context.Background()
+	positions := rf.base.getHashPositions(data)
+	if err := rf.base.limiter.wait(ctx, len(positions)*rf.opts.Buckets); err != nil {
+		return false, err
+	}
+
+	pipe, err := rf.base.pipeline()
+	if err != nil {
+		return false, err
+	}
+	defer rf.base.releasePipeline(pipe)
+	bits := make([][]*redis.IntCmd, rf.opts.Buckets)
+	for i, key := range rf.Keys() {
+		for _, pos := range positions {
+			bits[i] = append(bits[i], pipe.GetBit(ctx, key, rf.base.offset(pos)))
+		}
+	}
+	if err := execPipeline(ctx, pipe); err != nil {
+		return false, err
+	}
+
+	for _, bucket := range bits {
+		if allBitsSet(bucket) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// allBitsSet reports whether every executed GETBIT returned 1
+func allBitsSet(cmds []*redis.IntCmd) bool {
+	for _, cmd := range cmds {
+		if cmd.Val() == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Keys returns the keys of the buckets in the window, newest first. Buckets nothing was
+// added to do not exist in Redis.
+func (rf *RotatingBloomFilter) Keys() []string {
+	newest := time.Now().Truncate(rf.opts.Bucket)
+	keys := make([]string, rf.opts.Buckets)
+	for i := range keys {
+		keys[i] = rf.bucketKey(newest.Add(-time.Duration(i) * rf.opts.Bucket))
+	}
+	return keys
+}
+
+// bucket returns the filter of the bucket containing t, keeping the current one so its
+// per-key state (metadata, admission) carries over between Adds
+func (rf *RotatingBloomFilter) bucket(t time.Time) *bloomFilter {
+	start := t.Truncate(rf.opts.Bucket)
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.current == nil || !rf.start.Equal(start) {
+		rf.current = rf.base.withKey(rf.bucketKey(start))
+		rf.current.cache = nil
+		rf.start = start
+	}
+	return rf.current
+}
+
+// bucketKey returns the key of the bucket starting at start
+func (rf *RotatingBloomFilter) bucketKey(start time.Time) string {
+	return companionKey(rf.base.config.RedisKey, windowKeyPrefix+strconv.FormatInt(start.Unix(), 10))
+}
@@ -0,0 +1,312 @@
+package bloom
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default write-behind settings
+const (
+	defaultWriteBehindBatch    = 1000
+	defaultWriteBehindInterval = 100 * time.Millisecond
+	defaultWriteBehindQueue    = 100_000
+)
+
+// WriteBehindOptions configures the queue of a BufferedFilter
+type WriteBehindOptions struct {
+	// MaxBatch is the number of queued items that triggers a write (defaults to 1000)
+	MaxBatch int
+	// FlushInterval is the longest an item waits in the queue (defaults to 100ms)
+	FlushInterval time.Duration
+	// MaxQueued caps the number of queued items; Add blocks while the queue is full
+	// (defaults to 100000)
+	MaxQueued int
+	// OnError is called with the items of a batch whose write failed; they are dropped
+	OnError func(items [][]byte, err error)
+}
+
+// BufferedFilter is a write-behind filter: Add only enqueues the item in memory, and a
+// background worker writes the queue with one AddMany pipeline every MaxBatch items or
+// FlushInterval, whichever comes first. Items become visible to Exists once written, so
+// ingest paths trade a short visibility delay for not waiting on Redis per item. Queued
+// items are lost if the process dies; Close writes them before returning.
+type BufferedFilter struct {
+	filter   *bloomFilter
+	maxBatch int
+	interval time.Duration
+	onError  func(items [][]byte, err error)
+
+	items   chan queuedItem
+	flushes chan chan error
+	pauses  chan bool
+	closing chan struct{}
+	done    chan struct{}
+	// stopping is closed when Close starts, releasing Adds blocked on a full queue
+	stopping chan struct{}
+	stopOnce sync.Once
+	// mu guards closed; Add holds it for reading while it enqueues
+	mu     sync.RWMutex
+	closed bool
+
+	// batched is the number of items the worker holds, and oldest the enqueue time of
+	// the first of them in Unix nanoseconds, zero if it holds none
+	batched atomic.Int64
+	oldest  atomic.Int64
+}
+
+// queuedItem is an item waiting to be written
+type queuedItem struct {
+	data []byte
+	at   time.Time
+}
+
+// NewBufferedFilter creates a write-behind filter and starts its worker
+func NewBufferedFilter(cfg Config, opts WriteBehindOptions) (*BufferedFilter, error) {
+	bf, err := newBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := bf.checkParameters(context.Background()); err != nil {
+		return nil, err
+	}
+	b := &BufferedFilter{
+		filter:   bf,
+		maxBatch: opts.MaxBatch,
+		interval: opts.FlushInterval,
+		onError:  opts.OnError,
+		flushes:  make(chan chan error),
+		pauses:   make(chan bool),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+		stopping: make(chan struct{}),
+	}
+	if b.maxBatch <= 0 {
+		b.maxBatch = defaultWriteBehindBatch
+	}
+	if b.interval <= 0 {
+		b.interval = defaultWriteBehindInterval
+	}
+	queue := opts.MaxQueued
+	if queue <= 0 {
+		queue = defaultWriteBehindQueue
+	}
+	b.items = make(chan queuedItem, queue)
+	go b.run()
+	return b, nil
+}
+
+// Add enqueues an element, blocking only while the queue is full. The element is copied.
+// An Add blocked when Close is called returns ErrFilterClosed.
+func (b *BufferedFilter) Add(data []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrFilterClosed
+	}
+	select {
+	case b.items <- queuedItem{data: append([]byte(nil), data...), at: time.Now()}:
+		return nil
+	case <-b.stopping:
+		return ErrFilterClosed
+	}
+}
+
+// Exists checks if an element exists in Redis; queued elements are not seen until written
+func (b *BufferedFilter) Exists(data []byte) (bool, error) {
+	return b.filter.Exists(data)
+}
+
+// Queued returns the number of items waiting to be written
+func (b *BufferedFilter) Queued() int {
+	return len(b.items) + int(b.batched.Load())
+}
+
+// QueueAge returns how long the oldest item waiting to be written has been queued, or
+// zero if the queue is empty
+func (b *BufferedFilter) QueueAge() time.Duration {
+	oldest := b.oldest.Load()
+	if oldest == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, oldest))
+}
+
+// Pause stops the background writes, e.g. while Redis is failed over or migrated. Add
+// keeps enqueuing until the queue is full and then blocks; Flush, Drain and Close still
+// write. Pause returns once a write in progress has finished, so no batch is written
+// until Resume.
+func (b *BufferedFilter) Pause() {
+	b.setPaused(true)
+}
+
+// Resume restarts the background writes and writes the batch held while paused
+func (b *BufferedFilter) Resume() {
+	b.setPaused(false)
+}
+
+// setPaused hands the paused state to the worker; it does nothing once the worker stopped
+func (b *BufferedFilter) setPaused(paused bool) {
+	select {
+	case b.pauses <- paused:
+	case <-b.done:
+	}
+}
+
+// Flush writes the items queued so far and returns the first write error
+func (b *BufferedFilter) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case b.flushes <- reply:
+	case <-b.done:
+		return ErrFilterClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drain writes the queue, also while paused, until it is empty and returns the first
+// write error. Items added meanwhile are written too, so stop the producers first or
+// bound the wait with ctx.
+func (b *BufferedFilter) Drain(ctx context.Context) error {
+	var first error
+	for {
+		err := b.Flush(ctx)
+		if first == nil {
+			first = err
+		}
+		if err == ErrFilterClosed || ctx.Err() != nil || b.Queued() == 0 {
+			return first
+		}
+	}
+}
+
+// Close stops accepting items, writes the queue, also while paused, and stops the worker. It returns the
+// first error of the final writes, or ctx's error if they do not finish in time; the
+// worker then keeps writing in the background.
+func (b *BufferedFilter) Close(ctx context.Context) error {
+	// Blocked Adds hold mu for reading, so release them before taking it
+	b.stopOnce.Do(func() { close(b.stopping) })
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	err := b.Flush(ctx)
+	close(b.closing)
+	select {
+	case <-b.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run collects queued items into batches and writes them
+func (b *BufferedFilter) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	var batch [][]byte
+	paused := false
+	for {
+		// While paused, the worker holds at most one batch and leaves the rest queued
+		items := b.items
+		if paused && len(batch) >= b.maxBatch {
+			items = nil
+		}
+		select {
+		case item := <-items:
+			batch = b.hold(batch, item)
+			if len(batch) >= b.maxBatch && !paused {
+				batch, _ = b.write(batch)
+			}
+		case <-ticker.C:
+			if !paused {
+				batch, _ = b.write(batch)
+			}
+			b.reportQueue(paused)
+		case paused = <-b.pauses:
+			if !paused {
+				batch, _ = b.write(batch)
+			}
+			b.reportQueue(paused)
+		case reply := <-b.flushes:
+			var err error
+			batch, err = b.drain(batch)
+			reply <- err
+		case <-b.closing:
+			b.drain(batch)
+			return
+		}
+	}
+}
+
+// hold adds a dequeued item to batch
+func (b *BufferedFilter) hold(batch [][]byte, item queuedItem) [][]byte {
+	if len(batch) == 0 {
+		b.oldest.Store(item.at.UnixNano())
+	}
+	b.batched.Add(1)
+	return append(batch, item.data)
+}
+
+// reportQueue exports the queue depth and age and whether writes are paused
+func (b *BufferedFilter) reportQueue(paused bool) {
+	b.filter.metrics.SetGauge(MetricWriteBehindQueued, float64(b.Queued()))
+	b.filter.metrics.SetGauge(MetricWriteBehindQueueAge, b.QueueAge().Seconds())
+	value := 0.0
+	if paused {
+		value = 1
+	}
+	b.filter.metrics.SetGauge(MetricWriteBehindPaused, value)
+}
+
+// drain writes batch and every item queued, and returns the first write error
+func (b *BufferedFilter) drain(batch [][]byte) ([][]byte, error) {
+	var first error
+	for n := len(b.items); n > 0; n-- {
+		batch = b.hold(batch, <-b.items)
+		if len(batch) >= b.maxBatch {
+			var err error
+			if batch, err = b.write(batch); first == nil {
+				first = err
+			}
+		}
+	}
+	batch, err := b.write(batch)
+	if first == nil {
+		first = err
+	}
+	return batch, first
+}
+
+// write adds a batch and returns an empty batch to fill next
+func (b *BufferedFilter) write(batch [][]byte) ([][]byte, error) {
+	if len(batch) == 0 {
+		return batch, nil
+	}
+	err := b.filter.addItems(context.Background(), batch)
+	b.batched.Store(0)
+	b.oldest.Store(0)
+	if err != nil {
+		b.filter.metrics.IncCounter(MetricWriteBehindErrors, 1)
+		if b.onError != nil {
+			b.onError(batch, err)
+		}
+		// The callback may keep the failed batch
+		return nil, err
+	}
+	return batch[:0], nil
+}
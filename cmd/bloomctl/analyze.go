@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/devptyagi/redis-bloom-go/bloom"
+)
+
+// runAnalyze reports how a hash strategy distributes a sample of real keys
+func runAnalyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	keysPath := fs.String("keys", "-", "file with one sample key per line (- for stdin)")
+	n := fs.Uint64("n", 1_000_000, "expected insertions of the simulated filter")
+	p := fs.Float64("p", 0.01, "false positive rate of the simulated filter")
+	hash := fs.String("hash", bloom.HashXXHash, "hash strategy to analyze")
+	buckets := fs.Int("buckets", 1024, "number of buckets for the chi-square distribution test")
+	fs.Parse(args)
+
+	keys, err := readKeys(*keysPath)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.New("no sample keys provided")
+	}
+	strategy, err := bloom.NewHashStrategy(*hash)
+	if err != nil {
+		return err
+	}
+
+	report, err := bloom.AnalyzeHashStrategy(strategy, keys, bloom.AnalyzeOptions{
+		ExpectedInsertions: *n,
+		FalsePositiveRate:  *p,
+		Buckets:            *buckets,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("strategy:                 %s\n", *hash)
+	fmt.Printf("sample keys:              %d\n", report.Keys)
+	fmt.Printf("filter:                   %d bits, %d hashes\n", report.BitSize, report.HashCount)
+	fmt.Printf("chi-square / df:          %.1f / %d = %.3f\n", report.ChiSquare, report.DegreesOfFreedom, report.NormalizedChiSquare())
+	fmt.Printf("h1/h2 correlation:        %.4f\n", report.HashCorrelation)
+	fmt.Printf("identical position sets:  %d\n", report.IdenticalPositionSets)
+	fmt.Printf("fill ratio:               %.6f (ideal %.6f)\n", report.FillRatio, report.ExpectedFillRatio)
+	fmt.Printf("false positive rate:      %.6g (ideal %.6g)\n", report.ProjectedFalsePositiveRate, report.IdealFalsePositiveRate)
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/devptyagi/redis-bloom-go/bloom"
+)
+
+// runBenchHash benchmarks every registered hash strategy on a sample of keys
+func runBenchHash(args []string) error {
+	fs := flag.NewFlagSet("bench-hash", flag.ExitOnError)
+	keysPath := fs.String("keys", "-", "file with one sample key per line (- for stdin)")
+	n := fs.Uint64("n", 1_000_000, "expected insertions of the simulated filter")
+	p := fs.Float64("p", 0.01, "false positive rate of the simulated filter")
+	buckets := fs.Int("buckets", 1024, "number of buckets for the chi-square distribution test")
+	rounds := fs.Int("rounds", 3, "passes over the sample for the throughput measurement")
+	fs.Parse(args)
+
+	keys, err := readKeys(*keysPath)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.New("no sample keys provided")
+	}
+
+	opts := bloom.HashBenchmarkOptions{
+		ExpectedInsertions: *n,
+		FalsePositiveRate:  *p,
+		Buckets:            *buckets,
+		Rounds:             *rounds,
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tOPS/SEC\tALLOCS/OP\tBYTES/OP\tCHI-SQUARE\tDF\tCHI2/DF")
+	for _, name := range bloom.HashStrategyNames() {
+		strategy, err := bloom.NewHashStrategy(name)
+		if err != nil {
+			return err
+		}
+		result, err := bloom.BenchmarkHashStrategy(strategy, keys, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%.0f\t%.1f\t%.1f\t%.1f\t%d\t%.3f\n",
+			name, result.OpsPerSecond, result.AllocsPerOp, result.BytesPerOp,
+			result.ChiSquare, result.DegreesOfFreedom, result.NormalizedChiSquare())
+	}
+	return w.Flush()
+}
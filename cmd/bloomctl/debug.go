@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/devptyagi/redis-bloom-go/bloom"
+)
+
+// bitLayouts maps layout flag values to bit layouts
+var bitLayouts = map[string]bloom.BitLayout{
+	"msb":  bloom.BitLayoutMSBFirst,
+	"lsb":  bloom.BitLayoutLSBFirst,
+	"be64": bloom.BitLayoutBigEndian64,
+}
+
+// runDebug dispatches the debug subcommands
+func runDebug(args []string) error {
+	if len(args) == 0 || args[0] != "item" {
+		return errors.New(`usage: bloomctl debug item [flags] <item>`)
+	}
+	return runDebugItem(args[1:])
+}
+
+// runDebugItem prints the positions of an item and the current value of each bit
+func runDebugItem(args []string) error {
+	fs := flag.NewFlagSet("debug item", flag.ExitOnError)
+	conn := addRedisFlags(fs)
+	key := fs.String("key", "", "Redis key of the filter")
+	n := fs.Uint64("n", 0, "expected insertions the filter was created with; checked against the recorded size")
+	p := fs.Float64("p", 0, "false positive rate the filter was created with; checked against the recorded size")
+	hash := fs.String("hash", "", "hash strategy of the filter; checked against the recorded one")
+	layout := fs.String("layout", "msb", "bit layout of the filter (msb, lsb or be64)")
+	blocked := fs.Bool("blocked", false, "the filter uses the blocked layout")
+	partitioned := fs.Bool("partitioned", false, "the filter uses the partitioned layout")
+	seed := fs.Uint64("seed", 0, "hash seed of the filter")
+	salt := fs.String("salt", "", "hash salt of the filter")
+	keySeeded := fs.Bool("key-seeded", false, "the filter derives its seed from the key")
+	encoding := fs.String("encoding", bloom.EncoderString, "encoder used to turn the item into bytes")
+	fs.Parse(args)
+
+	if *key == "" || fs.NArg() != 1 {
+		return errors.New("-key and exactly one item are required")
+	}
+	if (*n == 0) != (*p == 0) {
+		return errors.New("-n and -p must be given together")
+	}
+	var strategy bloom.HashStrategy
+	if *hash != "" {
+		var err error
+		if strategy, err = bloom.NewHashStrategy(*hash); err != nil {
+			return err
+		}
+	}
+	bitLayout, ok := bitLayouts[*layout]
+	if !ok {
+		return fmt.Errorf("unknown layout %q", *layout)
+	}
+	item, err := bloom.EncodeText(*encoding, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	client, adapter := conn.connect()
+	defer client.Close()
+
+	bf, err := openDebugFilter(context.Background(), bloom.Config{
+		RedisKey:           *key,
+		RedisClient:        adapter,
+		ExpectedInsertions: *n,
+		FalsePositiveRate:  *p,
+		HashStrategy:       strategy,
+		BitLayout:          bitLayout,
+		Blocked:            *blocked,
+		Partitioned:        *partitioned,
+		Seed:               *seed,
+		Salt:               *salt,
+		KeySeeded:          *keySeeded,
+	})
+	if err != nil {
+		return err
+	}
+	explanation, err := bf.ExistsExplain(item)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("key: %s (slot %d)\n", explanation.Key, explanation.Slot)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "HASH\tPOSITION\tOFFSET\tBIT")
+	for i, bit := range explanation.Bits {
+		value := 0
+		if bit.Set {
+			value = 1
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\n", i, bit.Position, bit.Offset, value)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("exists: %v (%d of %d bits unset, read in %s)\n",
+		explanation.Exists, len(explanation.Unset()), len(explanation.Bits), explanation.Duration)
+	return nil
+}
+
+// openDebugFilter opens the filter with the bit size, hash count and hash strategy
+// recorded in its metadata. Those in cfg only override them: a set hash strategy or
+// sizing must match the recorded parameters, and a filter without metadata needs the
+// sizing. Layout and seed options are not recorded and are taken from cfg.
+func openDebugFilter(ctx context.Context, cfg bloom.Config) (bloom.BloomFilter, error) {
+	sized := cfg.ExpectedInsertions > 0
+	opened := cfg
+	opened.ExpectedInsertions, opened.FalsePositiveRate = 0, 0
+	bf, err := bloom.OpenBloomFilter(ctx, opened)
+	switch {
+	case errors.Is(err, bloom.ErrFilterNotFound) && sized:
+		return bloom.NewBloomFilter(cfg)
+	case errors.Is(err, bloom.ErrFilterNotFound):
+		return nil, fmt.Errorf("%w; pass -n and -p for filters written without metadata", err)
+	case err != nil || !sized:
+		return bf, err
+	}
+
+	// NewBloomFilter reports a sizing that does not lead to the recorded parameters
+	if cfg.HashStrategy == nil {
+		spec, err := filterSpec(bf)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.HashStrategy, err = bloom.NewHashStrategy(spec.Hash.Strategy); err != nil {
+			return nil, err
+		}
+	}
+	return bloom.NewBloomFilter(cfg)
+}
+
+// filterSpec decodes the descriptor of filter
+func filterSpec(filter bloom.BloomFilter) (*bloom.FilterSpec, error) {
+	raw, err := filter.ExportSpec()
+	if err != nil {
+		return nil, err
+	}
+	var spec bloom.FilterSpec
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
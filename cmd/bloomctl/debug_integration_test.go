@@ -0,0 +1,109 @@
+//go:build integration
+// +build integration
+
+// NOTE: These tests are designed to run inside a Docker container on the same Docker Compose network as the Redis services.
+// Use service names as hostnames (e.g., 'redis', 'redis-cluster') and internal ports (6379, 7000-7005).
+
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/devptyagi/redis-bloom-go/bloom"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDebugWithRealRedis(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     "redis:6379",
+		Password: "",
+		DB:       0,
+	})
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skipf("Redis not available, skipping integration test: %v", err)
+	}
+	defer client.Close()
+	redisClient := bloom.NewSingleNodeRedisClient(client)
+
+	key := "integration:debug:item"
+	client.Del(ctx, key, "{"+key+"}:meta")
+	defer client.Del(ctx, key, "{"+key+"}:meta")
+	created, err := bloom.NewBloomFilter(bloom.Config{
+		RedisKey:           key,
+		RedisClient:        redisClient,
+		ExpectedInsertions: 5000,
+		FalsePositiveRate:  0.001,
+		HashStrategy:       bloom.NewMurmur3Strategy(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Bloom Filter: %v", err)
+	}
+	item := []byte("debugged")
+	if err := created.Add(item); err != nil {
+		t.Fatalf("Failed to add element: %v", err)
+	}
+
+	t.Run("RecordedParameters", func(t *testing.T) {
+		bf, err := openDebugFilter(ctx, bloom.Config{RedisKey: key, RedisClient: redisClient})
+		if err != nil {
+			t.Fatalf("Failed to open filter: %v", err)
+		}
+		if got, want := bf.Positions(item), created.Positions(item); !reflect.DeepEqual(got, want) {
+			t.Errorf("Positions = %v, want the creator's %v", got, want)
+		}
+		explanation, err := bf.ExistsExplain(item)
+		if err != nil || !explanation.Exists {
+			t.Errorf("Expected the item to be found, got %+v, %v", explanation, err)
+		}
+	})
+
+	t.Run("MatchingOverrides", func(t *testing.T) {
+		_, err := openDebugFilter(ctx, bloom.Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 5000,
+			FalsePositiveRate:  0.001,
+		})
+		if err != nil {
+			t.Errorf("Expected the creator's sizing to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("MismatchingOverrides", func(t *testing.T) {
+		_, err := openDebugFilter(ctx, bloom.Config{
+			RedisKey:           key,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1_000_000,
+			FalsePositiveRate:  0.01,
+		})
+		if !errors.Is(err, bloom.ErrParameterMismatch) {
+			t.Errorf("Expected a parameter mismatch for another sizing, got %v", err)
+		}
+		_, err = openDebugFilter(ctx, bloom.Config{RedisKey: key, RedisClient: redisClient, HashStrategy: bloom.NewXXHashStrategy()})
+		if !errors.Is(err, bloom.ErrParameterMismatch) {
+			t.Errorf("Expected a parameter mismatch for another hash strategy, got %v", err)
+		}
+	})
+
+	t.Run("WithoutMetadata", func(t *testing.T) {
+		legacy := "integration:debug:legacy"
+		client.Del(ctx, "{"+legacy+"}:meta")
+		client.SetBit(ctx, legacy, 1, 1)
+		defer client.Del(ctx, legacy)
+		if _, err := openDebugFilter(ctx, bloom.Config{RedisKey: legacy, RedisClient: redisClient}); !errors.Is(err, bloom.ErrFilterNotFound) {
+			t.Errorf("Expected ErrFilterNotFound without metadata or sizing, got %v", err)
+		}
+		if _, err := openDebugFilter(ctx, bloom.Config{
+			RedisKey:           legacy,
+			RedisClient:        redisClient,
+			ExpectedInsertions: 1000,
+			FalsePositiveRate:  0.01,
+		}); err != nil {
+			t.Errorf("Expected the sizing flags to open a filter without metadata, got %v", err)
+		}
+	})
+}
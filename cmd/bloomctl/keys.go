@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// readKeys loads one key per line from path, or from stdin when path is "-"
+func readKeys(path string) ([][]byte, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var keys [][]byte
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		keys = append(keys, append([]byte(nil), line...))
+	}
+	return keys, scanner.Err()
+}
@@ -0,0 +1,63 @@
+// Command bloomctl provides operational tooling for Redis-backed Bloom filters
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// command is a bloomctl subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists all subcommands in the order they are shown in the usage text
+var commands = []command{
+	{"analyze", "Check a hash strategy's distribution and projected FPR on sample keys", runAnalyze},
+	{"bench-hash", "Benchmark every registered hash strategy on a sample of keys", runBenchHash},
+	{"serve", "Serve BF.* commands over the Redis protocol for RedisBloom clients", runServe},
+	{"debug", "Show the positions and bit values of an item (debug item)", runDebug},
+	{"rebuild-audit", "Replay an audit stream into a fresh filter with new parameters", runRebuildAudit},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "bloomctl %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "bloomctl: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// usage prints the list of subcommands
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: bloomctl <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, `Run "bloomctl <command> -h" for the flags of a command.`)
+}
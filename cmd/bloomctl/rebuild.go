@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/devptyagi/redis-bloom-go/bloom"
+)
+
+// runRebuildAudit replays an audit stream into a fresh filter with new parameters
+func runRebuildAudit(args []string) error {
+	fs := flag.NewFlagSet("rebuild-audit", flag.ExitOnError)
+	conn := addRedisFlags(fs)
+	stream := fs.String("stream", "", "audit stream to replay")
+	key := fs.String("key", "", "Redis key of the rebuilt filter")
+	n := fs.Uint64("n", 1_000_000, "expected insertions of the rebuilt filter")
+	p := fs.Float64("p", 0.01, "false positive rate of the rebuilt filter")
+	hash := fs.String("hash", bloom.HashXXHash, "hash strategy of the rebuilt filter")
+	ttl := fs.Duration("ttl", 0, "TTL of the rebuilt filter (0 for none)")
+	fs.Parse(args)
+
+	if *stream == "" || *key == "" {
+		return errors.New("-stream and -key are required")
+	}
+	strategy, err := bloom.NewHashStrategy(*hash)
+	if err != nil {
+		return err
+	}
+
+	client, adapter := conn.connect()
+	defer client.Close()
+
+	_, err = bloom.RebuildFromAudit(context.Background(), *stream, bloom.Config{
+		RedisKey:           *key,
+		RedisClient:        adapter,
+		ExpectedInsertions: *n,
+		FalsePositiveRate:  *p,
+		TTL:                *ttl,
+		HashStrategy:       strategy,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("rebuilt %s from %s\n", *key, *stream)
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/devptyagi/redis-bloom-go/bloom"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisFlags holds the connection flags shared by subcommands that talk to Redis
+type redisFlags struct {
+	addrs    *string
+	password *string
+	db       *int
+}
+
+// addRedisFlags registers the connection flags on fs
+func addRedisFlags(fs *flag.FlagSet) *redisFlags {
+	return &redisFlags{
+		addrs:    fs.String("addr", "localhost:6379", "Redis address; a comma-separated list connects to a cluster"),
+		password: fs.String("password", "", "Redis password"),
+		db:       fs.Int("db", 0, "Redis database (single node only)"),
+	}
+}
+
+// connect opens a client and wraps it in the matching adapter
+func (f *redisFlags) connect() (redis.UniversalClient, bloom.RedisClient) {
+	addrs := strings.Split(*f.addrs, ",")
+	if len(addrs) > 1 {
+		client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: addrs, Password: *f.password})
+		return client, bloom.NewClusterRedisClient(client)
+	}
+	client := redis.NewClient(&redis.Options{Addr: addrs[0], Password: *f.password, DB: *f.db})
+	return client, bloom.NewSingleNodeRedisClient(client)
+}
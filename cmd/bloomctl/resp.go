@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Limits on client requests, matching Redis defaults
+const (
+	maxRequestArgs = 1024 * 1024
+	maxBulkLength  = 512 * 1024 * 1024
+)
+
+// errProtocol is returned for requests that are not valid RESP
+var errProtocol = errors.New("protocol error")
+
+// respReader reads client requests: RESP arrays of bulk strings, or inline commands
+type respReader struct {
+	r *bufio.Reader
+}
+
+// readCommand reads the next request; an empty request is returned as nil
+func (rr *respReader) readCommand() ([][]byte, error) {
+	line, err := rr.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		// Inline commands, as typed into telnet or nc
+		fields := strings.Fields(string(line))
+		args := make([][]byte, len(fields))
+		for i, f := range fields {
+			args[i] = []byte(f)
+		}
+		return args, nil
+	}
+
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n > maxRequestArgs {
+		return nil, errProtocol
+	}
+	args := make([][]byte, 0, max(n, 0))
+	for i := 0; i < n; i++ {
+		line, err := rr.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol
+		}
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil || size < 0 || size > maxBulkLength {
+			return nil, errProtocol
+		}
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(rr.r, arg); err != nil {
+			return nil, err
+		}
+		if arg[size] != '\r' || arg[size+1] != '\n' {
+			return nil, errProtocol
+		}
+		args = append(args, arg[:size])
+	}
+	return args, nil
+}
+
+// readLine reads a CRLF-terminated line without the terminator
+func (rr *respReader) readLine() ([]byte, error) {
+	line, err := rr.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, errProtocol
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimRight(string(line), "\r\n")), nil
+}
+
+// respWriter writes RESP2 replies
+type respWriter struct {
+	w *bufio.Writer
+}
+
+// simple writes a simple string reply
+func (rw *respWriter) simple(s string) {
+	fmt.Fprintf(rw.w, "+%s\r\n", s)
+}
+
+// error writes an error reply; messages without an error code get the generic ERR code
+func (rw *respWriter) error(msg string) {
+	if code, _, _ := strings.Cut(msg, " "); code == "" || code != strings.ToUpper(code) {
+		msg = "ERR " + msg
+	}
+	fmt.Fprintf(rw.w, "-%s\r\n", strings.ReplaceAll(msg, "\r\n", " "))
+}
+
+// integer writes an integer reply
+func (rw *respWriter) integer(n int64) {
+	fmt.Fprintf(rw.w, ":%d\r\n", n)
+}
+
+// bulk writes a bulk string reply
+func (rw *respWriter) bulk(s string) {
+	fmt.Fprintf(rw.w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+// array writes the header of an array reply with n elements
+func (rw *respWriter) array(n int) {
+	fmt.Fprintf(rw.w, "*%d\r\n", n)
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/devptyagi/redis-bloom-go/bloom"
+)
+
+// Error replies, worded like those of RedisBloom
+const (
+	errWrongArgs    = "wrong number of arguments for '%s' command"
+	errItemExists   = "item exists"
+	errBadCapacity  = "(capacity should be larger than 0)"
+	errBadErrorRate = "(0 < error rate range < 1)"
+	errNotFound     = "not found"
+)
+
+// servedFilter is a filter together with the parameters reported by BF.INFO
+type servedFilter struct {
+	filter   bloom.BloomFilter
+	capacity uint64
+	size     int64
+	// inserted counts the items this process added that were not present before
+	inserted atomic.Int64
+	// mu serializes the test and add of in-memory filters, which lack TestAndAdd
+	mu sync.Mutex
+}
+
+// bloomServer answers BF.* commands from filters of this package
+type bloomServer struct {
+	client bloom.RedisClient
+	// memory is set when client keeps the bitmaps in process memory, so every filter
+	// is one this server created
+	memory   bool
+	capacity uint64
+	rate     float64
+	strategy string
+
+	mu      sync.Mutex
+	filters map[string]*servedFilter
+
+	// conns holds the open connections, so a shutdown can wait for them
+	connMu sync.Mutex
+	conns  map[net.Conn]struct{}
+	active sync.WaitGroup
+}
+
+// runServe serves the RedisBloom commands over the Redis protocol
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	conn := addRedisFlags(fs)
+	listen := fs.String("listen", "localhost:6380", "address to accept RESP connections on")
+	memory := fs.Bool("memory", false, "keep filters in process memory instead of Redis")
+	n := fs.Uint64("n", 100, "capacity of filters created implicitly by BF.ADD and BF.MADD")
+	p := fs.Float64("p", 0.01, "error rate of filters created implicitly by BF.ADD and BF.MADD")
+	hash := fs.String("hash", bloom.HashXXHash, "hash strategy of served filters")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 10*time.Second, "how long to wait for open connections on SIGINT or SIGTERM")
+	fs.Parse(args)
+
+	if _, err := bloom.NewHashStrategy(*hash); err != nil {
+		return err
+	}
+	srv := &bloomServer{capacity: *n, rate: *p, strategy: *hash, filters: make(map[string]*servedFilter)}
+	if *memory {
+		srv.client, srv.memory = bloom.NewMemoryRedisClient(), true
+	} else {
+		client, adapter := conn.connect()
+		defer client.Close()
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			return err
+		}
+		srv.client = adapter
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	log.Printf("serving BF.* commands on %s", ln.Addr())
+	err = srv.serve(ln)
+	// The deferred Close of the Redis client runs once the connections are done
+	srv.shutdown(*shutdownTimeout)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// serve accepts connections until the listener is closed
+func (s *bloomServer) serve(ln net.Listener) error {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		s.connMu.Lock()
+		if s.conns == nil {
+			s.conns = make(map[net.Conn]struct{})
+		}
+		s.conns[c] = struct{}{}
+		s.active.Add(1)
+		s.connMu.Unlock()
+		go func() {
+			defer func() {
+				s.connMu.Lock()
+				delete(s.conns, c)
+				s.connMu.Unlock()
+				s.active.Done()
+			}()
+			s.serveConn(c)
+		}()
+	}
+}
+
+// shutdown lets the open connections finish the commands they have received and waits
+// up to timeout for them to close; connections still open then are closed. Idle
+// connections are closed right away, as their next read fails.
+func (s *bloomServer) shutdown(timeout time.Duration) {
+	s.connMu.Lock()
+	log.Printf("shutting down, waiting for %d connections", len(s.conns))
+	for c := range s.conns {
+		c.SetReadDeadline(time.Now())
+	}
+	s.connMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		s.connMu.Lock()
+		log.Printf("closing %d connections still busy after %s", len(s.conns), timeout)
+		for c := range s.conns {
+			c.Close()
+		}
+		s.connMu.Unlock()
+	}
+}
+
+// serveConn answers the requests of one connection, flushing replies once the
+// pipelined requests already received have been handled
+func (s *bloomServer) serveConn(c net.Conn) {
+	defer c.Close()
+	r := &respReader{r: bufio.NewReader(c)}
+	w := &respWriter{w: bufio.NewWriter(c)}
+	for {
+		args, err := r.readCommand()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				w.error("Protocol error: " + err.Error())
+				w.w.Flush()
+			}
+			return
+		}
+		if len(args) > 0 {
+			if quit := s.dispatch(w, args); quit {
+				w.w.Flush()
+				return
+			}
+		}
+		if r.r.Buffered() == 0 {
+			if err := w.w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// dispatch runs one command and reports whether the connection should be closed
+func (s *bloomServer) dispatch(w *respWriter, args [][]byte) bool {
+	name := strings.ToUpper(string(args[0]))
+	switch name {
+	case "PING":
+		if len(args) > 1 {
+			w.bulk(string(args[1]))
+		} else {
+			w.simple("PONG")
+		}
+	case "QUIT":
+		w.simple("OK")
+		return true
+	case "BF.RESERVE":
+		s.reserve(w, args)
+	case "BF.ADD":
+		if len(args) != 3 {
+			w.error(fmt.Sprintf(errWrongArgs, strings.ToLower(name)))
+			return false
+		}
+		s.add(w, string(args[1]), args[2:])
+	case "BF.MADD":
+		if len(args) < 3 {
+			w.error(fmt.Sprintf(errWrongArgs, strings.ToLower(name)))
+			return false
+		}
+		w.array(len(args) - 2)
+		s.add(w, string(args[1]), args[2:])
+	case "BF.EXISTS":
+		if len(args) != 3 {
+			w.error(fmt.Sprintf(errWrongArgs, strings.ToLower(name)))
+			return false
+		}
+		s.exists(w, string(args[1]), args[2:])
+	case "BF.MEXISTS":
+		if len(args) < 3 {
+			w.error(fmt.Sprintf(errWrongArgs, strings.ToLower(name)))
+			return false
+		}
+		w.array(len(args) - 2)
+		s.exists(w, string(args[1]), args[2:])
+	case "BF.INFO":
+		s.info(w, args)
+	default:
+		w.error(fmt.Sprintf("unknown command '%s'", args[0]))
+	}
+	return false
+}
+
+// reserve handles BF.RESERVE key error_rate capacity
+func (s *bloomServer) reserve(w *respWriter, args [][]byte) {
+	if len(args) < 4 {
+		w.error(fmt.Sprintf(errWrongArgs, "bf.reserve"))
+		return
+	}
+	rate, err := strconv.ParseFloat(string(args[2]), 64)
+	if err != nil || rate <= 0 || rate >= 1 {
+		w.error(errBadErrorRate)
+		return
+	}
+	capacity, err := strconv.ParseUint(string(args[3]), 10, 64)
+	if err != nil || capacity == 0 {
+		w.error(errBadCapacity)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := string(args[1])
+	if _, ok := s.filters[key]; ok {
+		w.error(errItemExists)
+		return
+	}
+	f, err := s.createFilter(key, capacity, rate)
+	if err != nil {
+		w.error(replyError(err))
+		return
+	}
+	s.filters[key] = f
+	w.simple("OK")
+}
+
+// add adds items, replying 1 for each item that was not present before and 0 otherwise
+func (s *bloomServer) add(w *respWriter, key string, items [][]byte) {
+	f, err := s.filter(key, true)
+	if err != nil {
+		w.error(replyError(err))
+		return
+	}
+	for _, item := range items {
+		present, err := s.testAndAdd(f, item)
+		switch {
+		case err != nil:
+			w.error(err.Error())
+		case present:
+			w.integer(0)
+		default:
+			f.inserted.Add(1)
+			w.integer(1)
+		}
+	}
+}
+
+// testAndAdd adds item and reports whether it was present before. Filters in Redis use
+// the atomic TestAndAdd, so of concurrent clients adding the same item exactly one is
+// told it was new; in-memory filters are only written by this process and test and add
+// under the filter's lock.
+func (s *bloomServer) testAndAdd(f *servedFilter, item []byte) (bool, error) {
+	if !s.memory {
+		return f.filter.TestAndAdd(item)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	present, err := f.filter.Exists(item)
+	if err != nil || present {
+		return present, err
+	}
+	return false, f.filter.Add(item)
+}
+
+// exists replies 1 for each item that may be present and 0 otherwise. As in RedisBloom,
+// every item of a missing filter is absent.
+func (s *bloomServer) exists(w *respWriter, key string, items [][]byte) {
+	f, err := s.filter(key, false)
+	found := make([]bool, len(items))
+	if err == nil {
+		found, err = f.filter.ExistsMany(items)
+	} else if errors.Is(err, bloom.ErrFilterNotFound) {
+		err = nil
+	}
+	for i := range items {
+		switch {
+		case err != nil:
+			w.error(replyError(err))
+		case found[i]:
+			w.integer(1)
+		default:
+			w.integer(0)
+		}
+	}
+}
+
+// info handles BF.INFO key [CAPACITY | SIZE | FILTERS | ITEMS | EXPANSION]
+func (s *bloomServer) info(w *respWriter, args [][]byte) {
+	if len(args) != 2 && len(args) != 3 {
+		w.error(fmt.Sprintf(errWrongArgs, "bf.info"))
+		return
+	}
+	f, err := s.filter(string(args[1]), false)
+	if err != nil {
+		w.error(replyError(err))
+		return
+	}
+	fields := []struct {
+		option string
+		name   string
+		value  int64
+	}{
+		{"CAPACITY", "Capacity", int64(f.capacity)},
+		{"SIZE", "Size", f.size},
+		{"FILTERS", "Number of filters", 1},
+		{"ITEMS", "Number of items inserted", f.inserted.Load()},
+		{"EXPANSION", "Expansion rate", 0},
+	}
+
+	if len(args) == 3 {
+		option := strings.ToUpper(string(args[2]))
+		for _, field := range fields {
+			if field.option == option {
+				w.array(1)
+				w.integer(field.value)
+				return
+			}
+		}
+		w.error("Invalid information value")
+		return
+	}
+	w.array(2 * len(fields))
+	for _, field := range fields {
+		w.simple(field.name)
+		w.integer(field.value)
+	}
+}
+
+// filter returns the filter stored under key. A filter the server has not seen is opened
+// with the parameters recorded in Redis, so filters reserved by an earlier run or another
+// server keep theirs; a missing one is created with the default parameters if create is
+// set and reported as bloom.ErrFilterNotFound otherwise.
+func (s *bloomServer) filter(key string, create bool) (*servedFilter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.filters[key]; ok {
+		return f, nil
+	}
+	f, err := s.openFilter(key)
+	if errors.Is(err, bloom.ErrFilterNotFound) && create {
+		f, err = s.newFilter(key, s.capacity, s.rate)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.filters[key] = f
+	return f, nil
+}
+
+// openFilter opens the filter recorded under key
+func (s *bloomServer) openFilter(key string) (*servedFilter, error) {
+	if s.memory {
+		return nil, bloom.ErrFilterNotFound
+	}
+	filter, err := bloom.OpenBloomFilter(context.Background(), bloom.Config{RedisKey: key, RedisClient: s.client})
+	if err != nil {
+		return nil, err
+	}
+	return newServedFilter(filter, 0)
+}
+
+// createFilter creates the filter for BF.RESERVE. In Redis it fails with
+// bloom.ErrFilterExists if the key or its metadata already exists, so bitmaps written
+// before are not taken over with other parameters.
+func (s *bloomServer) createFilter(key string, capacity uint64, rate float64) (*servedFilter, error) {
+	if s.memory {
+		return s.newFilter(key, capacity, rate)
+	}
+	cfg, err := s.config(key, capacity, rate)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := bloom.CreateBloomFilter(context.Background(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newServedFilter(filter, capacity)
+}
+
+// newFilter builds a filter for key
+func (s *bloomServer) newFilter(key string, capacity uint64, rate float64) (*servedFilter, error) {
+	cfg, err := s.config(key, capacity, rate)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := bloom.NewBloomFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newServedFilter(filter, capacity)
+}
+
+// config returns the configuration of a filter for key
+func (s *bloomServer) config(key string, capacity uint64, rate float64) (bloom.Config, error) {
+	strategy, err := bloom.NewHashStrategy(s.strategy)
+	if err != nil {
+		return bloom.Config{}, err
+	}
+	return bloom.Config{
+		RedisKey:           key,
+		RedisClient:        s.client,
+		ExpectedInsertions: capacity,
+		FalsePositiveRate:  rate,
+		HashStrategy:       strategy,
+	}, nil
+}
+
+// newServedFilter reads the size of filter from its descriptor; a zero capacity is
+// derived from the bit and hash counts
+func newServedFilter(filter bloom.BloomFilter, capacity uint64) (*servedFilter, error) {
+	spec, err := filterSpec(filter)
+	if err != nil {
+		return nil, err
+	}
+	if capacity == 0 {
+		capacity = uint64(math.Round(float64(spec.Bits) * math.Ln2 / float64(spec.Hashes)))
+	}
+	return &servedFilter{filter: filter, capacity: capacity, size: int64((spec.Bits + 7) / 8)}, nil
+}
+
+// replyError words err like the RedisBloom reply for the same condition
+func replyError(err error) string {
+	switch {
+	case errors.Is(err, bloom.ErrFilterNotFound):
+		return errNotFound
+	case errors.Is(err, bloom.ErrFilterExists):
+		return errItemExists
+	}
+	return err.Error()
+}
@@ -0,0 +1,245 @@
+//go:build integration
+// +build integration
+
+// NOTE: These tests are designed to run inside a Docker container on the same Docker Compose network as the Redis services.
+// Use service names as hostnames (e.g., 'redis', 'redis-cluster') and internal ports (6379, 7000-7005).
+
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devptyagi/redis-bloom-go/bloom"
+	"github.com/redis/go-redis/v9"
+)
+
+// startServer serves srv on a local port and returns a client connected to it
+func startServer(t *testing.T, srv *bloomServer) *redis.Client {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go srv.serve(ln)
+	client := redis.NewClient(&redis.Options{Addr: ln.Addr().String()})
+	t.Cleanup(func() {
+		client.Close()
+		ln.Close()
+	})
+	return client
+}
+
+// newTestServer returns a server with the default parameters of runServe
+func newTestServer(client bloom.RedisClient, memory bool) *bloomServer {
+	return &bloomServer{
+		client:   client,
+		memory:   memory,
+		capacity: 100,
+		rate:     0.01,
+		strategy: bloom.HashXXHash,
+		filters:  make(map[string]*servedFilter),
+	}
+}
+
+func TestServeWithRealRedis(t *testing.T) {
+	upstream := redis.NewClient(&redis.Options{
+		Addr:     "redis:6379",
+		Password: "",
+		DB:       0,
+	})
+	ctx := context.Background()
+	if err := upstream.Ping(ctx).Err(); err != nil {
+		t.Skipf("Redis not available, skipping integration test: %v", err)
+	}
+	defer upstream.Close()
+	redisClient := bloom.NewSingleNodeRedisClient(upstream)
+
+	// Filters keep their parameters in the companion key {key}:meta
+	cleanup := func(keys ...string) {
+		for _, key := range keys {
+			upstream.Del(ctx, key, "{"+key+"}:meta")
+		}
+	}
+
+	t.Run("AddAndExists", func(t *testing.T) {
+		key := "integration:serve:add"
+		cleanup(key)
+		defer cleanup(key)
+		client := startServer(t, newTestServer(redisClient, false))
+
+		added, err := client.Do(ctx, "BF.ADD", key, "alice").Int()
+		if err != nil || added != 1 {
+			t.Fatalf("BF.ADD of a new item = %d, %v; want 1", added, err)
+		}
+		if added, _ := client.Do(ctx, "BF.ADD", key, "alice").Int(); added != 0 {
+			t.Errorf("BF.ADD of a present item = %d, want 0", added)
+		}
+		found, err := client.Do(ctx, "BF.MEXISTS", key, "alice", "bob").Int64Slice()
+		if err != nil {
+			t.Fatalf("BF.MEXISTS failed: %v", err)
+		}
+		if found[0] != 1 || found[1] != 0 {
+			t.Errorf("BF.MEXISTS = %v, want [1 0]", found)
+		}
+		items, err := client.Do(ctx, "BF.INFO", key, "ITEMS").Int64Slice()
+		if err != nil || items[0] != 1 {
+			t.Errorf("BF.INFO ITEMS = %v, %v; want [1]", items, err)
+		}
+	})
+
+	t.Run("ConcurrentAddCountsOnce", func(t *testing.T) {
+		key := "integration:serve:concurrent"
+		cleanup(key)
+		defer cleanup(key)
+		client := startServer(t, newTestServer(redisClient, false))
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		newItems := 0
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				added, err := client.Do(ctx, "BF.ADD", key, "shared").Int()
+				if err != nil {
+					t.Errorf("BF.ADD failed: %v", err)
+					return
+				}
+				mu.Lock()
+				newItems += added
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		if newItems != 1 {
+			t.Errorf("%d concurrent BF.ADD calls answered 1, want exactly one", newItems)
+		}
+		items, _ := client.Do(ctx, "BF.INFO", key, "ITEMS").Int64Slice()
+		if len(items) != 1 || items[0] != 1 {
+			t.Errorf("BF.INFO ITEMS = %v, want [1]", items)
+		}
+	})
+
+	t.Run("MissingFilterNotCreated", func(t *testing.T) {
+		key := "integration:serve:missing"
+		cleanup(key)
+		defer cleanup(key)
+		client := startServer(t, newTestServer(redisClient, false))
+
+		// Like RedisBloom, reads answer 0 for a missing filter, and only BF.INFO fails
+		if found, err := client.Do(ctx, "BF.EXISTS", key, "alice").Int(); err != nil || found != 0 {
+			t.Errorf("BF.EXISTS on a missing filter = %d, %v; want 0", found, err)
+		}
+		found, err := client.Do(ctx, "BF.MEXISTS", key, "alice", "bob").Int64Slice()
+		if err != nil || len(found) != 2 || found[0] != 0 || found[1] != 0 {
+			t.Errorf("BF.MEXISTS on a missing filter = %v, %v; want [0 0]", found, err)
+		}
+		if err := client.Do(ctx, "BF.INFO", key).Err(); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("BF.INFO on a missing filter = %v, want ERR not found", err)
+		}
+		if n := upstream.Exists(ctx, key, "{"+key+"}:meta").Val(); n != 0 {
+			t.Errorf("Reads created %d keys, want none", n)
+		}
+	})
+
+	t.Run("ReserveExistingKey", func(t *testing.T) {
+		key := "integration:serve:reserve"
+		cleanup(key)
+		defer cleanup(key)
+		// A bitmap written without this server's knowledge
+		if err := upstream.SetBit(ctx, key, 3, 1).Err(); err != nil {
+			t.Fatalf("Failed to write bitmap: %v", err)
+		}
+		client := startServer(t, newTestServer(redisClient, false))
+
+		err := client.Do(ctx, "BF.RESERVE", key, "0.01", "1000").Err()
+		if err == nil || !strings.Contains(err.Error(), "item exists") {
+			t.Errorf("BF.RESERVE over an existing key = %v, want ERR item exists", err)
+		}
+
+		cleanup(key)
+		if err := client.Do(ctx, "BF.RESERVE", key, "0.001", "5000").Err(); err != nil {
+			t.Fatalf("BF.RESERVE failed: %v", err)
+		}
+		if err := client.Do(ctx, "BF.RESERVE", key, "0.001", "5000").Err(); err == nil || !strings.Contains(err.Error(), "item exists") {
+			t.Errorf("Second BF.RESERVE = %v, want ERR item exists", err)
+		}
+	})
+
+	t.Run("ReservedParametersSurviveRestart", func(t *testing.T) {
+		key := "integration:serve:restart"
+		cleanup(key)
+		defer cleanup(key)
+		first := startServer(t, newTestServer(redisClient, false))
+		if err := first.Do(ctx, "BF.RESERVE", key, "0.001", "5000").Err(); err != nil {
+			t.Fatalf("BF.RESERVE failed: %v", err)
+		}
+		if err := first.Do(ctx, "BF.ADD", key, "alice").Err(); err != nil {
+			t.Fatalf("BF.ADD failed: %v", err)
+		}
+		size, _ := first.Do(ctx, "BF.INFO", key, "SIZE").Int64Slice()
+
+		second := startServer(t, newTestServer(redisClient, false))
+		if found, _ := second.Do(ctx, "BF.EXISTS", key, "alice").Int(); found != 1 {
+			t.Errorf("BF.EXISTS after a restart = %d, want 1", found)
+		}
+		reopened, err := second.Do(ctx, "BF.INFO", key, "SIZE").Int64Slice()
+		if err != nil || len(size) != 1 || len(reopened) != 1 || reopened[0] != size[0] {
+			t.Errorf("BF.INFO SIZE after a restart = %v, %v; want %v", reopened, err, size)
+		}
+	})
+
+	t.Run("GracefulShutdown", func(t *testing.T) {
+		key := "integration:serve:shutdown"
+		cleanup(key)
+		defer cleanup(key)
+		srv := newTestServer(redisClient, false)
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		served := make(chan error, 1)
+		go func() { served <- srv.serve(ln) }()
+		client := redis.NewClient(&redis.Options{Addr: ln.Addr().String(), MaxRetries: -1})
+		defer client.Close()
+		if err := client.Do(ctx, "BF.ADD", key, "alice").Err(); err != nil {
+			t.Fatalf("BF.ADD failed: %v", err)
+		}
+
+		ln.Close()
+		<-served
+		start := time.Now()
+		srv.shutdown(5 * time.Second)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Shutdown waited %s for an idle connection", elapsed)
+		}
+		if n := len(srv.conns); n != 0 {
+			t.Errorf("%d connections still open after shutdown", n)
+		}
+		if err := client.Do(ctx, "BF.EXISTS", key, "alice").Err(); err == nil {
+			t.Error("Expected the server to refuse commands after shutdown")
+		}
+	})
+
+	t.Run("Memory", func(t *testing.T) {
+		client := startServer(t, newTestServer(bloom.NewMemoryRedisClient(), true))
+		if found, err := client.Do(ctx, "BF.EXISTS", "users", "alice").Int(); err != nil || found != 0 {
+			t.Errorf("BF.EXISTS on a missing filter = %d, %v; want 0", found, err)
+		}
+		if err := client.Do(ctx, "BF.RESERVE", "users", "0.01", "1000").Err(); err != nil {
+			t.Fatalf("BF.RESERVE failed: %v", err)
+		}
+		added, err := client.Do(ctx, "BF.MADD", "users", "alice", "alice").Int64Slice()
+		if err != nil || added[0] != 1 || added[1] != 0 {
+			t.Errorf("BF.MADD = %v, %v; want [1 0]", added, err)
+		}
+		capacity, _ := client.Do(ctx, "BF.INFO", "users", "CAPACITY").Int64Slice()
+		if len(capacity) != 1 || capacity[0] != 1000 {
+			t.Errorf("BF.INFO CAPACITY = %v, want [1000]", capacity)
+		}
+	})
+}
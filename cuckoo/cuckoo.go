@@ -0,0 +1,320 @@
+// Package cuckoo implements a cuckoo filter stored in Redis. Unlike a Bloom filter, a
+// cuckoo filter supports deleting items, and at false-positive rates below about 3% it
+// needs less memory.
+package cuckoo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/devptyagi/redis-bloom-go/bloom"
+	"github.com/redis/go-redis/v9"
+)
+
+// Filter defaults and limits
+const (
+	defaultFingerprintBits = 16
+	defaultBucketSize      = 4
+	defaultMaxKicks        = 500
+	maxBucketSize          = 8
+	// loadFactor is the share of slots a filter can fill before inserts start to fail
+	loadFactor = 0.95
+	// maxSizeBytes is the largest string Redis stores
+	maxSizeBytes = 512 << 20
+	// altMultiplier mixes a fingerprint into the offset of its alternate bucket
+	altMultiplier = 0x5bd1e995
+)
+
+// Errors returned by cuckoo filters
+var (
+	ErrFilterFull        = errors.New("cuckoo filter is full")
+	ErrInvalidBucketSize = errors.New("bucket size must be between 1 and 8")
+	ErrCapacityTooLarge  = errors.New("cuckoo filter would exceed the 512 MiB Redis string limit")
+)
+
+// Config holds the configuration of a cuckoo filter
+type Config struct {
+	RedisKey string
+	// RedisClient must expose the full command set (bloom.CmdableProvider)
+	RedisClient bloom.RedisClient
+	// Capacity is the number of items the filter is sized for
+	Capacity uint64
+	// FingerprintBits is 8 (false-positive rate about 3%) or 16 (about 0.012%, the default)
+	FingerprintBits int
+	// BucketSize is the number of fingerprints per bucket (defaults to 4)
+	BucketSize int
+	// MaxKicks is the number of relocations an insert tries before the filter counts as
+	// full (defaults to 500)
+	MaxKicks int
+	TTL      time.Duration
+}
+
+// Filter is a cuckoo filter stored in a Redis string. Each item is reduced to a
+// fingerprint kept in one of two candidate buckets; inserts that find both full relocate
+// existing fingerprints to their other bucket. Fingerprints are stored big-endian, one
+// after another, so slot s of bucket b is the BITFIELD field u<bits> #(b*BucketSize+s).
+type Filter struct {
+	client     redis.Cmdable
+	key        string
+	width      int
+	bucketSize int
+	buckets    uint64
+	maxKicks   int
+	ttl        time.Duration
+}
+
+// bucketScript defines the storage helpers shared by the scripts. ARGV[1] is the number
+// of buckets, ARGV[2] the bucket size and ARGV[3] the fingerprint width in bytes.
+const bucketScript = `
+local key = KEYS[1]
+local n, b, w = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+
+local function readBucket(bucket)
+	local start = bucket * b * w
+	local s = redis.call('GETRANGE', key, start, start + b * w - 1)
+	local slots = {}
+	for i = 0, b - 1 do
+		local v = 0
+		for k = 1, w do
+			v = v * 256 + (string.byte(s, i * w + k) or 0)
+		end
+		slots[i] = v
+	end
+	return slots
+end
+
+local function write(bucket, slot, v)
+	local bytes = {}
+	for k = w, 1, -1 do
+		bytes[k] = v % 256
+		v = math.floor(v / 256)
+	end
+	redis.call('SETRANGE', key, (bucket * b + slot) * w, string.char(unpack(bytes)))
+end
+
+local function place(bucket, v)
+	local slots = readBucket(bucket)
+	for i = 0, b - 1 do
+		if slots[i] == 0 then
+			write(bucket, i, v)
+			return true
+		end
+	end
+	return false
+end
+
+-- 1540483477 is altMultiplier
+local function alt(bucket, v)
+	return ((v * 1540483477) % n - bucket + n) % n
+end
+`
+
+// insertScript stores fingerprint ARGV[6] in bucket ARGV[4] or ARGV[5], relocating up to
+// ARGV[7] fingerprints. It returns 1 on success; on failure it restores every relocated
+// fingerprint and returns 0. A positive TTL in milliseconds in ARGV[8] is applied on success.
+var insertScript = redis.NewScript(bucketScript + `
+local i1, i2, fp = tonumber(ARGV[4]), tonumber(ARGV[5]), tonumber(ARGV[6])
+local maxKicks, ttl = tonumber(ARGV[7]), tonumber(ARGV[8])
+
+local function done()
+	if ttl > 0 then
+		redis.call('PEXPIRE', key, ttl)
+	end
+	return 1
+end
+
+if place(i1, fp) or place(i2, fp) then
+	return done()
+end
+
+local undo = {}
+local bucket, v = i1, fp
+if math.random(2) == 2 then
+	bucket = i2
+end
+for k = 1, maxKicks do
+	local slot = math.random(b) - 1
+	local victim = readBucket(bucket)[slot]
+	write(bucket, slot, v)
+	undo[#undo + 1] = {bucket, slot, victim}
+	v = victim
+	bucket = alt(bucket, v)
+	if place(bucket, v) then
+		return done()
+	end
+end
+for k = #undo, 1, -1 do
+	write(undo[k][1], undo[k][2], undo[k][3])
+end
+return 0
+`)
+
+// deleteScript removes one copy of fingerprint ARGV[6] from bucket ARGV[4] or ARGV[5] and
+// returns 1, or 0 if neither bucket holds it
+var deleteScript = redis.NewScript(bucketScript + `
+local fp = tonumber(ARGV[6])
+for _, bucket in ipairs({tonumber(ARGV[4]), tonumber(ARGV[5])}) do
+	local slots = readBucket(bucket)
+	for i = 0, b - 1 do
+		if slots[i] == fp then
+			write(bucket, i, 0)
+			return 1
+		end
+	end
+end
+return 0
+`)
+
+// New creates a cuckoo filter. Nothing is written until the first Add.
+func New(cfg Config) (*Filter, error) {
+	if cfg.RedisKey == "" {
+		return nil, bloom.ErrEmptyRedisKey
+	}
+	if cfg.RedisClient == nil {
+		return nil, bloom.ErrNilRedisClient
+	}
+	provider, ok := cfg.RedisClient.(bloom.CmdableProvider)
+	if !ok {
+		return nil, bloom.ErrCommandsUnsupported
+	}
+	if cfg.Capacity == 0 {
+		return nil, bloom.ErrInvalidExpectedInsertions
+	}
+
+	f := &Filter{
+		client:     provider.Cmdable(),
+		key:        cfg.RedisKey,
+		bucketSize: cfg.BucketSize,
+		maxKicks:   cfg.MaxKicks,
+		ttl:        cfg.TTL,
+	}
+	switch cfg.FingerprintBits {
+	case 0:
+		f.width = defaultFingerprintBits / 8
+	case 8, 16:
+		f.width = cfg.FingerprintBits / 8
+	default:
+		return nil, bloom.ErrInvalidFingerprintBits
+	}
+	if f.bucketSize == 0 {
+		f.bucketSize = defaultBucketSize
+	}
+	if f.bucketSize < 1 || f.bucketSize > maxBucketSize {
+		return nil, ErrInvalidBucketSize
+	}
+	if f.maxKicks <= 0 {
+		f.maxKicks = defaultMaxKicks
+	}
+	f.buckets = uint64(math.Ceil(float64(cfg.Capacity) / float64(f.bucketSize) / loadFactor))
+	if f.SizeBytes() > maxSizeBytes {
+		return nil, ErrCapacityTooLarge
+	}
+	return f, nil
+}
+
+// Add inserts an element. Adding an element twice stores two copies, which takes two
+// Deletes to remove. It returns ErrFilterFull if no slot could be freed for the element;
+// the filter is left unchanged in that case.
+func (f *Filter) Add(data []byte) error {
+	fp, i1, i2 := f.locate(data)
+	inserted, err := insertScript.Run(context.Background(), f.client, []string{f.key},
+		f.args(fp, i1, i2, f.maxKicks, f.ttl.Milliseconds())...).Int()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return ErrFilterFull
+	}
+	return nil
+}
+
+// Exists checks if an element may be in the filter, reading both candidate buckets in
+// one pipeline
+func (f *Filter) Exists(data []byte) (bool, error) {
+	ctx := context.Background()
+	fp, i1, i2 := f.locate(data)
+	size := int64(f.bucketSize * f.width)
+
+	pipe := f.client.Pipeline()
+	reads := [2]*redis.StringCmd{}
+	for i, bucket := range [2]uint64{i1, i2} {
+		start := int64(bucket) * size
+		reads[i] = pipe.GetRange(ctx, f.key, start, start+size-1)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+
+	for _, read := range reads {
+		bucket := []byte(read.Val())
+		for slot := 0; slot < f.bucketSize; slot++ {
+			if f.slot(bucket, slot) == fp {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Delete removes one copy of an element and reports whether one was found. Only delete
+// elements that were added: deleting an element that merely shares a fingerprint and
+// bucket with another removes that other element instead.
+func (f *Filter) Delete(data []byte) (bool, error) {
+	fp, i1, i2 := f.locate(data)
+	deleted, err := deleteScript.Run(context.Background(), f.client, []string{f.key},
+		f.args(fp, i1, i2)...).Int()
+	if err != nil {
+		return false, err
+	}
+	return deleted == 1, nil
+}
+
+// SizeBytes returns the size of the filter in Redis once every bucket has been written
+func (f *Filter) SizeBytes() int64 {
+	return int64(f.buckets) * int64(f.bucketSize*f.width)
+}
+
+// locate returns the fingerprint of an element and its two candidate buckets
+func (f *Filter) locate(data []byte) (fp, i1, i2 uint64) {
+	hash := xxhash.Sum64(data)
+	fp = hash & (1<<(8*f.width) - 1)
+	if fp == 0 {
+		// Zero marks an empty slot
+		fp = 1
+	}
+	i1 = (hash >> 32) % f.buckets
+	return fp, i1, f.alt(i1, fp)
+}
+
+// alt returns the other candidate bucket of a fingerprint stored in bucket i. Applying it
+// twice returns i, so a relocated fingerprint can always find its way back.
+func (f *Filter) alt(i, fp uint64) uint64 {
+	return (fp*altMultiplier%f.buckets + f.buckets - i) % f.buckets
+}
+
+// slot decodes the fingerprint in a slot of a bucket read from Redis; missing bytes past
+// the end of the string are empty slots
+func (f *Filter) slot(bucket []byte, slot int) uint64 {
+	var v uint64
+	for k := 0; k < f.width; k++ {
+		v <<= 8
+		if i := slot*f.width + k; i < len(bucket) {
+			v |= uint64(bucket[i])
+		}
+	}
+	return v
+}
+
+// args builds the script arguments: the storage geometry, the element's buckets and
+// fingerprint, then extra
+func (f *Filter) args(fp, i1, i2 uint64, extra ...interface{}) []interface{} {
+	args := []interface{}{
+		strconv.FormatUint(f.buckets, 10), f.bucketSize, f.width,
+		strconv.FormatUint(i1, 10), strconv.FormatUint(i2, 10), strconv.FormatUint(fp, 10),
+	}
+	return append(args, extra...)
+}
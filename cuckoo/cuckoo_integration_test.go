@@ -0,0 +1,168 @@
+//go:build integration
+// +build integration
+
+// NOTE: These tests are designed to run inside a Docker container on the same Docker Compose network as the Redis services.
+// Use service names as hostnames (e.g., 'redis', 'redis-cluster') and internal ports (6379, 7000-7005).
+
+package cuckoo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/devptyagi/redis-bloom-go/bloom"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCuckooWithRealRedis(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     "redis:6379",
+		Password: "",
+		DB:       0,
+	})
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skipf("Redis not available, skipping integration test: %v", err)
+	}
+	defer client.Close()
+	redisClient := bloom.NewSingleNodeRedisClient(client)
+
+	t.Run("AddExistsDelete", func(t *testing.T) {
+		key := "integration:cuckoo:basic"
+		client.Del(ctx, key)
+		defer client.Del(ctx, key)
+		f, err := New(Config{RedisKey: key, RedisClient: redisClient, Capacity: 1000, TTL: time.Hour})
+		if err != nil {
+			t.Fatalf("Failed to create cuckoo filter: %v", err)
+		}
+		items := make([][]byte, 1000)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("cuckoo-%d", i))
+			if err := f.Add(items[i]); err != nil {
+				t.Fatalf("Failed to add %q: %v", items[i], err)
+			}
+		}
+		for _, data := range items {
+			if exists, err := f.Exists(data); err != nil || !exists {
+				t.Fatalf("%q should exist (exists=%v, err=%v)", data, exists, err)
+			}
+		}
+		// 16-bit fingerprints give a false-positive rate of about 0.012%
+		falsePositives := 0
+		for i := 0; i < 10000; i++ {
+			if exists, _ := f.Exists([]byte(fmt.Sprintf("cuckoo-absent-%d", i))); exists {
+				falsePositives++
+			}
+		}
+		if falsePositives > 5 {
+			t.Errorf("Expected about 1 false positive in 10000, got %d", falsePositives)
+		}
+
+		for _, data := range items[:500] {
+			if deleted, err := f.Delete(data); err != nil || !deleted {
+				t.Fatalf("Failed to delete %q (deleted=%v, err=%v)", data, deleted, err)
+			}
+		}
+		stillPresent := 0
+		for _, data := range items[:500] {
+			if exists, _ := f.Exists(data); exists {
+				stillPresent++
+			}
+		}
+		if stillPresent > 1 {
+			t.Errorf("%d deleted items still reported present", stillPresent)
+		}
+		for _, data := range items[500:] {
+			if exists, err := f.Exists(data); err != nil || !exists {
+				t.Fatalf("Deleting other items removed %q (exists=%v, err=%v)", data, exists, err)
+			}
+		}
+		if deleted, err := f.Delete([]byte("never-added")); err != nil || deleted {
+			t.Errorf("Delete of a missing item = %v, %v; want false", deleted, err)
+		}
+		if n := client.StrLen(ctx, key).Val(); n > f.SizeBytes() {
+			t.Errorf("Stored %d bytes, more than the %d of SizeBytes", n, f.SizeBytes())
+		}
+		if ttl := client.PTTL(ctx, key).Val(); ttl <= 0 || ttl > time.Hour {
+			t.Errorf("Expected the TTL to be applied, got %s", ttl)
+		}
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		key := "integration:cuckoo:duplicates"
+		client.Del(ctx, key)
+		defer client.Del(ctx, key)
+		f, err := New(Config{RedisKey: key, RedisClient: redisClient, Capacity: 100, FingerprintBits: 8})
+		if err != nil {
+			t.Fatalf("Failed to create cuckoo filter: %v", err)
+		}
+		data := []byte("twice")
+		for i := 0; i < 2; i++ {
+			if err := f.Add(data); err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+		}
+		// Each copy takes its own Delete
+		for i, want := range []bool{true, false} {
+			if deleted, err := f.Delete(data); err != nil || !deleted {
+				t.Fatalf("Delete %d failed (deleted=%v, err=%v)", i+1, deleted, err)
+			}
+			if exists, _ := f.Exists(data); exists != want {
+				t.Errorf("After %d deletes exists=%v, want %v", i+1, exists, want)
+			}
+		}
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		key := "integration:cuckoo:full"
+		client.Del(ctx, key)
+		defer client.Del(ctx, key)
+		f, err := New(Config{RedisKey: key, RedisClient: redisClient, Capacity: 16, BucketSize: 2, MaxKicks: 50})
+		if err != nil {
+			t.Fatalf("Failed to create cuckoo filter: %v", err)
+		}
+		var added [][]byte
+		for i := 0; i < 100; i++ {
+			data := []byte(fmt.Sprintf("full-%d", i))
+			before := client.Get(ctx, key).Val()
+			err := f.Add(data)
+			if errors.Is(err, ErrFilterFull) {
+				// A failed insert restores every relocated fingerprint
+				if after := client.Get(ctx, key).Val(); after != before {
+					t.Error("A failed insert changed the filter")
+				}
+				for _, data := range added {
+					if exists, _ := f.Exists(data); !exists {
+						t.Errorf("%q lost after a failed insert", data)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to add element: %v", err)
+			}
+			added = append(added, data)
+		}
+		t.Errorf("Expected ErrFilterFull after %d inserts into a filter sized for 16", len(added))
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, tc := range []struct {
+			cfg  Config
+			want error
+		}{
+			{Config{RedisClient: redisClient, Capacity: 10}, bloom.ErrEmptyRedisKey},
+			{Config{RedisKey: "k", RedisClient: redisClient}, bloom.ErrInvalidExpectedInsertions},
+			{Config{RedisKey: "k", RedisClient: redisClient, Capacity: 10, FingerprintBits: 12}, bloom.ErrInvalidFingerprintBits},
+			{Config{RedisKey: "k", RedisClient: redisClient, Capacity: 10, BucketSize: 9}, ErrInvalidBucketSize},
+			{Config{RedisKey: "k", RedisClient: redisClient, Capacity: 1 << 40}, ErrCapacityTooLarge},
+		} {
+			if _, err := New(tc.cfg); !errors.Is(err, tc.want) {
+				t.Errorf("New(%+v) = %v, want %v", tc.cfg, err, tc.want)
+			}
+		}
+	})
+}
@@ -76,7 +76,7 @@ test_single_node() {
     
     # Run integration tests
     print_status "Running integration tests..."
-    if go test -tags=integration -v ./bloom; then
+    if go test -tags=integration -v ./...; then
         print_success "Integration tests passed!"
     else
         print_error "Integration tests failed!"